@@ -0,0 +1,116 @@
+// Command authz-setup bootstraps OpenFGA for the authz gate: it finds or
+// creates the store, writes the bundled authorization model, and prints the
+// store ID to stdout, or to OPENFGA_STORE_ID_FILE when that is set.
+//
+// If OPENFGA_STORE_ID is set, that store is used as-is. Otherwise the store
+// named OPENFGA_STORE_NAME (default "saas-starter") is reused or created, so
+// the command is safe to run on every deploy.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"saas-authz/internal/authz"
+	"saas-authz/internal/config"
+)
+
+func main() {
+	modelFile := flag.String("model", "", "write this JSON model instead of the bundled one")
+	waitFor := flag.Duration("wait", 60*time.Second, "how long to wait for OpenFGA to become ready")
+	flag.Parse()
+
+	// Logs go to stderr so stdout carries only the store ID
+	log.SetOutput(os.Stderr)
+
+	cfg := config.Load()
+	storeName := getEnv("OPENFGA_STORE_NAME", "saas-starter")
+	storeIDFile := os.Getenv("OPENFGA_STORE_ID_FILE")
+
+	model := authz.BundledModel
+	if *modelFile != "" {
+		data, err := os.ReadFile(*modelFile)
+		if err != nil {
+			log.Fatalf("Failed to read model: %v", err)
+		}
+		model = string(data)
+	}
+
+	if err := waitForOpenFGA(cfg.OpenFGAURL, *waitFor); err != nil {
+		log.Fatalf("OpenFGA not ready: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client := authz.NewClient(cfg.OpenFGAURL, cfg.OpenFGAStoreID, "", false)
+
+	storeID := cfg.OpenFGAStoreID
+	if storeID == "" {
+		var err error
+		storeID, err = client.FindStore(ctx, storeName)
+		if err != nil {
+			log.Fatalf("Failed to look up store: %v", err)
+		}
+		if storeID == "" {
+			storeID, err = client.CreateStore(ctx, storeName)
+			if err != nil {
+				log.Fatalf("Failed to create store: %v", err)
+			}
+			log.Printf("Created store %q: %s", storeName, storeID)
+		} else {
+			log.Printf("Using existing store %q: %s", storeName, storeID)
+		}
+		client.UseStore(storeID)
+	}
+
+	modelID, err := client.WriteAuthorizationModel(ctx, model)
+	if err != nil {
+		log.Fatalf("Failed to write authorization model: %v", err)
+	}
+	log.Printf("Wrote authorization model: %s", modelID)
+
+	if storeIDFile != "" {
+		if err := os.WriteFile(storeIDFile, []byte(storeID+"\n"), 0644); err != nil {
+			log.Fatalf("Failed to write store ID file: %v", err)
+		}
+		log.Printf("Wrote store ID to %s", storeIDFile)
+		return
+	}
+	fmt.Println(storeID)
+}
+
+// waitForOpenFGA polls the health endpoint until OpenFGA answers or the
+// timeout passes
+func waitForOpenFGA(baseURL string, timeout time.Duration) error {
+	healthURL := strings.TrimSuffix(baseURL, "/") + "/healthz"
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := http.Get(healthURL)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("health check returned %s", resp.Status)
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		log.Printf("Waiting for OpenFGA at %s...", baseURL)
+		time.Sleep(2 * time.Second)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
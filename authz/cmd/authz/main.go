@@ -2,80 +2,266 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
 	"time"
 
 	"saas-authz/internal/auth"
 	"saas-authz/internal/authz"
 	"saas-authz/internal/config"
 	"saas-authz/internal/handlers"
+	"saas-authz/internal/metrics"
+	"saas-authz/internal/monitor"
+	"saas-authz/internal/ratelimit"
+	"saas-authz/internal/requestid"
+	"saas-authz/internal/webhook"
 
 	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
 )
 
 func main() {
 	cfg := config.Load()
+	setupLogging(cfg.LogFormat)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
 	log.Printf("Starting AuthZ service on port %s", cfg.Port)
 	log.Printf("OpenFGA URL: %s", cfg.OpenFGAURL)
-	log.Printf("Dev mode: %v", cfg.DevMode)
+	log.Printf("Environment: %s", cfg.Environment)
+	if !cfg.FailClosed {
+		log.Printf("WARNING: FAIL_CLOSED=false - requests are allowed when the authorization check errors")
+	}
+	if cfg.DevMode {
+		log.Printf("WARNING: DEV_MODE is enabled - unauthenticated requests are granted platform admin access. Never use this outside local development!")
+	}
 
 	// Initialize JWT validator
-	var jwtValidator *auth.JWTValidator
-	if len(cfg.JWTSecret) > 0 {
-		jwtValidator = auth.NewJWTValidator(cfg.JWTSecret)
-		log.Printf("JWT validator initialized")
+	jwtValidator, err := auth.NewJWTValidatorFromConfig(cfg.JWTAlg, cfg.JWTSecret, cfg.JWTPublicKeyPath)
+	if err != nil {
+		log.Fatalf("Invalid JWT configuration: %v", err)
+	}
+	if jwtValidator != nil {
+		jwtValidator.WithIssuerAudience(cfg.JWTIssuer, cfg.JWTAudience)
+		log.Printf("JWT validator initialized (%s, iss=%q, aud=%q)", jwtValidator.Alg(), cfg.JWTIssuer, cfg.JWTAudience)
 	} else {
 		log.Printf("Warning: JWT secret not configured")
 	}
 
 	// Initialize API key validator
-	var apiKeyValidator *auth.APIKeyValidator
+	var apiKeys handlers.APIKeys
 	if cfg.DatabaseURL != "" && len(cfg.APIKeySecret) > 0 {
-		var err error
-		apiKeyValidator, err = auth.NewAPIKeyValidator(cfg.DatabaseURL, cfg.APIKeySecret)
+		apiKeyValidator, err := auth.NewAPIKeyValidator(cfg.DatabaseURL, cfg.APIKeySecret)
 		if err != nil {
 			log.Printf("Warning: Failed to initialize API key validator: %v", err)
 		} else {
+			apiKeys = apiKeyValidator
 			log.Printf("API key validator initialized")
 		}
 	} else {
 		log.Printf("Warning: API key validation not configured")
 	}
 
+	// Initialize token revocation checks (logout, disabled users)
+	var revocations *auth.RevocationList
+	if cfg.DatabaseURL != "" && jwtValidator != nil {
+		revocations, err = auth.NewRevocationList(cfg.DatabaseURL, cfg.RevocationCacheTTL)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize token revocation checks: %v", err)
+		} else {
+			log.Printf("Token revocation checks enabled (cache TTL %s)", cfg.RevocationCacheTTL)
+		}
+	} else {
+		log.Printf("Warning: Token revocation checks not configured")
+	}
+
+	// Audit impersonation tokens on first use; without it they are rejected
+	var impersonations *auth.ImpersonationAudit
+	if cfg.DatabaseURL != "" && jwtValidator != nil {
+		impersonations, err = auth.NewImpersonationAudit(cfg.DatabaseURL)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize impersonation audit, impersonation tokens will be rejected: %v", err)
+		}
+	}
+
+	// Confirm workspaces belong to the caller's tenant before applying
+	// tenant roles; without it tenant admins don't inherit workspace rights
+	var workspaces handlers.WorkspaceTenants
+	if cfg.DatabaseURL != "" {
+		directory, err := auth.NewWorkspaceDirectory(cfg.DatabaseURL)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize workspace lookups, tenant roles won't apply to workspaces: %v", err)
+		} else {
+			workspaces = directory
+		}
+	}
+
 	// Initialize OpenFGA client
-	openfgaClient := authz.NewClient(cfg.OpenFGAURL, cfg.OpenFGAStoreID, cfg.DevMode)
+	openfgaClient := authz.NewClient(cfg.OpenFGAURL, cfg.OpenFGAStoreID, cfg.OpenFGAModelID, cfg.DevMode)
+	openfgaClient.EnableCheckCache(cfg.CheckCacheTTL, cfg.CheckCacheSize)
+	openfgaClient.SetRetryPolicy(cfg.OpenFGAMaxAttempts, cfg.OpenFGARetryBaseDelay)
+	if cfg.CheckCacheTTL > 0 && cfg.CheckCacheSize > 0 {
+		log.Printf("OpenFGA check cache enabled: ttl=%s size=%d", cfg.CheckCacheTTL, cfg.CheckCacheSize)
+	}
 	if !cfg.DevMode && cfg.OpenFGAStoreID != "" {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		if err := openfgaClient.Initialize(ctx); err != nil {
+			// A wrong pin is a config error; don't silently run without a model
+			if errors.Is(err, authz.ErrPinnedModelNotFound) {
+				log.Fatalf("Invalid OPENFGA_MODEL_ID: %v", err)
+			}
 			log.Printf("Warning: Failed to initialize OpenFGA client: %v", err)
+		} else if cfg.OpenFGAModelID != "" {
+			log.Printf("OpenFGA client initialized with pinned model %s", cfg.OpenFGAModelID)
 		} else {
-			log.Printf("OpenFGA client initialized")
+			log.Printf("OpenFGA client initialized with latest model")
+		}
+		if cfg.OpenFGAModelID == "" && cfg.OpenFGAModelRefreshInterval > 0 {
+			openfgaClient.StartModelRefresh(cfg.OpenFGAModelRefreshInterval)
+			log.Printf("OpenFGA model refresh enabled: interval=%s", cfg.OpenFGAModelRefreshInterval)
 		}
 	}
 
+	// Initialize denial spike alerting
+	var denialMonitor *monitor.DenialMonitor
+	if cfg.DenialAlertWebhookURL != "" {
+		notifier := webhook.NewNotifier(cfg.DenialAlertWebhookURL, cfg.WebhookSecret)
+		denialMonitor = monitor.NewDenialMonitor(notifier, cfg.DenialAlertThreshold, cfg.DenialAlertWindow, cfg.DenialAlertCooldown)
+		log.Printf("Denial spike alerting enabled: threshold=%d window=%s cooldown=%s",
+			cfg.DenialAlertThreshold, cfg.DenialAlertWindow, cfg.DenialAlertCooldown)
+	}
+
+	// Initialize per-tenant rate limits (shared via Postgres when available)
+	var tenantLimiter *ratelimit.TenantLimiter
+	if cfg.TenantRateLimitEnabled {
+		limits := map[string]int{
+			"basic":      cfg.TenantRateLimitBasic,
+			"advanced":   cfg.TenantRateLimitAdvanced,
+			"enterprise": cfg.TenantRateLimitEnterprise,
+		}
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		var plans ratelimit.PlanLookup
+		if cfg.DatabaseURL != "" {
+			pgStore, err := ratelimit.NewPostgresStore(cfg.DatabaseURL)
+			if err != nil {
+				log.Printf("Warning: Failed to initialize shared rate limit store, using in-memory counters: %v", err)
+			} else {
+				store = pgStore
+				plans = pgStore.TenantPlan
+			}
+		}
+		tenantLimiter = ratelimit.NewTenantLimiter(store, plans, limits, cfg.TenantRateLimitWindow)
+		log.Printf("Tenant rate limits enabled: basic=%d advanced=%d enterprise=%d per %s",
+			cfg.TenantRateLimitBasic, cfg.TenantRateLimitAdvanced, cfg.TenantRateLimitEnterprise, cfg.TenantRateLimitWindow)
+	}
+
+	// Load the public-route allowlist
+	publicRoutes, err := handlers.LoadPublicRoutes(cfg.PublicRoutes, cfg.PublicRoutesFile)
+	if err != nil {
+		log.Fatalf("Invalid public routes: %v", err)
+	}
+	log.Printf("Public routes: %d entries", len(publicRoutes))
+
+	// Load per-route relation rules
+	relationRules, err := handlers.LoadRelationRules(cfg.RelationRulesFile)
+	if err != nil {
+		log.Fatalf("Invalid relation rules: %v", err)
+	}
+	if len(relationRules) > 0 {
+		log.Printf("Relation rules: %d loaded from %s", len(relationRules), cfg.RelationRulesFile)
+	}
+
 	// Create handler
-	gateHandler := handlers.NewGateHandler(jwtValidator, apiKeyValidator, openfgaClient, cfg.DevMode)
+	gateHandler := handlers.NewGateHandler(handlers.GateOptions{
+		JWT:                     jwtValidator,
+		APIKey:                  apiKeys,
+		Revocations:             revocations,
+		Impersonations:          impersonations,
+		Authz:                   openfgaClient,
+		Denials:                 denialMonitor,
+		Limits:                  tenantLimiter,
+		Workspaces:              workspaces,
+		Public:                  publicRoutes,
+		Relations:               relationRules,
+		TokenCookie:             cfg.AuthCookieName,
+		DevMode:                 cfg.DevMode,
+		RequireForwardedHeaders: cfg.RequireForwardedHeaders,
+		FailClosed:              cfg.FailClosed,
+		DecisionHeader:          cfg.DecisionHeader,
+	})
 
 	// Setup Gin
 	if !cfg.DevMode {
 		gin.SetMode(gin.ReleaseMode)
 	}
-	r := gin.Default()
+	r := gin.New()
+	// c.ClientIP() only honours forwarding headers from trusted proxies, so
+	// clients can't spoof their address in the logs
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(requestid.Middleware(cfg.RequestIDHeader), requestid.Logger(), metrics.Middleware(), gin.Recovery())
 
-	// Health check
+	// Health check (liveness) and dependency readiness
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
+	databaseCheck := readinessCheck{name: "database"}
+	if cfg.DatabaseURL != "" {
+		// sql.Open doesn't connect; the pool dials on the first probe
+		readinessDB, err := sql.Open("postgres", cfg.DatabaseURL)
+		if err != nil {
+			log.Printf("Warning: Failed to set up database readiness check: %v", err)
+		} else {
+			databaseCheck.check = readinessDB.PingContext
+		}
+	}
+	r.GET("/ready", readyHandler(
+		databaseCheck,
+		readinessCheck{name: "openfga", check: openfgaClient.Ready},
+	))
 
 	// ForwardAuth endpoint
 	r.GET("/gate", gateHandler.Handle)
 	r.POST("/gate", gateHandler.Handle)
 
+	// Prometheus metrics, on their own port
+	if cfg.MetricsPort != "" {
+		go serveMetrics(cfg.MetricsPort)
+	}
+
 	// Start server
 	log.Printf("AuthZ service listening on :%s", cfg.Port)
 	if err := r.Run(":" + cfg.Port); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// serveMetrics exposes /metrics on port, separately from the gate so the
+// endpoint is only reachable from inside the network
+func serveMetrics(port string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	log.Printf("Metrics listening on :%s/metrics", port)
+	if err := http.ListenAndServe(":"+port, mux); err != nil {
+		log.Printf("Warning: Metrics server stopped: %v", err)
+	}
+}
+
+// setupLogging routes slog, and the standard log package through it, to
+// stdout in the given format
+func setupLogging(format string) {
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, nil)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, nil)
+	}
+	slog.SetDefault(slog.New(handler))
+}
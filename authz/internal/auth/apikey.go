@@ -9,7 +9,7 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 const KeyPrefix = "sk"
@@ -41,12 +41,29 @@ func NewAPIKeyValidator(databaseURL string, secret []byte) (*APIKeyValidator, er
 		return nil, err
 	}
 
+	if err := migrateAPIKeys(db); err != nil {
+		return nil, err
+	}
+
 	return &APIKeyValidator{
 		db:     db,
 		secret: secret,
 	}, nil
 }
 
+// migrateAPIKeys adds the multi-workspace scope columns to api_keys.
+// workspace_ids restricts a key to a set of workspaces; tenant_wide grants
+// every workspace in the key's tenant. Keys with only workspace_id set keep
+// their single-workspace behavior.
+func migrateAPIKeys(db *sql.DB) error {
+	_, err := db.Exec(`
+		ALTER TABLE IF EXISTS api_keys
+			ADD COLUMN IF NOT EXISTS workspace_ids TEXT[],
+			ADD COLUMN IF NOT EXISTS tenant_wide BOOLEAN NOT NULL DEFAULT FALSE
+	`)
+	return err
+}
+
 func (v *APIKeyValidator) Close() error {
 	if v.db != nil {
 		return v.db.Close()
@@ -117,36 +134,35 @@ func (v *APIKeyValidator) lookupKey(keyID string) (*Identity, string, error) {
 			ak.user_id,
 			ak.tenant_id,
 			ak.workspace_id,
+			ak.workspace_ids,
+			ak.tenant_wide,
 			ak.role,
 			ak.key_hash,
 			ak.revoked_at,
 			ak.expires_at,
 			u.email,
-			u.is_platform_admin
+			u.is_platform_admin,
+			u.disabled_at
 		FROM api_keys ak
 		LEFT JOIN users u ON ak.user_id = u.id
 		WHERE ak.key_id = $1
 	`
 
-	var userID, tenantID string
-	var workspaceID sql.NullString
-	var role string
-	var keyHash sql.NullString
-	var revokedAt sql.NullTime
-	var expiresAt sql.NullTime
-	var email sql.NullString
-	var isPlatformAdmin bool
-
+	var row keyRow
+	var isPlatformAdmin sql.NullBool
 	err := v.db.QueryRow(query, keyID).Scan(
-		&userID,
-		&tenantID,
-		&workspaceID,
-		&role,
-		&keyHash,
-		&revokedAt,
-		&expiresAt,
-		&email,
+		&row.userID,
+		&row.tenantID,
+		&row.workspaceID,
+		pq.Array(&row.workspaceIDs),
+		&row.tenantWide,
+		&row.role,
+		&row.keyHash,
+		&row.revokedAt,
+		&row.expiresAt,
+		&row.email,
 		&isPlatformAdmin,
+		&row.disabledAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -155,34 +171,79 @@ func (v *APIKeyValidator) lookupKey(keyID string) (*Identity, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	row.isPlatformAdmin = isPlatformAdmin.Bool
+
+	return row.identity(time.Now())
+}
 
-	if revokedAt.Valid {
+// keyRow is an API key joined with its user
+type keyRow struct {
+	userID, tenantID string
+	workspaceID      sql.NullString
+	workspaceIDs     []string
+	tenantWide       bool
+	role             string
+	keyHash          sql.NullString
+	revokedAt        sql.NullTime
+	expiresAt        sql.NullTime
+	email            sql.NullString
+	isPlatformAdmin  bool
+	disabledAt       sql.NullTime
+}
+
+// identity returns the key's identity and hash, or why it can't be used.
+// Disabled users' keys stop working at once, like their tokens; deleting an
+// account deletes its keys.
+func (r *keyRow) identity(now time.Time) (*Identity, string, error) {
+	if r.revokedAt.Valid {
 		return nil, "", ErrKeyRevoked
 	}
 
-	if expiresAt.Valid && expiresAt.Time.Before(time.Now()) {
+	if r.expiresAt.Valid && r.expiresAt.Time.Before(now) {
 		return nil, "", ErrKeyExpired
 	}
 
+	if r.disabledAt.Valid {
+		return nil, "", ErrUserDisabled
+	}
+
 	identity := &Identity{
-		UserID:          userID,
-		TenantID:        tenantID,
-		Role:            role,
-		IsPlatformAdmin: isPlatformAdmin,
+		UserID:          r.userID,
+		TenantID:        r.tenantID,
+		Role:            r.role,
+		IsPlatformAdmin: r.isPlatformAdmin,
 	}
 
-	if workspaceID.Valid {
-		identity.WorkspaceID = workspaceID.String
+	if r.tenantWide {
+		identity.AllWorkspaces = true
+	} else if len(r.workspaceIDs) > 0 {
+		identity.WorkspaceIDs = r.workspaceIDs
+	} else if r.workspaceID.Valid {
+		identity.WorkspaceID = r.workspaceID.String
 	}
 
-	if email.Valid {
-		identity.Email = email.String
+	if r.email.Valid {
+		identity.Email = r.email.String
 	}
 
 	hash := ""
-	if keyHash.Valid {
-		hash = keyHash.String
+	if r.keyHash.Valid {
+		hash = r.keyHash.String
 	}
 
 	return identity, hash, nil
 }
+
+// WorkspaceInTenant reports whether the workspace belongs to the tenant.
+// Used to authorize tenant-wide API keys.
+func (v *APIKeyValidator) WorkspaceInTenant(workspaceID, tenantID string) (bool, error) {
+	var exists bool
+	err := v.db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM workspaces WHERE id::text = $1 AND tenant_id::text = $2)`,
+		workspaceID, tenantID,
+	).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeyRowIdentity(t *testing.T) {
+	now := time.Now()
+	past := sql.NullTime{Time: now.Add(-time.Hour), Valid: true}
+	future := sql.NullTime{Time: now.Add(time.Hour), Valid: true}
+	key := func(modify func(*keyRow)) keyRow {
+		row := keyRow{
+			userID:      "user-1",
+			tenantID:    "tenant-1",
+			workspaceID: sql.NullString{String: "ws-1", Valid: true},
+			role:        "member",
+			keyHash:     sql.NullString{String: "hash", Valid: true},
+			email:       sql.NullString{String: "user@example.com", Valid: true},
+		}
+		if modify != nil {
+			modify(&row)
+		}
+		return row
+	}
+
+	tests := []struct {
+		name    string
+		row     keyRow
+		want    *Identity
+		wantErr error
+	}{
+		{"single workspace", key(nil), &Identity{UserID: "user-1", TenantID: "tenant-1", Role: "member", Email: "user@example.com", WorkspaceID: "ws-1"}, nil},
+		{"multi-workspace", key(func(r *keyRow) { r.workspaceIDs = []string{"ws-1", "ws-2"} }), &Identity{UserID: "user-1", TenantID: "tenant-1", Role: "member", Email: "user@example.com", WorkspaceIDs: []string{"ws-1", "ws-2"}}, nil},
+		{"tenant-wide", key(func(r *keyRow) { r.tenantWide = true }), &Identity{UserID: "user-1", TenantID: "tenant-1", Role: "member", Email: "user@example.com", AllWorkspaces: true}, nil},
+		{"unexpired", key(func(r *keyRow) { r.expiresAt = future }), &Identity{UserID: "user-1", TenantID: "tenant-1", Role: "member", Email: "user@example.com", WorkspaceID: "ws-1"}, nil},
+		{"revoked", key(func(r *keyRow) { r.revokedAt = past }), nil, ErrKeyRevoked},
+		{"expired", key(func(r *keyRow) { r.expiresAt = past }), nil, ErrKeyExpired},
+		{"disabled user", key(func(r *keyRow) { r.disabledAt = past }), nil, ErrUserDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hash, err := tt.row.identity(now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if hash != "hash" {
+				t.Errorf("hash = %q", hash)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("identity = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package auth
 
+import "time"
+
 // Identity represents the authenticated user/service
 type Identity struct {
 	UserID          string
@@ -9,4 +11,36 @@ type Identity struct {
 	Role            string
 	IsPlatformAdmin bool
 	KeyID           string // For API keys
+
+	// JWT ID and issue time, used to check revocation
+	TokenID   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+
+	// ImpersonatorID is the platform admin acting as UserID through an
+	// impersonation token
+	ImpersonatorID string
+
+	// Workspace scope for multi-workspace API keys. WorkspaceIDs lists the
+	// workspaces the key may act on; AllWorkspaces grants every workspace
+	// in TenantID.
+	WorkspaceIDs  []string
+	AllWorkspaces bool
+}
+
+// IsMultiWorkspace reports whether the identity is scoped to a set of
+// workspaces rather than a single one.
+func (id *Identity) IsMultiWorkspace() bool {
+	return id.AllWorkspaces || len(id.WorkspaceIDs) > 0
+}
+
+// HasWorkspace reports whether workspaceID is in the identity's explicit
+// workspace set. Tenant-wide scope must be checked against the database.
+func (id *Identity) HasWorkspace(workspaceID string) bool {
+	for _, ws := range id.WorkspaceIDs {
+		if ws == workspaceID {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,30 @@
+package auth
+
+import "testing"
+
+func TestIdentityWorkspaceScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        Identity
+		workspace string
+		wantMulti bool
+		wantHas   bool
+	}{
+		{"single workspace key", Identity{WorkspaceID: "ws-1"}, "ws-1", false, false},
+		{"unscoped identity", Identity{}, "ws-1", false, false},
+		{"listed workspace", Identity{WorkspaceIDs: []string{"ws-1", "ws-2"}}, "ws-2", true, true},
+		{"unlisted workspace", Identity{WorkspaceIDs: []string{"ws-1", "ws-2"}}, "ws-3", true, false},
+		{"empty workspace", Identity{WorkspaceIDs: []string{"ws-1"}}, "", true, false},
+		{"tenant-wide key", Identity{AllWorkspaces: true}, "ws-1", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.id.IsMultiWorkspace(); got != tt.wantMulti {
+				t.Errorf("IsMultiWorkspace() = %v, want %v", got, tt.wantMulti)
+			}
+			if got := tt.id.HasWorkspace(tt.workspace); got != tt.wantHas {
+				t.Errorf("HasWorkspace(%q) = %v, want %v", tt.workspace, got, tt.wantHas)
+			}
+		})
+	}
+}
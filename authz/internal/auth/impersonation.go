@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// auditImpersonationUsed must match models.AuditImpersonationUsed in the
+// backend, which owns the audit_logs table
+const auditImpersonationUsed = "impersonation.used"
+
+// ImpersonationAudit records the use of impersonation tokens in the
+// backend's audit log. Each token is recorded once per gate instance, on
+// first use; every request is still logged with its impersonator.
+type ImpersonationAudit struct {
+	db *sql.DB
+
+	mu   sync.Mutex
+	seen map[string]time.Time // token ID -> expiry
+}
+
+func NewImpersonationAudit(databaseURL string) (*ImpersonationAudit, error) {
+	if databaseURL == "" {
+		return nil, errors.New("database URL required for impersonation audit")
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &ImpersonationAudit{db: db, seen: make(map[string]time.Time)}, nil
+}
+
+func (a *ImpersonationAudit) Close() error {
+	if a.db != nil {
+		return a.db.Close()
+	}
+	return nil
+}
+
+// Record writes an impersonation.used entry the first time an impersonation
+// token is seen. Identities that aren't impersonating are ignored.
+func (a *ImpersonationAudit) Record(id *Identity) error {
+	if id.ImpersonatorID == "" {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	for tokenID, expiresAt := range a.seen {
+		if now.After(expiresAt) {
+			delete(a.seen, tokenID)
+		}
+	}
+	if _, ok := a.seen[id.TokenID]; ok && id.TokenID != "" {
+		return nil
+	}
+
+	_, err := a.db.Exec(`
+		INSERT INTO audit_logs (action, user_id, tenant_id, actor_id, created_at)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, NOW())`,
+		auditImpersonationUsed, id.UserID, id.TenantID, id.ImpersonatorID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if id.TokenID != "" {
+		a.seen[id.TokenID] = id.ExpiresAt
+	}
+	return nil
+}
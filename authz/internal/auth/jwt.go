@@ -1,18 +1,84 @@
 package auth
 
 import (
+	"crypto/rsa"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Supported JWT_ALG values
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+// JWTValidator verifies tokens signed with one configured algorithm. Tokens
+// whose alg header differs are rejected, so an RS256 public key can never be
+// used as an HMAC secret (algorithm confusion).
 type JWTValidator struct {
-	secret []byte
+	alg string
+	key interface{} // []byte for HS256, *rsa.PublicKey for RS256
+
+	// Expected iss and aud claims; empty skips the check
+	issuer   string
+	audience string
 }
 
+// NewJWTValidator validates HS256 tokens signed with secret
 func NewJWTValidator(secret []byte) *JWTValidator {
-	return &JWTValidator{secret: secret}
+	return &JWTValidator{alg: AlgHS256, key: secret}
+}
+
+// NewJWTValidatorRSA validates RS256 tokens signed by publicKey's private key
+func NewJWTValidatorRSA(publicKey *rsa.PublicKey) *JWTValidator {
+	return &JWTValidator{alg: AlgRS256, key: publicKey}
+}
+
+// NewJWTValidatorFromConfig builds the validator for alg (HS256 when
+// empty): HS256 uses secret, RS256 the PEM public key at publicKeyPath. It
+// returns nil without error when HS256 is selected but no secret is set.
+func NewJWTValidatorFromConfig(alg string, secret []byte, publicKeyPath string) (*JWTValidator, error) {
+	switch strings.ToUpper(alg) {
+	case "", AlgHS256:
+		if len(secret) == 0 {
+			return nil, nil
+		}
+		return NewJWTValidator(secret), nil
+
+	case AlgRS256:
+		if publicKeyPath == "" {
+			return nil, errors.New("JWT_PUBLIC_KEY_PATH is required for RS256")
+		}
+		pemData, err := os.ReadFile(publicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read jwt public key: %w", err)
+		}
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwt public key: %w", err)
+		}
+		return NewJWTValidatorRSA(publicKey), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALG %q (want HS256 or RS256)", alg)
+	}
+}
+
+// WithIssuerAudience makes the validator reject tokens whose iss or aud
+// claim doesn't match. Empty values are not checked.
+func (v *JWTValidator) WithIssuerAudience(issuer, audience string) *JWTValidator {
+	v.issuer = issuer
+	v.audience = audience
+	return v
+}
+
+// Alg returns the signing algorithm tokens must use
+func (v *JWTValidator) Alg() string {
+	return v.alg
 }
 
 type JWTClaims struct {
@@ -22,19 +88,30 @@ type JWTClaims struct {
 	TenantID        string `json:"tenant_id"`
 	IsPlatformAdmin bool   `json:"is_platform_admin"`
 	IsTenantAdmin   bool   `json:"is_tenant_admin"`
+	WorkspaceID     string `json:"workspace_id"` // set once a workspace is selected
+	Impersonator    string `json:"impersonator"` // platform admin acting as the subject
 }
 
 func (v *JWTValidator) Validate(tokenString string) (*Identity, error) {
-	if len(v.secret) == 0 {
-		return nil, errors.New("jwt secret not configured")
+	switch key := v.key.(type) {
+	case []byte:
+		if len(key) == 0 {
+			return nil, errors.New("jwt secret not configured")
+		}
+	case *rsa.PublicKey:
+		if key == nil {
+			return nil, errors.New("jwt public key not configured")
+		}
+	default:
+		return nil, errors.New("jwt key not configured")
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if token.Method.Alg() != v.alg {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return v.secret, nil
-	})
+		return v.key, nil
+	}, jwt.WithValidMethods([]string{v.alg}), jwt.WithIssuer(v.issuer), jwt.WithAudience(v.audience))
 
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
@@ -45,10 +122,20 @@ func (v *JWTValidator) Validate(tokenString string) (*Identity, error) {
 		return nil, errors.New("invalid token claims")
 	}
 
-	return &Identity{
+	identity := &Identity{
 		UserID:          claims.Subject,
 		Email:           claims.Email,
 		TenantID:        claims.TenantID,
+		WorkspaceID:     claims.WorkspaceID,
 		IsPlatformAdmin: claims.IsPlatformAdmin,
-	}, nil
+		TokenID:         claims.ID,
+		ImpersonatorID:  claims.Impersonator,
+	}
+	if claims.IssuedAt != nil {
+		identity.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		identity.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return identity, nil
 }
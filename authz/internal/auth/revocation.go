@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTokenRevoked = errors.New("token has been revoked")
+	ErrUserDisabled = errors.New("user account is disabled")
+)
+
+// RevocationList rejects access tokens revoked before they expire: tokens
+// whose jti is in the backend's revoked_tokens table (e.g. after logout),
+// tokens issued before their user's entry in revoked_users (e.g. after
+// account deletion) and all tokens of disabled users. The sets are cached
+// for ttl so the gate doesn't query the database on every request.
+type RevocationList struct {
+	db  *sql.DB
+	ttl time.Duration
+
+	mu            sync.Mutex
+	loadedAt      time.Time
+	revoked       map[string]bool
+	disabledUsers map[string]bool
+	revokedUsers  map[string]time.Time
+}
+
+func NewRevocationList(databaseURL string, ttl time.Duration) (*RevocationList, error) {
+	if databaseURL == "" {
+		return nil, errors.New("database URL required for token revocation")
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &RevocationList{db: db, ttl: ttl}, nil
+}
+
+func (r *RevocationList) Close() error {
+	if r.db != nil {
+		return r.db.Close()
+	}
+	return nil
+}
+
+// Check returns ErrTokenRevoked or ErrUserDisabled if the JWT identity may
+// no longer be used. If the lists can't be refreshed the previous ones are
+// kept; an error is returned only when they have never been loaded.
+func (r *RevocationList) Check(id *Identity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.loadedAt) > r.ttl {
+		if err := r.refresh(); err != nil {
+			if r.loadedAt.IsZero() {
+				return fmt.Errorf("load revocation list: %w", err)
+			}
+			log.Printf("Warning: Failed to refresh revocation list, using cached one: %v", err)
+		}
+	}
+
+	if id.TokenID != "" && r.revoked[id.TokenID] {
+		return ErrTokenRevoked
+	}
+	if revokedAt, ok := r.revokedUsers[id.UserID]; ok && !id.IssuedAt.After(revokedAt) {
+		return ErrTokenRevoked
+	}
+	if r.disabledUsers[id.UserID] {
+		return ErrUserDisabled
+	}
+	return nil
+}
+
+// refresh reloads unexpired revoked token IDs and users, and disabled
+// users. Caller must hold r.mu.
+func (r *RevocationList) refresh() error {
+	revoked := make(map[string]bool)
+	rows, err := r.db.Query(`SELECT jti FROM revoked_tokens WHERE expires_at > NOW()`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var jti string
+		if err := rows.Scan(&jti); err != nil {
+			rows.Close()
+			return err
+		}
+		revoked[jti] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	disabled := make(map[string]bool)
+	rows, err = r.db.Query(`SELECT id FROM users WHERE disabled_at IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return err
+		}
+		disabled[userID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	revokedUsers := make(map[string]time.Time)
+	rows, err = r.db.Query(`SELECT user_id, revoked_at FROM revoked_users WHERE expires_at > NOW()`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var userID string
+		var revokedAt time.Time
+		if err := rows.Scan(&userID, &revokedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		revokedUsers[userID] = revokedAt
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	r.revoked = revoked
+	r.disabledUsers = disabled
+	r.revokedUsers = revokedUsers
+	r.loadedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRevocationListCheck(t *testing.T) {
+	now := time.Now()
+	r := &RevocationList{
+		ttl:           time.Hour,
+		loadedAt:      now,
+		revoked:       map[string]bool{"revoked-jti": true},
+		disabledUsers: map[string]bool{"disabled-user": true},
+		revokedUsers:  map[string]time.Time{"deleted-user": now},
+	}
+
+	tests := []struct {
+		name string
+		id   Identity
+		want error
+	}{
+		{"active user", Identity{UserID: "user", TokenID: "jti", IssuedAt: now}, nil},
+		{"revoked token", Identity{UserID: "user", TokenID: "revoked-jti", IssuedAt: now}, ErrTokenRevoked},
+		{"disabled user, old token", Identity{UserID: "disabled-user", TokenID: "jti", IssuedAt: now.Add(-time.Hour)}, ErrUserDisabled},
+		{"disabled user, token issued after disabling", Identity{UserID: "disabled-user", TokenID: "jti", IssuedAt: now.Add(time.Hour)}, ErrUserDisabled},
+		{"token without jti", Identity{UserID: "user", IssuedAt: now}, nil},
+		{"revoked user, token issued before", Identity{UserID: "deleted-user", TokenID: "jti", IssuedAt: now.Add(-time.Minute)}, ErrTokenRevoked},
+		{"revoked user, token issued at revocation", Identity{UserID: "deleted-user", TokenID: "jti", IssuedAt: now}, ErrTokenRevoked},
+		{"revoked user, token issued after", Identity{UserID: "deleted-user", TokenID: "jti", IssuedAt: now.Add(time.Minute)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.Check(&tt.id); !errors.Is(err, tt.want) {
+				t.Errorf("Check() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// WorkspaceDirectory looks up which tenant a workspace belongs to in the
+// backend's workspaces table, so the gate only grants tenant-level roles on
+// a tenant's own workspaces. A workspace never changes tenant, so found
+// lookups are cached for the life of the process.
+type WorkspaceDirectory struct {
+	db *sql.DB
+
+	mu      sync.RWMutex
+	tenants map[string]string // workspace ID -> tenant ID
+}
+
+func NewWorkspaceDirectory(databaseURL string) (*WorkspaceDirectory, error) {
+	if databaseURL == "" {
+		return nil, errors.New("database URL required for workspace lookups")
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &WorkspaceDirectory{db: db, tenants: make(map[string]string)}, nil
+}
+
+func (d *WorkspaceDirectory) Close() error {
+	if d.db != nil {
+		return d.db.Close()
+	}
+	return nil
+}
+
+// TenantOf returns the ID of the workspace's tenant, or "" if there is no
+// such workspace
+func (d *WorkspaceDirectory) TenantOf(workspaceID string) (string, error) {
+	d.mu.RLock()
+	tenantID, ok := d.tenants[workspaceID]
+	d.mu.RUnlock()
+	if ok {
+		return tenantID, nil
+	}
+
+	err := d.db.QueryRow(`SELECT tenant_id::text FROM workspaces WHERE id::text = $1`, workspaceID).Scan(&tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	d.mu.Lock()
+	d.tenants[workspaceID] = tenantID
+	d.mu.Unlock()
+	return tenantID, nil
+}
@@ -0,0 +1,132 @@
+package authz
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkCache is an LRU cache of check results, each kept for ttl. A result
+// is dropped early when a tuple on one of the objects it depends on (the
+// checked object and the objects in its contextual tuples) is written or
+// deleted through this client. Changes that reach an object only through
+// stored parent links, or that are written by other processes, show up once
+// the entry expires. All methods are safe to call on a nil cache.
+type checkCache struct {
+	ttl     time.Duration
+	maxSize int
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used at the front
+}
+
+type checkCacheEntry struct {
+	key     string
+	objects []string
+	allowed bool
+	expires time.Time
+}
+
+// newCheckCache returns nil (no caching) unless ttl and maxSize are positive
+func newCheckCache(ttl time.Duration, maxSize int) *checkCache {
+	if ttl <= 0 || maxSize <= 0 {
+		return nil
+	}
+	return &checkCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// checkCacheKey identifies a check by user, relation, object, model and
+// contextual tuples, which can change the result
+func checkCacheKey(user, relation, object, modelID string, contextual []TupleKey) string {
+	parts := make([]string, 0, len(contextual))
+	for _, t := range contextual {
+		parts = append(parts, t.User+"#"+t.Relation+"@"+t.Object)
+	}
+	sort.Strings(parts)
+	return strings.Join([]string{user, relation, object, modelID, strings.Join(parts, ",")}, "|")
+}
+
+// checkDependencies lists the objects a check result depends on
+func checkDependencies(object string, contextual []TupleKey) []string {
+	objects := []string{object}
+	for _, t := range contextual {
+		userObject, _, _ := strings.Cut(t.User, "#")
+		objects = append(objects, userObject, t.Object)
+	}
+	return objects
+}
+
+func (c *checkCache) get(key string) (allowed, ok bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.entries[key]
+	if !found {
+		return false, false
+	}
+	entry := el.Value.(*checkCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(el)
+		return false, false
+	}
+	c.order.MoveToFront(el)
+	return entry.allowed, true
+}
+
+func (c *checkCache) put(key string, objects []string, allowed bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &checkCacheEntry{key: key, objects: objects, allowed: allowed, expires: c.now().Add(c.ttl)}
+	if el, found := c.entries[key]; found {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxSize {
+		c.remove(c.order.Back())
+	}
+}
+
+// invalidate drops every result that depends on object
+func (c *checkCache) invalidate(object string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		for _, dep := range el.Value.(*checkCacheEntry).objects {
+			if dep == object {
+				c.remove(el)
+				break
+			}
+		}
+		el = next
+	}
+}
+
+// remove deletes an entry. Caller must hold c.mu.
+func (c *checkCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*checkCacheEntry).key)
+}
@@ -0,0 +1,10 @@
+package authz
+
+import _ "embed"
+
+// BundledModel is the ReBAC/container authorization model in OpenFGA's JSON
+// format, as written by authz-setup. Keep it in sync with
+// deploy/openfga/model.fga.
+//
+//go:embed model.json
+var BundledModel string
@@ -0,0 +1,73 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Object types defined in deploy/openfga/model.fga. Tenants and workspaces
+// are both containers.
+const (
+	TypeUser      = "user"
+	TypePlatform  = "platform"
+	TypeContainer = "container"
+	TypeResource  = "resource"
+	TypeAPIKey    = "api_key"
+)
+
+var objectTypes = map[string]bool{
+	TypeUser:      true,
+	TypePlatform:  true,
+	TypeContainer: true,
+	TypeResource:  true,
+	TypeAPIKey:    true,
+}
+
+// ObjectRef is an OpenFGA object or user reference such as "container:ws-1"
+type ObjectRef struct {
+	Type string
+	ID   string
+}
+
+// UserRef returns the reference for a user ID
+func UserRef(id string) ObjectRef {
+	return ObjectRef{Type: TypeUser, ID: id}
+}
+
+// ContainerRef returns the reference for a tenant or workspace ID
+func ContainerRef(id string) ObjectRef {
+	return ObjectRef{Type: TypeContainer, ID: id}
+}
+
+// ParseObjectRef parses and validates a "type:id" reference
+func ParseObjectRef(s string) (ObjectRef, error) {
+	typ, id, ok := strings.Cut(s, ":")
+	if !ok {
+		return ObjectRef{}, fmt.Errorf("invalid object reference %q: expected type:id", s)
+	}
+
+	ref := ObjectRef{Type: typ, ID: id}
+	if err := ref.Validate(); err != nil {
+		return ObjectRef{}, err
+	}
+	return ref, nil
+}
+
+// Validate checks the type is defined in the model and the ID is usable
+func (r ObjectRef) Validate() error {
+	if !objectTypes[r.Type] {
+		return fmt.Errorf("invalid object reference %q: unknown type %q", r.String(), r.Type)
+	}
+	if r.ID == "" {
+		return fmt.Errorf("invalid object reference %q: empty id", r.String())
+	}
+	if strings.ContainsAny(r.ID, ":# \t\n") {
+		return fmt.Errorf("invalid object reference %q: id contains a reserved character", r.String())
+	}
+	return nil
+}
+
+// String returns the "type:id" form used in OpenFGA requests
+func (r ObjectRef) String() string {
+	return r.Type + ":" + r.ID
+}
@@ -0,0 +1,59 @@
+package authz
+
+import "testing"
+
+func TestParseObjectRef(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ObjectRef
+		wantErr bool
+	}{
+		{in: "user:123", want: UserRef("123")},
+		{in: "container:ws-1", want: ContainerRef("ws-1")},
+		{in: "api_key:key-1", want: ObjectRef{Type: TypeAPIKey, ID: "key-1"}},
+		{in: "platform:default", want: ObjectRef{Type: TypePlatform, ID: "default"}},
+		{in: "user:*", want: UserRef("*")},
+		{in: "ws-1", wantErr: true},
+		{in: "workspace:ws-1", wantErr: true}, // not a type in the model
+		{in: "container:", wantErr: true},
+		{in: ":ws-1", wantErr: true},
+		{in: "container:ws:1", wantErr: true},
+		{in: "container:ws-1#member", wantErr: true},
+		{in: "container:ws 1", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseObjectRef(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseObjectRef(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseObjectRef(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.in {
+				t.Errorf("String() = %q, want %q", got.String(), tt.in)
+			}
+		})
+	}
+}
+
+func TestValidateTuple(t *testing.T) {
+	tests := []struct {
+		name    string
+		tuple   TupleKey
+		wantErr bool
+	}{
+		{"valid", TupleKey{User: "user:1", Relation: "member", Object: "container:ws-1"}, false},
+		{"container parent", TenantParentTuple("t-1", "ws-1"), false},
+		{"bad user", TupleKey{User: "1", Relation: "member", Object: "container:ws-1"}, true},
+		{"bad object", TupleKey{User: "user:1", Relation: "member", Object: "container:"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateTuple(tt.tuple); (err != nil) != tt.wantErr {
+				t.Errorf("validateTuple() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
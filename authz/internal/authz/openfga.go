@@ -4,13 +4,28 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
+
+	"saas-authz/internal/metrics"
 )
 
+// ErrPinnedModelNotFound is returned by Initialize when the pinned
+// authorization model does not exist in the store
+var ErrPinnedModelNotFound = errors.New("pinned authorization model not found")
+
+// errModelNotFound is returned by check when OpenFGA no longer has the
+// model the check named
+var errModelNotFound = errors.New("authorization model not found")
+
 // Client provides authorization checks using OpenFGA
 type Client struct {
 	baseURL string
@@ -19,21 +34,129 @@ type Client struct {
 	client  *http.Client
 	mu      sync.RWMutex
 	devMode bool
+
+	// refreshMu serializes model refreshes so a burst of checks against a
+	// deleted model triggers one lookup
+	refreshMu sync.Mutex
+
+	// pinnedModelID, when set, is used instead of the store's latest model
+	// so model upgrades only take effect when the pin is changed
+	pinnedModelID string
+
+	// cache holds recent check results; nil disables caching
+	cache *checkCache
+
+	// maxAttempts and retryBaseDelay bound the retries of requests that
+	// fail with a connection error or a 5xx
+	maxAttempts    int
+	retryBaseDelay time.Duration
 }
 
-// NewClient creates a new OpenFGA authorization client
-func NewClient(baseURL, storeID string, devMode bool) *Client {
+// NewClient creates a new OpenFGA authorization client. modelID pins the
+// authorization model; leave it empty to use the latest.
+func NewClient(baseURL, storeID, modelID string, devMode bool) *Client {
 	return &Client{
-		baseURL: baseURL,
-		storeID: storeID,
-		devMode: devMode,
+		baseURL:       baseURL,
+		storeID:       storeID,
+		devMode:       devMode,
+		pinnedModelID: modelID,
 		client: &http.Client{
 			Timeout: 5 * time.Second,
 		},
+		maxAttempts: 1,
 	}
 }
 
-// Initialize fetches the latest authorization model ID
+// EnableCheckCache caches check results for ttl, keeping at most maxSize
+// results. A zero ttl or maxSize disables the cache.
+func (c *Client) EnableCheckCache(ttl time.Duration, maxSize int) {
+	c.cache = newCheckCache(ttl, maxSize)
+}
+
+// SetRetryPolicy retries requests that are safe to repeat (checks, reads,
+// lookups and tuple writes) that fail with a connection error or a 5xx up
+// to maxAttempts times in all, waiting baseDelay before the first
+// retry and doubling it (with jitter) before each one after. 4xx responses,
+// denials included, are never retried. Values below 1 attempt disable
+// retries.
+func (c *Client) SetRetryPolicy(maxAttempts int, baseDelay time.Duration) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	c.maxAttempts = maxAttempts
+	c.retryBaseDelay = baseDelay
+}
+
+// do sends req, retrying per the retry policy. Retries stop early when the
+// request's context is done or its deadline would pass during the backoff,
+// in which case the last response or error is returned. Only use it for
+// requests that are safe to send twice: a retry may follow a request that
+// OpenFGA carried out but whose response was lost.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := c.client.Do(req)
+		if !retryable(ctx, resp, err) || attempt >= c.maxAttempts || req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+
+		delay := backoff(c.retryBaseDelay, attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return resp, err
+		}
+		if ctx.Err() != nil {
+			return resp, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+
+		reason := "connection"
+		if resp != nil {
+			reason = "server_error"
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			req.Body = body
+		}
+		metrics.OpenFGARetries.Inc(reason)
+	}
+}
+
+// retryable reports whether a request may succeed if sent again: the
+// connection failed or timed out (but not because the caller gave up) or
+// OpenFGA answered with a server error
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// backoff returns the wait before retry number attempt: base doubled for
+// each earlier retry, randomized between half and the full value so
+// concurrent retries spread out
+func backoff(base time.Duration, attempt int) time.Duration {
+	delay := base << (attempt - 1)
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// Initialize resolves the authorization model ID: the pinned model after
+// checking it exists, or the latest model when unpinned
 func (c *Client) Initialize(ctx context.Context) error {
 	if c.devMode {
 		return nil
@@ -43,21 +166,113 @@ func (c *Client) Initialize(ctx context.Context) error {
 		return fmt.Errorf("store ID not configured")
 	}
 
-	url := fmt.Sprintf("%s/stores/%s/authorization-models", c.baseURL, c.storeID)
+	if c.pinnedModelID != "" {
+		return c.usePinnedModel(ctx)
+	}
+
+	_, err := c.RefreshModel(ctx)
+	return err
+}
+
+// RefreshModel re-resolves the store's latest authorization model and
+// switches checks to it, returning the model ID in use. Pinned clients keep
+// their pinned model. Refreshes are serialized; checks keep using the
+// previous model until the new ID is swapped in.
+func (c *Client) RefreshModel(ctx context.Context) (string, error) {
+	if c.devMode || c.pinnedModelID != "" {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.modelID, nil
+	}
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	return c.refreshModel(ctx)
+}
+
+// refreshStaleModel refreshes after a check found stale missing from the
+// store. When concurrent checks hit the same stale model, only the first
+// asks OpenFGA; the others reuse its result.
+func (c *Client) refreshStaleModel(ctx context.Context, stale string) (string, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.mu.RLock()
+	current := c.modelID
+	c.mu.RUnlock()
+
+	if current != stale {
+		return current, nil
+	}
+	return c.refreshModel(ctx)
+}
+
+// refreshModel looks up the latest model and swaps it in. Caller must hold
+// c.refreshMu.
+func (c *Client) refreshModel(ctx context.Context) (string, error) {
+	c.mu.RLock()
+	storeID := c.storeID
+	previous := c.modelID
+	c.mu.RUnlock()
+
+	if storeID == "" {
+		return "", fmt.Errorf("store ID not configured")
+	}
+
+	latest, err := c.latestModelID(ctx, storeID)
+	if err != nil {
+		return previous, err
+	}
+
+	c.mu.Lock()
+	c.modelID = latest
+	c.mu.Unlock()
+
+	if previous != "" && latest != previous {
+		log.Printf("OpenFGA authorization model changed: %s -> %s", previous, latest)
+	}
+	return latest, nil
+}
+
+// StartModelRefresh re-resolves the latest authorization model every
+// interval so unpinned clients pick up newly written models. Runs until the
+// process exits; a non-positive interval or a pinned model disables it.
+func (c *Client) StartModelRefresh(interval time.Duration) {
+	if interval <= 0 || c.devMode || c.pinnedModelID != "" {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if _, err := c.RefreshModel(ctx); err != nil {
+				log.Printf("Warning: Failed to refresh OpenFGA authorization model: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
+
+// latestModelID returns the ID of the store's newest authorization model
+func (c *Client) latestModelID(ctx context.Context, storeID string) (string, error) {
+	url := fmt.Sprintf("%s/stores/%s/authorization-models", c.baseURL, storeID)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return fmt.Errorf("failed to connect to OpenFGA: %w", err)
+		return "", fmt.Errorf("failed to connect to OpenFGA: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to get models: %s - %s", resp.Status, string(body))
+		return "", fmt.Errorf("failed to get models: %s - %s", resp.Status, string(body))
 	}
 
 	var result struct {
@@ -66,22 +281,246 @@ func (c *Client) Initialize(ctx context.Context) error {
 		} `json:"authorization_models"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return err
+		return "", err
 	}
 
 	if len(result.AuthorizationModels) == 0 {
-		return fmt.Errorf("no authorization models found")
+		return "", fmt.Errorf("no authorization models found")
+	}
+
+	return result.AuthorizationModels[0].ID, nil
+}
+
+// usePinnedModel verifies the pinned model exists in the store and selects it
+func (c *Client) usePinnedModel(ctx context.Context) error {
+	url := fmt.Sprintf("%s/stores/%s/authorization-models/%s", c.baseURL, c.storeID, c.pinnedModelID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to OpenFGA: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// OpenFGA answers 400 for malformed model IDs and 404 for unknown ones
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
+		return fmt.Errorf("%w: %s", ErrPinnedModelNotFound, c.pinnedModelID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to get model %s: %s - %s", c.pinnedModelID, resp.Status, string(body))
 	}
 
 	c.mu.Lock()
-	c.modelID = result.AuthorizationModels[0].ID
+	c.modelID = c.pinnedModelID
 	c.mu.Unlock()
 
 	return nil
 }
 
+// Ready checks that OpenFGA is reachable and the authorization model is
+// available. If no model was resolved at startup, resolving it is retried
+// here so the gate becomes ready once OpenFGA is.
+func (c *Client) Ready(ctx context.Context) error {
+	if c.devMode {
+		return nil
+	}
+
+	c.mu.RLock()
+	storeID := c.storeID
+	modelID := c.modelID
+	c.mu.RUnlock()
+
+	if modelID == "" {
+		return c.Initialize(ctx)
+	}
+
+	url := fmt.Sprintf("%s/stores/%s/authorization-models/%s", c.baseURL, storeID, modelID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to OpenFGA: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to get model %s: %s - %s", modelID, resp.Status, string(body))
+	}
+
+	return nil
+}
+
+// FindStore returns the ID of the store with the given name, or "" if there
+// is none
+func (c *Client) FindStore(ctx context.Context, name string) (string, error) {
+	continuationToken := ""
+	for {
+		endpoint := fmt.Sprintf("%s/stores?page_size=100", c.baseURL)
+		if continuationToken != "" {
+			endpoint += "&continuation_token=" + url.QueryEscape(continuationToken)
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return "", err
+		}
+
+		resp, err := c.do(req)
+		if err != nil {
+			return "", fmt.Errorf("failed to connect to OpenFGA: %w", err)
+		}
+
+		var result struct {
+			Stores []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"stores"`
+			ContinuationToken string `json:"continuation_token"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return "", fmt.Errorf("failed to list stores: %s - %s", resp.Status, string(body))
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return "", err
+		}
+
+		for _, store := range result.Stores {
+			if store.Name == name {
+				return store.ID, nil
+			}
+		}
+		if result.ContinuationToken == "" {
+			return "", nil
+		}
+		continuationToken = result.ContinuationToken
+	}
+}
+
+// CreateStore creates a store and makes it the client's store
+func (c *Client) CreateStore(ctx context.Context, name string) (string, error) {
+	body, _ := json.Marshal(map[string]string{"name": name})
+	url := fmt.Sprintf("%s/stores", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Not retried: a retry after a lost response would create a second store
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to OpenFGA: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to create store: %s - %s", resp.Status, string(body))
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.storeID = result.ID
+	c.mu.Unlock()
+
+	return result.ID, nil
+}
+
+// UseStore makes storeID the client's store
+func (c *Client) UseStore(storeID string) {
+	c.mu.Lock()
+	c.storeID = storeID
+	c.mu.Unlock()
+}
+
+// WriteAuthorizationModel writes modelJSON (OpenFGA's JSON model format) to
+// the store as a new model version and returns its ID. Unpinned clients
+// pick it up on their next RefreshModel.
+func (c *Client) WriteAuthorizationModel(ctx context.Context, modelJSON string) (string, error) {
+	c.mu.RLock()
+	storeID := c.storeID
+	c.mu.RUnlock()
+
+	if storeID == "" {
+		return "", fmt.Errorf("store ID not configured")
+	}
+	if !json.Valid([]byte(modelJSON)) {
+		return "", fmt.Errorf("authorization model is not valid JSON")
+	}
+
+	url := fmt.Sprintf("%s/stores/%s/authorization-models", c.baseURL, storeID)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(modelJSON))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Not retried: each write creates a new model version
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to OpenFGA: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("failed to write model: %s - %s", resp.Status, string(body))
+	}
+
+	var result struct {
+		AuthorizationModelID string `json:"authorization_model_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	return result.AuthorizationModelID, nil
+}
+
+// TupleKey is a relationship tuple, used for contextual tuples in checks
+type TupleKey struct {
+	User     string `json:"user"`
+	Relation string `json:"relation"`
+	Object   string `json:"object"`
+}
+
+// TenantParentTuple links a workspace container to its tenant container so
+// workspace checks inherit tenant-level roles without a stored parent tuple.
+// Only use it once the workspace is known to belong to the tenant, or a
+// tenant admin gains rights on other tenants' workspaces.
+func TenantParentTuple(tenantID, workspaceID string) TupleKey {
+	return TupleKey{
+		User:     ContainerRef(tenantID).String(),
+		Relation: "parent",
+		Object:   ContainerRef(workspaceID).String(),
+	}
+}
+
 // Check performs an authorization check
 func (c *Client) Check(ctx context.Context, userID, workspaceID, permission, path string) (bool, error) {
+	return c.CheckWithContext(ctx, userID, workspaceID, permission, path, nil)
+}
+
+// CheckWithContext performs an authorization check including contextual
+// tuples, which OpenFGA evaluates as if they were stored for this check only
+func (c *Client) CheckWithContext(ctx context.Context, userID, workspaceID, permission, path string, contextual []TupleKey) (bool, error) {
 	if c.devMode {
 		return true, nil
 	}
@@ -95,17 +534,61 @@ func (c *Client) Check(ctx context.Context, userID, workspaceID, permission, pat
 		return false, fmt.Errorf("authz client not initialized")
 	}
 
-	user := fmt.Sprintf("user:%s", userID)
-	object := fmt.Sprintf("workspace:%s", workspaceID)
+	user := UserRef(userID)
+	object := ContainerRef(workspaceID)
+	if err := user.Validate(); err != nil {
+		return false, err
+	}
+	if err := object.Validate(); err != nil {
+		return false, err
+	}
+	for _, t := range contextual {
+		if err := validateTuple(t); err != nil {
+			return false, err
+		}
+	}
 
+	key := checkCacheKey(user.String(), permission, object.String(), modelID, contextual)
+	if c.cache != nil {
+		if allowed, ok := c.cache.get(key); ok {
+			metrics.CheckCacheLookups.Inc("hit")
+			return allowed, nil
+		}
+		metrics.CheckCacheLookups.Inc("miss")
+	}
+
+	start := time.Now()
+	allowed, err := c.check(ctx, storeID, modelID, user, object, permission, contextual)
+	if errors.Is(err, errModelNotFound) && c.pinnedModelID == "" {
+		// The model was deleted or replaced; retry once on the latest
+		if latest, refreshErr := c.refreshStaleModel(ctx, modelID); refreshErr == nil && latest != modelID {
+			modelID = latest
+			key = checkCacheKey(user.String(), permission, object.String(), modelID, contextual)
+			allowed, err = c.check(ctx, storeID, modelID, user, object, permission, contextual)
+		}
+	}
+	metrics.ObserveCheck(time.Since(start), allowed, err)
+	if err == nil {
+		c.cache.put(key, checkDependencies(object.String(), contextual), allowed)
+	}
+	return allowed, err
+}
+
+// check calls the OpenFGA check API
+func (c *Client) check(ctx context.Context, storeID, modelID string, user, object ObjectRef, permission string, contextual []TupleKey) (bool, error) {
 	reqBody := map[string]interface{}{
 		"tuple_key": map[string]string{
-			"user":     user,
+			"user":     user.String(),
 			"relation": permission,
-			"object":   object,
+			"object":   object.String(),
 		},
 		"authorization_model_id": modelID,
 	}
+	if len(contextual) > 0 {
+		reqBody["contextual_tuples"] = map[string]interface{}{
+			"tuple_keys": contextual,
+		}
+	}
 
 	body, _ := json.Marshal(reqBody)
 	url := fmt.Sprintf("%s/stores/%s/check", c.baseURL, storeID)
@@ -115,7 +598,7 @@ func (c *Client) Check(ctx context.Context, userID, workspaceID, permission, pat
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return false, err
 	}
@@ -123,6 +606,9 @@ func (c *Client) Check(ctx context.Context, userID, workspaceID, permission, pat
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		if isModelNotFound(resp.StatusCode, body) {
+			return false, fmt.Errorf("%w: %s", errModelNotFound, modelID)
+		}
 		return false, fmt.Errorf("check failed: %s - %s", resp.Status, string(body))
 	}
 
@@ -136,12 +622,28 @@ func (c *Client) Check(ctx context.Context, userID, workspaceID, permission, pat
 	return result.Allowed, nil
 }
 
+// isModelNotFound reports whether an OpenFGA error response says the
+// requested authorization model doesn't exist
+func isModelNotFound(status int, body []byte) bool {
+	if status != http.StatusBadRequest && status != http.StatusNotFound {
+		return false
+	}
+	var result struct {
+		Code string `json:"code"`
+	}
+	return json.Unmarshal(body, &result) == nil && result.Code == "authorization_model_not_found"
+}
+
 // WriteTuple writes an authorization tuple
 func (c *Client) WriteTuple(ctx context.Context, user, relation, object string) error {
 	if c.devMode {
 		return nil
 	}
 
+	if err := validateTuple(TupleKey{User: user, Relation: relation, Object: object}); err != nil {
+		return err
+	}
+
 	c.mu.RLock()
 	storeID := c.storeID
 	modelID := c.modelID
@@ -168,7 +670,7 @@ func (c *Client) WriteTuple(ctx context.Context, user, relation, object string)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
@@ -179,6 +681,7 @@ func (c *Client) WriteTuple(ctx context.Context, user, relation, object string)
 		return fmt.Errorf("write failed: %s - %s", resp.Status, string(body))
 	}
 
+	c.cache.invalidate(object)
 	return nil
 }
 
@@ -188,6 +691,10 @@ func (c *Client) DeleteTuple(ctx context.Context, user, relation, object string)
 		return nil
 	}
 
+	if err := validateTuple(TupleKey{User: user, Relation: relation, Object: object}); err != nil {
+		return err
+	}
+
 	c.mu.RLock()
 	storeID := c.storeID
 	modelID := c.modelID
@@ -214,7 +721,7 @@ func (c *Client) DeleteTuple(ctx context.Context, user, relation, object string)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
@@ -225,5 +732,15 @@ func (c *Client) DeleteTuple(ctx context.Context, user, relation, object string)
 		return fmt.Errorf("delete failed: %s - %s", resp.Status, string(body))
 	}
 
+	c.cache.invalidate(object)
 	return nil
 }
+
+// validateTuple checks both ends of a tuple are well-formed references
+func validateTuple(t TupleKey) error {
+	if _, err := ParseObjectRef(t.User); err != nil {
+		return err
+	}
+	_, err := ParseObjectRef(t.Object)
+	return err
+}
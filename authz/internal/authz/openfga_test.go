@@ -0,0 +1,160 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeOpenFGA serves the model and check endpoints for store "store-1".
+// models lists the store's model IDs, newest first; checks against a model
+// not in the list fail with authorization_model_not_found.
+type fakeOpenFGA struct {
+	mu          sync.Mutex
+	models      []string
+	checkModels []string // authorization_model_id of each check
+	modelLists  int      // calls listing the models
+}
+
+func (f *fakeOpenFGA) hasModel(id string) bool {
+	for _, m := range f.models {
+		if m == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeOpenFGA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	path := strings.TrimPrefix(r.URL.Path, "/stores/store-1")
+	switch {
+	case path == "/authorization-models":
+		f.modelLists++
+		var models []map[string]string
+		for _, id := range f.models {
+			models = append(models, map[string]string{"id": id})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"authorization_models": models})
+	case strings.HasPrefix(path, "/authorization-models/"):
+		if !f.hasModel(strings.TrimPrefix(path, "/authorization-models/")) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"code": "authorization_model_not_found"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"authorization_model": map[string]string{}})
+	case path == "/check":
+		var req struct {
+			ModelID string `json:"authorization_model_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		f.checkModels = append(f.checkModels, req.ModelID)
+		if !f.hasModel(req.ModelID) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"code": "authorization_model_not_found"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"allowed": true})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeOpenFGA(t *testing.T, models ...string) (*fakeOpenFGA, string) {
+	t.Helper()
+	f := &fakeOpenFGA{models: models}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv.URL
+}
+
+func TestInitializeModel(t *testing.T) {
+	tests := []struct {
+		name      string
+		pinned    string
+		wantModel string
+		wantErr   error
+	}{
+		{"unpinned uses the latest", "", "m-2", nil},
+		{"pinned to the latest", "m-2", "m-2", nil},
+		{"pinned to an older model", "m-1", "m-1", nil},
+		{"pinned to a missing model", "m-9", "", ErrPinnedModelNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, url := newFakeOpenFGA(t, "m-2", "m-1")
+			c := NewClient(url, "store-1", tt.pinned, false)
+
+			err := c.Initialize(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Initialize() = %v, want %v", err, tt.wantErr)
+			}
+			if c.modelID != tt.wantModel {
+				t.Errorf("model = %q, want %q", c.modelID, tt.wantModel)
+			}
+		})
+	}
+}
+
+func TestPinnedModelIgnoresNewerModels(t *testing.T) {
+	fga, url := newFakeOpenFGA(t, "m-1")
+	c := NewClient(url, "store-1", "m-1", false)
+	ctx := context.Background()
+	if err := c.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A newer model is written; the pinned client keeps using its pin
+	fga.models = []string{"m-2", "m-1"}
+	if model, err := c.RefreshModel(ctx); err != nil || model != "m-1" {
+		t.Errorf("RefreshModel() = %q, %v; want m-1", model, err)
+	}
+	if _, err := c.Check(ctx, "user-1", "ws-1", "can_read", "/"); err != nil {
+		t.Fatal(err)
+	}
+	if fga.modelLists != 0 {
+		t.Errorf("pinned client listed models %d times", fga.modelLists)
+	}
+
+	// When the pinned model disappears checks fail instead of silently
+	// moving to another model
+	fga.models = []string{"m-2"}
+	if _, err := c.Check(ctx, "user-1", "ws-2", "can_read", "/"); !errors.Is(err, errModelNotFound) {
+		t.Errorf("Check() = %v, want errModelNotFound", err)
+	}
+	for _, m := range fga.checkModels {
+		if m != "m-1" {
+			t.Errorf("check used model %q, want m-1", m)
+		}
+	}
+}
+
+func TestUnpinnedModelFollowsLatest(t *testing.T) {
+	fga, url := newFakeOpenFGA(t, "m-1")
+	c := NewClient(url, "store-1", "", false)
+	ctx := context.Background()
+	if err := c.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The model is replaced; the next check refreshes and retries once
+	fga.models = []string{"m-2"}
+	allowed, err := c.Check(ctx, "user-1", "ws-1", "can_read", "/")
+	if err != nil || !allowed {
+		t.Fatalf("Check() = %v, %v; want allowed", allowed, err)
+	}
+	if want := []string{"m-1", "m-2"}; strings.Join(fga.checkModels, ",") != strings.Join(want, ",") {
+		t.Errorf("checks used models %v, want %v", fga.checkModels, want)
+	}
+	if c.modelID != "m-2" {
+		t.Errorf("model = %q, want m-2", c.modelID)
+	}
+}
@@ -1,6 +1,12 @@
 package config
 
-import "os"
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Config struct {
 	Port           string
@@ -10,9 +16,118 @@ type Config struct {
 	OpenFGAURL     string
 	OpenFGAStoreID string
 	DevMode        bool
+
+	// JWTAlg is the only signing algorithm accepted: HS256 (verified with
+	// JWTSecret) or RS256 (verified with the PEM public key at
+	// JWTPublicKeyPath)
+	JWTAlg           string
+	JWTPublicKeyPath string
+
+	// JWTIssuer and JWTAudience, when set, must match the token's iss and
+	// aud claims, so tokens minted by other services are rejected
+	JWTIssuer   string
+	JWTAudience string
+
+	// AuthCookieName, when set, is the cookie the backend stores browsers'
+	// access tokens in (the backend's AUTH_COOKIE_NAME). Requests without an
+	// Authorization header are authenticated from it.
+	AuthCookieName string
+
+	// OpenFGAModelID pins the authorization model used for checks. Empty
+	// means the store's latest model, re-resolved every
+	// OpenFGAModelRefreshInterval (zero disables) and whenever a check names
+	// a model OpenFGA no longer has.
+	OpenFGAModelID              string
+	OpenFGAModelRefreshInterval time.Duration
+
+	// OpenFGAMaxAttempts bounds how often a request that failed with a
+	// connection error or a 5xx is sent in all (1 disables retries);
+	// OpenFGARetryBaseDelay is the backoff before the first retry, doubled
+	// for each one after
+	OpenFGAMaxAttempts    int
+	OpenFGARetryBaseDelay time.Duration
+
+	// Environment is the deployment environment (development, staging,
+	// production). Dev mode is refused when it is "production".
+	Environment string
+
+	// RequireForwardedHeaders rejects gate requests without X-Forwarded-Uri /
+	// X-Forwarded-Method (fail closed). When false they are logged and the
+	// request proceeds with empty values.
+	RequireForwardedHeaders bool
+
+	// FailClosed denies requests when the OpenFGA check errors instead of
+	// letting them through. Defaults to true unless DEV_MODE is on.
+	FailClosed bool
+
+	// DecisionHeader adds X-Authz-Decision to allowed gate responses,
+	// recording why access was granted (public, dev, admin-bypass, or the
+	// checked relation and object)
+	DecisionHeader bool
+
+	// LogFormat selects structured log output: "json" (one JSON object per
+	// line, for log aggregation) or "text" (key=value)
+	LogFormat string
+
+	// RequestIDHeader carries the correlation ID. The gate echoes it in its
+	// response so Traefik forwards it upstream; must match the backend.
+	RequestIDHeader string
+
+	// TrustedProxies lists the proxy IPs or CIDRs (Traefik) whose
+	// X-Forwarded-For and X-Real-IP headers are believed when resolving the
+	// client IP. Empty trusts none, so the peer address is always used.
+	TrustedProxies []string
+
+	// Per-tenant rate limits, in requests per window by plan tier
+	// (<= 0 = unlimited). Counters are shared via Postgres when DATABASE_URL
+	// is set, otherwise kept in memory.
+	TenantRateLimitEnabled    bool
+	TenantRateLimitWindow     time.Duration
+	TenantRateLimitBasic      int
+	TenantRateLimitAdvanced   int
+	TenantRateLimitEnterprise int
+
+	// PublicRoutes is the gate's allowlist of unauthenticated routes as
+	// comma-separated "[METHOD] path" entries (trailing "*" for a prefix);
+	// PublicRoutesFile, a JSON file of {method, path, path_prefix} entries,
+	// takes precedence. With neither, the built-in defaults apply.
+	PublicRoutes     string
+	PublicRoutesFile string
+
+	// RelationRulesFile is a JSON file of {method, path, relation} rules
+	// choosing the OpenFGA relation the gate checks per route. Unmatched
+	// requests map GET->can_read, POST/PUT/PATCH->can_write,
+	// DELETE->can_manage.
+	RelationRulesFile string
+
+	// CheckCacheTTL is how long OpenFGA check results are cached, bounding
+	// how stale a decision can be; CheckCacheSize caps the cached results
+	// (least recently used are evicted). Zero for either disables caching.
+	CheckCacheTTL  time.Duration
+	CheckCacheSize int
+
+	// MetricsPort serves Prometheus metrics at /metrics on a separate
+	// listener that is not routed through Traefik. Empty disables it.
+	MetricsPort string
+
+	// RevocationCacheTTL is how long the gate caches revoked token IDs and
+	// disabled users read from DATABASE_URL, i.e. how long a logged-out
+	// token may keep working
+	RevocationCacheTTL time.Duration
+
+	// Webhooks
+	WebhookSecret []byte
+
+	// Denial spike alerting (disabled when URL is empty)
+	DenialAlertWebhookURL string
+	DenialAlertThreshold  int
+	DenialAlertWindow     time.Duration
+	DenialAlertCooldown   time.Duration
 }
 
 func Load() *Config {
+	devMode := getEnv("DEV_MODE", "false") == "true"
+
 	return &Config{
 		Port:           getEnv("PORT", "8002"),
 		JWTSecret:      []byte(getEnv("JWT_SECRET", "")),
@@ -20,8 +135,64 @@ func Load() *Config {
 		DatabaseURL:    getEnv("DATABASE_URL", ""),
 		OpenFGAURL:     getEnv("OPENFGA_URL", "http://openfga:8080"),
 		OpenFGAStoreID: getEnv("OPENFGA_STORE_ID", ""),
-		DevMode:        getEnv("DEV_MODE", "false") == "true",
+		DevMode:        devMode,
+		Environment:    getEnv("ENVIRONMENT", "development"),
+		OpenFGAModelID: getEnv("OPENFGA_MODEL_ID", ""),
+
+		OpenFGAModelRefreshInterval: getEnvDuration("OPENFGA_MODEL_REFRESH_INTERVAL", time.Minute),
+		OpenFGAMaxAttempts:          getEnvInt("OPENFGA_MAX_ATTEMPTS", 3),
+		OpenFGARetryBaseDelay:       getEnvDuration("OPENFGA_RETRY_BASE_DELAY", 50*time.Millisecond),
+
+		JWTAlg:           getEnv("JWT_ALG", "HS256"),
+		JWTPublicKeyPath: getEnv("JWT_PUBLIC_KEY_PATH", ""),
+		JWTIssuer:        getEnv("JWT_ISSUER", "saas-starter-kit"),
+		JWTAudience:      getEnv("JWT_AUDIENCE", "saas-starter-kit"),
+		AuthCookieName:   getEnv("AUTH_COOKIE_NAME", ""),
+
+		RequireForwardedHeaders: getEnv("REQUIRE_FORWARDED_HEADERS", "true") == "true",
+		FailClosed:              getEnv("FAIL_CLOSED", strconv.FormatBool(!devMode)) == "true",
+		RequestIDHeader:         getEnv("REQUEST_ID_HEADER", "X-Request-ID"),
+		LogFormat:               getEnv("LOG_FORMAT", "json"),
+		TrustedProxies:          getEnvList("TRUSTED_PROXIES"),
+		DecisionHeader:          getEnv("AUTHZ_DECISION_HEADER", "false") == "true",
+
+		TenantRateLimitEnabled:    getEnv("TENANT_RATE_LIMIT_ENABLED", "true") == "true",
+		TenantRateLimitWindow:     getEnvDuration("TENANT_RATE_LIMIT_WINDOW", time.Minute),
+		TenantRateLimitBasic:      getEnvInt("TENANT_RATE_LIMIT_BASIC", 300),
+		TenantRateLimitAdvanced:   getEnvInt("TENANT_RATE_LIMIT_ADVANCED", 1200),
+		TenantRateLimitEnterprise: getEnvInt("TENANT_RATE_LIMIT_ENTERPRISE", 6000),
+
+		PublicRoutes:     getEnv("PUBLIC_ROUTES", ""),
+		PublicRoutesFile: getEnv("PUBLIC_ROUTES_FILE", ""),
+
+		RelationRulesFile: getEnv("RELATION_RULES_FILE", ""),
+
+		CheckCacheTTL:  getEnvDuration("CHECK_CACHE_TTL", 5*time.Second),
+		CheckCacheSize: getEnvInt("CHECK_CACHE_SIZE", 10000),
+
+		MetricsPort: getEnv("METRICS_PORT", ""),
+
+		RevocationCacheTTL: getEnvDuration("REVOCATION_CACHE_TTL", 10*time.Second),
+
+		WebhookSecret: []byte(getEnv("WEBHOOK_SECRET", "")),
+
+		DenialAlertWebhookURL: getEnv("DENIAL_ALERT_WEBHOOK_URL", ""),
+		DenialAlertThreshold:  getEnvInt("DENIAL_ALERT_THRESHOLD", 50),
+		DenialAlertWindow:     getEnvDuration("DENIAL_ALERT_WINDOW", time.Minute),
+		DenialAlertCooldown:   getEnvDuration("DENIAL_ALERT_COOLDOWN", 15*time.Minute),
+	}
+}
+
+// Validate rejects unsafe combinations. Dev mode lets unauthenticated
+// requests through as a platform admin, so it must never run in production.
+func (c *Config) Validate() error {
+	if c.DevMode && strings.EqualFold(c.Environment, "production") {
+		return errors.New("DEV_MODE=true is not allowed when ENVIRONMENT=production")
+	}
+	if c.MetricsPort != "" && c.MetricsPort == c.Port {
+		return errors.New("METRICS_PORT must differ from PORT so metrics are not served publicly")
 	}
+	return nil
 }
 
 func getEnv(key, defaultVal string) string {
@@ -30,3 +201,32 @@ func getEnv(key, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+func getEnvInt(key string, defaultVal int) int {
+	if val := os.Getenv(key); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			return n
+		}
+	}
+	return defaultVal
+}
+
+// getEnvList reads a comma-separated list, ignoring blank entries
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+	}
+	return defaultVal
+}
@@ -0,0 +1,52 @@
+package config
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"dev mode in development", Config{DevMode: true, Environment: "development", Port: "8002"}, false},
+		{"dev mode in production", Config{DevMode: true, Environment: "production", Port: "8002"}, true},
+		{"dev mode in production, any case", Config{DevMode: true, Environment: "PRODUCTION", Port: "8002"}, true},
+		{"production without dev mode", Config{Environment: "production", Port: "8002"}, false},
+		{"metrics on their own port", Config{Port: "8002", MetricsPort: "9090"}, false},
+		{"metrics on the public port", Config{Port: "8002", MetricsPort: "8002"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadDevModeDefaults(t *testing.T) {
+	tests := []struct {
+		name           string
+		devMode        string
+		failClosed     string
+		wantFailClosed bool
+	}{
+		{"production defaults", "", "", true},
+		{"dev mode fails open by default", "true", "", false},
+		{"explicit fail closed in dev mode", "true", "true", true},
+		{"explicit fail open", "", "false", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEV_MODE", tt.devMode)
+			t.Setenv("FAIL_CLOSED", tt.failClosed)
+			cfg := Load()
+			if cfg.DevMode != (tt.devMode == "true") {
+				t.Errorf("DevMode = %v", cfg.DevMode)
+			}
+			if cfg.FailClosed != tt.wantFailClosed {
+				t.Errorf("FailClosed = %v, want %v", cfg.FailClosed, tt.wantFailClosed)
+			}
+		})
+	}
+}
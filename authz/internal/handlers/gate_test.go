@@ -0,0 +1,517 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"saas-authz/internal/auth"
+	"saas-authz/internal/authz"
+	"saas-authz/internal/ratelimit"
+	"saas-authz/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("test-secret")
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// signToken issues an HS256 access token for testSecret
+func signToken(t *testing.T, claims auth.JWTClaims) string {
+	t.Helper()
+	if claims.Subject == "" {
+		claims.Subject = "user-1"
+	}
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// forwardAuth sends a ForwardAuth request for method and uri, as Traefik
+// would, with the given headers
+func forwardAuth(h *GateHandler, method, uri string, headers map[string]string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.GET("/auth", h.Handle)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth", nil)
+	req.Header.Set("X-Forwarded-Method", method)
+	req.Header.Set("X-Forwarded-Uri", uri)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCookieAuthNeedsCSRFHeader(t *testing.T) {
+	h := NewGateHandler(GateOptions{
+		JWT:                     auth.NewJWTValidator(testSecret),
+		TokenCookie:             "access_token",
+		RequireForwardedHeaders: true,
+	})
+	token := signToken(t, auth.JWTClaims{})
+	cookie := "access_token=" + token
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    int
+	}{
+		{"cookie read", http.MethodGet, map[string]string{"Cookie": cookie}, http.StatusOK},
+		{"cookie write without header", http.MethodPost, map[string]string{"Cookie": cookie}, http.StatusForbidden},
+		{"cookie delete without header", http.MethodDelete, map[string]string{"Cookie": cookie}, http.StatusForbidden},
+		{"cookie write with header", http.MethodPost, map[string]string{"Cookie": cookie, csrfHeader: "XMLHttpRequest"}, http.StatusOK},
+		{"bearer write", http.MethodPost, map[string]string{"Authorization": "Bearer " + token}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := forwardAuth(h, tt.method, "/api/v1/tenant", tt.headers); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyAllowsWorkspace(t *testing.T) {
+	// No API key validator, so tenant-wide keys can't be checked
+	h := NewGateHandler(GateOptions{})
+
+	tests := []struct {
+		name    string
+		id      auth.Identity
+		want    bool
+		wantErr bool
+	}{
+		{"listed workspace", auth.Identity{WorkspaceID: "ws-2", WorkspaceIDs: []string{"ws-1", "ws-2"}}, true, false},
+		{"unlisted workspace", auth.Identity{WorkspaceID: "ws-3", WorkspaceIDs: []string{"ws-1", "ws-2"}}, false, false},
+		{"tenant-wide without validator fails closed", auth.Identity{WorkspaceID: "ws-1", TenantID: "t-1", AllWorkspaces: true}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.keyAllowsWorkspace(&tt.id)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("keyAllowsWorkspace() = %v, %v; want %v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMissingForwardedHeaders(t *testing.T) {
+	token := signToken(t, auth.JWTClaims{})
+	bearer := map[string]string{"Authorization": "Bearer " + token}
+
+	tests := []struct {
+		name    string
+		require bool
+		method  string
+		uri     string
+		want    int
+	}{
+		{"both present", true, http.MethodGet, "/api/v1/tenant", http.StatusOK},
+		{"missing uri", true, http.MethodGet, "", http.StatusBadRequest},
+		{"missing method", true, "", "/api/v1/tenant", http.StatusBadRequest},
+		{"missing both", true, "", "", http.StatusBadRequest},
+		{"missing uri, not required", false, http.MethodGet, "", http.StatusOK},
+		{"missing both, not required", false, "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewGateHandler(GateOptions{
+				JWT:                     auth.NewJWTValidator(testSecret),
+				RequireForwardedHeaders: tt.require,
+			})
+			w := forwardAuth(h, tt.method, tt.uri, bearer)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusBadRequest && !strings.Contains(w.Body.String(), "missing_forwarded_headers") {
+				t.Errorf("body = %s, want missing_forwarded_headers", w.Body.String())
+			}
+		})
+	}
+
+	// Without the headers nothing matches a public route, so the request
+	// still needs credentials
+	h := NewGateHandler(GateOptions{JWT: auth.NewJWTValidator(testSecret)})
+	if w := forwardAuth(h, "", "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+// TestGateForwardsRequestID follows a request ID through the gate: Traefik
+// copies the ID the gate responds with onto the upstream request (see
+// deploy/traefik/dynamic.yml), and the gate's own log entry carries it
+func TestGateForwardsRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	h := NewGateHandler(GateOptions{JWT: auth.NewJWTValidator(testSecret), RequireForwardedHeaders: true})
+	r := gin.New()
+	r.Use(requestid.Middleware("X-Request-ID"))
+	r.GET("/auth", h.Handle)
+
+	token := signToken(t, auth.JWTClaims{})
+	tests := []struct {
+		name     string
+		incoming string
+		auth     string
+		want     int
+	}{
+		{"allowed with a client ID", "client-req-1", "Bearer " + token, http.StatusOK},
+		{"denied with a client ID", "client-req-2", "", http.StatusUnauthorized},
+		{"allowed without an ID", "", "Bearer " + token, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/auth", nil)
+			req.Header.Set("X-Forwarded-Method", http.MethodGet)
+			req.Header.Set("X-Forwarded-Uri", "/api/v1/tenant")
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-ID", tt.incoming)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+
+			id := w.Header().Get("X-Request-ID")
+			if id == "" || (tt.incoming != "" && id != tt.incoming) {
+				t.Errorf("X-Request-ID = %q, want %q", id, tt.incoming)
+			}
+			if !strings.Contains(logs.String(), `"request_id":"`+id+`"`) {
+				t.Errorf("gate log = %s, want request_id %s", logs.String(), id)
+			}
+		})
+	}
+}
+
+func TestGateTenantRateLimit(t *testing.T) {
+	limits := ratelimit.NewTenantLimiter(ratelimit.NewMemoryStore(), nil, map[string]int{ratelimit.DefaultTier: 2}, time.Minute)
+	h := NewGateHandler(GateOptions{JWT: auth.NewJWTValidator(testSecret), Limits: limits})
+
+	tests := []struct {
+		name          string
+		claims        auth.JWTClaims
+		want          int
+		wantRemaining string
+	}{
+		{"first request", auth.JWTClaims{TenantID: "t-1"}, http.StatusOK, "1"},
+		{"second request", auth.JWTClaims{TenantID: "t-1"}, http.StatusOK, "0"},
+		{"over the limit", auth.JWTClaims{TenantID: "t-1"}, http.StatusTooManyRequests, "0"},
+		{"other tenant", auth.JWTClaims{TenantID: "t-2"}, http.StatusOK, "1"},
+		{"platform admin is exempt", auth.JWTClaims{TenantID: "t-1", IsPlatformAdmin: true}, http.StatusOK, ""},
+		{"no tenant", auth.JWTClaims{}, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := forwardAuth(h, http.MethodGet, "/api/v1/tenant", map[string]string{
+				"Authorization": "Bearer " + signToken(t, tt.claims),
+			})
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if got := w.Header().Get("X-Tenant-RateLimit-Remaining"); got != tt.wantRemaining {
+				t.Errorf("X-Tenant-RateLimit-Remaining = %q, want %q", got, tt.wantRemaining)
+			}
+			if tt.want == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Error("429 without Retry-After")
+			}
+		})
+	}
+}
+
+// checkServer fakes an OpenFGA store "store-1" with model "m-1" whose checks
+// answer allowed, or fail with status when it isn't 200
+func checkServer(t *testing.T, status int, allowed bool) *authz.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/stores/store-1/authorization-models/m-1":
+			w.Write([]byte(`{"authorization_model":{}}`))
+		case "/stores/store-1/check":
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]bool{"allowed": allowed})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client := authz.NewClient(srv.URL, "store-1", "m-1", false)
+	if err := client.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestDecisionHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       GateOptions
+		method     string
+		uri        string
+		claims     *auth.JWTClaims // nil sends no credential
+		want       int
+		wantHeader string
+	}{
+		{
+			name:       "public route",
+			uri:        "/api/v1/health",
+			want:       http.StatusOK,
+			wantHeader: decisionPublic,
+		},
+		{
+			name:       "dev mode",
+			opts:       GateOptions{DevMode: true},
+			uri:        "/api/v1/tenant",
+			want:       http.StatusOK,
+			wantHeader: decisionDev,
+		},
+		{
+			name:       "no workspace",
+			uri:        "/api/v1/tenant",
+			claims:     &auth.JWTClaims{},
+			want:       http.StatusOK,
+			wantHeader: decisionAuthenticated,
+		},
+		{
+			name:       "platform admin",
+			uri:        "/api/v1/documents",
+			claims:     &auth.JWTClaims{WorkspaceID: "ws-1", IsPlatformAdmin: true},
+			want:       http.StatusOK,
+			wantHeader: decisionAdminBypass,
+		},
+		{
+			name:       "checked read",
+			opts:       GateOptions{Authz: checkServer(t, http.StatusOK, true)},
+			uri:        "/api/v1/documents",
+			claims:     &auth.JWTClaims{WorkspaceID: "ws-1"},
+			want:       http.StatusOK,
+			wantHeader: "can_read container:ws-1",
+		},
+		{
+			name:       "checked write",
+			opts:       GateOptions{Authz: checkServer(t, http.StatusOK, true)},
+			method:     http.MethodPost,
+			uri:        "/api/v1/documents",
+			claims:     &auth.JWTClaims{WorkspaceID: "ws-1"},
+			want:       http.StatusOK,
+			wantHeader: "can_write container:ws-1",
+		},
+		{
+			name:   "denied",
+			opts:   GateOptions{Authz: checkServer(t, http.StatusOK, false)},
+			uri:    "/api/v1/documents",
+			claims: &auth.JWTClaims{WorkspaceID: "ws-1"},
+			want:   http.StatusForbidden,
+		},
+		{
+			name:       "check error fails open",
+			opts:       GateOptions{Authz: checkServer(t, http.StatusInternalServerError, false)},
+			uri:        "/api/v1/documents",
+			claims:     &auth.JWTClaims{WorkspaceID: "ws-1"},
+			want:       http.StatusOK,
+			wantHeader: decisionFailOpen,
+		},
+		{
+			name:   "check error fails closed",
+			opts:   GateOptions{Authz: checkServer(t, http.StatusInternalServerError, false), FailClosed: true},
+			uri:    "/api/v1/documents",
+			claims: &auth.JWTClaims{WorkspaceID: "ws-1"},
+			want:   http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		for _, enabled := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/enabled=%v", tt.name, enabled), func(t *testing.T) {
+				opts := tt.opts
+				opts.JWT = auth.NewJWTValidator(testSecret)
+				opts.DecisionHeader = enabled
+				h := NewGateHandler(opts)
+
+				method := tt.method
+				if method == "" {
+					method = http.MethodGet
+				}
+				headers := map[string]string{}
+				if tt.claims != nil {
+					headers["Authorization"] = "Bearer " + signToken(t, *tt.claims)
+				}
+				w := forwardAuth(h, method, tt.uri, headers)
+				if w.Code != tt.want {
+					t.Fatalf("status = %d, want %d", w.Code, tt.want)
+				}
+
+				want := tt.wantHeader
+				if !enabled {
+					want = ""
+				}
+				if got := w.Header().Get("X-Authz-Decision"); got != want {
+					t.Errorf("X-Authz-Decision = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+}
+
+// workspaceTenants maps workspace IDs to tenant IDs; err fails every lookup
+type workspaceTenants struct {
+	tenants map[string]string
+	err     error
+}
+
+func (w workspaceTenants) TenantOf(workspaceID string) (string, error) {
+	return w.tenants[workspaceID], w.err
+}
+
+func TestGateTenantParentTuple(t *testing.T) {
+	// The fake OpenFGA allows only checks carrying the tenant parent tuple,
+	// as for a tenant admin with no workspace membership
+	var checks int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/stores/store-1/authorization-models/m-1":
+			w.Write([]byte(`{"authorization_model":{}}`))
+		case "/stores/store-1/check":
+			checks++
+			var req struct {
+				Contextual struct {
+					TupleKeys []authz.TupleKey `json:"tuple_keys"`
+				} `json:"contextual_tuples"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			allowed := len(req.Contextual.TupleKeys) == 1 &&
+				req.Contextual.TupleKeys[0] == authz.TenantParentTuple("tenant-a", "ws-a")
+			json.NewEncoder(w).Encode(map[string]bool{"allowed": allowed})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client := authz.NewClient(srv.URL, "store-1", "m-1", false)
+	if err := client.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	directory := workspaceTenants{tenants: map[string]string{"ws-a": "tenant-a", "ws-b": "tenant-b"}}
+
+	tests := []struct {
+		name       string
+		workspaces WorkspaceTenants
+		workspace  string
+		want       int
+		wantCheck  bool
+	}{
+		{"own tenant's workspace", directory, "ws-a", http.StatusOK, true},
+		{"other tenant's workspace", directory, "ws-b", http.StatusForbidden, false},
+		{"unknown workspace", directory, "ws-x", http.StatusForbidden, false},
+		{"lookup failure checks without tenant roles", workspaceTenants{err: errors.New("db down")}, "ws-a", http.StatusForbidden, true},
+		{"no directory checks without tenant roles", nil, "ws-a", http.StatusForbidden, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks = 0
+			h := NewGateHandler(GateOptions{
+				JWT:        auth.NewJWTValidator(testSecret),
+				Authz:      client,
+				Workspaces: tt.workspaces,
+				FailClosed: true,
+			})
+			w := forwardAuth(h, http.MethodDelete, "/api/v1/workspaces/"+tt.workspace, map[string]string{
+				"Authorization":  "Bearer " + signToken(t, auth.JWTClaims{TenantID: "tenant-a"}),
+				"X-Workspace-ID": tt.workspace,
+			})
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if (checks > 0) != tt.wantCheck {
+				t.Errorf("OpenFGA checked %d times, want a check %v", checks, tt.wantCheck)
+			}
+		})
+	}
+}
+
+// apiKeys is a fake APIKeys holding identities by token and workspaces by
+// tenant
+type apiKeys struct {
+	keys       map[string]auth.Identity
+	workspaces map[string]string
+}
+
+func (k apiKeys) Validate(token string) (*auth.Identity, error) {
+	id, ok := k.keys[token]
+	if !ok {
+		return nil, auth.ErrKeyNotFound
+	}
+	return &id, nil
+}
+
+func (k apiKeys) WorkspaceInTenant(workspaceID, tenantID string) (bool, error) {
+	return k.workspaces[workspaceID] == tenantID, nil
+}
+
+func TestGateMultiWorkspaceKey(t *testing.T) {
+	keys := apiKeys{
+		keys: map[string]auth.Identity{
+			"sk-single": {UserID: "u-1", TenantID: "t-1", WorkspaceID: "ws-1"},
+			"sk-multi":  {UserID: "u-1", TenantID: "t-1", WorkspaceIDs: []string{"ws-1", "ws-2"}},
+			"sk-tenant": {UserID: "u-1", TenantID: "t-1", AllWorkspaces: true},
+		},
+		workspaces: map[string]string{"ws-1": "t-1", "ws-2": "t-1", "ws-3": "t-2"},
+	}
+	h := NewGateHandler(GateOptions{APIKey: keys, Authz: checkServer(t, http.StatusOK, true), FailClosed: true})
+
+	tests := []struct {
+		name      string
+		key       string
+		workspace string
+		want      int
+	}{
+		{"single-workspace key without header", "sk-single", "", http.StatusOK},
+		{"multi-workspace key without header", "sk-multi", "", http.StatusForbidden},
+		{"multi-workspace key in scope", "sk-multi", "ws-2", http.StatusOK},
+		{"multi-workspace key out of scope", "sk-multi", "ws-3", http.StatusForbidden},
+		{"tenant-wide key without header", "sk-tenant", "", http.StatusOK},
+		{"tenant-wide key in tenant", "sk-tenant", "ws-2", http.StatusOK},
+		{"tenant-wide key outside tenant", "sk-tenant", "ws-3", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{"Authorization": "Bearer " + tt.key}
+			if tt.workspace != "" {
+				headers["X-Workspace-ID"] = tt.workspace
+			}
+			w := forwardAuth(h, http.MethodGet, "/api/v1/documents", headers)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
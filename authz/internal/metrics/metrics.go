@@ -0,0 +1,71 @@
+// Package metrics exposes the gate's Prometheus metrics: gate decisions,
+// authentication methods, OpenFGA check latency and HTTP request duration.
+// Metrics are served on a separate port (METRICS_PORT) so they are never
+// routed publicly through Traefik.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// GateDecisions counts gate requests by outcome: allow, deny or error
+	GateDecisions = NewCounterVec("authz_gate_decisions_total",
+		"Gate requests by outcome (allow, deny, error).", "outcome")
+
+	// GateAuthentications counts authentication attempts by method (jwt,
+	// apikey, dev) and result (success, failure)
+	GateAuthentications = NewCounterVec("authz_gate_authentications_total",
+		"Gate authentication attempts by method (jwt, apikey, dev) and result.", "method", "result")
+
+	// OpenFGACheckDuration is the latency of OpenFGA checks by result
+	// (allowed, denied, error)
+	OpenFGACheckDuration = NewHistogramVec("authz_openfga_check_duration_seconds",
+		"Latency of OpenFGA check calls by result (allowed, denied, error).", nil, "result")
+
+	// CheckCacheLookups counts OpenFGA check cache lookups by result (hit,
+	// miss)
+	CheckCacheLookups = NewCounterVec("authz_check_cache_lookups_total",
+		"OpenFGA check cache lookups by result (hit, miss).", "result")
+
+	// OpenFGARetries counts OpenFGA requests sent again after a transient
+	// failure, by reason (connection, server_error)
+	OpenFGARetries = NewCounterVec("authz_openfga_retries_total",
+		"OpenFGA requests retried after a transient failure, by reason (connection, server_error).", "reason")
+
+	// HTTPRequestDuration is the latency of requests to this service by
+	// method, route and status
+	HTTPRequestDuration = NewHistogramVec("authz_http_request_duration_seconds",
+		"Duration of HTTP requests by method, route and status.", nil, "method", "route", "status")
+)
+
+// ObserveCheck records the latency and result of an OpenFGA check
+func ObserveCheck(elapsed time.Duration, allowed bool, err error) {
+	result := "denied"
+	switch {
+	case err != nil:
+		result = "error"
+	case allowed:
+		result = "allowed"
+	}
+	OpenFGACheckDuration.Observe(elapsed.Seconds(), result)
+}
+
+// Middleware records HTTPRequestDuration per matched route; unmatched
+// requests share the "unmatched" route so paths can't explode the series
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		HTTPRequestDuration.Observe(time.Since(start).Seconds(),
+			c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
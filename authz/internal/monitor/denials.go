@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"saas-authz/internal/webhook"
+)
+
+// EventDenialSpike is the webhook event type fired when denials spike
+const EventDenialSpike = "authz.denial_spike"
+
+// maxTrackedDenials bounds the denials kept for the window, so a flood of
+// denials can't grow memory without limit. Beyond it the oldest are dropped
+// and the alert's total reports the cap.
+const maxTrackedDenials = 10000
+
+type denial struct {
+	at   time.Time
+	kind string
+}
+
+// DenialMonitor tracks unauthorized/forbidden decisions over a sliding window
+// and fires a webhook when they exceed a threshold. After firing, further
+// alerts are suppressed until the cooldown has elapsed.
+type DenialMonitor struct {
+	mu        sync.Mutex
+	notifier  *webhook.Notifier
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	denials   []denial
+	lastFired time.Time
+	now       func() time.Time
+}
+
+// NewDenialMonitor creates a denial spike monitor
+func NewDenialMonitor(notifier *webhook.Notifier, threshold int, window, cooldown time.Duration) *DenialMonitor {
+	return &DenialMonitor{
+		notifier:  notifier,
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Record registers a denial of the given kind ("unauthorized" or "forbidden").
+// It is safe to call on a nil monitor.
+func (m *DenialMonitor) Record(kind string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	now := m.now()
+	m.prune(now)
+	if len(m.denials) >= m.limit() {
+		m.denials = m.denials[len(m.denials)-m.limit()+1:]
+	}
+	m.denials = append(m.denials, denial{at: now, kind: kind})
+
+	if len(m.denials) < m.threshold {
+		m.mu.Unlock()
+		return
+	}
+	if !m.lastFired.IsZero() && now.Sub(m.lastFired) < m.cooldown {
+		m.mu.Unlock()
+		return
+	}
+	m.lastFired = now
+
+	counts := make(map[string]int)
+	for _, d := range m.denials {
+		counts[d.kind]++
+	}
+	event := webhook.Event{
+		Type:      EventDenialSpike,
+		Timestamp: now,
+		Data: map[string]interface{}{
+			"total":          len(m.denials),
+			"by_kind":        counts,
+			"threshold":      m.threshold,
+			"window_seconds": int(m.window.Seconds()),
+		},
+	}
+	m.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := m.notifier.Send(ctx, event); err != nil {
+			log.Printf("[monitor] Failed to send denial spike alert: %v", err)
+		}
+	}()
+}
+
+// limit is how many denials are kept: maxTrackedDenials, or the threshold
+// if higher so the alert can still fire
+func (m *DenialMonitor) limit() int {
+	return max(m.threshold, maxTrackedDenials)
+}
+
+// prune drops denials older than the window. Caller must hold m.mu.
+func (m *DenialMonitor) prune(now time.Time) {
+	cutoff := now.Add(-m.window)
+	i := 0
+	for i < len(m.denials) && m.denials[i].at.Before(cutoff) {
+		i++
+	}
+	m.denials = m.denials[i:]
+}
@@ -0,0 +1,118 @@
+package monitor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"saas-authz/internal/webhook"
+)
+
+// alertReceiver is a webhook endpoint that hands received events to the test
+func alertReceiver(t *testing.T) (*webhook.Notifier, <-chan webhook.Event) {
+	t.Helper()
+	events := make(chan webhook.Event, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event webhook.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decode alert: %v", err)
+		}
+		events <- event
+	}))
+	t.Cleanup(srv.Close)
+	return webhook.NewNotifier(srv.URL, nil), events
+}
+
+func expectAlert(t *testing.T, events <-chan webhook.Event) webhook.Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a denial spike alert")
+		return webhook.Event{}
+	}
+}
+
+func expectNoAlert(t *testing.T, events <-chan webhook.Event) {
+	t.Helper()
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected alert: %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDenialMonitorFiresOnceThenCoolsDown(t *testing.T) {
+	notifier, events := alertReceiver(t)
+	m := NewDenialMonitor(notifier, 5, time.Minute, 10*time.Minute)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		m.Record("forbidden")
+	}
+	expectNoAlert(t, events)
+
+	m.Record("unauthorized")
+	event := expectAlert(t, events)
+	if event.Type != EventDenialSpike {
+		t.Errorf("type = %q, want %q", event.Type, EventDenialSpike)
+	}
+	if total := event.Data["total"]; total != float64(5) {
+		t.Errorf("total = %v, want 5", total)
+	}
+	byKind, _ := event.Data["by_kind"].(map[string]interface{})
+	if byKind["forbidden"] != float64(4) || byKind["unauthorized"] != float64(1) {
+		t.Errorf("by_kind = %v, want 4 forbidden and 1 unauthorized", byKind)
+	}
+
+	// Still spiking, but within the cooldown
+	now = now.Add(30 * time.Second)
+	for i := 0; i < 20; i++ {
+		m.Record("forbidden")
+	}
+	expectNoAlert(t, events)
+
+	// After the cooldown a new spike alerts again
+	now = now.Add(10 * time.Minute)
+	for i := 0; i < 5; i++ {
+		m.Record("forbidden")
+	}
+	expectAlert(t, events)
+}
+
+func TestDenialMonitorWindow(t *testing.T) {
+	notifier, events := alertReceiver(t)
+	m := NewDenialMonitor(notifier, 3, time.Minute, time.Minute)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	// Denials spread wider than the window never add up to the threshold
+	for i := 0; i < 10; i++ {
+		m.Record("forbidden")
+		now = now.Add(40 * time.Second)
+	}
+	expectNoAlert(t, events)
+}
+
+func TestDenialMonitorBoundsMemory(t *testing.T) {
+	notifier, _ := alertReceiver(t)
+	m := NewDenialMonitor(notifier, 10, time.Hour, time.Hour)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	for i := 0; i < 3*maxTrackedDenials; i++ {
+		m.Record("forbidden")
+	}
+	if got := len(m.denials); got > maxTrackedDenials {
+		t.Errorf("tracked %d denials, want at most %d", got, maxTrackedDenials)
+	}
+}
+
+func TestDenialMonitorNil(t *testing.T) {
+	var m *DenialMonitor
+	m.Record("forbidden") // must not panic
+}
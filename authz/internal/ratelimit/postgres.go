@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// PostgresStore keeps counters in Postgres so all gate replicas share them.
+// It also resolves tenant plans from the backend's subscription tables.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore connects to the database and creates the counter table
+func NewPostgresStore(databaseURL string) (*PostgresStore, error) {
+	if databaseURL == "" {
+		return nil, errors.New("database URL required for shared rate limits")
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS rate_limit_counters (
+			key          TEXT        NOT NULL,
+			window_start TIMESTAMPTZ NOT NULL,
+			count        INTEGER     NOT NULL DEFAULT 0,
+			PRIMARY KEY (key, window_start)
+		)
+	`); err != nil {
+		return nil, err
+	}
+
+	return &PostgresStore{db: db}, nil
+}
+
+func (s *PostgresStore) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// Increment implements Store
+func (s *PostgresStore) Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	windowStart := time.Now().Truncate(window)
+
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO rate_limit_counters (key, window_start, count)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (key, window_start)
+		DO UPDATE SET count = rate_limit_counters.count + 1
+		RETURNING count
+	`, key, windowStart).Scan(&count)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	// First hit of a new window: drop this key's old windows so the table
+	// stays one row per active key
+	if count == 1 {
+		s.db.ExecContext(ctx, `DELETE FROM rate_limit_counters WHERE key = $1 AND window_start < $2`, key, windowStart)
+	}
+
+	return count, windowStart.Add(window), nil
+}
+
+// TenantPlan implements PlanLookup using the backend's subscriptions table
+func (s *PostgresStore) TenantPlan(ctx context.Context, tenantID string) (string, error) {
+	var tier string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT p.tier
+		FROM subscriptions s
+		JOIN plans p ON p.id = s.plan_id
+		WHERE s.tenant_id = $1 AND s.status IN ('active', 'trialing')
+	`, tenantID).Scan(&tier)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return tier, nil
+}
@@ -0,0 +1,178 @@
+// Package ratelimit implements fixed-window request limits. Counters live in
+// a Store so every gate replica shares them; limits for a tenant are derived
+// from its subscription plan.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultTier applies to tenants without an active subscription
+const DefaultTier = "basic"
+
+// planCacheTTL is how long a tenant's plan tier is cached before it is
+// looked up again
+const planCacheTTL = time.Minute
+
+// Store counts hits per key in fixed windows
+type Store interface {
+	// Increment records a hit for key in the current window and returns the
+	// window's count and when it resets
+	Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
+}
+
+// PlanLookup returns a tenant's plan tier ("basic", "advanced",
+// "enterprise"), or "" if it has no active subscription
+type PlanLookup func(ctx context.Context, tenantID string) (string, error)
+
+// Result describes a limit decision
+type Result struct {
+	Allowed   bool
+	Tier      string
+	Limit     int
+	Remaining int
+	ResetAt   time.Time
+}
+
+type cachedPlan struct {
+	tier      string
+	expiresAt time.Time
+}
+
+// TenantLimiter enforces per-tenant request limits based on plan tier
+type TenantLimiter struct {
+	store  Store
+	plans  PlanLookup
+	limits map[string]int // tier -> requests per window; <= 0 means unlimited
+	window time.Duration
+
+	mu        sync.Mutex
+	planCache map[string]cachedPlan
+}
+
+// NewTenantLimiter creates a tenant limiter. plans may be nil, in which case
+// every tenant gets DefaultTier limits.
+func NewTenantLimiter(store Store, plans PlanLookup, limits map[string]int, window time.Duration) *TenantLimiter {
+	return &TenantLimiter{
+		store:     store,
+		plans:     plans,
+		limits:    limits,
+		window:    window,
+		planCache: make(map[string]cachedPlan),
+	}
+}
+
+// Allow counts a request for the tenant and reports whether it is within the
+// tenant's limit. Tenants never share a counter.
+func (l *TenantLimiter) Allow(ctx context.Context, tenantID string) (Result, error) {
+	tier := l.tierFor(ctx, tenantID)
+	limit := l.limits[tier]
+	if limit <= 0 {
+		return Result{Allowed: true, Tier: tier}, nil
+	}
+
+	count, resetAt, err := l.store.Increment(ctx, "tenant:"+tenantID, l.window)
+	if err != nil {
+		return Result{}, err
+	}
+
+	remaining := limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{
+		Allowed:   count <= limit,
+		Tier:      tier,
+		Limit:     limit,
+		Remaining: remaining,
+		ResetAt:   resetAt,
+	}, nil
+}
+
+// tierFor resolves the tenant's plan tier, falling back to DefaultTier when
+// the lookup fails or the tier has no configured limit
+func (l *TenantLimiter) tierFor(ctx context.Context, tenantID string) string {
+	if l.plans == nil {
+		return DefaultTier
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	cached, ok := l.planCache[tenantID]
+	l.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.tier
+	}
+
+	tier, err := l.plans(ctx, tenantID)
+	if err != nil {
+		// Keep using a stale tier rather than downgrading on a DB blip
+		if ok {
+			return cached.tier
+		}
+		return DefaultTier
+	}
+	if _, known := l.limits[tier]; !known {
+		tier = DefaultTier
+	}
+
+	l.mu.Lock()
+	l.planCache[tenantID] = cachedPlan{tier: tier, expiresAt: now.Add(planCacheTTL)}
+	l.mu.Unlock()
+
+	return tier
+}
+
+// ============================================================================
+// In-memory store
+// ============================================================================
+
+type memoryCounter struct {
+	count   int
+	resetAt time.Time
+}
+
+// MemoryStore keeps counters in process. Use it only for a single gate
+// replica; limits are not shared across instances.
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*memoryCounter
+	now      func() time.Time
+}
+
+// NewMemoryStore creates an in-process counter store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: make(map[string]*memoryCounter),
+		now:      time.Now,
+	}
+}
+
+// Increment implements Store
+func (s *MemoryStore) Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.resetAt) {
+		s.prune(now)
+		c = &memoryCounter{resetAt: now.Truncate(window).Add(window)}
+		s.counters[key] = c
+	}
+	c.count++
+
+	return c.count, c.resetAt, nil
+}
+
+// prune drops expired counters. Caller must hold s.mu.
+func (s *MemoryStore) prune(now time.Time) {
+	for key, c := range s.counters {
+		if !now.Before(c.resetAt) {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testLimits = map[string]int{"basic": 3, "advanced": 5, "enterprise": 0}
+
+func TestTenantLimiterAllow(t *testing.T) {
+	plans := map[string]string{
+		"t-basic":      "basic",
+		"t-advanced":   "advanced",
+		"t-enterprise": "enterprise",
+		"t-unknown":    "platinum",
+		"t-none":       "",
+	}
+	lookup := func(ctx context.Context, tenantID string) (string, error) {
+		if tenantID == "t-error" {
+			return "", errors.New("database unavailable")
+		}
+		return plans[tenantID], nil
+	}
+
+	tests := []struct {
+		name        string
+		tenant      string
+		plans       PlanLookup
+		wantTier    string
+		wantAllowed int // of 10 requests
+	}{
+		{"basic plan", "t-basic", lookup, "basic", 3},
+		{"advanced plan", "t-advanced", lookup, "advanced", 5},
+		{"unlimited plan", "t-enterprise", lookup, "enterprise", 10},
+		{"unknown tier falls back to the default", "t-unknown", lookup, DefaultTier, 3},
+		{"no subscription gets the default", "t-none", lookup, DefaultTier, 3},
+		{"lookup error gets the default", "t-error", lookup, DefaultTier, 3},
+		{"no plan lookup", "t-advanced", nil, DefaultTier, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewTenantLimiter(NewMemoryStore(), tt.plans, testLimits, time.Minute)
+			allowed := 0
+			for i := 0; i < 10; i++ {
+				res, err := l.Allow(context.Background(), tt.tenant)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.Tier != tt.wantTier {
+					t.Fatalf("tier = %q, want %q", res.Tier, tt.wantTier)
+				}
+				if res.Allowed {
+					allowed++
+				}
+				if res.Limit > 0 && res.Remaining != max(res.Limit-(i+1), 0) {
+					t.Errorf("request %d: remaining = %d", i, res.Remaining)
+				}
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed %d of 10, want %d", allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestTenantLimiterIsolatesTenants(t *testing.T) {
+	l := NewTenantLimiter(NewMemoryStore(), nil, testLimits, time.Minute)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		l.Allow(ctx, "noisy")
+	}
+	if res, _ := l.Allow(ctx, "noisy"); res.Allowed {
+		t.Error("noisy tenant allowed over its limit")
+	}
+	if res, _ := l.Allow(ctx, "quiet"); !res.Allowed {
+		t.Error("quiet tenant limited by another tenant's traffic")
+	}
+}
+
+func TestTenantLimiterKeepsStaleTier(t *testing.T) {
+	failing := false
+	l := NewTenantLimiter(NewMemoryStore(), func(ctx context.Context, tenantID string) (string, error) {
+		if failing {
+			return "", errors.New("database unavailable")
+		}
+		return "advanced", nil
+	}, testLimits, time.Minute)
+	ctx := context.Background()
+
+	if res, _ := l.Allow(ctx, "t-1"); res.Tier != "advanced" {
+		t.Fatalf("tier = %q, want advanced", res.Tier)
+	}
+
+	// An expired cache entry is kept when the lookup fails
+	failing = true
+	l.planCache["t-1"] = cachedPlan{tier: "advanced", expiresAt: time.Now().Add(-time.Second)}
+	if res, _ := l.Allow(ctx, "t-1"); res.Tier != "advanced" {
+		t.Errorf("tier after failed lookup = %q, want advanced", res.Tier)
+	}
+}
+
+func TestMemoryStoreWindows(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		advance   time.Duration
+		key       string
+		wantCount int
+		wantReset time.Time
+	}{
+		{"first hit", 0, "a", 1, now.Add(time.Minute)},
+		{"same window", 30 * time.Second, "a", 2, now.Add(time.Minute)},
+		{"other key", 0, "b", 1, now.Add(time.Minute)},
+		{"window end resets", 30 * time.Second, "a", 1, now.Add(2 * time.Minute)},
+	}
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		count, resetAt, err := s.Increment(ctx, tt.key, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != tt.wantCount || !resetAt.Equal(tt.wantReset) {
+			t.Errorf("%s: Increment() = %d, %v; want %d, %v", tt.name, count, resetAt, tt.wantCount, tt.wantReset)
+		}
+	}
+	if _, ok := s.counters["b"]; ok {
+		t.Error("expired counter not pruned")
+	}
+}
@@ -0,0 +1,79 @@
+package requestid
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool // whether the incoming ID is reused
+	}{
+		{"reuses a valid ID", "abc-123_DEF.4", true},
+		{"generates one when missing", "", false},
+		{"replaces one with spaces", "abc 123", false},
+		{"replaces one with a newline", "abc\nforged log line", false},
+		{"replaces one that is too long", strings.Repeat("a", maxLength+1), false},
+		{"accepts the maximum length", strings.Repeat("a", maxLength), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			r := gin.New()
+			r.Use(Middleware("X-Request-ID"))
+			r.GET("/", func(c *gin.Context) {
+				seen = Get(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-ID", tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			echoed := w.Header().Get("X-Request-ID")
+			if echoed != seen {
+				t.Errorf("response header %q, context %q", echoed, seen)
+			}
+			if tt.keep && seen != tt.incoming {
+				t.Errorf("ID = %q, want the incoming %q", seen, tt.incoming)
+			}
+			if !tt.keep && (seen == tt.incoming || !valid(seen)) {
+				t.Errorf("ID = %q, want a fresh valid ID", seen)
+			}
+		})
+	}
+}
+
+func TestLoggerIncludesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	r := gin.New()
+	r.Use(Middleware("X-Request-ID"), Logger())
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(buf.String(), `"request_id":"req-42"`) {
+		t.Errorf("log = %s, want request_id req-42", buf.String())
+	}
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/saas-starter-kit/backend/internal/api/handlers"
 	"github.com/yourusername/saas-starter-kit/backend/internal/api/middleware"
 	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/fga"
+	"github.com/yourusername/saas-starter-kit/backend/internal/hierarchy"
 	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/password"
+	"github.com/yourusername/saas-starter-kit/backend/internal/revocation"
+	"github.com/yourusername/saas-starter-kit/backend/internal/session"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -15,16 +23,42 @@ import (
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	if _, err := password.NewHasher(cfg.PasswordHash, cfg.BcryptCost); err != nil {
+		log.Fatalf("Invalid password hashing config: %v", err)
+	}
 
-	// Connect to database
-	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+	// Connect to database and run migrations, waiting for the database to
+	// come up (e.g. during a deploy) instead of crash-looping
+	db, err := connectDatabase(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	// Normalize legacy mixed-case emails
+	if cfg.EmailCaseInsensitive {
+		if err := models.NormalizeUserEmails(db); err != nil {
+			log.Printf("Warning: Failed to normalize user emails: %v", err)
+		}
+	}
+
+	// Back workspace slug uniqueness with a database constraint
+	if cfg.UniqueWorkspaceSlugs {
+		if err := models.EnforceUniqueWorkspaceSlugs(db); err != nil {
+			log.Printf("Warning: Failed to enforce unique workspace slugs: %v", err)
+		}
 	}
 
-	// Run migrations
-	if err := models.AutoMigrate(db); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	// Generic hierarchy mode: load the levels and create their tables
+	var hierarchyConfig *hierarchy.Config
+	if cfg.UseHierarchy {
+		hierarchyConfig = hierarchy.LoadFromEnv()
+		if err := hierarchyConfig.Validate(); err != nil {
+			log.Fatalf("Invalid hierarchy config: %v", err)
+		}
+		if err := hierarchy.AutoMigrate(db); err != nil {
+			log.Fatalf("Failed to migrate hierarchy tables: %v", err)
+		}
+		log.Printf("Hierarchy mode: %d levels, %s to %s", hierarchyConfig.Depth(), hierarchyConfig.RootLevel, hierarchyConfig.LeafLevel)
 	}
 
 	// Seed default plans
@@ -32,65 +66,194 @@ func main() {
 		log.Fatalf("Failed to seed plans: %v", err)
 	}
 
+	// Delete expired auth state in the background
+	startCleanupJanitor(db, cfg.CleanupInterval)
+
 	// Create Gin router
-	r := gin.Default()
+	r := gin.New()
+	// c.ClientIP() only honours forwarding headers from trusted proxies, so
+	// clients can't dodge the per-IP rate limits by spoofing their address
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(middleware.RequestID(cfg.RequestIDHeader), middleware.Logger(), gin.Recovery())
 
 	// CORS middleware
-	r.Use(middleware.CORS(cfg.FrontendURL))
+	r.Use(middleware.CORS(cfg))
 
-	// Health check
+	// Request body limits; document content may be larger than API payloads
+	r.Use(middleware.BodyLimit(cfg.MaxBodyBytes, map[string]int64{
+		"/api/v1/workspaces/:id/documents":        cfg.MaxDocumentBodyBytes,
+		"/api/v1/workspaces/:id/documents/:docId": cfg.MaxDocumentBodyBytes,
+	}))
+
+	// Health check (liveness) and dependency readiness
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
+	openfgaCheck := readinessCheck{name: "openfga"}
+	if fgaClient := fga.NewClient(cfg.OpenFGAURL, cfg.OpenFGAStoreID); fgaClient != nil {
+		openfgaCheck.check = fgaClient.Ready
+	}
+	r.GET("/ready", readyHandler(
+		readinessCheck{name: "database", check: func(ctx context.Context) error {
+			return db.WithContext(ctx).Exec("SELECT 1").Error
+		}},
+		openfgaCheck,
+	))
 
 	// Initialize handlers
+	sessions := session.NewTracker(db, cfg.SessionIdleTimeout, cfg.SessionAbsoluteTimeout)
+	revocations := revocation.NewList(db, cfg.RevocationCacheTTL)
 	authHandler := handlers.NewAuthHandler(db, cfg)
 	tenantHandler := handlers.NewTenantHandler(db, cfg)
 	workspaceHandler := handlers.NewWorkspaceHandler(db, cfg)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db, cfg)
+	documentHandler := handlers.NewDocumentHandler(db, cfg)
+	projectHandler := handlers.NewProjectHandler(db, cfg)
+	feedHandler := handlers.NewFeedHandler(db, cfg)
+	invitationHandler := handlers.NewInvitationHandler(db, cfg)
+	billingHandler := handlers.NewBillingHandler(db, cfg)
+	ssoHandler := handlers.NewSSOHandler(db, cfg)
+	adminHandler := handlers.NewAdminHandler(db, cfg)
+
+	// Rate limits for the unauthenticated auth endpoints
+	rateLimitStore := middleware.NewMemoryRateLimitStore()
+	ipLimiter := middleware.NewRateLimiter(rateLimitStore, cfg.AuthRateLimitPerIP)
+	emailLimiter := middleware.NewRateLimiter(rateLimitStore, cfg.AuthRateLimitPerEmail)
 
 	// API v1 routes
 	v1 := r.Group("/api/v1")
 	{
 		// Auth routes (public)
 		auth := v1.Group("/auth")
+		auth.Use(ipLimiter.PerIP())
 		{
+			// Sign-in methods
+			auth.GET("/providers", authHandler.ListProviders)
+
 			// Social OAuth
 			auth.GET("/social/:provider/login", authHandler.InitiateOAuth)
 			auth.POST("/social/callback", authHandler.HandleOAuthCallback)
 
+			// Tenant SSO (OIDC)
+			auth.GET("/sso/:tenantSlug/login", ssoHandler.Login)
+			auth.POST("/sso/callback", ssoHandler.Callback)
+
 			// Email/Password
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/verify-email", authHandler.VerifyEmail)
-			auth.POST("/login", authHandler.Login)
-			auth.POST("/forgot-password", authHandler.ForgotPassword)
+			auth.POST("/login", emailLimiter.PerEmail(), authHandler.Login)
+			auth.POST("/forgot-password", emailLimiter.PerEmail(), authHandler.ForgotPassword)
 			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.POST("/resend-link", authHandler.ResendLink)
+
+			// Token lifecycle
+			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.POST("/logout", authHandler.Logout)
+
+			// Two-factor auth
+			auth.POST("/2fa/login", authHandler.TwoFactorLogin)
+			auth.POST("/2fa/setup", middleware.RequireAuth(cfg, sessions, revocations), middleware.RequireFreshAuth(cfg.ReauthMaxAge), authHandler.SetupTwoFactor)
+			auth.POST("/2fa/verify", middleware.RequireAuth(cfg, sessions, revocations), middleware.RequireFreshAuth(cfg.ReauthMaxAge), authHandler.VerifyTwoFactor)
 
 			// Protected
-			auth.GET("/me", middleware.RequireAuth(cfg), authHandler.GetCurrentUser)
+			auth.GET("/me", middleware.RequireAuth(cfg, sessions, revocations), authHandler.GetCurrentUser)
+			auth.DELETE("/me", middleware.RequireAuth(cfg, sessions, revocations), middleware.RequireFreshAuth(cfg.ReauthMaxAge), authHandler.DeleteAccount)
 		}
 
-		// Tenant routes (require auth)
-		tenant := v1.Group("/tenant")
-		tenant.Use(middleware.RequireAuth(cfg))
+		// Webhooks (public; verified by signature)
+		v1.POST("/webhooks/stripe", billingHandler.StripeWebhook)
+
+		// Platform admin routes. Impersonation tokens can't start another
+		// impersonation.
+		admin := v1.Group("/admin")
+		admin.Use(middleware.RequireAuth(cfg, sessions, revocations))
+		admin.Use(middleware.ForbidImpersonation())
 		{
-			tenant.GET("", tenantHandler.GetCurrentTenant)
-			tenant.GET("/plans", tenantHandler.ListPlans)
-			tenant.POST("/select-plan", tenantHandler.SelectPlan)
-			tenant.POST("/setup", tenantHandler.SetupOrganization)
-			tenant.GET("/check-slug", tenantHandler.CheckSlug)
+			admin.POST("/impersonate/:userId", adminHandler.Impersonate)
 		}
 
-		// Workspace routes (require auth + tenant)
-		workspaces := v1.Group("/workspaces")
-		workspaces.Use(middleware.RequireAuth(cfg))
-		workspaces.Use(middleware.RequireTenant(db))
-		{
-			workspaces.GET("", workspaceHandler.List)
-			workspaces.POST("", workspaceHandler.Create)
-			workspaces.GET("/:id", workspaceHandler.Get)
-			workspaces.DELETE("/:id", workspaceHandler.Delete)
-			workspaces.GET("/:id/members", workspaceHandler.ListMembers)
-			workspaces.POST("/:id/members", workspaceHandler.AddMember)
+		if cfg.UseHierarchy {
+			// Generic hierarchy (USE_HIERARCHY): container routes generated
+			// from the configured levels replace the tenant and workspace
+			// routes below
+			registerHierarchyRoutes(v1, db, cfg, hierarchyConfig, sessions, revocations)
+		} else {
+			// Current user's memberships across tenants (require auth only)
+			me := v1.Group("/me")
+			me.Use(middleware.RequireAuth(cfg, sessions, revocations))
+			{
+				me.GET("/memberships", workspaceHandler.ListMyMemberships)
+			}
+
+			// Tenant routes (require auth)
+			tenant := v1.Group("/tenant")
+			tenant.Use(middleware.RequireAuth(cfg, sessions, revocations))
+			{
+				tenant.GET("", tenantHandler.GetCurrentTenant)
+				tenant.GET("/plans", tenantHandler.ListPlans)
+				tenant.POST("/select-plan", middleware.ForbidImpersonation(), tenantHandler.SelectPlan)
+				tenant.POST("/setup", middleware.ForbidImpersonation(), tenantHandler.SetupOrganization)
+				tenant.GET("/check-slug", tenantHandler.CheckSlug)
+				tenant.GET("/usage", middleware.RequireTenant(db), middleware.RequireTenantAdmin(db), tenantHandler.GetUsage)
+				tenant.POST("/billing/checkout", middleware.ForbidImpersonation(), middleware.RequireTenant(db), middleware.RequireTenantAdmin(db), billingHandler.Checkout)
+				tenant.POST("/transfer-ownership", middleware.RequireTenant(db), middleware.RequireTenantAdmin(db), middleware.RequireFreshAuth(cfg.ReauthMaxAge), tenantHandler.TransferOwnership)
+				tenant.GET("/sso", middleware.RequireTenant(db), middleware.RequireTenantAdmin(db), ssoHandler.GetConfig)
+				tenant.PUT("/sso", middleware.RequireTenant(db), middleware.RequireTenantAdmin(db), middleware.RequireFreshAuth(cfg.ReauthMaxAge), ssoHandler.UpdateConfig)
+				tenant.DELETE("/sso", middleware.RequireTenant(db), middleware.RequireTenantAdmin(db), middleware.RequireFreshAuth(cfg.ReauthMaxAge), ssoHandler.DeleteConfig)
+			}
+
+			// Workspace routes (require auth + tenant)
+			workspaces := v1.Group("/workspaces")
+			workspaces.Use(middleware.RequireAuth(cfg, sessions, revocations))
+			workspaces.Use(middleware.RequireTenant(db))
+			{
+				workspaces.GET("", workspaceHandler.List)
+				workspaces.POST("", workspaceHandler.Create)
+				workspaces.GET("/:id", workspaceHandler.Get)
+				workspaces.DELETE("/:id", workspaceHandler.Delete)
+				workspaces.POST("/:id/select", workspaceHandler.Select)
+				workspaces.GET("/:id/members", workspaceHandler.ListMembers)
+				workspaces.POST("/:id/members", workspaceHandler.AddMember)
+				workspaces.PUT("/:id/members/:userId", workspaceHandler.UpdateMember)
+				workspaces.DELETE("/:id/members/:userId", workspaceHandler.RemoveMember)
+				workspaces.POST("/:id/invitations", invitationHandler.Create)
+
+				// Workspace-scoped resources
+				workspaces.GET("/:id/resources", feedHandler.List)
+				workspaces.GET("/:id/documents", documentHandler.List)
+				workspaces.POST("/:id/documents", documentHandler.Create)
+				workspaces.GET("/:id/documents/:docId", documentHandler.Get)
+				workspaces.PUT("/:id/documents/:docId", documentHandler.Update)
+				workspaces.DELETE("/:id/documents/:docId", documentHandler.Delete)
+				workspaces.GET("/:id/documents/:docId/shares", documentHandler.ListShares)
+				workspaces.POST("/:id/documents/:docId/shares", documentHandler.Share)
+				workspaces.GET("/:id/projects", projectHandler.List)
+				workspaces.POST("/:id/projects", projectHandler.Create)
+				workspaces.GET("/:id/projects/:projectId", projectHandler.Get)
+				workspaces.PUT("/:id/projects/:projectId", projectHandler.Update)
+				workspaces.DELETE("/:id/projects/:projectId", projectHandler.Delete)
+			}
+
+			// Invitation routes (require auth; invitees may not have a tenant yet)
+			invitations := v1.Group("/invitations")
+			invitations.Use(middleware.RequireAuth(cfg, sessions, revocations))
+			{
+				invitations.POST("/accept", invitationHandler.Accept)
+			}
+
+			// API key routes (require auth + tenant)
+			keys := v1.Group("/keys")
+			keys.Use(middleware.RequireAuth(cfg, sessions, revocations))
+			keys.Use(middleware.RequireTenant(db))
+			{
+				keys.GET("", apiKeyHandler.List)
+
+				// Sensitive: require a recent sign-in
+				keys.POST("", middleware.RequireFreshAuth(cfg.ReauthMaxAge), apiKeyHandler.Create)
+				keys.DELETE("/:id", middleware.RequireFreshAuth(cfg.ReauthMaxAge), apiKeyHandler.Revoke)
+			}
 		}
 	}
 
@@ -100,3 +263,120 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// registerHierarchyRoutes mounts the container routes of every configured
+// level under /api/v1/<url_path>, plus the hierarchy-wide ones
+func registerHierarchyRoutes(v1 *gin.RouterGroup, db *gorm.DB, cfg *config.Config, h *hierarchy.Config, sessions *session.Tracker, revocations *revocation.List) {
+	containerHandler := handlers.NewContainerHandler(db, cfg, h)
+
+	v1.GET("/hierarchy", middleware.RequireAuth(cfg, sessions, revocations), containerHandler.GetHierarchyConfig)
+	v1.POST("/hierarchy/purge", middleware.RequireAuth(cfg, sessions, revocations), containerHandler.PurgeDeletedContainers)
+	v1.GET("/me/memberships", middleware.RequireAuth(cfg, sessions, revocations), containerHandler.ListMyMemberships)
+
+	for _, level := range h.Levels {
+		containers := v1.Group("/" + level.URLPath)
+		containers.Use(middleware.RequireAuth(cfg, sessions, revocations))
+		containers.Use(middleware.ResolveRoot(db))
+		containers.Use(handlers.ForLevel(level.Name))
+		{
+			containers.GET("", containerHandler.ListContainers)
+			containers.POST("", containerHandler.CreateContainer)
+			containers.GET("/:id", containerHandler.GetContainer)
+			containers.PUT("/:id", containerHandler.UpdateContainer)
+			containers.DELETE("/:id", containerHandler.DeleteContainer)
+			containers.POST("/:id/move", containerHandler.MoveContainer)
+			containers.POST("/:id/restore", containerHandler.RestoreContainer)
+			containers.GET("/:id/members", containerHandler.ListMembers)
+			containers.POST("/:id/members", containerHandler.AddMember)
+			containers.PUT("/:id/members/:userId", containerHandler.UpdateMember)
+			containers.DELETE("/:id/members/:userId", containerHandler.RemoveMember)
+		}
+	}
+}
+
+// maxDBStartupBackoff caps the wait between database startup attempts
+const maxDBStartupBackoff = 30 * time.Second
+
+// connectDatabase opens the database and runs migrations, retrying with
+// exponential backoff up to cfg.DBStartupAttempts times
+func connectDatabase(cfg *config.Config) (*gorm.DB, error) {
+	var db *gorm.DB
+	err := retryStartup(cfg.DBStartupAttempts, cfg.DBStartupBackoff, func() error {
+		conn, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+		if err != nil {
+			return err
+		}
+		if err := models.AutoMigrate(conn); err != nil {
+			if sqlDB, dbErr := conn.DB(); dbErr == nil {
+				sqlDB.Close()
+			}
+			return fmt.Errorf("migrations failed: %w", err)
+		}
+		db = conn
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("database unavailable after %w", err)
+	}
+	return db, nil
+}
+
+// sleep waits between startup attempts; tests replace it
+var sleep = time.Sleep
+
+// retryStartup calls try until it succeeds, at most attempts times (at
+// least once), waiting backoff after the first failure and doubling the
+// wait up to maxDBStartupBackoff. The error names the attempts made.
+func retryStartup(attempts int, backoff time.Duration, try func() error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if lastErr = try(); lastErr == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		log.Printf("Database not ready (attempt %d/%d): %v; retrying in %s", attempt, attempts, lastErr, backoff)
+		sleep(backoff)
+		backoff *= 2
+		if backoff > maxDBStartupBackoff {
+			backoff = maxDBStartupBackoff
+		}
+	}
+	return fmt.Errorf("%d attempts: %w", attempts, lastErr)
+}
+
+// startCleanupJanitor runs models.CleanupExpired and models.ExpireTrials
+// every interval in the background; interval <= 0 disables it
+func startCleanupJanitor(db *gorm.DB, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			deleted, err := models.CleanupExpired(db)
+			if err != nil {
+				log.Printf("Cleanup janitor: %v", err)
+			}
+			for table, n := range deleted {
+				if n > 0 {
+					log.Printf("Cleanup janitor: removed %d expired %s", n, table)
+				}
+			}
+
+			expired, err := models.ExpireTrials(db)
+			if err != nil {
+				log.Printf("Cleanup janitor: expire trials: %v", err)
+			} else if expired > 0 {
+				log.Printf("Cleanup janitor: moved %d expired trials to past_due", expired)
+			}
+		}
+	}()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds each dependency check in /ready
+const readinessTimeout = 3 * time.Second
+
+// Per-dependency statuses reported by /ready
+const (
+	readyOK            = "ok"
+	readyUnavailable   = "unavailable"
+	readyNotConfigured = "not_configured"
+)
+
+// readinessCheck is a dependency checked by /ready. A nil check means the
+// dependency isn't configured and doesn't affect readiness.
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// readyHandler runs the checks concurrently for Kubernetes readiness probes,
+// responding 503 if any fails. Errors are logged rather than returned, so
+// the response doesn't leak connection details. /health stays a cheap
+// liveness probe.
+// GET /ready
+func readyHandler(checks ...readinessCheck) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		statuses := make(map[string]string, len(checks))
+		ready := true
+
+		for _, rc := range checks {
+			if rc.check == nil {
+				statuses[rc.name] = readyNotConfigured
+				continue
+			}
+
+			wg.Add(1)
+			go func(rc readinessCheck) {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+				defer cancel()
+				err := rc.check(ctx)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					log.Printf("Readiness check %s failed: %v", rc.name, err)
+					statuses[rc.name] = readyUnavailable
+					ready = false
+					return
+				}
+				statuses[rc.name] = readyOK
+			}(rc)
+		}
+		wg.Wait()
+
+		if !ready {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "checks": statuses})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": statuses})
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/fga"
+	"github.com/yourusername/saas-starter-kit/backend/internal/hierarchy"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// transferOwnedResources hands the documents and projects user owns in a
+// workspace that others can reach to a successor (see workspaceSuccessor),
+// so deleting the account doesn't cascade to shared work. Resources in
+// workspaces without a successor are deleted with the account. It returns
+// the OpenFGA owner tuples to write and delete, and how many resources
+// moved.
+func transferOwnedResources(tx *gorm.DB, user *models.User) (writes, deletes []fga.TupleKey, transferred int64, err error) {
+	var workspaceIDs []uuid.UUID
+	if err := tx.Raw(`SELECT workspace_id FROM documents WHERE owner_id = ?
+		UNION SELECT workspace_id FROM projects WHERE owner_id = ?`, user.ID, user.ID).
+		Scan(&workspaceIDs).Error; err != nil {
+		return nil, nil, 0, err
+	}
+
+	for _, workspaceID := range workspaceIDs {
+		successor, err := workspaceSuccessor(tx, workspaceID, user.ID)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if successor == uuid.Nil {
+			continue
+		}
+
+		var docIDs []uuid.UUID
+		if err := tx.Model(&models.Document{}).
+			Where("workspace_id = ? AND owner_id = ?", workspaceID, user.ID).
+			Pluck("id", &docIDs).Error; err != nil {
+			return nil, nil, 0, err
+		}
+		if len(docIDs) > 0 {
+			if err := tx.Model(&models.Document{}).Where("id IN ?", docIDs).Update("owner_id", successor).Error; err != nil {
+				return nil, nil, 0, err
+			}
+		}
+		for _, docID := range docIDs {
+			writes = append(writes, fga.Tuple("user", successor.String(), "owner", "document", docID.String()))
+			deletes = append(deletes, fga.Tuple("user", user.ID.String(), "owner", "document", docID.String()))
+		}
+
+		result := tx.Model(&models.Project{}).
+			Where("workspace_id = ? AND owner_id = ?", workspaceID, user.ID).
+			Update("owner_id", successor)
+		if result.Error != nil {
+			return nil, nil, 0, result.Error
+		}
+		transferred += int64(len(docIDs)) + result.RowsAffected
+	}
+	return writes, deletes, transferred, nil
+}
+
+// workspaceSuccessor picks who inherits a departing user's resources in a
+// workspace: another workspace admin, else an admin of the workspace's
+// tenant, else the longest-standing other member. It returns uuid.Nil when
+// nobody else has access.
+func workspaceSuccessor(tx *gorm.DB, workspaceID, userID uuid.UUID) (uuid.UUID, error) {
+	var membership models.Membership
+	err := tx.Where("workspace_id = ? AND user_id <> ? AND role = ?", workspaceID, userID, "admin").
+		Order("created_at").First(&membership).Error
+	if err == nil {
+		return membership.UserID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, err
+	}
+
+	var admin models.User
+	err = tx.Joins("JOIN workspaces ON workspaces.tenant_id = users.admin_of_tenant_id").
+		Where("workspaces.id = ? AND users.id <> ?", workspaceID, userID).
+		Order("users.created_at").First(&admin).Error
+	if err == nil {
+		return admin.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, err
+	}
+
+	err = tx.Where("workspace_id = ? AND user_id <> ?", workspaceID, userID).
+		Order("created_at").First(&membership).Error
+	if err == nil {
+		return membership.UserID, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, nil
+	}
+	return uuid.Nil, err
+}
+
+// removeAccessGrants deletes user's document shares and container
+// memberships, returning the OpenFGA tuples to delete with them. Container
+// memberships only exist in hierarchy mode.
+func removeAccessGrants(tx *gorm.DB, user *models.User, useHierarchy bool) ([]fga.TupleKey, error) {
+	var tuples []fga.TupleKey
+
+	var shares []models.DocumentShare
+	if err := tx.Where("user_id = ?", user.ID).Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range shares {
+		tuples = append(tuples, fga.Tuple("user", user.ID.String(), s.Role, "document", s.DocumentID.String()))
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.DocumentShare{}).Error; err != nil {
+		return nil, err
+	}
+
+	if !useHierarchy {
+		return tuples, nil
+	}
+
+	// Soft-deleted memberships go too: the account's data is erased
+	var memberships []hierarchy.ContainerMembership
+	if err := tx.Unscoped().Where("user_id = ?", user.ID).Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range memberships {
+		if m.DeletedAt.Valid || !containerRelations[hierarchy.Role(m.Role)] {
+			continue
+		}
+		tuples = append(tuples, fga.Tuple("user", user.ID.String(), m.Role, "container", m.ContainerID.String()))
+	}
+	if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&hierarchy.ContainerMembership{}).Error; err != nil {
+		return nil, err
+	}
+	return tuples, nil
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// AdminHandler handles platform administration endpoints
+type AdminHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewAdminHandler(db *gorm.DB, cfg *config.Config) *AdminHandler {
+	return &AdminHandler{db: db, cfg: cfg}
+}
+
+// Impersonate issues a short-lived access token that acts as another user,
+// so support engineers can reproduce their issues. The token carries the
+// admin's ID in the impersonator claim; the authz gate forwards it as
+// X-Impersonator-ID. No refresh token is issued and the token has no
+// auth_time, so actions that require a recent sign-in stay out of reach.
+// POST /api/v1/admin/impersonate/:userId
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var admin models.User
+	if err := h.db.First(&admin, "id = ?", userID).Error; err != nil || !admin.IsPlatformAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "message": "Platform admin access required"})
+		return
+	}
+
+	targetID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid user ID"})
+		return
+	}
+	if targetID == admin.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "You cannot impersonate yourself"})
+		return
+	}
+
+	var target models.User
+	if err := h.db.First(&target, "id = ?", targetID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "User not found"})
+		return
+	}
+	if target.IsPlatformAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "message": "Platform admins cannot be impersonated"})
+		return
+	}
+	if target.DisabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "user_disabled", "message": "User account is disabled"})
+		return
+	}
+
+	ttl := h.cfg.ImpersonationTokenTTL
+	if ttl <= 0 || ttl > h.cfg.AccessTokenTTL {
+		ttl = h.cfg.AccessTokenTTL
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	claims := jwt.MapClaims{
+		"sub":             target.ID.String(),
+		"email":           target.Email,
+		"name":            target.Name,
+		"type":            "platform",
+		"email_verified":  target.EmailVerified,
+		"is_tenant_admin": target.IsTenantAdmin,
+		"impersonator":    admin.ID.String(),
+		"exp":             expiresAt.Unix(),
+	}
+	if target.AdminOfTenantID != nil {
+		claims["tenant_id"] = target.AdminOfTenantID.String()
+	}
+
+	token, err := signAccessToken(h.cfg, claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to issue token"})
+		return
+	}
+
+	if err := h.db.Create(&models.AuditLog{
+		Action:   models.AuditImpersonationStarted,
+		UserID:   target.ID,
+		TenantID: target.AdminOfTenantID,
+		ActorID:  &admin.ID,
+	}).Error; err != nil {
+		// Impersonation must never go unrecorded
+		log.Printf("Failed to record impersonation of %s by %s: %v", target.ID, admin.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to record impersonation"})
+		return
+	}
+	log.Printf("Platform admin %s impersonating user %s until %s", admin.ID, target.ID, expiresAt.Format(time.RFC3339))
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":    token,
+		"token_type":      "Bearer",
+		"expires_in":      int(ttl.Seconds()),
+		"impersonator_id": admin.ID,
+		"user": gin.H{
+			"id":    target.ID,
+			"email": target.Email,
+			"name":  target.Name,
+		},
+	})
+}
+
+// carryImpersonation copies the impersonator and expiry of the current
+// impersonation token (set by RequireAuth) onto claims for a token reissued
+// from it, so switching workspace neither drops the real actor nor extends
+// the session
+func carryImpersonation(c *gin.Context, claims jwt.MapClaims) {
+	impersonator, ok := c.Get("impersonator_id")
+	if !ok {
+		return
+	}
+	claims["impersonator"] = impersonator
+	if expiresAt, ok := c.Get("impersonation_expires_at"); ok {
+		claims["exp"] = expiresAt.(time.Time).Unix()
+	}
+}
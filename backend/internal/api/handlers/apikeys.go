@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/hierarchy"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix must match auth.KeyPrefix in the authz service
+const apiKeyPrefix = "sk"
+
+type APIKeyHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewAPIKeyHandler(db *gorm.DB, cfg *config.Config) *APIKeyHandler {
+	return &APIKeyHandler{db: db, cfg: cfg}
+}
+
+// Create provisions a new API key. The full key is only returned here.
+// POST /api/v1/keys
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var req struct {
+		Name         string      `json:"name" binding:"required"`
+		WorkspaceID  *uuid.UUID  `json:"workspace_id"`
+		WorkspaceIDs []uuid.UUID `json:"workspace_ids"`
+		TenantWide   bool        `json:"tenant_wide"`
+		Role         string      `json:"role"`
+		ExpiresAt    *time.Time  `json:"expires_at"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Key name is required"})
+		return
+	}
+
+	role := hierarchy.Role(req.Role)
+	if role == "" {
+		role = hierarchy.DefaultRole
+	}
+	if !workspaceLevel.IsValidRole(role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_role", "message": "Role must be admin, member, or viewer"})
+		return
+	}
+
+	scopes := 0
+	if req.WorkspaceID != nil {
+		scopes++
+	}
+	if len(req.WorkspaceIDs) > 0 {
+		scopes++
+	}
+	if req.TenantWide {
+		scopes++
+	}
+	if scopes > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_scope", "message": "Specify only one of workspace_id, workspace_ids, or tenant_wide"})
+		return
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Expiry must be in the future"})
+		return
+	}
+
+	tenantUUID, userUUID, ok := parseTenantAndUser(c)
+	if !ok {
+		return
+	}
+
+	isTenantAdmin, _ := c.Get("is_tenant_admin")
+	admin, _ := isTenantAdmin.(bool)
+
+	// Tenant-wide and admin keys can only be issued by tenant admins
+	if (req.TenantWide || role.AtLeast(hierarchy.RoleAdmin)) && !admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Only tenant admins can create tenant-wide or admin keys"})
+		return
+	}
+
+	workspaceIDs := req.WorkspaceIDs
+	if req.WorkspaceID != nil {
+		workspaceIDs = []uuid.UUID{*req.WorkspaceID}
+	}
+	for _, wsID := range workspaceIDs {
+		if !h.canAccessWorkspace(tenantUUID, userUUID, wsID, admin) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "You don't have access to workspace " + wsID.String()})
+			return
+		}
+	}
+
+	keyID, secret, err := generateAPIKeyParts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to generate key"})
+		return
+	}
+	rawKey := apiKeyPrefix + "-" + keyID + "-" + secret
+
+	apiKey := models.APIKey{
+		KeyID:       keyID,
+		KeyHash:     hashToken(rawKey),
+		Name:        req.Name,
+		UserID:      userUUID,
+		TenantID:    tenantUUID,
+		WorkspaceID: req.WorkspaceID,
+		TenantWide:  req.TenantWide,
+		Role:        string(role),
+		ExpiresAt:   req.ExpiresAt,
+	}
+	for _, wsID := range req.WorkspaceIDs {
+		apiKey.WorkspaceIDs = append(apiKey.WorkspaceIDs, wsID.String())
+	}
+
+	if err := h.db.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to create key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created. Store it now; it will not be shown again.",
+		"key":     rawKey,
+		"api_key": apiKeyResponse(&apiKey),
+	})
+}
+
+// List returns key metadata for the tenant. Tenant admins see all keys,
+// other users only their own.
+// GET /api/v1/keys
+func (h *APIKeyHandler) List(c *gin.Context) {
+	tenantID, _ := c.Get("tenant_id")
+	userID, _ := c.Get("user_id")
+	isTenantAdmin, _ := c.Get("is_tenant_admin")
+
+	query := h.db.Where("tenant_id = ?", tenantID)
+	if admin, _ := isTenantAdmin.(bool); !admin {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var keys []models.APIKey
+	if err := query.Order("created_at DESC").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to fetch keys"})
+		return
+	}
+
+	result := make([]gin.H, len(keys))
+	for i := range keys {
+		result[i] = apiKeyResponse(&keys[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": result})
+}
+
+// Revoke revokes an API key
+// DELETE /api/v1/keys/:id
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	keyID := c.Param("id")
+	tenantID, _ := c.Get("tenant_id")
+	userID, _ := c.Get("user_id")
+	isTenantAdmin, _ := c.Get("is_tenant_admin")
+
+	if _, err := uuid.Parse(keyID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "API key not found"})
+		return
+	}
+
+	var apiKey models.APIKey
+	if err := h.db.Where("id = ? AND tenant_id = ?", keyID, tenantID).First(&apiKey).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "API key not found"})
+		return
+	}
+
+	if admin, _ := isTenantAdmin.(bool); !admin && apiKey.UserID.String() != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "You can only revoke your own keys"})
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		now := time.Now()
+		apiKey.RevokedAt = &now
+		if err := h.db.Model(&apiKey).Update("revoked_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to revoke key"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key revoked",
+		"api_key": apiKeyResponse(&apiKey),
+	})
+}
+
+// ============================================================================
+// Helpers
+// ============================================================================
+
+// canAccessWorkspace checks the workspace belongs to the tenant and the user
+// is a member of it (tenant admins can access every workspace)
+func (h *APIKeyHandler) canAccessWorkspace(tenantID, userID, workspaceID uuid.UUID, isTenantAdmin bool) bool {
+	var workspace models.Workspace
+	if err := h.db.Where("id = ? AND tenant_id = ?", workspaceID, tenantID).First(&workspace).Error; err != nil {
+		return false
+	}
+	if isTenantAdmin {
+		return true
+	}
+
+	var count int64
+	h.db.Model(&models.Membership{}).Where("user_id = ? AND workspace_id = ?", userID, workspaceID).Count(&count)
+	return count > 0
+}
+
+// parseTenantAndUser reads the tenant and user IDs set by the auth middleware
+func parseTenantAndUser(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	tenantID, _ := c.Get("tenant_id")
+	userID, _ := c.Get("user_id")
+
+	tenantUUID, err := uuid.Parse(tenantID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_tenant", "message": "Invalid tenant ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_user", "message": "Invalid user ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return tenantUUID, userUUID, true
+}
+
+// generateAPIKeyParts returns a 16-hex-char key ID and a 64-hex-char secret
+func generateAPIKeyParts() (keyID, secret string, err error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(idBytes), hex.EncodeToString(secretBytes), nil
+}
+
+func apiKeyResponse(k *models.APIKey) gin.H {
+	resp := gin.H{
+		"id":          k.ID,
+		"key_id":      k.KeyID,
+		"prefix":      apiKeyPrefix + "-" + k.KeyID,
+		"name":        k.Name,
+		"user_id":     k.UserID,
+		"tenant_id":   k.TenantID,
+		"tenant_wide": k.TenantWide,
+		"role":        k.Role,
+		"active":      k.IsActive(),
+		"created_at":  k.CreatedAt,
+	}
+
+	if k.WorkspaceID != nil {
+		resp["workspace_id"] = k.WorkspaceID
+	}
+	if len(k.WorkspaceIDs) > 0 {
+		resp["workspace_ids"] = k.WorkspaceIDs
+	}
+	if k.ExpiresAt != nil {
+		resp["expires_at"] = k.ExpiresAt
+	}
+	if k.RevokedAt != nil {
+		resp["revoked_at"] = k.RevokedAt
+	}
+
+	return resp
+}
@@ -3,38 +3,125 @@ package handlers
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/api/middleware"
 	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/email"
+	"github.com/yourusername/saas-starter-kit/backend/internal/fga"
 	"github.com/yourusername/saas-starter-kit/backend/internal/models"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/yourusername/saas-starter-kit/backend/internal/password"
+	"github.com/yourusername/saas-starter-kit/backend/internal/session"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/github"
 	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/microsoft"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// refreshTokenTTL is how long a refresh token stays valid before re-login is required
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// Lifetimes of emailed links. An expired link can be exchanged for a fresh
+// one (ResendLink) for resendWindow after it expires.
+const (
+	verifyTokenTTL = 24 * time.Hour
+	resetTokenTTL  = 1 * time.Hour
+	resendWindow   = 7 * 24 * time.Hour
+)
+
+// errSoleAdmin is returned when deleting the only admin of a tenant
+var errSoleAdmin = errors.New("sole tenant admin")
+
+// Link types accepted by ResendLink
+const (
+	linkTypeVerification  = "verification"
+	linkTypePasswordReset = "password_reset"
 )
 
 type AuthHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db        *gorm.DB
+	cfg       *config.Config
+	mailer    email.Sender
+	passwords *password.Policy
+	hasher    *password.Hasher
+	sessions  *session.Tracker
+	fga       *fga.Client
 }
 
 func NewAuthHandler(db *gorm.DB, cfg *config.Config) *AuthHandler {
-	return &AuthHandler{db: db, cfg: cfg}
+	// main validates the hashing config at startup
+	hasher, _ := password.NewHasher(cfg.PasswordHash, cfg.BcryptCost)
+	return &AuthHandler{
+		db:        db,
+		cfg:       cfg,
+		mailer:    email.NewSender(cfg),
+		passwords: password.NewPolicy(cfg.PasswordMinLength, cfg.PasswordRequireMixed, cfg.PasswordBreachCheck),
+		hasher:    hasher,
+		sessions:  session.NewTracker(db, cfg.SessionIdleTimeout, cfg.SessionAbsoluteTimeout),
+		fga:       fga.NewClient(cfg.OpenFGAURL, cfg.OpenFGAStoreID),
+	}
 }
 
 // ============================================================================
 // OAuth Login
 // ============================================================================
 
+// ListProviders reports the sign-in methods available, so the frontend only
+// shows buttons for configured social providers. sso tells whether any
+// organization (or, with ?tenant=<slug>, that organization) has SSO set up.
+// GET /api/v1/auth/providers
+func (h *AuthHandler) ListProviders(c *gin.Context) {
+	social := []gin.H{}
+	for _, p := range []struct {
+		id, name   string
+		configured bool
+	}{
+		{"google", "Google", h.cfg.HasGoogleOAuth()},
+		{"github", "GitHub", h.cfg.HasGitHubOAuth()},
+		{"microsoft", "Microsoft", h.cfg.HasMicrosoftOAuth()},
+	} {
+		if p.configured {
+			social = append(social, gin.H{
+				"id":        p.id,
+				"name":      p.name,
+				"login_url": "/api/v1/auth/social/" + p.id + "/login",
+			})
+		}
+	}
+
+	query := h.db.Model(&models.Tenant{}).Where("is_active = ? AND sso_configured = ?", true, true)
+	if slug := c.Query("tenant"); slug != "" {
+		query = query.Where("slug = ?", strings.ToLower(slug))
+	}
+	var ssoTenants int64
+	if err := query.Count(&ssoTenants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to load providers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"social": social,
+		"local":  true,
+		"sso":    ssoTenants > 0,
+	})
+}
+
 // InitiateOAuth starts the OAuth flow
 // GET /api/v1/auth/social/:provider/login
 func (h *AuthHandler) InitiateOAuth(c *gin.Context) {
@@ -70,6 +157,12 @@ func (h *AuthHandler) InitiateOAuth(c *gin.Context) {
 			Scopes:       []string{"user:email"},
 			Endpoint:     github.Endpoint,
 		}
+	case "microsoft":
+		if !h.cfg.HasMicrosoftOAuth() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "provider_not_configured", "message": "Microsoft OAuth is not configured"})
+			return
+		}
+		oauthConfig = h.microsoftOAuthConfig()
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_provider", "message": "Unsupported OAuth provider"})
 		return
@@ -80,18 +173,23 @@ func (h *AuthHandler) InitiateOAuth(c *gin.Context) {
 	rand.Read(stateBytes)
 	state := base64.URLEncoding.EncodeToString(stateBytes)
 
+	// PKCE: the code can only be redeemed with this verifier, so an
+	// intercepted code is useless. Providers without PKCE ignore it.
+	verifier := oauth2.GenerateVerifier()
+
 	// Store state in database
 	oauthState := models.OAuthState{
-		State:     state,
-		Provider:  provider,
-		Plan:      plan,
-		Flow:      flow,
-		ExpiresAt: time.Now().Add(10 * time.Minute),
+		State:        state,
+		Provider:     provider,
+		Plan:         plan,
+		Flow:         flow,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(10 * time.Minute),
 	}
 	h.db.Create(&oauthState)
 
 	// Generate auth URL
-	authURL := oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
+	authURL := oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
 
 	c.JSON(http.StatusOK, gin.H{
 		"auth_url": authURL,
@@ -114,7 +212,7 @@ func (h *AuthHandler) HandleOAuthCallback(c *gin.Context) {
 
 	// Verify state
 	var oauthState models.OAuthState
-	if err := h.db.Where("state = ?", req.State).First(&oauthState).Error; err != nil {
+	if err := h.db.Where("state = ? AND provider <> ?", req.State, ssoProvider).First(&oauthState).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_state", "message": "Invalid or expired state"})
 		return
 	}
@@ -129,61 +227,94 @@ func (h *AuthHandler) HandleOAuthCallback(c *gin.Context) {
 	}
 
 	// Exchange code for user info
-	email, name, picture, err := h.exchangeOAuthCode(oauthState.Provider, req.Code)
+	email, name, picture, err := h.exchangeOAuthCode(oauthState.Provider, req.Code, oauthState.CodeVerifier)
 	if err != nil {
 		log.Printf("OAuth exchange failed: %v", err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "auth_failed", "message": "Failed to authenticate with provider"})
 		return
 	}
 
-	// Find or create user
+	user, err := h.socialUser(oauthState.Provider, email, name, picture, oauthState.Plan)
+	if errors.Is(err, errProviderMismatch) {
+		c.JSON(http.StatusConflict, gin.H{"error": "account_exists", "message": "An account with this email already exists; sign in the way you created it"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load social login user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to sign in"})
+		return
+	}
+	if rejectDisabled(c, user) {
+		return
+	}
+
+	// Generate JWT
+	authTime := time.Now()
+	token, err := h.generateToken(user, authTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to generate token"})
+		return
+	}
+
+	refreshToken, err := h.issueRefreshToken(h.db, user, authTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to generate token"})
+		return
+	}
+	setAuthCookie(c, h.cfg, token)
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":       token,
+		"refresh_token":      refreshToken,
+		"user":               userResponse(user),
+		"needs_tenant_setup": user.AdminOfTenantID == nil,
+		"flow":               oauthState.Flow,
+	})
+}
+
+// errProviderMismatch is returned by socialUser when the email belongs to
+// an account created another way
+var errProviderMismatch = errors.New("account belongs to another provider")
+
+// socialUser finds the provider's account for email, updating its profile,
+// or creates it. Accounts created another way (password, another provider)
+// are never linked: a provider asserting an email it doesn't control would
+// otherwise take them over.
+func (h *AuthHandler) socialUser(provider, email, name, picture, plan string) (*models.User, error) {
+	email = h.cfg.NormalizeEmail(email)
 	var user models.User
-	result := h.db.Where("email = ?", email).First(&user)
-	if result.Error == gorm.ErrRecordNotFound {
+	err := h.db.Where("email = ?", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		user = models.User{
 			Email:         email,
 			Name:          name,
 			Picture:       picture,
-			AuthProvider:  oauthState.Provider,
+			AuthProvider:  provider,
 			EmailVerified: true,
 			LastLogin:     time.Now(),
 		}
-		if oauthState.Plan != "" {
-			user.SelectedPlanTier = models.PlanTier(oauthState.Plan)
+		if plan != "" {
+			user.SelectedPlanTier = models.PlanTier(plan)
 		}
 		if err := h.db.Create(&user).Error; err != nil {
-			log.Printf("Failed to create user: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to create account"})
-			return
+			return nil, err
 		}
-	} else if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Database error"})
-		return
-	} else {
-		// Update existing user
-		user.LastLogin = time.Now()
-		user.Name = name
-		user.Picture = picture
-		if user.AuthProvider == "" {
-			user.AuthProvider = oauthState.Provider
-			user.EmailVerified = true
-		}
-		h.db.Save(&user)
+		return &user, nil
 	}
-
-	// Generate JWT
-	token, err := h.generateToken(&user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to generate token"})
-		return
+		return nil, err
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"access_token":       token,
-		"user":               userResponse(&user),
-		"needs_tenant_setup": user.AdminOfTenantID == nil,
-		"flow":               oauthState.Flow,
-	})
+	if user.AuthProvider != provider {
+		return nil, errProviderMismatch
+	}
+	user.LastLogin = time.Now()
+	user.Name = name
+	user.Picture = picture
+	if err := h.db.Save(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
 // ============================================================================
@@ -195,58 +326,139 @@ func (h *AuthHandler) HandleOAuthCallback(c *gin.Context) {
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req struct {
 		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required,min=8"`
+		Password string `json:"password" binding:"required"`
 		Name     string `json:"name"`
 		Plan     string `json:"plan"`
+		Username string `json:"username"`
+
+		// InvitationToken accepts a workspace invitation sent to this email
+		// as part of signing up
+		InvitationToken string `json:"invitation_token"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid email or password"})
+		respondInvalidRequest(c, "Invalid email or password", err, &req)
+		return
+	}
+	req.Email = h.cfg.NormalizeEmail(req.Email)
+	req.Username = normalizeUsername(req.Username)
+	if req.Username != "" && !usernamePattern.MatchString(req.Username) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_username",
+			"message": "Usernames are 3-32 characters of letters, digits, '.', '_' and '-', starting with a letter or digit",
+			"fields":  gin.H{"username": "is invalid"},
+		})
 		return
 	}
 
+	if !h.checkPassword(c, req.Password) {
+		return
+	}
+
+	var invitation *models.Invitation
+	if req.InvitationToken != "" {
+		var ok bool
+		if invitation, ok = loadPendingInvitation(c, h.db, req.InvitationToken); !ok {
+			return
+		}
+		if invitation.Email != req.Email {
+			c.JSON(http.StatusForbidden, gin.H{"error": "email_mismatch", "message": "This invitation was sent to a different email address"})
+			return
+		}
+	}
+
 	// Check if email exists
 	var existing models.User
 	if err := h.db.Where("email = ?", req.Email).First(&existing).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "email_exists", "message": "An account with this email already exists"})
 		return
 	}
+	if req.Username != "" {
+		var count int64
+		h.db.Model(&models.User{}).Where("username = ?", req.Username).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "username_exists", "message": "This username is already taken"})
+			return
+		}
+	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := h.hasher.Hash(req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to process password"})
 		return
 	}
 
-	// Generate verification token
-	verifyToken := generateRandomToken(32)
-	expiry := time.Now().Add(24 * time.Hour)
-
 	user := models.User{
 		Email:         req.Email,
 		Name:          req.Name,
 		AuthProvider:  "local",
 		EmailVerified: false,
-		PasswordHash:  string(hashedPassword),
-		VerifyToken:   verifyToken,
-		VerifyExpiry:  &expiry,
+		PasswordHash:  hashedPassword,
 	}
 	if req.Plan != "" {
 		user.SelectedPlanTier = models.PlanTier(req.Plan)
 	}
+	if req.Username != "" {
+		user.Username = &req.Username
+	}
+
+	// The invitation link was delivered to this address, which proves it
+	// just like a verification link would
+	if invitation != nil {
+		user.EmailVerified = true
+
+		if err := h.db.Create(&user).Error; err != nil {
+			respondCreateUserError(c, err)
+			return
+		}
+		if _, err := acceptInvitation(h.db, invitation, &user); err != nil {
+			log.Printf("Failed to accept invitation during registration: %v", err)
+			c.JSON(http.StatusCreated, gin.H{
+				"message":             "Account created, but the invitation could not be accepted. You can sign in now.",
+				"invitation_accepted": false,
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":             "Account created and invitation accepted. You can sign in now.",
+			"invitation_accepted": true,
+			"workspace_id":        invitation.WorkspaceID,
+		})
+		return
+	}
 
+	verifyToken := setVerifyToken(&user)
 	if err := h.db.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to create account"})
+		respondCreateUserError(c, err)
 		return
 	}
 
-	// TODO: Send verification email
+	h.sendVerificationEmail(&user, verifyToken)
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message":      "Account created. Please check your email to verify your account.",
-		"verify_token": verifyToken, // In production, only send via email
-	})
+	resp := gin.H{
+		"message": "Account created. Please check your email to verify your account.",
+	}
+	if exposeTokens(h.cfg, h.mailer) {
+		resp["verify_token"] = verifyToken
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// respondCreateUserError reports a failed signup insert. The existence
+// checks in Register race with concurrent signups, so the unique indexes
+// have the last word: their violations are conflicts, not server errors.
+func respondCreateUserError(c *gin.Context, err error) {
+	switch {
+	case models.IsUniqueViolation(err, models.UserEmailIndex), models.IsUniqueViolation(err, models.UserEmailLowerIndex):
+		c.JSON(http.StatusConflict, gin.H{"error": "email_exists", "message": "An account with this email already exists"})
+	case models.IsUniqueViolation(err, models.UserUsernameIndex):
+		c.JSON(http.StatusConflict, gin.H{"error": "username_exists", "message": "This username is already taken"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to create account"})
+	}
 }
 
 // VerifyEmail verifies email address
@@ -257,7 +469,7 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Token is required"})
+		respondInvalidRequest(c, "Token is required", err, &req)
 		return
 	}
 
@@ -268,7 +480,10 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	}
 
 	if user.IsVerifyTokenExpired() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "token_expired", "message": "Verification token has expired"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token_expired", "message": "Verification token has expired", "can_resend": canResend(user.VerifyExpiry)})
+		return
+	}
+	if rejectDisabled(c, &user) {
 		return
 	}
 
@@ -278,30 +493,43 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	user.LastLogin = time.Now()
 	h.db.Save(&user)
 
-	token, _ := h.generateToken(&user)
+	token, _ := h.generateToken(&user, user.LastLogin)
+	refreshToken, err := h.issueRefreshToken(h.db, &user, user.LastLogin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to generate token"})
+		return
+	}
+	setAuthCookie(c, h.cfg, token)
 
 	c.JSON(http.StatusOK, gin.H{
 		"access_token":       token,
+		"refresh_token":      refreshToken,
 		"user":               userResponse(&user),
 		"needs_tenant_setup": user.AdminOfTenantID == nil,
 	})
 }
 
-// Login handles email/password login
+// Login handles email-or-username/password login. identifier takes an
+// email or a username; email is still accepted on its own.
 // POST /api/v1/auth/login
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required"`
+		Identifier string `json:"identifier"`
+		Email      string `json:"email"`
+		Password   string `json:"password" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid credentials"})
+		respondInvalidRequest(c, "Invalid credentials", err, &req)
+		return
+	}
+	if loginIdentifier(req.Identifier, req.Email) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid credentials", "fields": gin.H{"identifier": "is required"}})
 		return
 	}
 
 	var user models.User
-	if err := h.db.Where("email = ? AND auth_provider = ?", req.Email, "local").First(&user).Error; err != nil {
+	if err := h.findLocalUser(loginIdentifier(req.Identifier, req.Email)).First(&user).Error; err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_credentials", "message": "Invalid email or password"})
 		return
 	}
@@ -311,19 +539,60 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+	ok, rehash := h.hasher.Verify(user.PasswordHash, req.Password)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_credentials", "message": "Invalid email or password"})
 		return
 	}
+	if rejectDisabled(c, &user) {
+		return
+	}
+
+	// Move the hash to the configured algorithm and cost while we have the
+	// plaintext; on failure the old hash keeps working
+	if rehash {
+		if hashed, err := h.hasher.Hash(req.Password); err != nil {
+			log.Printf("Failed to re-hash password for user %s: %v", user.ID, err)
+		} else if err := h.db.Model(&user).Update("password_hash", hashed).Error; err != nil {
+			log.Printf("Failed to store re-hashed password for user %s: %v", user.ID, err)
+		}
+	}
+
+	// Password is correct but a second factor is still required
+	if user.TOTPEnabled {
+		challenge, err := h.generateChallengeToken(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_in":          int(challengeTokenTTL.Seconds()),
+		})
+		return
+	}
+
+	h.completeLogin(c, &user)
+}
 
+// completeLogin records the login and responds with access and refresh tokens
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User) {
 	user.LastLogin = time.Now()
-	h.db.Save(&user)
+	h.db.Save(user)
 
-	token, _ := h.generateToken(&user)
+	token, _ := h.generateToken(user, user.LastLogin)
+	refreshToken, err := h.issueRefreshToken(h.db, user, user.LastLogin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to generate token"})
+		return
+	}
+	setAuthCookie(c, h.cfg, token)
 
 	c.JSON(http.StatusOK, gin.H{
 		"access_token":       token,
-		"user":               userResponse(&user),
+		"refresh_token":      refreshToken,
+		"user":               userResponse(user),
 		"needs_tenant_setup": user.AdminOfTenantID == nil,
 	})
 }
@@ -332,33 +601,39 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // POST /api/v1/auth/forgot-password
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	var req struct {
-		Email string `json:"email" binding:"required,email"`
+		Identifier string `json:"identifier"`
+		Email      string `json:"email"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid email"})
+		respondInvalidRequest(c, "Invalid email", err, &req)
+		return
+	}
+	if loginIdentifier(req.Identifier, req.Email) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid email", "fields": gin.H{"identifier": "is required"}})
 		return
 	}
 
 	var user models.User
-	if err := h.db.Where("email = ? AND auth_provider = ?", req.Email, "local").First(&user).Error; err != nil {
+	if err := h.findLocalUser(loginIdentifier(req.Identifier, req.Email)).First(&user).Error; err != nil {
 		// Don't reveal if email exists
 		c.JSON(http.StatusOK, gin.H{"message": "If an account exists, a reset link has been sent."})
 		return
 	}
 
-	resetToken := generateRandomToken(32)
-	expiry := time.Now().Add(1 * time.Hour)
-	user.ResetToken = resetToken
-	user.ResetExpiry = &expiry
+	resetToken := setResetToken(&user)
 	h.db.Save(&user)
 
-	// TODO: Send reset email
+	h.sendPasswordResetEmail(&user, resetToken)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "If an account exists, a reset link has been sent.",
-		"reset_token": resetToken, // In production, only send via email
-	})
+	resp := gin.H{
+		"message": "If an account exists, a reset link has been sent.",
+	}
+	if exposeTokens(h.cfg, h.mailer) {
+		resp["reset_token"] = resetToken
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
 // ResetPassword resets the password
@@ -366,11 +641,15 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var req struct {
 		Token    string `json:"token" binding:"required"`
-		Password string `json:"password" binding:"required,min=8"`
+		Password string `json:"password" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid request"})
+		respondInvalidRequest(c, "Invalid request", err, &req)
+		return
+	}
+
+	if !h.checkPassword(c, req.Password) {
 		return
 	}
 
@@ -381,12 +660,16 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	}
 
 	if user.IsResetTokenExpired() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "token_expired", "message": "Reset token has expired"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token_expired", "message": "Reset token has expired", "can_resend": canResend(user.ResetExpiry)})
 		return
 	}
 
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	user.PasswordHash = string(hashedPassword)
+	hashedPassword, err := h.hasher.Hash(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to process password"})
+		return
+	}
+	user.PasswordHash = hashedPassword
 	user.ResetToken = ""
 	user.ResetExpiry = nil
 	h.db.Save(&user)
@@ -394,6 +677,210 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Password reset successful"})
 }
 
+// ResendLink emails a fresh verification or password reset link in exchange
+// for an expired one, so an expired link isn't a dead end. The response is
+// the same whether or not the token was recognized, and each expired token
+// can be exchanged only once since the new token replaces it.
+// POST /api/v1/auth/resend-link
+func (h *AuthHandler) ResendLink(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+		Type  string `json:"type" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Token and type are required"})
+		return
+	}
+
+	resp := gin.H{
+		"message": "If the link is recognized, a new one has been sent.",
+	}
+
+	switch req.Type {
+	case linkTypeVerification:
+		var user models.User
+		err := h.db.Where("verify_token = ? AND email_verified = ?", req.Token, false).First(&user).Error
+		if err == nil && user.IsVerifyTokenExpired() && canResend(user.VerifyExpiry) {
+			token := setVerifyToken(&user)
+			h.db.Save(&user)
+			h.sendVerificationEmail(&user, token)
+			if exposeTokens(h.cfg, h.mailer) {
+				resp["verify_token"] = token
+			}
+		}
+	case linkTypePasswordReset:
+		var user models.User
+		err := h.db.Where("reset_token = ? AND auth_provider = ?", req.Token, "local").First(&user).Error
+		if err == nil && user.IsResetTokenExpired() && canResend(user.ResetExpiry) {
+			token := setResetToken(&user)
+			h.db.Save(&user)
+			h.sendPasswordResetEmail(&user, token)
+			if exposeTokens(h.cfg, h.mailer) {
+				resp["reset_token"] = token
+			}
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Type must be verification or password_reset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// setVerifyToken gives the user a new email verification token
+func setVerifyToken(user *models.User) string {
+	token := generateRandomToken(32)
+	expiry := time.Now().Add(verifyTokenTTL)
+	user.VerifyToken = token
+	user.VerifyExpiry = &expiry
+	return token
+}
+
+// setResetToken gives the user a new password reset token
+func setResetToken(user *models.User) string {
+	token := generateRandomToken(32)
+	expiry := time.Now().Add(resetTokenTTL)
+	user.ResetToken = token
+	user.ResetExpiry = &expiry
+	return token
+}
+
+// canResend reports whether a link that expired at expiry may still be
+// exchanged for a new one
+func canResend(expiry *time.Time) bool {
+	return expiry != nil && time.Since(*expiry) < resendWindow
+}
+
+// exposeTokens reports whether emailed tokens may be returned in API
+// responses: only in dev mode, and only when the message wasn't really sent
+func exposeTokens(cfg *config.Config, mailer email.Sender) bool {
+	_, noop := mailer.(*email.NoopSender)
+	return cfg.DevMode && noop
+}
+
+func (h *AuthHandler) sendVerificationEmail(user *models.User, token string) {
+	if err := h.mailer.Send(email.VerificationEmail(h.cfg.FrontendURL, user.Email, user.Name, token)); err != nil {
+		log.Printf("Failed to send verification email: %v", err)
+	}
+}
+
+func (h *AuthHandler) sendPasswordResetEmail(user *models.User, token string) {
+	if err := h.mailer.Send(email.PasswordResetEmail(h.cfg.FrontendURL, user.Email, user.Name, token)); err != nil {
+		log.Printf("Failed to send password reset email: %v", err)
+	}
+}
+
+// RefreshToken exchanges a refresh token for a new access token
+// POST /api/v1/auth/refresh
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Refresh token is required"})
+		return
+	}
+
+	var stored models.RefreshToken
+	if err := h.db.Where("token_hash = ?", hashToken(req.RefreshToken)).First(&stored).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token", "message": "Invalid refresh token"})
+		return
+	}
+
+	if !stored.IsValid() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "token_expired", "message": "Refresh token has expired or been revoked"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", stored.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token", "message": "Invalid refresh token"})
+		return
+	}
+	if rejectDisabled(c, &user) {
+		return
+	}
+
+	// Refreshing counts as activity; a timed-out session can't be revived
+	if err := h.sessions.Touch(user.ID.String(), stored.AuthTime.Unix()); err != nil {
+		if errors.Is(err, session.ErrExpired) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "session_expired", "message": "Your session has expired, please sign in again"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to refresh token"})
+		return
+	}
+
+	// Rotate: revoke the presented token and issue a new one atomically.
+	// The revoked = false guard ensures a concurrent refresh with the same
+	// token can only succeed once.
+	var newRefreshToken string
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked = ?", stored.ID, false).
+			Update("revoked", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var err error
+		// Refreshing does not re-authenticate; carry the original auth time
+		newRefreshToken, err = h.issueRefreshToken(tx, &user, stored.AuthTime)
+		return err
+	})
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "token_expired", "message": "Refresh token has expired or been revoked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to refresh token"})
+		return
+	}
+
+	token, err := h.generateToken(&user, stored.AuthTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to generate token"})
+		return
+	}
+	setAuthCookie(c, h.cfg, token)
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  token,
+		"refresh_token": newRefreshToken,
+		"user":          userResponse(&user),
+	})
+}
+
+// Logout revokes a refresh token and, when sent as a Bearer token or
+// cookie, the access token, so the authz gate rejects it before it expires.
+// The access token cookie is cleared.
+// POST /api/v1/auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Refresh token is required"})
+		return
+	}
+
+	// Don't reveal whether the token existed
+	h.db.Model(&models.RefreshToken{}).
+		Where("token_hash = ?", hashToken(req.RefreshToken)).
+		Update("revoked", true)
+
+	h.revokeAccessToken(middleware.AuthorizationHeader(c, h.cfg))
+	clearAuthCookie(c, h.cfg)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
 // GetCurrentUser returns the current user
 // GET /api/v1/auth/me
 func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
@@ -408,11 +895,166 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	c.JSON(http.StatusOK, userResponse(&user))
 }
 
+// DeleteAccount permanently deletes the current user (right to erasure):
+// their memberships, document shares, refresh tokens, backup codes, API
+// keys and invitations go with the account, as does OAuth provider
+// linkage, which lives on the user row. Documents and projects in
+// workspaces others can reach are transferred rather than deleted, and all
+// of the user's access tokens are revoked. The sole admin of a tenant must
+// transfer ownership first.
+// DELETE /api/v1/auth/me
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "User not found"})
+		return
+	}
+
+	var tupleWrites, tupleDeletes []fga.TupleKey
+	var transferred int64
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if user.AdminOfTenantID != nil {
+			var otherAdmins int64
+			if err := tx.Model(&models.User{}).
+				Where("admin_of_tenant_id = ? AND id <> ?", *user.AdminOfTenantID, user.ID).
+				Count(&otherAdmins).Error; err != nil {
+				return err
+			}
+			if otherAdmins == 0 {
+				return errSoleAdmin
+			}
+		}
+
+		var err error
+		tupleWrites, tupleDeletes, transferred, err = transferOwnedResources(tx, &user)
+		if err != nil {
+			return err
+		}
+		grants, err := removeAccessGrants(tx, &user, h.cfg.UseHierarchy)
+		if err != nil {
+			return err
+		}
+		tupleDeletes = append(tupleDeletes, grants...)
+
+		for _, model := range []interface{}{&models.Membership{}, &models.RefreshToken{}, &models.BackupCode{}, &models.APIKey{}} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		// Invitations to the user's address, and pending ones they sent
+		if err := tx.Where("email = ? OR (invited_by_id = ? AND accepted_at IS NULL)", user.Email, user.ID).
+			Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+
+		// Access tokens can't be listed, so revoke everything issued so far
+		now := time.Now()
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&models.RevokedUser{
+			UserID:    user.ID,
+			RevokedAt: now,
+			ExpiresAt: now.Add(h.cfg.AccessTokenTTL),
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&models.AuditLog{
+			Action:   models.AuditAccountDeleted,
+			UserID:   user.ID,
+			TenantID: user.AdminOfTenantID,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&user).Error
+	})
+	if errors.Is(err, errSoleAdmin) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "sole_admin",
+			"message": "You are the only admin of your organization. Transfer ownership to another member before deleting your account.",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to delete account"})
+		return
+	}
+
+	// The database is the source of truth, so a failed sync is only logged
+	if err := h.fga.Write(c.Request.Context(), tupleWrites, nil); err != nil {
+		log.Printf("Failed to write transferred owner tuples to OpenFGA: %v", err)
+	}
+	if err := h.fga.Delete(c.Request.Context(), tupleDeletes); err != nil {
+		log.Printf("Failed to delete tuples of deleted user %s from OpenFGA: %v", user.ID, err)
+	}
+	clearAuthCookie(c, h.cfg)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted", "transferred_resources": transferred})
+}
+
 // ============================================================================
 // Helpers
 // ============================================================================
 
-func (h *AuthHandler) generateToken(user *models.User) (string, error) {
+// usernamePattern excludes "@", so an identifier containing one is always an
+// email and never matches two accounts
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,31}$`)
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// loginIdentifier returns the identifier of a login-style request, falling
+// back to the older email field
+func loginIdentifier(identifier, email string) string {
+	if identifier = strings.TrimSpace(identifier); identifier != "" {
+		return identifier
+	}
+	return strings.TrimSpace(email)
+}
+
+// findLocalUser scopes a query to the password account identified by an
+// email (anything containing "@") or a username
+func (h *AuthHandler) findLocalUser(identifier string) *gorm.DB {
+	query := h.db.Where("auth_provider = ?", "local")
+	if strings.Contains(identifier, "@") {
+		return query.Where("email = ?", h.cfg.NormalizeEmail(identifier))
+	}
+	return query.Where("username = ?", normalizeUsername(identifier))
+}
+
+// checkPassword applies the password policy, responding with a
+// weak_password error listing the failed rules. On failure it writes the
+// response and returns false.
+func (h *AuthHandler) checkPassword(c *gin.Context, pw string) bool {
+	failed := h.passwords.Validate(c.Request.Context(), pw)
+	if len(failed) == 0 {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":        "weak_password",
+		"message":      "Password does not meet the requirements",
+		"failed_rules": failed,
+		"min_length":   h.cfg.PasswordMinLength,
+	})
+	return false
+}
+
+// rejectDisabled responds with 403 account_disabled and returns true if
+// user's account is disabled, so no tokens are issued to it
+func rejectDisabled(c *gin.Context, user *models.User) bool {
+	if user.DisabledAt == nil {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "account_disabled", "message": "This account has been disabled"})
+	return true
+}
+
+// generateToken issues an access token. authTime is when the user last
+// actually authenticated and is used for step-up checks (RequireFreshAuth).
+func (h *AuthHandler) generateToken(user *models.User, authTime time.Time) (string, error) {
 	claims := jwt.MapClaims{
 		"sub":            user.ID.String(),
 		"email":          user.Email,
@@ -420,19 +1062,90 @@ func (h *AuthHandler) generateToken(user *models.User) (string, error) {
 		"type":           "platform",
 		"email_verified": user.EmailVerified,
 		"is_tenant_admin": user.IsTenantAdmin,
-		"iat":            time.Now().Unix(),
-		"exp":            time.Now().Add(24 * time.Hour).Unix(),
+		"auth_time":      authTime.Unix(),
 	}
 
 	if user.AdminOfTenantID != nil {
 		claims["tenant_id"] = user.AdminOfTenantID.String()
 	}
 
+	return signAccessToken(h.cfg, claims)
+}
+
+// signAccessToken adds the registered claims every access token carries
+// (iss, aud, jti, iat and exp from ACCESS_TOKEN_TTL, capped at the session
+// timeouts, unless already set) and signs the token
+func signAccessToken(cfg *config.Config, claims jwt.MapClaims) (string, error) {
+	now := time.Now()
+	claims["iss"] = cfg.JWTIssuer
+	claims["aud"] = cfg.JWTAudience
+	claims["jti"] = uuid.New().String()
+	claims["iat"] = now.Unix()
+	if _, ok := claims["exp"]; !ok {
+		authTime, _ := claims["auth_time"].(int64)
+		claims["exp"] = session.Expiry(now.Add(cfg.AccessTokenTTL), authTime, cfg.SessionIdleTimeout, cfg.SessionAbsoluteTimeout).Unix()
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(h.cfg.GetJWTSecret())
+	return token.SignedString(cfg.GetJWTSecret())
+}
+
+// revokeAccessToken records the jti of a valid Bearer access token in
+// revoked_tokens. Missing or invalid tokens and tokens without a jti are
+// ignored.
+func (h *AuthHandler) revokeAccessToken(authHeader string) {
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return
+	}
+
+	token, err := jwt.Parse(parts[1], func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return h.cfg.GetJWTSecret(), nil
+	})
+	if err != nil || !token.Valid {
+		return
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return
+	}
+	jti, _ := claims["jti"].(string)
+	sub, _ := claims.GetSubject()
+	exp, _ := claims.GetExpirationTime()
+	userID, err := uuid.Parse(sub)
+	if jti == "" || exp == nil || err != nil {
+		return
+	}
+
+	revoked := models.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: exp.Time}
+	if err := h.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error; err != nil {
+		log.Printf("Failed to revoke access token: %v", err)
+	}
 }
 
-func (h *AuthHandler) exchangeOAuthCode(provider, code string) (email, name, picture string, err error) {
+// issueRefreshToken creates and persists a new refresh token for the user,
+// returning the raw token. Only its hash is stored.
+func (h *AuthHandler) issueRefreshToken(db *gorm.DB, user *models.User, authTime time.Time) (string, error) {
+	raw := generateRandomToken(32)
+	refreshToken := models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(raw),
+		AuthTime:  authTime,
+		ExpiresAt: session.Expiry(time.Now().Add(refreshTokenTTL), authTime.Unix(), 0, h.cfg.SessionAbsoluteTimeout),
+	}
+	if err := db.Create(&refreshToken).Error; err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// exchangeOAuthCode redeems the authorization code, sending the PKCE
+// verifier when the flow started with one
+func (h *AuthHandler) exchangeOAuthCode(provider, code, verifier string) (email, name, picture string, err error) {
 	var oauthConfig *oauth2.Config
 
 	switch provider {
@@ -452,11 +1165,21 @@ func (h *AuthHandler) exchangeOAuthCode(provider, code string) (email, name, pic
 			Scopes:       []string{"user:email"},
 			Endpoint:     github.Endpoint,
 		}
+	case "microsoft":
+		if !h.cfg.HasMicrosoftOAuth() {
+			return "", "", "", fmt.Errorf("provider not configured: %s", provider)
+		}
+		oauthConfig = h.microsoftOAuthConfig()
 	default:
 		return "", "", "", fmt.Errorf("unsupported provider: %s", provider)
 	}
 
-	token, err := oauthConfig.Exchange(context.Background(), code)
+	var opts []oauth2.AuthCodeOption
+	if verifier != "" {
+		opts = append(opts, oauth2.VerifierOption(verifier))
+	}
+
+	token, err := oauthConfig.Exchange(context.Background(), code, opts...)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -468,6 +1191,8 @@ func (h *AuthHandler) exchangeOAuthCode(provider, code string) (email, name, pic
 		return h.getGoogleUserInfo(client)
 	case "github":
 		return h.getGitHubUserInfo(client)
+	case "microsoft":
+		return h.getMicrosoftUserInfo(client, token)
 	}
 
 	return "", "", "", fmt.Errorf("unsupported provider")
@@ -544,15 +1269,121 @@ func (h *AuthHandler) getGitHubUserInfo(client *http.Client) (email, name, pictu
 	return userInfo.Email, userInfo.Name, userInfo.AvatarURL, nil
 }
 
+func (h *AuthHandler) microsoftOAuthConfig() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     h.cfg.MicrosoftClientID,
+		ClientSecret: h.cfg.MicrosoftClientSecret,
+		RedirectURL:  h.cfg.AppURL + "/api/v1/auth/social/callback",
+		Scopes:       []string{"openid", "email", "profile", "User.Read"},
+		Endpoint:     microsoft.AzureADEndpoint(h.cfg.MicrosoftTenantID),
+	}
+}
+
+// getMicrosoftUserInfo reads the email from the ID token, which unlike
+// Graph's mail and userPrincipalName says whether it was verified (see
+// microsoftVerifiedEmail), and the name and photo from Graph
+func (h *AuthHandler) getMicrosoftUserInfo(client *http.Client, token *oauth2.Token) (email, name, picture string, err error) {
+	idToken, _ := token.Extra("id_token").(string)
+	if idToken == "" {
+		return "", "", "", errors.New("microsoft: no ID token in the token response")
+	}
+	// The token came straight from Microsoft's token endpoint over TLS, so
+	// its signature needn't be checked (OpenID Connect Core 3.1.3.7)
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(idToken, claims); err != nil {
+		return "", "", "", fmt.Errorf("microsoft: parse ID token: %w", err)
+	}
+	if email, err = microsoftVerifiedEmail(claims, h.cfg.MicrosoftTenantID); err != nil {
+		return "", "", "", err
+	}
+
+	resp, err := client.Get("https://graph.microsoft.com/v1.0/me")
+	if err != nil {
+		return "", "", "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	var userInfo struct {
+		DisplayName string `json:"displayName"`
+	}
+
+	if err := json.Unmarshal(body, &userInfo); err != nil {
+		return "", "", "", err
+	}
+
+	// Fetch a small profile photo and inline it as a data URL (Graph photos
+	// are not publicly addressable). Missing photos return 404.
+	photoResp, err := client.Get("https://graph.microsoft.com/v1.0/me/photos/48x48/$value")
+	if err == nil {
+		defer photoResp.Body.Close()
+		if photoResp.StatusCode == http.StatusOK {
+			photo, _ := io.ReadAll(photoResp.Body)
+			contentType := photoResp.Header.Get("Content-Type")
+			if contentType == "" {
+				contentType = "image/jpeg"
+			}
+			picture = "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(photo)
+		}
+	}
+
+	return email, userInfo.DisplayName, picture, nil
+}
+
+// microsoftConsumersTenantID is the tenant of personal Microsoft accounts,
+// whose emails Microsoft verifies
+const microsoftConsumersTenantID = "9188040d-6c67-4c5b-b112-36a304b66dad"
+
+// microsoftVerifiedEmail returns the email of a Microsoft ID token if it can
+// be trusted. Entra tenant admins can set any unverified email on their
+// users, so with the multi-tenant authorities (common, organizations) the
+// email is accepted only from personal accounts or with the xms_edov
+// (email domain owner verified) optional claim. When tenantID names one
+// directory, tokens must come from it and its admins are trusted.
+func microsoftVerifiedEmail(claims jwt.MapClaims, tenantID string) (string, error) {
+	email, _ := claims["email"].(string)
+	if email == "" {
+		return "", errors.New("microsoft: ID token has no email claim")
+	}
+	tid, _ := claims["tid"].(string)
+
+	switch strings.ToLower(tenantID) {
+	case "", "common", "organizations", "consumers":
+	default:
+		if !strings.EqualFold(tid, tenantID) {
+			return "", fmt.Errorf("microsoft: token from tenant %q, want %q", tid, tenantID)
+		}
+		return email, nil
+	}
+
+	if tid == microsoftConsumersTenantID {
+		return email, nil
+	}
+	switch verified := claims["xms_edov"].(type) {
+	case bool:
+		if verified {
+			return email, nil
+		}
+	case string:
+		if verified == "1" || strings.EqualFold(verified, "true") {
+			return email, nil
+		}
+	}
+	return "", errors.New("microsoft: email not verified (no xms_edov claim)")
+}
+
 func userResponse(user *models.User) gin.H {
 	resp := gin.H{
 		"id":              user.ID,
 		"email":           user.Email,
+		"username":        user.Username,
 		"name":            user.Name,
 		"picture":         user.Picture,
 		"auth_provider":   user.AuthProvider,
 		"email_verified":  user.EmailVerified,
 		"is_tenant_admin": user.IsTenantAdmin,
+		"totp_enabled":    user.TOTPEnabled,
 		"created_at":      user.CreatedAt,
 	}
 
@@ -572,3 +1403,19 @@ func generateRandomToken(length int) string {
 	rand.Read(bytes)
 	return base64.URLEncoding.EncodeToString(bytes)
 }
+
+// authTimeFromContext returns the auth_time of the current access token as
+// set by RequireAuth, or the zero time if unknown
+func authTimeFromContext(c *gin.Context) time.Time {
+	if v, ok := c.Get("auth_time"); ok {
+		if ts, ok := v.(int64); ok && ts > 0 {
+			return time.Unix(ts, 0)
+		}
+	}
+	return time.Time{}
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
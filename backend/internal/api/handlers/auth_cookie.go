@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+)
+
+// setAuthCookie stores a newly issued access token in the AUTH_COOKIE_NAME
+// cookie (HttpOnly, so scripts can't read it) for browsers that don't send
+// the Authorization header. It does nothing when the cookie is disabled.
+func setAuthCookie(c *gin.Context, cfg *config.Config, token string) {
+	if cfg.AuthCookieName == "" {
+		return
+	}
+	http.SetCookie(c.Writer, authCookie(cfg, token, int(cfg.AccessTokenTTL.Seconds())))
+}
+
+// clearAuthCookie expires the access token cookie
+func clearAuthCookie(c *gin.Context, cfg *config.Config) {
+	if cfg.AuthCookieName == "" {
+		return
+	}
+	cookie := authCookie(cfg, "", -1)
+	cookie.Expires = time.Unix(0, 0)
+	http.SetCookie(c.Writer, cookie)
+}
+
+func authCookie(cfg *config.Config, value string, maxAge int) *http.Cookie {
+	sameSite := http.SameSiteLaxMode
+	switch cfg.AuthCookieSameSite {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return &http.Cookie{
+		Name:     cfg.AuthCookieName,
+		Value:    value,
+		Path:     "/",
+		Domain:   cfg.AuthCookieDomain,
+		MaxAge:   maxAge,
+		Secure:   cfg.AuthCookieSecure || sameSite == http.SameSiteNoneMode, // browsers drop SameSite=None without Secure
+		HttpOnly: true,
+		SameSite: sameSite,
+	}
+}
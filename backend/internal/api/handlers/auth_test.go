@@ -0,0 +1,568 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yourusername/saas-starter-kit/backend/internal/email"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/revocation"
+	"github.com/yourusername/saas-starter-kit/backend/internal/testutil"
+)
+
+func TestDisabledUserGetsNoTokens(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	h := NewAuthHandler(db, cfg)
+
+	r := gin.New()
+	r.POST("/login", h.Login)
+	r.POST("/refresh", h.RefreshToken)
+	r.POST("/2fa/login", h.TwoFactorLogin)
+
+	active := createUser(t, db, cfg, "active@example.com")
+	disabled := createUser(t, db, cfg, "disabled@example.com")
+	now := time.Now()
+	if err := db.Model(disabled).Update("disabled_at", now).Error; err != nil {
+		t.Fatal(err)
+	}
+	disabled.DisabledAt = &now
+
+	tests := []struct {
+		name string
+		path string
+		body func(t *testing.T, user *models.User) gin.H
+	}{
+		{"login", "/login", func(t *testing.T, user *models.User) gin.H {
+			return gin.H{"email": user.Email, "password": testPassword}
+		}},
+		{"refresh", "/refresh", func(t *testing.T, user *models.User) gin.H {
+			token, err := h.issueRefreshToken(db, user, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return gin.H{"refresh_token": token}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodPost, tt.path, tt.body(t, active))
+			expectStatus(t, w, http.StatusOK)
+
+			w = serve(r, http.MethodPost, tt.path, tt.body(t, disabled))
+			expectStatus(t, w, http.StatusForbidden)
+			if code := errorCode(t, w); code != "account_disabled" {
+				t.Errorf("error = %q, want account_disabled", code)
+			}
+		})
+	}
+
+	t.Run("2fa login", func(t *testing.T) {
+		if err := db.Model(disabled).Update("totp_enabled", true).Error; err != nil {
+			t.Fatal(err)
+		}
+		challenge, err := h.generateChallengeToken(disabled)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := serve(r, http.MethodPost, "/2fa/login", gin.H{"challenge_token": challenge, "code": "000000"})
+		expectStatus(t, w, http.StatusForbidden)
+		if code := errorCode(t, w); code != "account_disabled" {
+			t.Errorf("error = %q, want account_disabled", code)
+		}
+	})
+}
+
+func TestDeleteAccount(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	h := NewAuthHandler(db, cfg)
+
+	leaving := createUser(t, db, cfg, "leaving@example.com")
+	colleague := createUser(t, db, cfg, "colleague@example.com")
+
+	shared := createWorkspace(t, db, "shared")
+	addMember(t, db, shared, leaving, "member")
+	addMember(t, db, shared, colleague, "admin")
+	private := createWorkspace(t, db, "private")
+	addMember(t, db, private, leaving, "admin")
+
+	sharedDoc := models.Document{WorkspaceID: shared.ID, OwnerID: leaving.ID, Title: "Shared"}
+	privateDoc := models.Document{WorkspaceID: private.ID, OwnerID: leaving.ID, Title: "Private"}
+	colleagueDoc := models.Document{WorkspaceID: shared.ID, OwnerID: colleague.ID, Title: "Colleague's"}
+	sharedProject := models.Project{WorkspaceID: shared.ID, OwnerID: leaving.ID, Name: "Shared"}
+	for _, v := range []interface{}{&sharedDoc, &privateDoc, &colleagueDoc, &sharedProject} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	records := []interface{}{
+		&models.DocumentShare{DocumentID: colleagueDoc.ID, UserID: leaving.ID, Role: "editor"},
+		&models.Invitation{WorkspaceID: shared.ID, Email: leaving.Email, Token: "to-leaving", InvitedByID: colleague.ID, ExpiresAt: time.Now().Add(time.Hour)},
+		&models.Invitation{WorkspaceID: shared.ID, Email: "new@example.com", Token: "from-leaving", InvitedByID: leaving.ID, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	for _, v := range records {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := asUser(leaving.ID.String(), nil)
+	r.DELETE("/me", h.DeleteAccount)
+	w := serve(r, http.MethodDelete, "/me", nil)
+	expectStatus(t, w, http.StatusOK)
+
+	// Shared work moves to the workspace admin; private work goes
+	var doc models.Document
+	if err := db.First(&doc, "id = ?", sharedDoc.ID).Error; err != nil || doc.OwnerID != colleague.ID {
+		t.Errorf("shared document owner = %v (err %v), want %v", doc.OwnerID, err, colleague.ID)
+	}
+	var project models.Project
+	if err := db.First(&project, "id = ?", sharedProject.ID).Error; err != nil || project.OwnerID != colleague.ID {
+		t.Errorf("shared project owner = %v (err %v), want %v", project.OwnerID, err, colleague.ID)
+	}
+
+	counts := []struct {
+		name  string
+		model interface{}
+		query string
+		args  []interface{}
+		want  int64
+	}{
+		{"user", &models.User{}, "id = ?", []interface{}{leaving.ID}, 0},
+		{"private document", &models.Document{}, "id = ?", []interface{}{privateDoc.ID}, 0},
+		{"colleague's document", &models.Document{}, "id = ?", []interface{}{colleagueDoc.ID}, 1},
+		{"shares", &models.DocumentShare{}, "user_id = ?", []interface{}{leaving.ID}, 0},
+		{"memberships", &models.Membership{}, "user_id = ?", []interface{}{leaving.ID}, 0},
+		{"invitations", &models.Invitation{}, "email = ? OR invited_by_id = ?", []interface{}{leaving.Email, leaving.ID}, 0},
+		{"revocation", &models.RevokedUser{}, "user_id = ?", []interface{}{leaving.ID}, 1},
+	}
+	for _, tt := range counts {
+		var n int64
+		if err := db.Model(tt.model).Where(tt.query, tt.args...).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		if n != tt.want {
+			t.Errorf("%s: %d rows, want %d", tt.name, n, tt.want)
+		}
+	}
+
+	// Every access token issued so far is revoked
+	if err := revocation.NewList(db, 0).Check(leaving.ID.String(), time.Now().Add(-time.Second)); !errors.Is(err, revocation.ErrTokenRevoked) {
+		t.Errorf("Check() = %v, want ErrTokenRevoked", err)
+	}
+}
+
+func TestRegisterConflicts(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	h := NewAuthHandler(db, cfg)
+	r := gin.New()
+	r.POST("/register", h.Register)
+
+	createUser(t, db, cfg, "taken@example.com")
+
+	tests := []struct {
+		name      string
+		email     string
+		wantCode  int
+		wantError string
+	}{
+		{"new email", "new@example.com", http.StatusCreated, ""},
+		{"same email", "taken@example.com", http.StatusConflict, "email_exists"},
+		{"other case", "Taken@Example.com", http.StatusConflict, "email_exists"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serve(r, http.MethodPost, "/register", gin.H{"email": tc.email, "password": testPassword})
+			expectStatus(t, w, tc.wantCode)
+			if tc.wantError != "" {
+				if code := errorCode(t, w); code != tc.wantError {
+					t.Errorf("error = %q, want %q", code, tc.wantError)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginIgnoresEmailCase(t *testing.T) {
+	tests := []struct {
+		name            string
+		caseInsensitive bool
+		email           string
+		wantCode        int
+	}{
+		{"same case", true, "alice@example.com", http.StatusOK},
+		{"mixed case", true, "Alice@Example.COM", http.StatusOK},
+		{"mixed case with spaces", true, "  ALICE@example.com ", http.StatusOK},
+		{"mixed case when case-sensitive", false, "Alice@Example.COM", http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			cfg := testConfig()
+			cfg.EmailCaseInsensitive = tc.caseInsensitive
+			h := NewAuthHandler(db, cfg)
+			r := gin.New()
+			r.POST("/login", h.Login)
+			createUser(t, db, cfg, "alice@example.com")
+
+			w := serve(r, http.MethodPost, "/login", gin.H{"email": tc.email, "password": testPassword})
+			expectStatus(t, w, tc.wantCode)
+		})
+	}
+}
+
+// A signup racing another past the existence check hits a unique index;
+// that is a conflict, not a server error
+func TestRespondCreateUserError(t *testing.T) {
+	violation := func(index string) error {
+		return fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505", ConstraintName: index})
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  int
+		wantError string
+	}{
+		{"email", violation(models.UserEmailIndex), http.StatusConflict, "email_exists"},
+		{"email, other case", violation(models.UserEmailLowerIndex), http.StatusConflict, "email_exists"},
+		{"username", violation(models.UserUsernameIndex), http.StatusConflict, "username_exists"},
+		{"other violation", violation("idx_other"), http.StatusInternalServerError, "internal_error"},
+		{"other error", errors.New("connection reset"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			respondCreateUserError(c, tc.err)
+			expectStatus(t, w, tc.wantCode)
+			if code := errorCode(t, w); code != tc.wantError {
+				t.Errorf("error = %q, want %q", code, tc.wantError)
+			}
+		})
+	}
+}
+
+func TestTokensCarryAuthTime(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	h := NewAuthHandler(db, cfg)
+
+	r := gin.New()
+	r.POST("/login", h.Login)
+	r.POST("/refresh", h.RefreshToken)
+
+	user := createUser(t, db, cfg, "user@example.com")
+	signedIn := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name string
+		path string
+		body func(t *testing.T) gin.H
+		want func(issued int64) bool
+	}{
+		{"login sets it to now", "/login", func(t *testing.T) gin.H {
+			return gin.H{"email": user.Email, "password": testPassword}
+		}, func(issued int64) bool {
+			return time.Since(time.Unix(issued, 0)) < time.Minute
+		}},
+		{"refresh keeps the original sign-in", "/refresh", func(t *testing.T) gin.H {
+			token, err := h.issueRefreshToken(db, user, signedIn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return gin.H{"refresh_token": token}
+		}, func(issued int64) bool {
+			return issued == signedIn.Unix()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodPost, tt.path, tt.body(t))
+			expectStatus(t, w, http.StatusOK)
+
+			var resp struct {
+				AccessToken string `json:"access_token"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			claims := jwt.MapClaims{}
+			if _, _, err := jwt.NewParser().ParseUnverified(resp.AccessToken, claims); err != nil {
+				t.Fatal(err)
+			}
+			authTime, _ := claims["auth_time"].(float64)
+			if !tt.want(int64(authTime)) {
+				t.Errorf("auth_time = %v", time.Unix(int64(authTime), 0))
+			}
+		})
+	}
+}
+
+func TestAuthTimeFromContext(t *testing.T) {
+	signedIn := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  time.Time
+	}{
+		{"set by RequireAuth", signedIn.Unix(), signedIn},
+		{"token without auth_time", int64(0), time.Time{}},
+		{"unset", nil, time.Time{}},
+		{"wrong type", "1700000000", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			if tt.value != nil {
+				c.Set("auth_time", tt.value)
+			}
+			if got := authTimeFromContext(c); !got.Equal(tt.want) {
+				t.Errorf("authTimeFromContext() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResendLink(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	cfg.DevMode = true // returns the new token
+	h := NewAuthHandler(db, cfg)
+
+	r := gin.New()
+	r.POST("/resend-link", h.ResendLink)
+	r.POST("/verify-email", h.VerifyEmail)
+	r.POST("/reset-password", h.ResetPassword)
+
+	tests := []struct {
+		name        string
+		linkType    string
+		expiredFor  time.Duration // negative while the link is still valid
+		verified    bool
+		wantResent  bool
+		wantExpired bool // whether using the old link reports token_expired
+	}{
+		{"expired verification link", linkTypeVerification, time.Hour, false, true, true},
+		{"verification link expired long ago", linkTypeVerification, resendWindow + time.Hour, false, false, true},
+		{"valid verification link", linkTypeVerification, -time.Hour, false, false, false},
+		{"already verified", linkTypeVerification, time.Hour, true, false, false},
+		{"expired reset link", linkTypePasswordReset, time.Minute, false, true, true},
+		{"reset link expired long ago", linkTypePasswordReset, resendWindow + time.Hour, false, false, true},
+		{"valid reset link", linkTypePasswordReset, -time.Minute, false, false, false},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createUser(t, db, cfg, fmt.Sprintf("user%d@example.com", i))
+			oldToken := generateRandomToken(32)
+			expiry := time.Now().Add(-tt.expiredFor)
+			column, tokenKey, usePath := "verify", "verify_token", "/verify-email"
+			if tt.linkType == linkTypePasswordReset {
+				column, tokenKey, usePath = "reset", "reset_token", "/reset-password"
+			}
+			if err := db.Model(user).Updates(map[string]interface{}{
+				column + "_token":  oldToken,
+				column + "_expiry": expiry,
+				"email_verified":   tt.verified,
+			}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			// An expired link says whether it can still be resent
+			w := serve(r, http.MethodPost, usePath, gin.H{"token": oldToken, "password": "New-Horse-Battery-42"})
+			if tt.wantExpired {
+				var resp struct {
+					Error     string `json:"error"`
+					CanResend bool   `json:"can_resend"`
+				}
+				json.Unmarshal(w.Body.Bytes(), &resp)
+				if resp.Error != "token_expired" || resp.CanResend != (tt.expiredFor < resendWindow) {
+					t.Errorf("using the old link: %s, want token_expired with can_resend %v", w.Body.String(), tt.expiredFor < resendWindow)
+				}
+			}
+
+			w = serve(r, http.MethodPost, "/resend-link", gin.H{"token": oldToken, "type": tt.linkType})
+			expectStatus(t, w, http.StatusOK)
+			var resp map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			newToken, resent := resp[tokenKey].(string)
+			if resent != tt.wantResent {
+				t.Fatalf("resent = %v, want %v; body %s", resent, tt.wantResent, w.Body.String())
+			}
+			if !resent {
+				return
+			}
+
+			// The new token replaces the old one, which can't be exchanged again
+			if newToken == oldToken {
+				t.Error("resent the same token")
+			}
+			w = serve(r, http.MethodPost, "/resend-link", gin.H{"token": oldToken, "type": tt.linkType})
+			var again map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &again)
+			if _, ok := again[tokenKey]; ok {
+				t.Error("old token exchanged twice")
+			}
+			w = serve(r, http.MethodPost, usePath, gin.H{"token": newToken, "password": "New-Horse-Battery-42"})
+			expectStatus(t, w, http.StatusOK)
+		})
+	}
+}
+
+func TestResendLinkValidation(t *testing.T) {
+	db := testutil.NewDB(t)
+	h := NewAuthHandler(db, testConfig())
+	r := gin.New()
+	r.POST("/resend-link", h.ResendLink)
+
+	tests := []struct {
+		name string
+		body gin.H
+		want int
+	}{
+		{"unknown token", gin.H{"token": "nope", "type": linkTypeVerification}, http.StatusOK},
+		{"unknown type", gin.H{"token": "nope", "type": "magic_link"}, http.StatusBadRequest},
+		{"missing token", gin.H{"type": linkTypePasswordReset}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodPost, "/resend-link", tt.body)
+			expectStatus(t, w, tt.want)
+		})
+	}
+}
+
+// deliveringSender stands in for a configured SMTP sender
+type deliveringSender struct{}
+
+func (deliveringSender) Send(email.Message) error { return nil }
+func (deliveringSender) Enabled() bool            { return true }
+
+func TestTokensOnlyExposedWithoutSMTP(t *testing.T) {
+	tests := []struct {
+		name      string
+		devMode   bool
+		mailer    email.Sender
+		wantToken bool
+	}{
+		{"dev mode without SMTP", true, &email.NoopSender{}, true},
+		{"dev mode with SMTP", true, deliveringSender{}, false},
+		{"production without SMTP", false, &email.NoopSender{}, false},
+		{"production with SMTP", false, deliveringSender{}, false},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			cfg := testConfig()
+			cfg.DevMode = tt.devMode
+			h := NewAuthHandler(db, cfg)
+			h.mailer = tt.mailer
+			r := gin.New()
+			r.POST("/forgot-password", h.ForgotPassword)
+			user := createUser(t, db, cfg, fmt.Sprintf("user%d@example.com", i))
+
+			w := serve(r, http.MethodPost, "/forgot-password", gin.H{"email": user.Email})
+			expectStatus(t, w, http.StatusOK)
+			var resp map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if _, got := resp["reset_token"]; got != tt.wantToken {
+				t.Errorf("reset_token in response = %v, want %v", got, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestSocialUser(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	h := NewAuthHandler(db, cfg)
+
+	local := createUser(t, db, cfg, "local@example.com")
+	google, err := h.socialUser("google", "Google@Example.com", "Google", "", "")
+	if err != nil {
+		t.Fatalf("create google user: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		email    string
+		wantErr  error
+		wantID   uuid.UUID
+	}{
+		{"same provider signs in", "google", "google@example.com", nil, google.ID},
+		{"same provider any case", "google", "GOOGLE@example.com", nil, google.ID},
+		{"other provider is not linked", "microsoft", "google@example.com", errProviderMismatch, uuid.Nil},
+		{"password account is not linked", "microsoft", local.Email, errProviderMismatch, uuid.Nil},
+		{"new email creates account", "microsoft", "new@example.com", nil, uuid.Nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := h.socialUser(tt.provider, tt.email, "Name", "", "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.wantID != uuid.Nil && user.ID != tt.wantID {
+				t.Errorf("user = %s, want %s", user.ID, tt.wantID)
+			}
+			if user.AuthProvider != tt.provider || !user.EmailVerified {
+				t.Errorf("user provider %q verified %v", user.AuthProvider, user.EmailVerified)
+			}
+		})
+	}
+
+	var reloaded models.User
+	db.First(&reloaded, "id = ?", local.ID)
+	if reloaded.AuthProvider != "local" || reloaded.Name != local.Name {
+		t.Errorf("password account changed: %+v", reloaded)
+	}
+}
+
+func TestMicrosoftVerifiedEmail(t *testing.T) {
+	const tenant = "11111111-2222-3333-4444-555555555555"
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		tenantID string
+		wantErr  bool
+	}{
+		{"no email", jwt.MapClaims{"tid": microsoftConsumersTenantID}, "common", true},
+		{"personal account", jwt.MapClaims{"email": "a@example.com", "tid": microsoftConsumersTenantID}, "common", false},
+		{"work account unverified", jwt.MapClaims{"email": "a@example.com", "tid": tenant}, "common", true},
+		{"work account xms_edov bool", jwt.MapClaims{"email": "a@example.com", "tid": tenant, "xms_edov": true}, "organizations", false},
+		{"work account xms_edov string", jwt.MapClaims{"email": "a@example.com", "tid": tenant, "xms_edov": "1"}, "", false},
+		{"work account xms_edov false", jwt.MapClaims{"email": "a@example.com", "tid": tenant, "xms_edov": false}, "common", true},
+		{"configured tenant", jwt.MapClaims{"email": "a@example.com", "tid": tenant}, tenant, false},
+		{"other tenant", jwt.MapClaims{"email": "a@example.com", "tid": "other", "xms_edov": true}, tenant, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := microsoftVerifiedEmail(tt.claims, tt.tenantID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && email != "a@example.com" {
+				t.Errorf("email = %q", email)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/saas-starter-kit/backend/internal/billing"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxWebhookBody bounds webhook payloads read into memory
+const maxWebhookBody = 1 << 20
+
+// BillingHandler connects tenant subscriptions to Stripe: Checkout creates
+// the Stripe subscription, and webhooks keep the local Subscription in sync
+type BillingHandler struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	stripe *billing.Client
+}
+
+func NewBillingHandler(db *gorm.DB, cfg *config.Config) *BillingHandler {
+	h := &BillingHandler{db: db, cfg: cfg}
+	if cfg.HasStripe() {
+		h.stripe = billing.NewClient(cfg.StripeSecretKey)
+	}
+	return h
+}
+
+// Checkout creates a Stripe Checkout session for a paid plan and returns
+// its URL. The plan takes effect when Stripe reports the subscription.
+// POST /api/v1/tenant/billing/checkout
+func (h *BillingHandler) Checkout(c *gin.Context) {
+	var req struct {
+		Plan string `json:"plan" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Plan is required"})
+		return
+	}
+
+	if h.stripe == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "billing_unavailable", "message": "Billing is not configured"})
+		return
+	}
+
+	var plan models.Plan
+	if err := h.db.Where("tier = ? AND is_active = ?", req.Plan, true).First(&plan).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_plan", "message": "Invalid plan tier"})
+		return
+	}
+	if plan.MonthlyPriceCents <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "plan_not_billable", "message": "This plan is free and needs no checkout"})
+		return
+	}
+
+	tenantID := c.GetString("tenant_id")
+
+	var subscription models.Subscription
+	h.db.Where("tenant_id = ?", tenantID).First(&subscription)
+
+	frontend := strings.TrimSuffix(h.cfg.FrontendURL, "/")
+	session, err := h.stripe.CreateCheckoutSession(billing.CheckoutParams{
+		TenantID:      tenantID,
+		PlanTier:      string(plan.Tier),
+		PlanName:      plan.Name,
+		MonthlyCents:  plan.MonthlyPriceCents,
+		Currency:      h.cfg.StripeCurrency,
+		CustomerID:    subscription.StripeCustomerID,
+		CustomerEmail: c.GetString("user_email"),
+		SuccessURL:    frontend + "/settings/billing?checkout=success",
+		CancelURL:     frontend + "/settings/billing?checkout=cancelled",
+	})
+	if err != nil {
+		log.Printf("Stripe checkout failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "billing_error", "message": "Failed to start checkout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id":   session.ID,
+		"checkout_url": session.URL,
+	})
+}
+
+// StripeWebhook applies Stripe subscription lifecycle events to the local
+// Subscription. Unknown events and subscriptions are acknowledged and
+// ignored so Stripe doesn't retry them.
+// POST /api/v1/webhooks/stripe
+func (h *BillingHandler) StripeWebhook(c *gin.Context) {
+	if h.cfg.StripeWebhookSecret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "billing_unavailable", "message": "Billing is not configured"})
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Failed to read body"})
+		return
+	}
+
+	event, err := billing.ParseWebhook(payload, c.GetHeader("Stripe-Signature"), h.cfg.StripeWebhookSecret)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_signature", "message": "Webhook signature verification failed"})
+		return
+	}
+
+	switch event.Type {
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		var sub billing.Subscription
+		if err := json.Unmarshal(event.Data.Object, &sub); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid subscription object"})
+			return
+		}
+		err = h.syncSubscription(&sub, event.Type == "customer.subscription.deleted")
+
+	case "invoice.payment_failed":
+		var invoice billing.Invoice
+		if err := json.Unmarshal(event.Data.Object, &invoice); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid invoice object"})
+			return
+		}
+		err = h.markPastDue(&invoice)
+	}
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Stripe webhook %s (%s) failed: %v", event.ID, event.Type, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to process event"})
+		return
+	}
+	if err != nil {
+		log.Printf("Stripe webhook %s (%s): no matching subscription", event.ID, event.Type)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// syncSubscription copies a Stripe subscription's state onto the local one,
+// found by Stripe ID or, the first time, by the tenant_id metadata set at
+// checkout
+func (h *BillingHandler) syncSubscription(sub *billing.Subscription, deleted bool) error {
+	var local models.Subscription
+	err := h.db.Where("stripe_subscription_id = ?", sub.ID).First(&local).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) && sub.Metadata["tenant_id"] != "" {
+		err = h.db.Where("tenant_id = ?", sub.Metadata["tenant_id"]).First(&local).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	local.StripeSubscriptionID = sub.ID
+	local.StripeCustomerID = sub.Customer
+	local.Status = localStatus(sub.Status)
+	if sub.CurrentPeriodStart > 0 {
+		local.CurrentPeriodStart = time.Unix(sub.CurrentPeriodStart, 0)
+	}
+	if sub.CurrentPeriodEnd > 0 {
+		local.CurrentPeriodEnd = time.Unix(sub.CurrentPeriodEnd, 0)
+	}
+
+	if deleted {
+		local.Status = "cancelled"
+	}
+	if local.Status == "cancelled" {
+		cancelledAt := time.Now()
+		if sub.CanceledAt != nil {
+			cancelledAt = time.Unix(*sub.CanceledAt, 0)
+		}
+		local.CancelledAt = &cancelledAt
+	} else {
+		local.CancelledAt = nil
+	}
+
+	// Switch plans when the subscription was bought for a different one
+	if tier := sub.Metadata["plan_tier"]; tier != "" && !deleted {
+		var plan models.Plan
+		if err := h.db.Where("tier = ?", tier).First(&plan).Error; err == nil {
+			local.PlanID = plan.ID
+		}
+	}
+
+	return h.db.Save(&local).Error
+}
+
+// markPastDue flags the subscription of an unpaid invoice. Stripe follows
+// up with subscription updates as its retries succeed or give up.
+func (h *BillingHandler) markPastDue(invoice *billing.Invoice) error {
+	if invoice.Subscription == "" {
+		return gorm.ErrRecordNotFound
+	}
+	result := h.db.Model(&models.Subscription{}).
+		Where("stripe_subscription_id = ?", invoice.Subscription).
+		Update("status", "past_due")
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// localStatus maps Stripe subscription statuses onto ours (active,
+// trialing, incomplete, past_due, cancelled). Lapsed payments count as
+// past_due.
+func localStatus(stripeStatus string) string {
+	switch stripeStatus {
+	case "active", "trialing", "incomplete":
+		return stripeStatus
+	case "canceled", "incomplete_expired":
+		return "cancelled"
+	default: // past_due, unpaid, paused
+		return "past_due"
+	}
+}
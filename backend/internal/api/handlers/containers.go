@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/events"
+	"github.com/yourusername/saas-starter-kit/backend/internal/fga"
 	"github.com/yourusername/saas-starter-kit/backend/internal/hierarchy"
 	"gorm.io/gorm"
 )
@@ -18,6 +24,8 @@ type ContainerHandler struct {
 	cfg        *config.Config
 	hierarchy  *hierarchy.Config
 	repository *hierarchy.Repository
+	fga        *fga.Client
+	emitter    events.Emitter
 }
 
 // NewContainerHandler creates a new container handler
@@ -27,9 +35,68 @@ func NewContainerHandler(db *gorm.DB, cfg *config.Config, h *hierarchy.Config) *
 		cfg:        cfg,
 		hierarchy:  h,
 		repository: hierarchy.NewRepository(db, h),
+		fga:        fga.NewClient(cfg.OpenFGAURL, cfg.OpenFGAStoreID),
+		emitter:    events.NewEmitter(cfg),
 	}
 }
 
+// containerRelations are the roles the OpenFGA model defines as direct user
+// relations on a container
+var containerRelations = map[hierarchy.Role]bool{
+	hierarchy.RoleAdmin:  true,
+	hierarchy.RoleMember: true,
+	hierarchy.RoleViewer: true,
+}
+
+// ForLevel binds a route group to a hierarchy level: it sets the :level
+// parameter the container handlers read, for routes generated from the
+// level's url_path
+func ForLevel(level string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Params = append(c.Params, gin.Param{Key: "level", Value: level})
+		c.Next()
+	}
+}
+
+// authorize checks that container belongs to the root the request acts in
+// and that the caller holds permission on it, writing the error response
+// otherwise. Containers of other roots are reported as not found.
+func (h *ContainerHandler) authorize(c *gin.Context, container *hierarchy.ResourceContainer, permission hierarchy.Permission, deniedMessage string) bool {
+	displayName := container.Level
+	if level := h.hierarchy.GetLevel(container.Level); level != nil {
+		displayName = level.DisplayName
+	}
+
+	rootID, _ := c.Get("root_id")
+	if rootID == nil || rootID.(string) != container.RootID.String() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": displayName + " not found"})
+		return false
+	}
+
+	allowed, err := h.permitted(c, container.ID, permission)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to resolve permissions"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": deniedMessage})
+		return false
+	}
+	return true
+}
+
+// permitted reports whether the caller has permission on a container in
+// any root. Prefer authorize, which also confines the caller to their root.
+func (h *ContainerHandler) permitted(c *gin.Context, containerID uuid.UUID, permission hierarchy.Permission) (bool, error) {
+	userID, _ := c.Get("user_id")
+	userUUID, _ := uuid.Parse(userID.(string))
+	permissions, err := h.repository.EffectivePermissions(userUUID, containerID)
+	if err != nil {
+		return false, err
+	}
+	return permissions.Has(permission), nil
+}
+
 // ListContainers lists containers at a given level
 // GET /api/v1/{level_url_path}
 func (h *ContainerHandler) ListContainers(c *gin.Context) {
@@ -62,8 +129,9 @@ func (h *ContainerHandler) ListContainers(c *gin.Context) {
 		return
 	}
 
-	// For non-root levels, list containers user has access to
-	containers, err := h.repository.GetUserContainers(userUUID, level)
+	// For non-root levels, list containers user has access to, including
+	// those inherited from admin roles on ancestors
+	containers, err := h.repository.GetUserContainersWithInheritance(userUUID, level)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to fetch containers"})
 		return
@@ -101,10 +169,22 @@ func (h *ContainerHandler) CreateContainer(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	userUUID, _ := uuid.Parse(userID.(string))
 
-	// Generate slug if not provided
-	slug := req.Slug
-	if slug == "" {
-		slug = generateSlug(req.Name)
+	// Normalize an explicit slug; a colliding one is rejected below rather
+	// than silently changed
+	explicitSlug := req.Slug != ""
+	slug := generateSlug(req.Name)
+	if explicitSlug {
+		slug = generateSlug(req.Slug)
+		if slug == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_slug", "message": "Slug must contain letters or numbers"})
+			return
+		}
+		if isReservedSlug(slug) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reserved_slug", "message": "This slug is reserved"})
+			return
+		}
+	} else if slug == "" {
+		slug = levelConfig.Name
 	}
 
 	// Determine parent
@@ -135,6 +215,16 @@ func (h *ContainerHandler) CreateContainer(c *gin.Context) {
 		}
 	}
 
+	// Slugs are unique within the parent. Generated ones get a suffix.
+	if explicitSlug {
+		if !h.slugAvailable(level, slug, parentID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "slug_exists", "message": "A " + levelConfig.DisplayName + " with this slug already exists"})
+			return
+		}
+	} else {
+		slug = h.uniqueSlug(level, slug, parentID)
+	}
+
 	// Create container
 	container, err := h.repository.CreateContainer(level, slug, req.Name, parentID)
 	if err != nil {
@@ -143,11 +233,28 @@ func (h *ContainerHandler) CreateContainer(c *gin.Context) {
 	}
 
 	// Add creator as admin
-	if err := h.repository.AddMember(userUUID, container.ID, "admin"); err != nil {
+	if err := h.repository.AddMember(userUUID, container.ID, hierarchy.RoleAdmin); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to add membership"})
 		return
 	}
 
+	// The first root a user creates becomes the one ResolveRoot picks for
+	// them
+	if levelConfig.IsRoot {
+		h.db.Model(&hierarchy.User{}).
+			Where("id = ? AND admin_of_root_id IS NULL", userUUID).
+			Updates(map[string]interface{}{"admin_of_root_id": container.ID, "is_root_admin": true})
+	}
+
+	h.emitter.Emit(events.ContainerCreated, map[string]interface{}{
+		"container_id": container.ID,
+		"level":        level,
+		"slug":         container.Slug,
+		"name":         container.DisplayName,
+		"parent_id":    parentID,
+		"actor_id":     userUUID,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message":        levelConfig.DisplayName + " created successfully",
 		levelConfig.Name: containerResponse(container, levelConfig),
@@ -190,10 +297,217 @@ func (h *ContainerHandler) GetContainer(c *gin.Context) {
 		return
 	}
 
+	// The caller's effective permissions, so clients can show what they may do
+	userID, _ := c.Get("user_id")
+	userUUID, _ := uuid.Parse(userID.(string))
+	permissions, err := h.repository.EffectivePermissions(userUUID, container.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to resolve permissions"})
+		return
+	}
+
+	response := containerResponse(container, levelConfig)
+	response["permissions"] = permissions.List()
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateContainer updates a container's display name, slug and metadata
+// PUT /api/v1/{level_url_path}/:id
+func (h *ContainerHandler) UpdateContainer(c *gin.Context) {
+	level := c.Param("level")
+	levelConfig := h.hierarchy.GetLevel(level)
+	if levelConfig == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "invalid_level", "message": "Unknown hierarchy level"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Invalid container ID"})
+		return
+	}
+
+	container, err := h.repository.GetContainer(id)
+	if err != nil || container.Level != level {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": levelConfig.DisplayName + " not found"})
+		return
+	}
+	if !h.authorize(c, container, hierarchy.PermissionManage, "Only admins can update this "+levelConfig.DisplayName) {
+		return
+	}
+
+	var req struct {
+		Name     *string          `json:"name"`
+		Slug     *string          `json:"slug"`
+		Metadata *json.RawMessage `json:"metadata"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Name cannot be empty"})
+			return
+		}
+		container.DisplayName = name
+	}
+
+	if req.Slug != nil && *req.Slug != container.Slug {
+		// The root slug identifies the organization; keep it stable
+		if levelConfig.IsRoot {
+			c.JSON(http.StatusForbidden, gin.H{"error": "cannot_change_root_slug", "message": "Cannot change the slug of the root organization"})
+			return
+		}
+
+		slug := generateSlug(*req.Slug)
+		if slug == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_slug", "message": "Slug must contain letters or numbers"})
+			return
+		}
+		if isReservedSlug(slug) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reserved_slug", "message": "This slug is reserved"})
+			return
+		}
+
+		// Slugs are unique within the parent
+		if existing, err := h.repository.GetContainerBySlug(level, slug, container.ParentID); err == nil && existing.ID != container.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "slug_taken", "message": "Slug is already in use"})
+			return
+		}
+		container.Slug = slug
+	}
+
+	if req.Metadata != nil {
+		if !json.Valid(*req.Metadata) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_metadata", "message": "Metadata must be valid JSON"})
+			return
+		}
+		container.Metadata = string(*req.Metadata)
+	}
+
+	if err := h.repository.UpdateContainer(container); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to update container"})
+		return
+	}
+
 	c.JSON(http.StatusOK, containerResponse(container, levelConfig))
 }
 
-// DeleteContainer deletes a container
+// MoveContainer moves a container under a different parent
+// POST /api/v1/{level_url_path}/:id/move
+func (h *ContainerHandler) MoveContainer(c *gin.Context) {
+	level := c.Param("level")
+	levelConfig := h.hierarchy.GetLevel(level)
+	if levelConfig == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "invalid_level", "message": "Unknown hierarchy level"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Invalid container ID"})
+		return
+	}
+
+	var req struct {
+		ParentID string `json:"parent_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "parent_id is required"})
+		return
+	}
+	parentID, err := uuid.Parse(req.ParentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Invalid parent ID"})
+		return
+	}
+
+	container, err := h.repository.GetContainer(id)
+	if err != nil || container.Level != level {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": levelConfig.DisplayName + " not found"})
+		return
+	}
+
+	// Cannot move root container
+	if levelConfig.IsRoot {
+		c.JSON(http.StatusForbidden, gin.H{"error": "cannot_move_root", "message": "Cannot move root organization"})
+		return
+	}
+
+	// Moving takes manage permission on both the container and its new
+	// parent
+	if !h.authorize(c, container, hierarchy.PermissionManage, "Only admins can move this "+levelConfig.DisplayName) {
+		return
+	}
+	parent, err := h.repository.GetContainer(parentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "parent_not_found", "message": "New parent not found"})
+		return
+	}
+
+	// Moving to another organization also takes manage on both roots. The
+	// other organization is reported as not found to callers who don't
+	// administer it.
+	if parent.RootID != container.RootID {
+		root, err := h.repository.GetContainer(container.RootID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to load organization"})
+			return
+		}
+		if !h.authorize(c, root, hierarchy.PermissionManage, "Only organization admins can move containers to another organization") {
+			return
+		}
+		allowed, err := h.permitted(c, parent.RootID, hierarchy.PermissionManage)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to resolve permissions"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusNotFound, gin.H{"error": "parent_not_found", "message": "New parent not found"})
+			return
+		}
+	}
+
+	allowed, err := h.permitted(c, parent.ID, hierarchy.PermissionManage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to resolve permissions"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Only admins of the new parent can move containers into it"})
+		return
+	}
+
+	// Slugs are unique within the parent
+	if existing, err := h.repository.GetContainerBySlug(level, container.Slug, &parentID); err == nil && existing.ID != container.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "slug_taken", "message": "The new parent already has a " + levelConfig.DisplayName + " with this slug"})
+		return
+	}
+
+	moved, err := h.repository.MoveContainer(id, parentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, hierarchy.ErrMoveIntoDescendant):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "move_cycle", "message": "Cannot move a container under itself or one of its descendants"})
+		case errors.Is(err, hierarchy.ErrInvalidParentLevel):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_parent", "message": "New parent is not at the level above " + levelConfig.DisplayName})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "parent_not_found", "message": "New parent not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to move container"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, containerResponse(moved, levelConfig))
+}
+
+// DeleteContainer soft-deletes a container along with its descendants and
+// memberships. It can be brought back with RestoreContainer until purged.
 // DELETE /api/v1/{level_url_path}/:id
 func (h *ContainerHandler) DeleteContainer(c *gin.Context) {
 	level := c.Param("level")
@@ -211,7 +525,7 @@ func (h *ContainerHandler) DeleteContainer(c *gin.Context) {
 	}
 
 	container, err := h.repository.GetContainer(id)
-	if err != nil {
+	if err != nil || container.Level != level {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": levelConfig.DisplayName + " not found"})
 		return
 	}
@@ -221,9 +535,12 @@ func (h *ContainerHandler) DeleteContainer(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "cannot_delete_root", "message": "Cannot delete root organization"})
 		return
 	}
+	if !h.authorize(c, container, hierarchy.PermissionManage, "Only admins can delete this "+levelConfig.DisplayName) {
+		return
+	}
 
-	// Delete container (cascades to children and memberships)
-	if err := h.db.Delete(container).Error; err != nil {
+	// Soft delete container (cascades to children and memberships)
+	if err := h.repository.SoftDeleteContainer(container.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to delete container"})
 		return
 	}
@@ -231,6 +548,82 @@ func (h *ContainerHandler) DeleteContainer(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": levelConfig.DisplayName + " deleted successfully"})
 }
 
+// RestoreContainer restores a soft-deleted container
+// POST /api/v1/{level_url_path}/:id/restore
+func (h *ContainerHandler) RestoreContainer(c *gin.Context) {
+	level := c.Param("level")
+	levelConfig := h.hierarchy.GetLevel(level)
+	if levelConfig == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "invalid_level", "message": "Unknown hierarchy level"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Invalid container ID"})
+		return
+	}
+
+	// Root containers can't be deleted, so only their descendants are
+	// restored, within the root the request acts in
+	rootID, _ := c.Get("root_id")
+	container, err := h.repository.GetContainerIncludeDeleted(id)
+	if err != nil || container.Level != level || container.ParentID == nil || rootID != container.RootID.String() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": levelConfig.DisplayName + " not found"})
+		return
+	}
+
+	// The container's memberships were deleted with it, so restoring takes
+	// manage on the parent, which covers the container as it does for
+	// DeleteContainer
+	parent, err := h.repository.GetContainer(*container.ParentID)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "parent_deleted", "message": "Restore the parent first"})
+		return
+	}
+	if !h.authorize(c, parent, hierarchy.PermissionManage, "Only admins of the parent can restore this "+levelConfig.DisplayName) {
+		return
+	}
+
+	if err := h.repository.RestoreContainer(id); err != nil {
+		if errors.Is(err, hierarchy.ErrParentDeleted) {
+			c.JSON(http.StatusConflict, gin.H{"error": "parent_deleted", "message": "Restore the parent first"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to restore container"})
+		return
+	}
+
+	container, _ = h.repository.GetContainer(id)
+	c.JSON(http.StatusOK, containerResponse(container, levelConfig))
+}
+
+// PurgeDeletedContainers permanently removes containers soft-deleted more
+// than older_than_days days ago (default 30). Platform admins only.
+// POST /api/v1/hierarchy/purge
+func (h *ContainerHandler) PurgeDeletedContainers(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var user hierarchy.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil || !user.IsPlatformAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "message": "Platform admin access required"})
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("older_than_days", "30"))
+	if err != nil || days < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "older_than_days must be a non-negative integer"})
+		return
+	}
+
+	purged, err := h.repository.PurgeDeleted(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to purge deleted containers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": purged, "older_than_days": days})
+}
+
 // ListMembers lists members of a container
 // GET /api/v1/{level_url_path}/:id/members
 func (h *ContainerHandler) ListMembers(c *gin.Context) {
@@ -295,6 +688,16 @@ func (h *ContainerHandler) AddMember(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Valid email is required"})
 		return
 	}
+	req.Email = h.cfg.NormalizeEmail(req.Email)
+
+	container, err := h.repository.GetContainer(id)
+	if err != nil || container.Level != level {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": levelConfig.DisplayName + " not found"})
+		return
+	}
+	if !h.authorize(c, container, hierarchy.PermissionManageMembers, "Only admins can manage members of this "+levelConfig.DisplayName) {
+		return
+	}
 
 	// Find user by email
 	var user hierarchy.User
@@ -310,18 +713,11 @@ func (h *ContainerHandler) AddMember(c *gin.Context) {
 	}
 
 	// Validate role
-	role := req.Role
+	role := hierarchy.Role(req.Role)
 	if role == "" {
-		role = "member"
+		role = hierarchy.DefaultRole
 	}
-	validRole := false
-	for _, r := range levelConfig.Roles {
-		if r == role {
-			validRole = true
-			break
-		}
-	}
-	if !validRole {
+	if !levelConfig.IsValidRole(role) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":       "invalid_role",
 			"message":     "Invalid role for this level",
@@ -336,6 +732,16 @@ func (h *ContainerHandler) AddMember(c *gin.Context) {
 		return
 	}
 
+	actorID, _ := c.Get("user_id")
+	h.emitter.Emit(events.MemberAdded, map[string]interface{}{
+		"container_id": id,
+		"level":        level,
+		"user_id":      user.ID,
+		"email":        user.Email,
+		"role":         role,
+		"actor_id":     actorID,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Member added successfully",
 		"member": gin.H{
@@ -347,17 +753,227 @@ func (h *ContainerHandler) AddMember(c *gin.Context) {
 	})
 }
 
+// UpdateMember changes a member's role in a container
+// PUT /api/v1/{level_url_path}/:id/members/:userId
+func (h *ContainerHandler) UpdateMember(c *gin.Context) {
+	level := c.Param("level")
+	levelConfig := h.hierarchy.GetLevel(level)
+	if levelConfig == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "invalid_level", "message": "Unknown hierarchy level"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Invalid container ID"})
+		return
+	}
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Invalid user ID"})
+		return
+	}
+
+	container, err := h.repository.GetContainer(id)
+	if err != nil || container.Level != level {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": levelConfig.DisplayName + " not found"})
+		return
+	}
+	if !h.authorize(c, container, hierarchy.PermissionManageMembers, "Only admins can manage members of this "+levelConfig.DisplayName) {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Role is required"})
+		return
+	}
+
+	role := hierarchy.Role(req.Role)
+	if !levelConfig.IsValidRole(role) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":       "invalid_role",
+			"message":     "Invalid role for this level",
+			"valid_roles": levelConfig.Roles,
+		})
+		return
+	}
+
+	membership, err := h.repository.UpdateMemberRole(userID, id, role)
+	if err != nil {
+		switch {
+		case errors.Is(err, hierarchy.ErrLastAdmin):
+			c.JSON(http.StatusConflict, gin.H{"error": "last_admin", "message": "Cannot demote the last admin of this " + levelConfig.DisplayName})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_member", "message": "User is not a member"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to update member"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member updated successfully",
+		"member": gin.H{
+			"user_id":      membership.UserID,
+			"container_id": membership.ContainerID,
+			"role":         membership.Role,
+			"updated_at":   membership.UpdatedAt,
+		},
+	})
+}
+
+// RemoveMember removes a member from a container, along with the member's
+// OpenFGA tuple on it when OpenFGA is configured
+// DELETE /api/v1/{level_url_path}/:id/members/:userId
+func (h *ContainerHandler) RemoveMember(c *gin.Context) {
+	level := c.Param("level")
+	levelConfig := h.hierarchy.GetLevel(level)
+	if levelConfig == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "invalid_level", "message": "Unknown hierarchy level"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Invalid container ID"})
+		return
+	}
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "message": "Invalid user ID"})
+		return
+	}
+
+	container, err := h.repository.GetContainer(id)
+	if err != nil || container.Level != level {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": levelConfig.DisplayName + " not found"})
+		return
+	}
+	if !h.authorize(c, container, hierarchy.PermissionManageMembers, "Only admins can manage members of this "+levelConfig.DisplayName) {
+		return
+	}
+
+	membership, err := h.repository.GetMembership(userID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_member", "message": "User is not a member"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to remove member"})
+		}
+		return
+	}
+
+	if err := h.repository.RemoveMember(userID, id); err != nil {
+		switch {
+		case errors.Is(err, hierarchy.ErrLastAdmin):
+			c.JSON(http.StatusConflict, gin.H{"error": "last_admin", "message": "Cannot remove the last admin of this " + levelConfig.DisplayName})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_member", "message": "User is not a member"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to remove member"})
+		}
+		return
+	}
+
+	// The database is the source of truth, so a failed tuple delete is only
+	// logged. Roles the OpenFGA model has no container relation for can't
+	// have a tuple.
+	if containerRelations[hierarchy.Role(membership.Role)] {
+		tuple := fga.Tuple("user", userID.String(), membership.Role, "container", id.String())
+		if err := h.fga.Delete(c.Request.Context(), []fga.TupleKey{tuple}); err != nil {
+			log.Printf("Failed to delete membership tuple from OpenFGA: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// ListMyMemberships lists the containers the current user is a direct
+// member of, at any level, with their role and the root container each
+// belongs to. Paginated with limit (default 50, max 100) and offset.
+// GET /api/v1/me/memberships
+func (h *ContainerHandler) ListMyMemberships(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userUUID, _ := uuid.Parse(userID.(string))
+
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	memberships, total, err := h.repository.ListUserMemberships(userUUID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to fetch memberships"})
+		return
+	}
+
+	// Load the roots of this page in one query
+	seen := make(map[uuid.UUID]bool)
+	var rootIDs []uuid.UUID
+	for _, m := range memberships {
+		if !seen[m.Container.RootID] {
+			seen[m.Container.RootID] = true
+			rootIDs = append(rootIDs, m.Container.RootID)
+		}
+	}
+	roots, err := h.repository.GetContainers(rootIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to fetch memberships"})
+		return
+	}
+	rootsByID := make(map[uuid.UUID]*hierarchy.ResourceContainer, len(roots))
+	for i := range roots {
+		rootsByID[roots[i].ID] = &roots[i]
+	}
+
+	result := make([]gin.H, 0, len(memberships))
+	for _, m := range memberships {
+		levelConfig := h.hierarchy.GetLevel(m.Container.Level)
+		if levelConfig == nil {
+			continue // level removed from the hierarchy config
+		}
+		entry := gin.H{
+			"role":      m.Role,
+			"joined_at": m.CreatedAt,
+			"container": containerResponse(&m.Container, levelConfig),
+		}
+		if root := rootsByID[m.Container.RootID]; root != nil {
+			entry["root"] = gin.H{
+				"id":           root.ID,
+				"slug":         root.Slug,
+				"display_name": root.DisplayName,
+			}
+		}
+		result = append(result, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"memberships": result,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
 // GetHierarchyConfig returns the hierarchy configuration
 // GET /api/v1/hierarchy
 func (h *ContainerHandler) GetHierarchyConfig(c *gin.Context) {
 	levels := make([]gin.H, len(h.hierarchy.Levels))
 	for i, level := range h.hierarchy.Levels {
+		permissions := make(map[hierarchy.Role][]hierarchy.Permission, len(level.Roles))
+		for _, role := range level.Roles {
+			permissions[role] = level.PermissionsFor(role).List()
+		}
 		levels[i] = gin.H{
 			"name":         level.Name,
 			"display_name": level.DisplayName,
 			"plural":       level.Plural,
 			"url_path":     level.URLPath,
 			"roles":        level.Roles,
+			"permissions":  permissions,
 			"is_root":      level.IsRoot,
 		}
 	}
@@ -392,6 +1008,35 @@ func containerResponse(container *hierarchy.ResourceContainer, levelConfig *hier
 	}
 }
 
+// maxSlugSuffix is the last numbered suffix (-2, -3, ...) tried for a
+// generated slug before falling back to a random one
+const maxSlugSuffix = 10
+
+// slugAvailable reports whether slug is neither reserved nor used by another
+// level container under parentID
+func (h *ContainerHandler) slugAvailable(level, slug string, parentID *uuid.UUID) bool {
+	if isReservedSlug(slug) {
+		return false
+	}
+	_, err := h.repository.GetContainerBySlug(level, slug, parentID)
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// uniqueSlug returns base, or base with the first free suffix, so generated
+// slugs never collide under the same parent
+func (h *ContainerHandler) uniqueSlug(level, base string, parentID *uuid.UUID) string {
+	if h.slugAvailable(level, base, parentID) {
+		return base
+	}
+	for i := 2; i <= maxSlugSuffix; i++ {
+		slug := base + "-" + strconv.Itoa(i)
+		if h.slugAvailable(level, slug, parentID) {
+			return slug
+		}
+	}
+	return base + "-" + uuid.New().String()[:8]
+}
+
 func generateSlug(name string) string {
 	slug := strings.ToLower(name)
 	slug = regexp.MustCompile(`[^a-z0-9\s-]`).ReplaceAllString(slug, "")
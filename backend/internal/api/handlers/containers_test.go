@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/hierarchy"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// containerFixture is an organization administered by admin with two teams,
+// eng (with project api) and ops (administered by opsAdmin), and a second
+// organization with team sales, administered by outsider. member is a
+// member of eng.
+type containerFixture struct {
+	db       *gorm.DB
+	handler  *ContainerHandler
+	repo     *hierarchy.Repository
+	org      *hierarchy.ResourceContainer
+	eng      *hierarchy.ResourceContainer
+	api      *hierarchy.ResourceContainer
+	ops      *hierarchy.ResourceContainer
+	otherOrg *hierarchy.ResourceContainer
+	sales    *hierarchy.ResourceContainer
+	admin    *models.User
+	member   *models.User
+	opsAdmin *models.User
+	outsider *models.User
+}
+
+func newContainerFixture(t *testing.T) *containerFixture {
+	t.Helper()
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	h := hierarchy.MLPlatformConfig()
+	f := &containerFixture{
+		db:      db,
+		handler: NewContainerHandler(db, cfg, h),
+		repo:    hierarchy.NewRepository(db, h),
+	}
+
+	f.org = f.createContainer(t, "tenant", "acme", nil)
+	f.eng = f.createContainer(t, "team", "eng", &f.org.ID)
+	f.api = f.createContainer(t, "project", "api", &f.eng.ID)
+	f.ops = f.createContainer(t, "team", "ops", &f.org.ID)
+	f.otherOrg = f.createContainer(t, "tenant", "globex", nil)
+	f.sales = f.createContainer(t, "team", "sales", &f.otherOrg.ID)
+
+	f.admin = createUser(t, db, cfg, "admin@example.com")
+	f.member = createUser(t, db, cfg, "member@example.com")
+	f.opsAdmin = createUser(t, db, cfg, "ops@example.com")
+	f.outsider = createUser(t, db, cfg, "outsider@example.com")
+	f.addMember(t, f.admin, f.org, hierarchy.RoleAdmin)
+	f.addMember(t, f.member, f.eng, hierarchy.RoleMember)
+	f.addMember(t, f.opsAdmin, f.ops, hierarchy.RoleAdmin)
+	f.addMember(t, f.outsider, f.otherOrg, hierarchy.RoleAdmin)
+	return f
+}
+
+func (f *containerFixture) createContainer(t *testing.T, level, slug string, parentID *uuid.UUID) *hierarchy.ResourceContainer {
+	t.Helper()
+	container, err := f.repo.CreateContainer(level, slug, slug, parentID)
+	if err != nil {
+		t.Fatalf("create %s: %v", level, err)
+	}
+	return container
+}
+
+func (f *containerFixture) addMember(t *testing.T, user *models.User, container *hierarchy.ResourceContainer, role hierarchy.Role) {
+	t.Helper()
+	if err := f.repo.AddMember(user.ID, container.ID, role); err != nil {
+		t.Fatalf("add member: %v", err)
+	}
+}
+
+// router runs requests as user, acting in root
+func (f *containerFixture) router(user *models.User, root *hierarchy.ResourceContainer) *gin.Engine {
+	r := asUser(user.ID.String(), gin.H{"root_id": root.ID.String()})
+	for _, level := range []string{"team", "project"} {
+		containers := r.Group("/"+level, ForLevel(level))
+		containers.PUT("/:id", f.handler.UpdateContainer)
+		containers.DELETE("/:id", f.handler.DeleteContainer)
+		containers.POST("/:id/move", f.handler.MoveContainer)
+		containers.POST("/:id/restore", f.handler.RestoreContainer)
+		containers.POST("/:id/members", f.handler.AddMember)
+		containers.PUT("/:id/members/:userId", f.handler.UpdateMember)
+		containers.DELETE("/:id/members/:userId", f.handler.RemoveMember)
+	}
+	return r
+}
+
+func TestMemberChangesNeedManageMembers(t *testing.T) {
+	requests := []struct {
+		name   string
+		method string
+		path   func(f *containerFixture) string
+		body   interface{}
+	}{
+		{"add", http.MethodPost, func(f *containerFixture) string {
+			return "/team/" + f.eng.ID.String() + "/members"
+		}, gin.H{"email": "outsider@example.com", "role": "member"}},
+		{"update", http.MethodPut, func(f *containerFixture) string {
+			return "/team/" + f.eng.ID.String() + "/members/" + f.member.ID.String()
+		}, gin.H{"role": "admin"}},
+		{"remove", http.MethodDelete, func(f *containerFixture) string {
+			return "/team/" + f.eng.ID.String() + "/members/" + f.member.ID.String()
+		}, nil},
+	}
+	callers := []struct {
+		name      string
+		caller    func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer)
+		wantCode  int
+		wantError string
+	}{
+		{"org admin", func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer) {
+			return f.admin, f.org
+		}, 0, ""},
+		{"team member", func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer) {
+			return f.member, f.org
+		}, http.StatusForbidden, "access_denied"},
+		{"admin of another organization", func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer) {
+			return f.outsider, f.otherOrg
+		}, http.StatusNotFound, "not_found"},
+	}
+
+	for _, req := range requests {
+		for _, tc := range callers {
+			t.Run(req.name+"/"+tc.name, func(t *testing.T) {
+				f := newContainerFixture(t)
+				user, root := tc.caller(f)
+				w := serve(f.router(user, root), req.method, req.path(f), req.body)
+
+				if tc.wantCode == 0 {
+					if w.Code >= 300 {
+						t.Fatalf("status = %d, want success; body %s", w.Code, w.Body.String())
+					}
+					return
+				}
+				expectStatus(t, w, tc.wantCode)
+				if code := errorCode(t, w); code != tc.wantError {
+					t.Errorf("error = %q, want %q", code, tc.wantError)
+				}
+				if m, err := f.repo.GetMembership(f.member.ID, f.eng.ID); err != nil || m.Role != string(hierarchy.RoleMember) {
+					t.Errorf("membership changed by a denied request: %+v, %v", m, err)
+				}
+			})
+		}
+	}
+}
+
+func TestMoveContainer(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(t *testing.T, f *containerFixture)
+		caller    func(f *containerFixture) *models.User
+		parent    func(f *containerFixture) *hierarchy.ResourceContainer
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:      "into an organization the caller doesn't administer",
+			caller:    func(f *containerFixture) *models.User { return f.admin },
+			parent:    func(f *containerFixture) *hierarchy.ResourceContainer { return f.sales },
+			wantCode:  http.StatusNotFound,
+			wantError: "parent_not_found",
+		},
+		{
+			name: "into another organization, managing only the new parent",
+			setup: func(t *testing.T, f *containerFixture) {
+				f.addMember(t, f.admin, f.sales, hierarchy.RoleAdmin)
+			},
+			caller:    func(f *containerFixture) *models.User { return f.admin },
+			parent:    func(f *containerFixture) *hierarchy.ResourceContainer { return f.sales },
+			wantCode:  http.StatusNotFound,
+			wantError: "parent_not_found",
+		},
+		{
+			name: "into another organization without managing this one",
+			setup: func(t *testing.T, f *containerFixture) {
+				f.addMember(t, f.member, f.api, hierarchy.RoleAdmin)
+				f.addMember(t, f.member, f.otherOrg, hierarchy.RoleAdmin)
+			},
+			caller:    func(f *containerFixture) *models.User { return f.member },
+			parent:    func(f *containerFixture) *hierarchy.ResourceContainer { return f.sales },
+			wantCode:  http.StatusForbidden,
+			wantError: "access_denied",
+		},
+		{
+			name:      "without manage on the container",
+			caller:    func(f *containerFixture) *models.User { return f.opsAdmin },
+			parent:    func(f *containerFixture) *hierarchy.ResourceContainer { return f.ops },
+			wantCode:  http.StatusForbidden,
+			wantError: "access_denied",
+		},
+		{
+			name: "without manage on the new parent",
+			setup: func(t *testing.T, f *containerFixture) {
+				// member may manage api itself, but not ops
+				f.addMember(t, f.member, f.api, hierarchy.RoleAdmin)
+			},
+			caller:    func(f *containerFixture) *models.User { return f.member },
+			parent:    func(f *containerFixture) *hierarchy.ResourceContainer { return f.ops },
+			wantCode:  http.StatusForbidden,
+			wantError: "access_denied",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newContainerFixture(t)
+			if tc.setup != nil {
+				tc.setup(t, f)
+			}
+
+			w := serve(f.router(tc.caller(f), f.org), http.MethodPost,
+				"/project/"+f.api.ID.String()+"/move", gin.H{"parent_id": tc.parent(f).ID})
+			expectStatus(t, w, tc.wantCode)
+			if code := errorCode(t, w); code != tc.wantError {
+				t.Errorf("error = %q, want %q", code, tc.wantError)
+			}
+
+			api, err := f.repo.GetContainer(f.api.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *api.ParentID != f.eng.ID || api.RootID != f.org.ID || api.Path != f.api.Path {
+				t.Errorf("denied move changed the container: parent %s, root %s, path %s", api.ParentID, api.RootID, api.Path)
+			}
+		})
+	}
+
+	t.Run("org admin", func(t *testing.T) {
+		f := newContainerFixture(t)
+		w := serve(f.router(f.admin, f.org), http.MethodPost,
+			"/project/"+f.api.ID.String()+"/move", gin.H{"parent_id": f.ops.ID})
+		expectStatus(t, w, http.StatusOK)
+
+		api, err := f.repo.GetContainer(f.api.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *api.ParentID != f.ops.ID || api.Path != f.ops.Path+"/"+api.ID.String() || api.RootID != f.org.ID {
+			t.Errorf("moved project: parent %s, path %s, root %s", api.ParentID, api.Path, api.RootID)
+		}
+		// Only the moved container changes
+		if eng, err := f.repo.GetContainer(f.eng.ID); err != nil || eng.Path != f.eng.Path {
+			t.Errorf("old parent changed: %+v, %v", eng, err)
+		}
+	})
+
+	t.Run("admin of both organizations", func(t *testing.T) {
+		f := newContainerFixture(t)
+		f.addMember(t, f.admin, f.otherOrg, hierarchy.RoleAdmin)
+		w := serve(f.router(f.admin, f.org), http.MethodPost,
+			"/project/"+f.api.ID.String()+"/move", gin.H{"parent_id": f.sales.ID})
+		expectStatus(t, w, http.StatusOK)
+
+		api, err := f.repo.GetContainer(f.api.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *api.ParentID != f.sales.ID || api.Path != f.sales.Path+"/"+api.ID.String() || api.RootID != f.otherOrg.ID {
+			t.Errorf("moved project: parent %s, path %s, root %s", api.ParentID, api.Path, api.RootID)
+		}
+	})
+
+	t.Run("repository rewrites descendants", func(t *testing.T) {
+		db := testutil.NewDB(t)
+		repo := hierarchy.NewRepository(db, &hierarchy.Config{Levels: []hierarchy.Level{
+			{Name: "tenant", IsRoot: true}, {Name: "division"}, {Name: "team"}, {Name: "project"},
+		}})
+		create := func(level, slug string, parentID *uuid.UUID) *hierarchy.ResourceContainer {
+			c, err := repo.CreateContainer(level, slug, slug, parentID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return c
+		}
+		org := create("tenant", "acme", nil)
+		east := create("division", "east", &org.ID)
+		west := create("division", "west", &org.ID)
+		team := create("team", "eng", &east.ID)
+		project := create("project", "api", &team.ID)
+
+		if _, err := repo.MoveContainer(team.ID, west.ID); err != nil {
+			t.Fatalf("MoveContainer: %v", err)
+		}
+		project, err := repo.GetContainer(project.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := west.Path + "/" + team.ID.String() + "/" + project.ID.String()
+		if project.Path != want || project.Depth != 3 {
+			t.Errorf("descendant path, depth = %q, %d; want %q, 3", project.Path, project.Depth, want)
+		}
+	})
+
+	t.Run("repository moves a subtree to another root", func(t *testing.T) {
+		f := newContainerFixture(t)
+		if _, err := f.repo.MoveContainer(f.eng.ID, f.otherOrg.ID); err != nil {
+			t.Fatalf("MoveContainer: %v", err)
+		}
+		for _, tc := range []struct {
+			id       uuid.UUID
+			wantPath string
+		}{
+			{f.eng.ID, f.otherOrg.Path + "/" + f.eng.ID.String()},
+			{f.api.ID, f.otherOrg.Path + "/" + f.eng.ID.String() + "/" + f.api.ID.String()},
+		} {
+			c, err := f.repo.GetContainer(tc.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.RootID != f.otherOrg.ID || c.Path != tc.wantPath {
+				t.Errorf("%s: root %s, path %q; want %s, %q", c.Slug, c.RootID, c.Path, f.otherOrg.ID, tc.wantPath)
+			}
+		}
+	})
+}
+
+func TestUpdateContainer(t *testing.T) {
+	tests := []struct {
+		name      string
+		caller    func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer)
+		wantCode  int
+		wantError string
+	}{
+		{"org admin", func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer) {
+			return f.admin, f.org
+		}, http.StatusOK, ""},
+		{"team member", func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer) {
+			return f.member, f.org
+		}, http.StatusForbidden, "access_denied"},
+		{"admin of another organization", func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer) {
+			return f.outsider, f.otherOrg
+		}, http.StatusNotFound, "not_found"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newContainerFixture(t)
+			user, root := tc.caller(f)
+			w := serve(f.router(user, root), http.MethodPut, "/team/"+f.eng.ID.String(),
+				gin.H{"name": "Engineering", "slug": "engineering"})
+			expectStatus(t, w, tc.wantCode)
+
+			eng, err := f.repo.GetContainer(f.eng.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantError != "" {
+				if code := errorCode(t, w); code != tc.wantError {
+					t.Errorf("error = %q, want %q", code, tc.wantError)
+				}
+				if eng.Slug != "eng" {
+					t.Errorf("denied update changed the slug to %q", eng.Slug)
+				}
+				return
+			}
+
+			if eng.Slug != "engineering" || eng.DisplayName != "Engineering" {
+				t.Errorf("slug, name = %q, %q; want engineering, Engineering", eng.Slug, eng.DisplayName)
+			}
+			// Paths are built from IDs, so renaming leaves the team's and
+			// its descendants' paths alone
+			if eng.Path != f.eng.Path {
+				t.Errorf("team path = %q, want %q", eng.Path, f.eng.Path)
+			}
+			api, err := f.repo.GetContainer(f.api.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if api.Path != f.api.Path || !strings.HasPrefix(api.Path, eng.Path+"/") {
+				t.Errorf("project path = %q, want %q under %q", api.Path, f.api.Path, eng.Path)
+			}
+			if ancestors, err := f.repo.GetAncestors(api.ID); err != nil || len(ancestors) != 2 || ancestors[0].Slug != "engineering" {
+				t.Errorf("project ancestors = %+v, %v; want the renamed team then the organization", ancestors, err)
+			}
+		})
+	}
+}
+
+func TestDeleteAndRestoreContainer(t *testing.T) {
+	callers := []struct {
+		name      string
+		caller    func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer)
+		wantCode  int
+		wantError string
+	}{
+		{"org admin", func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer) {
+			return f.admin, f.org
+		}, http.StatusOK, ""},
+		{"team member", func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer) {
+			return f.member, f.org
+		}, http.StatusForbidden, "access_denied"},
+		{"admin of another organization", func(f *containerFixture) (*models.User, *hierarchy.ResourceContainer) {
+			return f.outsider, f.otherOrg
+		}, http.StatusNotFound, "not_found"},
+	}
+
+	for _, tc := range callers {
+		t.Run("delete/"+tc.name, func(t *testing.T) {
+			f := newContainerFixture(t)
+			user, root := tc.caller(f)
+			w := serve(f.router(user, root), http.MethodDelete, "/project/"+f.api.ID.String(), nil)
+			expectStatus(t, w, tc.wantCode)
+
+			_, err := f.repo.GetContainer(f.api.ID)
+			if tc.wantError == "" {
+				if err == nil {
+					t.Error("project still visible after delete")
+				}
+				return
+			}
+			if code := errorCode(t, w); code != tc.wantError {
+				t.Errorf("error = %q, want %q", code, tc.wantError)
+			}
+			if err != nil {
+				t.Errorf("denied delete removed the project: %v", err)
+			}
+		})
+
+		t.Run("restore/"+tc.name, func(t *testing.T) {
+			f := newContainerFixture(t)
+			if err := f.repo.SoftDeleteContainer(f.api.ID); err != nil {
+				t.Fatal(err)
+			}
+			user, root := tc.caller(f)
+			w := serve(f.router(user, root), http.MethodPost, "/project/"+f.api.ID.String()+"/restore", nil)
+			expectStatus(t, w, tc.wantCode)
+
+			_, err := f.repo.GetContainer(f.api.ID)
+			if tc.wantError == "" {
+				if err != nil {
+					t.Errorf("project not restored: %v", err)
+				}
+				return
+			}
+			if code := errorCode(t, w); code != tc.wantError {
+				t.Errorf("error = %q, want %q", code, tc.wantError)
+			}
+			if err == nil {
+				t.Error("denied restore brought the project back")
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/events"
+	"github.com/yourusername/saas-starter-kit/backend/internal/fga"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/resources"
+	"gorm.io/gorm"
+)
+
+var (
+	validDocumentVisibility = map[string]bool{"workspace": true, "private": true}
+	validDocumentStatus     = map[string]bool{"draft": true, "published": true, "archived": true}
+	validShareRole          = map[string]bool{"editor": true, "viewer": true}
+)
+
+// DocumentHandler serves workspace documents. Access is relationship-based:
+// the owner, users the document is shared with, and (for workspace
+// visibility) workspace members. Relationships are mirrored to OpenFGA.
+type DocumentHandler struct {
+	db      *gorm.DB
+	cfg     *config.Config
+	repo    *resources.Repository
+	fga     *fga.Client
+	emitter events.Emitter
+}
+
+func NewDocumentHandler(db *gorm.DB, cfg *config.Config) *DocumentHandler {
+	return &DocumentHandler{
+		db:      db,
+		cfg:     cfg,
+		repo:    resources.NewRepository(db),
+		fga:     fga.NewClient(cfg.OpenFGAURL, cfg.OpenFGAStoreID),
+		emitter: events.NewEmitter(cfg),
+	}
+}
+
+// List returns the workspace documents visible to the current user
+// GET /api/v1/workspaces/:id/documents
+func (h *DocumentHandler) List(c *gin.Context) {
+	access, ok := loadWorkspaceAccess(c, h.db)
+	if !ok {
+		return
+	}
+
+	docs, err := h.repo.ListDocuments(access.workspace.ID, access.userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to fetch documents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"documents": docs})
+}
+
+// Create creates a document owned by the current user
+// POST /api/v1/workspaces/:id/documents
+func (h *DocumentHandler) Create(c *gin.Context) {
+	var req struct {
+		Title      string `json:"title" binding:"required"`
+		Content    string `json:"content"`
+		Visibility string `json:"visibility"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Document title is required"})
+		return
+	}
+
+	if req.Visibility == "" {
+		req.Visibility = "workspace"
+	}
+	if !validDocumentVisibility[req.Visibility] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_visibility", "message": "Visibility must be workspace or private"})
+		return
+	}
+
+	access, ok := loadWorkspaceAccess(c, h.db)
+	if !ok {
+		return
+	}
+	if !access.canWrite() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Viewers cannot create documents"})
+		return
+	}
+	if !withinWorkspaceQuota(c, h.db, access.workspace, &models.Document{}, "documents", func(p *models.Plan) int { return p.MaxDocumentsPerWorkspace }) {
+		return
+	}
+
+	doc := &models.Document{
+		WorkspaceID: access.workspace.ID,
+		OwnerID:     access.userID,
+		Title:       req.Title,
+		Content:     req.Content,
+		Visibility:  req.Visibility,
+		Status:      "draft",
+	}
+
+	if err := h.repo.CreateDocument(doc); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to create document"})
+		return
+	}
+
+	writes := []fga.TupleKey{
+		fga.Tuple("container", doc.WorkspaceID.String(), "container", "document", doc.ID.String()),
+		fga.Tuple("user", doc.OwnerID.String(), "owner", "document", doc.ID.String()),
+	}
+	if doc.Visibility == "workspace" {
+		writes = append(writes, workspaceVisibleTuple(doc))
+	}
+	h.syncTuples(c, writes, nil)
+
+	h.emitter.Emit(events.DocumentCreated, map[string]interface{}{
+		"document_id":  doc.ID,
+		"workspace_id": doc.WorkspaceID,
+		"title":        doc.Title,
+		"visibility":   doc.Visibility,
+		"actor_id":     access.userID,
+	})
+
+	c.Header("ETag", versionETag(doc.Version))
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Document created successfully",
+		"document": doc,
+	})
+}
+
+// Get returns a document the current user can read
+// GET /api/v1/workspaces/:id/documents/:docId
+func (h *DocumentHandler) Get(c *gin.Context) {
+	access, doc, ok := h.loadDocument(c)
+	if !ok {
+		return
+	}
+
+	if !h.canRead(access, doc) {
+		// Don't reveal that a private document exists
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Document not found"})
+		return
+	}
+
+	c.Header("ETag", versionETag(doc.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"document":    doc,
+		"permissions": h.permissions(access, doc),
+	})
+}
+
+// Update edits a document. Owners, editors and workspace admins may edit;
+// only owners and admins may change visibility.
+// PUT /api/v1/workspaces/:id/documents/:docId
+func (h *DocumentHandler) Update(c *gin.Context) {
+	var req struct {
+		Title           *string `json:"title"`
+		Content         *string `json:"content"`
+		Visibility      *string `json:"visibility"`
+		Status          *string `json:"status"`
+		ExpectedVersion *int    `json:"expected_version"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	access, doc, ok := h.loadDocument(c)
+	if !ok {
+		return
+	}
+
+	if !h.canRead(access, doc) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Document not found"})
+		return
+	}
+	if !h.canWrite(access, doc) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "You cannot edit this document"})
+		return
+	}
+
+	expected, sent, ok := expectedVersion(c, req.ExpectedVersion)
+	if !ok {
+		return
+	}
+	if sent && expected != doc.Version {
+		versionConflict(c, doc.Version)
+		return
+	}
+
+	wasVisible := doc.Visibility == "workspace"
+	if req.Title != nil {
+		doc.Title = *req.Title
+	}
+	if req.Content != nil {
+		doc.Content = *req.Content
+	}
+	if req.Status != nil {
+		if !validDocumentStatus[*req.Status] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_status", "message": "Status must be draft, published, or archived"})
+			return
+		}
+		doc.Status = *req.Status
+	}
+	if req.Visibility != nil && *req.Visibility != doc.Visibility {
+		if !validDocumentVisibility[*req.Visibility] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_visibility", "message": "Visibility must be workspace or private"})
+			return
+		}
+		if !h.canShare(access, doc) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Only the owner or a workspace admin can change visibility"})
+			return
+		}
+		doc.Visibility = *req.Visibility
+	}
+
+	if err := h.repo.UpdateDocument(doc); err != nil {
+		if errors.Is(err, resources.ErrVersionConflict) {
+			current, _ := h.repo.GetDocument(doc.WorkspaceID, doc.ID)
+			if current != nil {
+				versionConflict(c, current.Version)
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to update document"})
+		return
+	}
+
+	if isVisible := doc.Visibility == "workspace"; isVisible != wasVisible {
+		if isVisible {
+			h.syncTuples(c, []fga.TupleKey{workspaceVisibleTuple(doc)}, nil)
+		} else {
+			h.syncTuples(c, nil, []fga.TupleKey{workspaceVisibleTuple(doc)})
+		}
+	}
+
+	c.Header("ETag", versionETag(doc.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Document updated successfully",
+		"document": doc,
+	})
+}
+
+// Delete deletes a document. Only the owner or a workspace admin may delete.
+// DELETE /api/v1/workspaces/:id/documents/:docId
+func (h *DocumentHandler) Delete(c *gin.Context) {
+	access, doc, ok := h.loadDocument(c)
+	if !ok {
+		return
+	}
+
+	if !h.canRead(access, doc) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Document not found"})
+		return
+	}
+	if !h.canShare(access, doc) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Only the owner or a workspace admin can delete this document"})
+		return
+	}
+
+	shares, err := h.repo.DeleteDocument(doc.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to delete document"})
+		return
+	}
+
+	deletes := []fga.TupleKey{
+		fga.Tuple("container", doc.WorkspaceID.String(), "container", "document", doc.ID.String()),
+		fga.Tuple("user", doc.OwnerID.String(), "owner", "document", doc.ID.String()),
+	}
+	if doc.Visibility == "workspace" {
+		deletes = append(deletes, workspaceVisibleTuple(doc))
+	}
+	for _, s := range shares {
+		deletes = append(deletes, fga.Tuple("user", s.UserID.String(), s.Role, "document", doc.ID.String()))
+	}
+	h.syncTuples(c, nil, deletes)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document deleted successfully"})
+}
+
+// ListShares returns the users a document is shared with
+// GET /api/v1/workspaces/:id/documents/:docId/shares
+func (h *DocumentHandler) ListShares(c *gin.Context) {
+	access, doc, ok := h.loadDocument(c)
+	if !ok {
+		return
+	}
+
+	if !h.canRead(access, doc) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Document not found"})
+		return
+	}
+
+	shares, err := h.repo.ListShares(doc.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to fetch shares"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+// Share grants a workspace member a role on a document. Only the owner or
+// a workspace admin may share.
+// POST /api/v1/workspaces/:id/documents/:docId/shares
+func (h *DocumentHandler) Share(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+		Role  string `json:"role"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Valid email is required"})
+		return
+	}
+	req.Email = h.cfg.NormalizeEmail(req.Email)
+
+	if req.Role == "" {
+		req.Role = "viewer"
+	}
+	if !validShareRole[req.Role] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_role", "message": "Role must be editor or viewer"})
+		return
+	}
+
+	access, doc, ok := h.loadDocument(c)
+	if !ok {
+		return
+	}
+
+	if !h.canRead(access, doc) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Document not found"})
+		return
+	}
+	if !h.canShare(access, doc) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Only the owner or a workspace admin can share this document"})
+		return
+	}
+
+	// Documents are only shared within their workspace
+	var user models.User
+	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user_not_found", "message": "User not found with this email"})
+		return
+	}
+	var membership models.Membership
+	if err := h.db.Where("user_id = ? AND workspace_id = ?", user.ID, doc.WorkspaceID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "not_member", "message": "User is not a member of this workspace"})
+		return
+	}
+	if user.ID == doc.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "already_owner", "message": "User already owns this document"})
+		return
+	}
+
+	share, err := h.repo.ShareDocument(doc.ID, user.ID, req.Role)
+	if err != nil {
+		if errors.Is(err, resources.ErrAlreadyShared) {
+			c.JSON(http.StatusConflict, gin.H{"error": "already_shared", "message": "Document is already shared with this user"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to share document"})
+		return
+	}
+
+	h.syncTuples(c, []fga.TupleKey{fga.Tuple("user", user.ID.String(), share.Role, "document", doc.ID.String())}, nil)
+
+	h.emitter.Emit(events.DocumentShared, map[string]interface{}{
+		"document_id":  doc.ID,
+		"workspace_id": doc.WorkspaceID,
+		"user_id":      user.ID,
+		"email":        user.Email,
+		"role":         share.Role,
+		"actor_id":     access.userID,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Document shared successfully",
+		"share":   share,
+	})
+}
+
+// loadDocument loads the caller's workspace access and the :docId document
+// in that workspace. On failure it writes the response and returns ok=false.
+func (h *DocumentHandler) loadDocument(c *gin.Context) (*workspaceAccess, *models.Document, bool) {
+	access, ok := loadWorkspaceAccess(c, h.db)
+	if !ok {
+		return nil, nil, false
+	}
+
+	docID, err := uuid.Parse(c.Param("docId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Document not found"})
+		return nil, nil, false
+	}
+
+	doc, err := h.repo.GetDocument(access.workspace.ID, docID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Document not found"})
+		return nil, nil, false
+	}
+
+	return access, doc, true
+}
+
+func (h *DocumentHandler) canRead(access *workspaceAccess, doc *models.Document) bool {
+	return doc.Visibility == "workspace" || h.canWrite(access, doc) ||
+		h.repo.ShareRole(doc.ID, access.userID) == "viewer"
+}
+
+func (h *DocumentHandler) canWrite(access *workspaceAccess, doc *models.Document) bool {
+	return h.canShare(access, doc) || h.repo.ShareRole(doc.ID, access.userID) == "editor"
+}
+
+// canShare covers sharing, visibility changes and deletion
+func (h *DocumentHandler) canShare(access *workspaceAccess, doc *models.Document) bool {
+	return doc.OwnerID == access.userID || access.isAdmin()
+}
+
+func (h *DocumentHandler) permissions(access *workspaceAccess, doc *models.Document) gin.H {
+	canShare := h.canShare(access, doc)
+	return gin.H{
+		"can_read":   true,
+		"can_write":  h.canWrite(access, doc),
+		"can_share":  canShare,
+		"can_delete": canShare,
+	}
+}
+
+// syncTuples mirrors relationship changes to OpenFGA. The database is the
+// source of truth for the backend, so failures are logged, not returned.
+func (h *DocumentHandler) syncTuples(c *gin.Context, writes, deletes []fga.TupleKey) {
+	if err := h.fga.Write(c.Request.Context(), writes, deletes); err != nil {
+		log.Printf("Failed to sync document tuples to OpenFGA: %v", err)
+	}
+}
+
+// workspaceVisibleTuple opens a document to its workspace's readers; it
+// exists only while the document's visibility is "workspace"
+func workspaceVisibleTuple(doc *models.Document) fga.TupleKey {
+	return fga.Tuple("container", doc.WorkspaceID.String(), "workspace_visible", "document", doc.ID.String())
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/resources"
+	"gorm.io/gorm"
+)
+
+// FeedHandler serves the combined documents and projects feed of a
+// workspace, for recent activity views
+type FeedHandler struct {
+	db        *gorm.DB
+	repo      *resources.Repository
+	documents *DocumentHandler
+}
+
+func NewFeedHandler(db *gorm.DB, cfg *config.Config) *FeedHandler {
+	return &FeedHandler{db: db, repo: resources.NewRepository(db), documents: NewDocumentHandler(db, cfg)}
+}
+
+// List returns a page of the workspace's documents the caller can see and
+// its projects, most recently updated first, each with a type and the
+// caller's permissions. Filter with ?type=document|project and ?status=;
+// paginate with limit (default 50, max 100) and offset.
+// GET /api/v1/workspaces/:id/resources
+func (h *FeedHandler) List(c *gin.Context) {
+	filter := resources.FeedFilter{Type: c.Query("type"), Status: c.Query("status")}
+	switch filter.Type {
+	case "", resources.TypeDocument, resources.TypeProject:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_type", "message": "Type must be document or project"})
+		return
+	}
+	if filter.Status != "" && !validDocumentStatus[filter.Status] && !validProjectStatus[filter.Status] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_status", "message": "Status must be a document or project status"})
+		return
+	}
+
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	access, ok := loadWorkspaceAccess(c, h.db)
+	if !ok {
+		return
+	}
+
+	entries, total, err := h.repo.ListFeed(access.workspace.ID, access.userID, filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to fetch resources"})
+		return
+	}
+
+	items := make([]gin.H, len(entries))
+	for i, e := range entries {
+		item := gin.H{"type": e.Type, "id": e.ID, "updated_at": e.UpdatedAt}
+		if e.Document != nil {
+			item["document"] = e.Document
+			item["permissions"] = h.documents.permissions(access, e.Document)
+		} else {
+			item["project"] = e.Project
+			item["permissions"] = projectPermissions(access, e.Project)
+		}
+		items[i] = item
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resources": items,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/password"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const testPassword = "Correct-Horse-42"
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testConfig is the default config with fast hashing and no outbound calls
+func testConfig() *config.Config {
+	cfg := config.Load()
+	cfg.JWTSecret = "test-secret"
+	cfg.BcryptCost = bcrypt.MinCost
+	cfg.PasswordBreachCheck = false
+	cfg.SMTPHost = ""
+	return cfg
+}
+
+// createUser stores a verified local user with testPassword
+func createUser(t *testing.T, db *gorm.DB, cfg *config.Config, email string) *models.User {
+	t.Helper()
+	hasher, err := password.NewHasher(cfg.PasswordHash, cfg.BcryptCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{
+		Email:         email,
+		Name:          email,
+		AuthProvider:  "local",
+		EmailVerified: true,
+		PasswordHash:  hash,
+		LastLogin:     time.Now(),
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// serve runs one JSON request through r
+func serve(r *gin.Engine, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// asUser returns a router whose requests run as userID, with extra context
+// values such as "tenant_id" or "root_id"
+func asUser(userID string, values gin.H) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		for k, v := range values {
+			c.Set(k, v)
+		}
+	})
+	return r
+}
+
+// errorCode returns the "error" field of a JSON error response
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+	return resp.Error
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, want, w.Body.String())
+	}
+}
+
+// createWorkspace stores a workspace in a new tenant
+func createWorkspace(t *testing.T, db *gorm.DB, slug string) *models.Workspace {
+	t.Helper()
+	tenant := &models.Tenant{Slug: slug, DisplayName: slug}
+	if err := db.Create(tenant).Error; err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	workspace := &models.Workspace{TenantID: tenant.ID, Slug: slug, DisplayName: slug}
+	if err := db.Create(workspace).Error; err != nil {
+		t.Fatalf("create workspace: %v", err)
+	}
+	return workspace
+}
+
+func addMember(t *testing.T, db *gorm.DB, workspace *models.Workspace, user *models.User, role string) {
+	t.Helper()
+	if err := db.Create(&models.Membership{UserID: user.ID, WorkspaceID: workspace.ID, Role: role}).Error; err != nil {
+		t.Fatalf("add member: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/email"
+	"github.com/yourusername/saas-starter-kit/backend/internal/hierarchy"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// invitationTTL is how long an invitation link can be accepted
+const invitationTTL = 7 * 24 * time.Hour
+
+// errInvitationUsed is returned when an invitation was accepted concurrently
+var errInvitationUsed = errors.New("invitation already accepted")
+
+// InvitationHandler invites people to workspaces by email, including people
+// who don't have an account yet
+type InvitationHandler struct {
+	db        *gorm.DB
+	cfg       *config.Config
+	mailer    email.Sender
+	workspace *WorkspaceHandler
+}
+
+func NewInvitationHandler(db *gorm.DB, cfg *config.Config) *InvitationHandler {
+	return &InvitationHandler{
+		db:        db,
+		cfg:       cfg,
+		mailer:    email.NewSender(cfg),
+		workspace: NewWorkspaceHandler(db, cfg),
+	}
+}
+
+// Create invites an email address to a workspace and emails them a link.
+// Re-inviting an address replaces its pending invitation.
+// POST /api/v1/workspaces/:id/invitations
+func (h *InvitationHandler) Create(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+		Role  string `json:"role"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Valid email is required"})
+		return
+	}
+	req.Email = h.cfg.NormalizeEmail(req.Email)
+
+	role := hierarchy.Role(req.Role)
+	if role == "" {
+		role = hierarchy.DefaultRole
+	}
+	if !workspaceLevel.IsValidRole(role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_role", "message": "Role must be admin, member, or viewer"})
+		return
+	}
+
+	tenantID, _ := c.Get("tenant_id")
+	userID, _ := c.Get("user_id")
+
+	var workspace models.Workspace
+	if err := h.db.Where("id = ? AND tenant_id = ?", c.Param("id"), tenantID).First(&workspace).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Workspace not found"})
+		return
+	}
+
+	if !h.workspace.can(userID, &workspace, hierarchy.PermissionManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Only workspace or tenant admins can invite members"})
+		return
+	}
+
+	var inviter models.User
+	if err := h.db.First(&inviter, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_user", "message": "Invalid user ID"})
+		return
+	}
+
+	var memberCount int64
+	h.db.Model(&models.Membership{}).
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.workspace_id = ? AND users.email = ?", workspace.ID, req.Email).
+		Count(&memberCount)
+	if memberCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "already_member", "message": "User is already a member of this workspace"})
+		return
+	}
+
+	token := generateRandomToken(32)
+	invitation := models.Invitation{
+		WorkspaceID: workspace.ID,
+		Email:       req.Email,
+		Role:        string(role),
+		Token:       hashToken(token),
+		InvitedByID: inviter.ID,
+		ExpiresAt:   time.Now().Add(invitationTTL),
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("workspace_id = ? AND email = ? AND accepted_at IS NULL", workspace.ID, req.Email).
+			Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&invitation).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to create invitation"})
+		return
+	}
+
+	workspaceName := workspace.DisplayName
+	if workspaceName == "" {
+		workspaceName = workspace.Slug
+	}
+	inviterName := inviter.Name
+	if inviterName == "" {
+		inviterName = inviter.Email
+	}
+	if err := h.mailer.Send(email.InvitationEmail(h.cfg.FrontendURL, req.Email, inviterName, workspaceName, token)); err != nil {
+		log.Printf("Failed to send invitation email: %v", err)
+	}
+
+	resp := gin.H{
+		"message":    "Invitation sent",
+		"invitation": invitation,
+	}
+	if exposeTokens(h.cfg, h.mailer) {
+		resp["token"] = token
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// Accept accepts an invitation for the signed-in user, whose email must
+// match the invited address, and adds them to the workspace
+// POST /api/v1/invitations/accept
+func (h *InvitationHandler) Accept(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Token is required"})
+		return
+	}
+
+	invitation, ok := loadPendingInvitation(c, h.db, req.Token)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_user", "message": "Invalid user ID"})
+		return
+	}
+
+	if h.cfg.NormalizeEmail(user.Email) != invitation.Email {
+		c.JSON(http.StatusForbidden, gin.H{"error": "email_mismatch", "message": "This invitation was sent to a different email address"})
+		return
+	}
+
+	membership, err := acceptInvitation(h.db, invitation, &user)
+	if err != nil {
+		if errors.Is(err, errInvitationUsed) {
+			invitationUsed(c)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to accept invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invitation accepted",
+		"member": gin.H{
+			"user_id":      user.ID,
+			"email":        user.Email,
+			"name":         user.Name,
+			"role":         membership.Role,
+			"workspace_id": membership.WorkspaceID,
+		},
+	})
+}
+
+// loadPendingInvitation finds the invitation for a raw token, responding
+// with distinct errors for unknown, already-accepted and expired invitations.
+// On failure it writes the response and returns ok=false.
+func loadPendingInvitation(c *gin.Context, db *gorm.DB, token string) (*models.Invitation, bool) {
+	var invitation models.Invitation
+	if err := db.Where("token = ?", hashToken(token)).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "invalid_invitation", "message": "Invitation not found"})
+		return nil, false
+	}
+
+	if invitation.AcceptedAt != nil {
+		invitationUsed(c)
+		return nil, false
+	}
+
+	if invitation.IsExpired() {
+		c.JSON(http.StatusGone, gin.H{"error": "invitation_expired", "message": "This invitation has expired; ask a workspace admin to send a new one"})
+		return nil, false
+	}
+
+	return &invitation, true
+}
+
+func invitationUsed(c *gin.Context) {
+	c.JSON(http.StatusConflict, gin.H{"error": "invitation_already_accepted", "message": "This invitation has already been used"})
+}
+
+// acceptInvitation marks the invitation accepted and gives the user its
+// role in the workspace. Existing members keep their current role. It
+// returns errInvitationUsed if the invitation was accepted concurrently.
+func acceptInvitation(db *gorm.DB, invitation *models.Invitation, user *models.User) (*models.Membership, error) {
+	var membership models.Membership
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Update("accepted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvitationUsed
+		}
+		invitation.AcceptedAt = &now
+
+		err := tx.Where("user_id = ? AND workspace_id = ?", user.ID, invitation.WorkspaceID).First(&membership).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		membership = models.Membership{
+			UserID:      user.ID,
+			WorkspaceID: invitation.WorkspaceID,
+			Role:        invitation.Role,
+		}
+		return tx.Create(&membership).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
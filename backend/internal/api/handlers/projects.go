@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/resources"
+	"gorm.io/gorm"
+)
+
+var (
+	validProjectEnvironment = map[string]bool{"development": true, "staging": true, "production": true}
+	validProjectStatus      = map[string]bool{"active": true, "paused": true, "archived": true}
+)
+
+// ProjectHandler serves workspace projects. Access is attribute-based:
+// members may create and edit projects, but anything touching the
+// production environment requires a workspace admin.
+type ProjectHandler struct {
+	db   *gorm.DB
+	cfg  *config.Config
+	repo *resources.Repository
+}
+
+func NewProjectHandler(db *gorm.DB, cfg *config.Config) *ProjectHandler {
+	return &ProjectHandler{db: db, cfg: cfg, repo: resources.NewRepository(db)}
+}
+
+// List returns a workspace's projects, optionally filtered by ?environment=
+// GET /api/v1/workspaces/:id/projects
+func (h *ProjectHandler) List(c *gin.Context) {
+	access, ok := loadWorkspaceAccess(c, h.db)
+	if !ok {
+		return
+	}
+
+	projects, err := h.repo.ListProjects(access.workspace.ID, c.Query("environment"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to fetch projects"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"projects": projects})
+}
+
+// Create creates a project
+// POST /api/v1/workspaces/:id/projects
+func (h *ProjectHandler) Create(c *gin.Context) {
+	var req struct {
+		Name        string   `json:"name" binding:"required"`
+		Description string   `json:"description"`
+		Environment string   `json:"environment"`
+		Tags        []string `json:"tags"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Project name is required"})
+		return
+	}
+
+	if req.Environment == "" {
+		req.Environment = "development"
+	}
+	if !validProjectEnvironment[req.Environment] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_environment", "message": "Environment must be development, staging, or production"})
+		return
+	}
+
+	access, ok := loadWorkspaceAccess(c, h.db)
+	if !ok {
+		return
+	}
+	if !h.canChange(c, access, req.Environment) {
+		return
+	}
+	if !withinWorkspaceQuota(c, h.db, access.workspace, &models.Project{}, "projects", func(p *models.Plan) int { return p.MaxProjectsPerWorkspace }) {
+		return
+	}
+
+	proj := &models.Project{
+		WorkspaceID: access.workspace.ID,
+		OwnerID:     access.userID,
+		Name:        req.Name,
+		Description: req.Description,
+		Environment: req.Environment,
+		Status:      "active",
+		Tags:        models.StringArray(req.Tags),
+	}
+
+	if err := h.repo.CreateProject(proj); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to create project"})
+		return
+	}
+
+	c.Header("ETag", versionETag(proj.Version))
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Project created successfully",
+		"project": proj,
+	})
+}
+
+// Get returns a project
+// GET /api/v1/workspaces/:id/projects/:projectId
+func (h *ProjectHandler) Get(c *gin.Context) {
+	access, proj, ok := h.loadProject(c)
+	if !ok {
+		return
+	}
+
+	c.Header("ETag", versionETag(proj.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"project":     proj,
+		"permissions": projectPermissions(access, proj),
+	})
+}
+
+// projectPermissions applies the project policy to what the caller may do
+// with proj
+func projectPermissions(access *workspaceAccess, proj *models.Project) gin.H {
+	canChange := access.canWrite() && (proj.Environment != "production" || access.isAdmin())
+	return gin.H{
+		"can_read":   true,
+		"can_write":  canChange,
+		"can_delete": canChange && (proj.OwnerID == access.userID || access.isAdmin()),
+	}
+}
+
+// Update edits a project. Moving a project into or out of production, or
+// editing a production project, requires a workspace admin.
+// PUT /api/v1/workspaces/:id/projects/:projectId
+func (h *ProjectHandler) Update(c *gin.Context) {
+	var req struct {
+		Name            *string   `json:"name"`
+		Description     *string   `json:"description"`
+		Environment     *string   `json:"environment"`
+		Status          *string   `json:"status"`
+		Tags            *[]string `json:"tags"`
+		ExpectedVersion *int      `json:"expected_version"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	access, proj, ok := h.loadProject(c)
+	if !ok {
+		return
+	}
+
+	if !h.canChange(c, access, proj.Environment) {
+		return
+	}
+
+	expected, sent, ok := expectedVersion(c, req.ExpectedVersion)
+	if !ok {
+		return
+	}
+	if sent && expected != proj.Version {
+		versionConflict(c, proj.Version)
+		return
+	}
+
+	if req.Environment != nil && *req.Environment != proj.Environment {
+		if !validProjectEnvironment[*req.Environment] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_environment", "message": "Environment must be development, staging, or production"})
+			return
+		}
+		if !h.canChange(c, access, *req.Environment) {
+			return
+		}
+		proj.Environment = *req.Environment
+	}
+	if req.Status != nil {
+		if !validProjectStatus[*req.Status] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_status", "message": "Status must be active, paused, or archived"})
+			return
+		}
+		proj.Status = *req.Status
+	}
+	if req.Name != nil {
+		proj.Name = *req.Name
+	}
+	if req.Description != nil {
+		proj.Description = *req.Description
+	}
+	if req.Tags != nil {
+		proj.Tags = models.StringArray(*req.Tags)
+	}
+
+	if err := h.repo.UpdateProject(proj); err != nil {
+		if errors.Is(err, resources.ErrVersionConflict) {
+			current, _ := h.repo.GetProject(proj.WorkspaceID, proj.ID)
+			if current != nil {
+				versionConflict(c, current.Version)
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to update project"})
+		return
+	}
+
+	c.Header("ETag", versionETag(proj.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Project updated successfully",
+		"project": proj,
+	})
+}
+
+// Delete deletes a project. The owner or a workspace admin may delete;
+// production projects require an admin.
+// DELETE /api/v1/workspaces/:id/projects/:projectId
+func (h *ProjectHandler) Delete(c *gin.Context) {
+	access, proj, ok := h.loadProject(c)
+	if !ok {
+		return
+	}
+
+	if !h.canChange(c, access, proj.Environment) {
+		return
+	}
+	if proj.OwnerID != access.userID && !access.isAdmin() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Only the owner or a workspace admin can delete this project"})
+		return
+	}
+
+	if err := h.repo.DeleteProject(proj.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to delete project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
+// loadProject loads the caller's workspace access and the :projectId
+// project in that workspace. On failure it writes the response and returns
+// ok=false.
+func (h *ProjectHandler) loadProject(c *gin.Context) (*workspaceAccess, *models.Project, bool) {
+	access, ok := loadWorkspaceAccess(c, h.db)
+	if !ok {
+		return nil, nil, false
+	}
+
+	projectID, err := uuid.Parse(c.Param("projectId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Project not found"})
+		return nil, nil, false
+	}
+
+	proj, err := h.repo.GetProject(access.workspace.ID, projectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Project not found"})
+		return nil, nil, false
+	}
+
+	return access, proj, true
+}
+
+// canChange applies the project policy for a change in the given
+// environment: viewers can't change projects, and only admins can touch
+// production. On denial it writes the response and returns false.
+func (h *ProjectHandler) canChange(c *gin.Context, access *workspaceAccess, environment string) bool {
+	if !access.canWrite() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Viewers cannot change projects"})
+		return false
+	}
+	if environment == "production" && !access.isAdmin() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "production_admin_only", "message": "Only workspace admins can change production projects"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/hierarchy"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// workspaceAccess is the caller's standing in the workspace a resource
+// request is scoped to
+type workspaceAccess struct {
+	workspace   *models.Workspace
+	userID      uuid.UUID
+	permissions hierarchy.PermissionSet
+}
+
+// isAdmin reports whether the caller may manage the workspace, including
+// production changes and other members' resources
+func (a *workspaceAccess) isAdmin() bool {
+	return a.permissions.Has(hierarchy.PermissionManage)
+}
+
+// canWrite reports whether the caller may create and edit resources
+func (a *workspaceAccess) canWrite() bool {
+	return a.permissions.Has(hierarchy.PermissionWrite)
+}
+
+// workspacePermissions expands the user's workspace role into permissions.
+// Tenant admins get admin permissions in every workspace of their tenant.
+func workspacePermissions(db *gorm.DB, userID interface{}, workspace *models.Workspace) hierarchy.PermissionSet {
+	var membership models.Membership
+	var role hierarchy.Role
+	if err := db.Where("user_id = ? AND workspace_id = ?", userID, workspace.ID).First(&membership).Error; err == nil {
+		role = hierarchy.Role(membership.Role)
+	}
+
+	permissions := workspaceLevel.PermissionsFor(role)
+	if !permissions.Has(hierarchy.PermissionManage) {
+		var user models.User
+		if err := db.First(&user, "id = ?", userID).Error; err == nil && user.AdminOfTenantID != nil && *user.AdminOfTenantID == workspace.TenantID {
+			permissions = workspaceLevel.PermissionsFor(hierarchy.RoleAdmin)
+		}
+	}
+	return permissions
+}
+
+// loadWorkspaceAccess loads the :id workspace within the caller's tenant and
+// their permissions in it. On failure it writes the response and returns ok=false.
+func loadWorkspaceAccess(c *gin.Context, db *gorm.DB) (*workspaceAccess, bool) {
+	tenantID, _ := c.Get("tenant_id")
+	userID, _ := c.Get("user_id")
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_user", "message": "Invalid user ID"})
+		return nil, false
+	}
+
+	var workspace models.Workspace
+	if err := db.Where("id = ? AND tenant_id = ?", c.Param("id"), tenantID).First(&workspace).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Workspace not found"})
+		return nil, false
+	}
+
+	access := &workspaceAccess{
+		workspace:   &workspace,
+		userID:      userUUID,
+		permissions: workspacePermissions(db, userUUID, &workspace),
+	}
+
+	if !access.permissions.Has(hierarchy.PermissionRead) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "You don't have access to this workspace"})
+		return nil, false
+	}
+
+	return access, true
+}
+
+// effectivePlan returns the plan whose limits apply to tenant, which must be
+// loaded with Subscription.Plan. Paid plan limits apply only while the
+// subscription is paid up; otherwise the tenant gets Basic limits. Tenants
+// without a subscription have no limits (nil).
+func effectivePlan(db *gorm.DB, tenant *models.Tenant) *models.Plan {
+	if tenant.Subscription == nil {
+		return nil
+	}
+	plan := tenant.Subscription.Plan
+	if !tenant.Subscription.IsActive() {
+		db.Where("tier = ?", models.PlanTierBasic).First(&plan)
+	}
+	return &plan
+}
+
+// withinWorkspaceQuota checks that the workspace holds fewer than its plan's
+// quota of model rows before one is created; quota picks the limit from the
+// plan (-1 is unlimited). Otherwise it responds 403 quota_exceeded and
+// returns false.
+func withinWorkspaceQuota(c *gin.Context, db *gorm.DB, workspace *models.Workspace, model interface{}, resource string, quota func(*models.Plan) int) bool {
+	var tenant models.Tenant
+	if err := db.Preload("Subscription.Plan").First(&tenant, "id = ?", workspace.TenantID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to load tenant plan"})
+		return false
+	}
+
+	plan := effectivePlan(db, &tenant)
+	if plan == nil {
+		return true
+	}
+	limit := quota(plan)
+	if limit < 0 {
+		return true
+	}
+
+	var count int64
+	if err := db.Model(model).Where("workspace_id = ?", workspace.ID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to check workspace quota"})
+		return false
+	}
+	if int(count) >= limit {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "quota_exceeded",
+			"message": fmt.Sprintf("This workspace has reached the maximum number of %s for your plan", resource),
+			"limit":   limit,
+		})
+		return false
+	}
+	return true
+}
+
+// Page sizes for paginated lists
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// pagination reads the limit and offset query parameters; limit defaults to
+// defaultPageLimit and is capped at maxPageLimit. It responds 400 and
+// returns ok=false for values that aren't non-negative integers.
+func pagination(c *gin.Context) (limit, offset int, ok bool) {
+	limit, offset = defaultPageLimit, 0
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_pagination", "message": "limit must be a positive integer"})
+			return 0, 0, false
+		}
+		limit = min(n, maxPageLimit)
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_pagination", "message": "offset must be a non-negative integer"})
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
+
+// versionETag formats a resource version as an ETag
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// expectedVersion returns the version the client based its update on, from
+// the If-Match header or the expected_version body field (If-Match wins).
+// It responds 400 and returns ok=false for a malformed If-Match.
+func expectedVersion(c *gin.Context, bodyVersion *int) (version int, sent bool, ok bool) {
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		v, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_if_match", "message": "If-Match must be a version number"})
+			return 0, false, false
+		}
+		return v, true, true
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, true, true
+	}
+	return 0, false, true
+}
+
+// versionConflict responds 409 with the current version so the client can
+// reload and retry
+func versionConflict(c *gin.Context, current int) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":           "version_conflict",
+		"message":         "The resource was modified by someone else; reload and try again",
+		"current_version": current,
+	})
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/resources"
+	"github.com/yourusername/saas-starter-kit/backend/internal/testutil"
+)
+
+// resourceRouter serves the workspace document and project routes as user
+// in tenant
+func resourceRouter(documents *DocumentHandler, projects *ProjectHandler, user *models.User, tenant string) *gin.Engine {
+	r := asUser(user.ID.String(), gin.H{"tenant_id": tenant})
+	r.GET("/workspaces/:id/documents/:docId", documents.Get)
+	r.POST("/workspaces/:id/documents", documents.Create)
+	r.DELETE("/workspaces/:id/documents/:docId", documents.Delete)
+	r.GET("/workspaces/:id/projects", projects.List)
+	r.POST("/workspaces/:id/projects", projects.Create)
+	return r
+}
+
+func TestWorkspaceResourceAccess(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	documents := NewDocumentHandler(db, cfg)
+	projects := NewProjectHandler(db, cfg)
+	repo := resources.NewRepository(db)
+
+	ws := createWorkspace(t, db, "acme")
+	otherWS := &models.Workspace{TenantID: ws.TenantID, Slug: "acme-2", DisplayName: "acme-2"}
+	if err := db.Create(otherWS).Error; err != nil {
+		t.Fatal(err)
+	}
+	elsewhere := createWorkspace(t, db, "globex")
+
+	admin := createUser(t, db, cfg, "admin@example.com")
+	member := createUser(t, db, cfg, "member@example.com")
+	viewer := createUser(t, db, cfg, "viewer@example.com")
+	nonMember := createUser(t, db, cfg, "nonmember@example.com")
+	outsider := createUser(t, db, cfg, "outsider@example.com")
+	addMember(t, db, ws, admin, "admin")
+	addMember(t, db, ws, member, "member")
+	addMember(t, db, ws, viewer, "viewer")
+	addMember(t, db, elsewhere, outsider, "admin")
+
+	newDoc := func(workspace *models.Workspace, owner *models.User, visibility string) string {
+		doc := &models.Document{WorkspaceID: workspace.ID, OwnerID: owner.ID, Title: "doc", Visibility: visibility, Status: "draft"}
+		if err := repo.CreateDocument(doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc.ID.String()
+	}
+	open := newDoc(ws, admin, "workspace")
+	private := newDoc(ws, admin, "private")
+	inOther := newDoc(otherWS, admin, "workspace")
+	adminDoc := newDoc(ws, admin, "workspace")
+	memberDoc := newDoc(ws, member, "workspace")
+
+	wsPath := "/workspaces/" + ws.ID.String()
+	tests := []struct {
+		name     string
+		user     *models.User
+		tenant   string
+		method   string
+		path     string
+		body     interface{}
+		want     int
+		wantCode string
+	}{
+		{"member reads a workspace document", member, "", http.MethodGet, wsPath + "/documents/" + open, nil, http.StatusOK, ""},
+		{"member can't see a private document", member, "", http.MethodGet, wsPath + "/documents/" + private, nil, http.StatusNotFound, "not_found"},
+		{"owner reads their private document", admin, "", http.MethodGet, wsPath + "/documents/" + private, nil, http.StatusOK, ""},
+		{"document of another workspace", admin, "", http.MethodGet, wsPath + "/documents/" + inOther, nil, http.StatusNotFound, "not_found"},
+		{"other tenant's user", outsider, elsewhere.TenantID.String(), http.MethodGet, wsPath + "/documents/" + open, nil, http.StatusNotFound, "not_found"},
+		{"non-member of the tenant", nonMember, "", http.MethodGet, wsPath + "/projects", nil, http.StatusForbidden, "access_denied"},
+		{"viewer can't create documents", viewer, "", http.MethodPost, wsPath + "/documents", gin.H{"title": "t"}, http.StatusForbidden, "access_denied"},
+		{"member creates a document", member, "", http.MethodPost, wsPath + "/documents", gin.H{"title": "t"}, http.StatusCreated, ""},
+		{"member can't delete others' documents", member, "", http.MethodDelete, wsPath + "/documents/" + adminDoc, nil, http.StatusForbidden, "access_denied"},
+		{"admin deletes a member's document", admin, "", http.MethodDelete, wsPath + "/documents/" + memberDoc, nil, http.StatusOK, ""},
+		{"viewer can't create projects", viewer, "", http.MethodPost, wsPath + "/projects", gin.H{"name": "p"}, http.StatusForbidden, "access_denied"},
+		{"member creates a development project", member, "", http.MethodPost, wsPath + "/projects", gin.H{"name": "p"}, http.StatusCreated, ""},
+		{"member can't create production projects", member, "", http.MethodPost, wsPath + "/projects", gin.H{"name": "p", "environment": "production"}, http.StatusForbidden, "production_admin_only"},
+		{"admin creates a production project", admin, "", http.MethodPost, wsPath + "/projects", gin.H{"name": "p", "environment": "production"}, http.StatusCreated, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := tt.tenant
+			if tenant == "" {
+				tenant = ws.TenantID.String()
+			}
+			w := serve(resourceRouter(documents, projects, tt.user, tenant), tt.method, tt.path, tt.body)
+			expectStatus(t, w, tt.want)
+			if tt.wantCode != "" {
+				if code := errorCode(t, w); code != tt.wantCode {
+					t.Errorf("error = %q, want %q", code, tt.wantCode)
+				}
+			}
+		})
+	}
+}
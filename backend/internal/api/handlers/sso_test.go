@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/testutil"
+)
+
+func TestSSOProvisionUser(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	h := NewSSOHandler(db, cfg)
+
+	var tenantA, tenantB models.Tenant
+	db.First(&tenantA, "id = ?", createWorkspace(t, db, "tenant-a").TenantID)
+	db.First(&tenantB, "id = ?", createWorkspace(t, db, "tenant-b").TenantID)
+
+	member := createUser(t, db, cfg, "member@example.com")
+	var workspace models.Workspace
+	db.First(&workspace, "tenant_id = ?", tenantA.ID)
+	addMember(t, db, &workspace, member, "member")
+
+	ssoA, err := h.provisionUser(&tenantA, "sso-a@example.com", "A", "")
+	if err != nil {
+		t.Fatalf("create SSO user: %v", err)
+	}
+	if _, err := h.provisionUser(&tenantB, "sso-b@example.com", "B", ""); err != nil {
+		t.Fatalf("create SSO user: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		email   string
+		wantErr error
+	}{
+		{"created by this tenant's SSO", "sso-a@example.com", nil},
+		{"invited member with a password", "member@example.com", errSSOAccountExists},
+		{"created by another tenant's SSO", "sso-b@example.com", errSSOAccountExists},
+		{"new account", "new@example.com", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := h.provisionUser(&tenantA, tt.email, "Name", "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if user.SSOTenantID == nil || *user.SSOTenantID != tenantA.ID {
+				t.Errorf("SSOTenantID = %v, want %s", user.SSOTenantID, tenantA.ID)
+			}
+		})
+	}
+
+	var reloaded models.User
+	db.First(&reloaded, "id = ?", ssoA.ID)
+	if reloaded.Name != "Name" {
+		t.Errorf("SSO user name = %q, want updated", reloaded.Name)
+	}
+}
+
+func TestPublicAddressOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"93.184.216.34:443", false},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", false},
+		{"127.0.0.1:80", true},
+		{"[::1]:80", true},
+		{"10.0.0.5:443", true},
+		{"172.16.0.1:443", true},
+		{"192.168.1.1:443", true},
+		{"169.254.169.254:80", true},
+		{"100.64.0.1:443", true},
+		{"0.0.0.0:80", true},
+		{"[::ffff:127.0.0.1]:80", true},
+		{"[fd00::1]:443", true},
+		{"[fe80::1]:443", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := publicAddressOnly("tcp", tt.address, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errSSOPrivateAddress) {
+				t.Errorf("err = %v, want errSSOPrivateAddress", err)
+			}
+		})
+	}
+}
+
+func TestUpdateConfigRejectsPrivateIssuer(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+
+	var fetched bool
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = true
+		http.NotFound(w, r)
+	}))
+	defer idp.Close()
+
+	var tenant models.Tenant
+	db.First(&tenant, "id = ?", createWorkspace(t, db, "acme").TenantID)
+
+	r := asUser("admin", gin.H{"tenant": &tenant})
+	r.PUT("/tenant/sso", NewSSOHandler(db, cfg).UpdateConfig)
+
+	w := serve(r, http.MethodPut, "/tenant/sso", gin.H{
+		"issuer":        idp.URL,
+		"client_id":     "client",
+		"client_secret": "secret",
+	})
+	expectStatus(t, w, http.StatusBadRequest)
+	if code := errorCode(t, w); code != "invalid_issuer" {
+		t.Errorf("error = %q, want invalid_issuer", code)
+	}
+	if fetched {
+		t.Error("issuer on a loopback address was fetched")
+	}
+}
@@ -9,18 +9,21 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/api/middleware"
 	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/hierarchy"
 	"github.com/yourusername/saas-starter-kit/backend/internal/models"
 	"gorm.io/gorm"
 )
 
 type TenantHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db   *gorm.DB
+	cfg  *config.Config
+	auth *AuthHandler
 }
 
 func NewTenantHandler(db *gorm.DB, cfg *config.Config) *TenantHandler {
-	return &TenantHandler{db: db, cfg: cfg}
+	return &TenantHandler{db: db, cfg: cfg, auth: NewAuthHandler(db, cfg)}
 }
 
 // GetCurrentTenant returns the current user's tenant
@@ -99,11 +102,12 @@ func (h *TenantHandler) SelectPlan(c *gin.Context) {
 
 	// For Basic plan, auto-create tenant
 	if planTier == models.PlanTierBasic {
-		tenant, token, err := h.autoCreateTenant(&user)
+		tenant, token, err := h.autoCreateTenant(&user, authTimeFromContext(c))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to create tenant"})
 			return
 		}
+		setAuthCookie(c, h.cfg, token)
 
 		c.JSON(http.StatusOK, gin.H{
 			"message":      "Plan selected and tenant created",
@@ -137,12 +141,9 @@ func (h *TenantHandler) CheckSlug(c *gin.Context) {
 	}
 
 	// Check reserved slugs
-	reserved := []string{"admin", "api", "www", "app", "dashboard", "settings", "login", "signup", "auth"}
-	for _, r := range reserved {
-		if slug == r {
-			c.JSON(http.StatusOK, gin.H{"available": false, "reason": "reserved"})
-			return
-		}
+	if isReservedSlug(slug) {
+		c.JSON(http.StatusOK, gin.H{"available": false, "reason": "reserved"})
+		return
 	}
 
 	// Check if slug exists
@@ -226,20 +227,29 @@ func (h *TenantHandler) SetupOrganization(c *gin.Context) {
 		return
 	}
 
-	// Create subscription
+	// Create subscription. Paid plans with a trial start trialing; without
+	// one and with billing enabled they stay incomplete (Basic limits) until
+	// Stripe reports the checkout was paid.
+	status := "active"
+	if h.cfg.HasStripe() && plan.MonthlyPriceCents > 0 {
+		status = "incomplete"
+	}
 	subscription := models.Subscription{
 		TenantID:           tenant.ID,
 		PlanID:             plan.ID,
-		Status:             "active",
+		Status:             status,
 		CurrentPeriodStart: time.Now(),
 		CurrentPeriodEnd:   time.Now().AddDate(0, 1, 0), // 1 month from now
 	}
+	subscription.StartTrial(&plan, time.Now())
 
 	if err := tx.Create(&subscription).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to create subscription"})
 		return
 	}
+	subscription.Plan = plan
+	tenant.Subscription = &subscription
 
 	// Create default workspace
 	workspace := models.Workspace{
@@ -259,7 +269,7 @@ func (h *TenantHandler) SetupOrganization(c *gin.Context) {
 	membership := models.Membership{
 		UserID:      user.ID,
 		WorkspaceID: workspace.ID,
-		Role:        "admin",
+		Role:        string(hierarchy.RoleAdmin),
 	}
 
 	if err := tx.Create(&membership).Error; err != nil {
@@ -280,21 +290,232 @@ func (h *TenantHandler) SetupOrganization(c *gin.Context) {
 	tx.Commit()
 
 	// Generate new token with tenant_id
-	token, _ := h.generateTenantToken(&user, &tenant)
+	token, _ := h.generateTenantToken(&user, &tenant, authTimeFromContext(c))
+	setAuthCookie(c, h.cfg, token)
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message":      "Organization created successfully",
-		"tenant":       tenantResponse(&tenant),
-		"workspace":    workspaceResponse(&workspace),
-		"access_token": token,
+		"message":           "Organization created successfully",
+		"tenant":            tenantResponse(&tenant),
+		"workspace":         workspaceResponse(&workspace),
+		"access_token":      token,
+		"checkout_required": subscription.Status == "incomplete",
 	})
 }
 
+// TransferOwnership makes another member of the tenant its admin and
+// demotes the caller. The caller's access token is revoked and replaced by
+// the returned one; the new admin's claims change on their next refresh.
+// POST /api/v1/tenant/transfer-ownership
+func (h *TenantHandler) TransferOwnership(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Valid email is required"})
+		return
+	}
+	req.Email = h.cfg.NormalizeEmail(req.Email)
+
+	tenant := c.MustGet("tenant").(*models.Tenant)
+	userID, _ := c.Get("user_id")
+
+	var current models.User
+	if err := h.db.First(&current, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_user", "message": "Invalid user ID"})
+		return
+	}
+	if current.AdminOfTenantID == nil || *current.AdminOfTenantID != tenant.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not_tenant_admin", "message": "Only tenant administrators can perform this action"})
+		return
+	}
+
+	var target models.User
+	if err := h.db.Where("email = ?", req.Email).First(&target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_member", "message": "User is not a member of this organization"})
+		return
+	}
+	if target.ID == current.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "You already own this organization"})
+		return
+	}
+
+	var memberships int64
+	h.db.Model(&models.Membership{}).
+		Joins("JOIN workspaces ON workspaces.id = memberships.workspace_id").
+		Where("memberships.user_id = ? AND workspaces.tenant_id = ?", target.ID, tenant.ID).
+		Count(&memberships)
+	if memberships == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_member", "message": "User is not a member of this organization"})
+		return
+	}
+
+	// A user administers at most one tenant
+	if target.AdminOfTenantID != nil && *target.AdminOfTenantID != tenant.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "admin_of_other_tenant", "message": "User already administers another organization"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&target).Updates(map[string]interface{}{
+			"is_tenant_admin":    true,
+			"admin_of_tenant_id": tenant.ID,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&current).Updates(map[string]interface{}{
+			"is_tenant_admin":    false,
+			"admin_of_tenant_id": nil,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(tenant).Update("admin_user_id", target.ID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to transfer ownership"})
+		return
+	}
+
+	// The caller's token still claims tenant admin rights
+	h.auth.revokeAccessToken(middleware.AuthorizationHeader(c, h.cfg))
+	token, _ := h.auth.generateToken(&current, authTimeFromContext(c))
+	setAuthCookie(c, h.cfg, token)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Ownership transferred",
+		"tenant":        tenantResponse(tenant),
+		"admin_user_id": target.ID,
+		"access_token":  token,
+	})
+}
+
+// GetUsage returns the tenant's current usage against the limits of its
+// effective plan, and its subscription status, so admins can see how close
+// they are to a limit before hitting it. A limit of -1 is unlimited.
+// GET /api/v1/tenant/usage
+func (h *TenantHandler) GetUsage(c *gin.Context) {
+	tenant := c.MustGet("tenant").(*models.Tenant)
+	if err := h.db.Preload("Subscription.Plan").First(tenant, "id = ?", tenant.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to load tenant plan"})
+		return
+	}
+
+	var workspaces []models.Workspace
+	if err := h.db.Where("tenant_id = ?", tenant.ID).Order("created_at ASC").Find(&workspaces).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to count usage"})
+		return
+	}
+
+	var members int64
+	err := h.db.Model(&models.Membership{}).
+		Joins("JOIN workspaces ON workspaces.id = memberships.workspace_id").
+		Where("workspaces.tenant_id = ?", tenant.ID).
+		Distinct("memberships.user_id").
+		Count(&members).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to count usage"})
+		return
+	}
+
+	documents, err := h.countPerWorkspace(&models.Document{}, "documents", tenant.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to count usage"})
+		return
+	}
+	projects, err := h.countPerWorkspace(&models.Project{}, "projects", tenant.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to count usage"})
+		return
+	}
+
+	// Tenants without a subscription have no limits
+	limits := models.Plan{MaxWorkspaces: -1, MaxUsersPerTenant: -1, MaxDocumentsPerWorkspace: -1, MaxProjectsPerWorkspace: -1}
+	if plan := effectivePlan(h.db, tenant); plan != nil {
+		limits = *plan
+	}
+
+	var totalDocuments, totalProjects int64
+	perWorkspace := make([]gin.H, len(workspaces))
+	for i, ws := range workspaces {
+		totalDocuments += documents[ws.ID]
+		totalProjects += projects[ws.ID]
+		perWorkspace[i] = gin.H{
+			"id":           ws.ID,
+			"slug":         ws.Slug,
+			"display_name": ws.DisplayName,
+			"documents":    gin.H{"used": documents[ws.ID], "limit": limits.MaxDocumentsPerWorkspace},
+			"projects":     gin.H{"used": projects[ws.ID], "limit": limits.MaxProjectsPerWorkspace},
+		}
+	}
+
+	resp := gin.H{
+		"plan":         nil,
+		"subscription": nil,
+		"usage": gin.H{
+			"workspaces": gin.H{"used": len(workspaces), "limit": limits.MaxWorkspaces},
+			"members":    gin.H{"used": members, "limit": limits.MaxUsersPerTenant},
+			"documents":  gin.H{"used": totalDocuments},
+			"projects":   gin.H{"used": totalProjects},
+		},
+		"workspaces": perWorkspace,
+	}
+	if sub := tenant.Subscription; sub != nil {
+		resp["plan"] = gin.H{"tier": limits.Tier, "name": limits.Name}
+		resp["subscription"] = gin.H{
+			"status":             sub.Status,
+			"active":             sub.IsActive(),
+			"plan_tier":          sub.Plan.Tier,
+			"current_period_end": sub.CurrentPeriodEnd,
+			"cancelled_at":       sub.CancelledAt,
+			"trial_ends_at":      sub.TrialEndsAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// countPerWorkspace counts the rows of model's table in each of the tenant's
+// workspaces with a single grouped query
+func (h *TenantHandler) countPerWorkspace(model interface{}, table string, tenantID uuid.UUID) (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		WorkspaceID uuid.UUID
+		Count       int64
+	}
+	err := h.db.Model(model).
+		Select(table+".workspace_id, COUNT(*) AS count").
+		Joins("JOIN workspaces ON workspaces.id = "+table+".workspace_id").
+		Where("workspaces.tenant_id = ?", tenantID).
+		Group(table + ".workspace_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.WorkspaceID] = row.Count
+	}
+	return counts, nil
+}
+
 // ============================================================================
 // Helpers
 // ============================================================================
 
-func (h *TenantHandler) autoCreateTenant(user *models.User) (*models.Tenant, string, error) {
+// reservedSlugs clash with app routes, so no organization or container may
+// use them
+var reservedSlugs = []string{"admin", "api", "www", "app", "dashboard", "settings", "login", "signup", "auth"}
+
+func isReservedSlug(slug string) bool {
+	for _, r := range reservedSlugs {
+		if slug == r {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *TenantHandler) autoCreateTenant(user *models.User, authTime time.Time) (*models.Tenant, string, error) {
 	tx := h.db.Begin()
 
 	// Generate slug from email
@@ -347,11 +568,14 @@ func (h *TenantHandler) autoCreateTenant(user *models.User) (*models.Tenant, str
 		CurrentPeriodStart: time.Now(),
 		CurrentPeriodEnd:   time.Now().AddDate(1, 0, 0), // 1 year for free plan
 	}
+	subscription.StartTrial(&plan, time.Now())
 
 	if err := tx.Create(&subscription).Error; err != nil {
 		tx.Rollback()
 		return nil, "", err
 	}
+	subscription.Plan = plan
+	tenant.Subscription = &subscription
 
 	// Create default workspace
 	workspace := models.Workspace{
@@ -370,7 +594,7 @@ func (h *TenantHandler) autoCreateTenant(user *models.User) (*models.Tenant, str
 	membership := models.Membership{
 		UserID:      user.ID,
 		WorkspaceID: workspace.ID,
-		Role:        "admin",
+		Role:        string(hierarchy.RoleAdmin),
 	}
 
 	if err := tx.Create(&membership).Error; err != nil {
@@ -389,12 +613,12 @@ func (h *TenantHandler) autoCreateTenant(user *models.User) (*models.Tenant, str
 	tx.Commit()
 
 	// Generate token
-	token, _ := h.generateTenantToken(user, &tenant)
+	token, _ := h.generateTenantToken(user, &tenant, authTime)
 
 	return &tenant, token, nil
 }
 
-func (h *TenantHandler) generateTenantToken(user *models.User, tenant *models.Tenant) (string, error) {
+func (h *TenantHandler) generateTenantToken(user *models.User, tenant *models.Tenant, authTime time.Time) (string, error) {
 	claims := jwt.MapClaims{
 		"sub":             user.ID.String(),
 		"email":           user.Email,
@@ -403,12 +627,10 @@ func (h *TenantHandler) generateTenantToken(user *models.User, tenant *models.Te
 		"email_verified":  user.EmailVerified,
 		"is_tenant_admin": true,
 		"tenant_id":       tenant.ID.String(),
-		"iat":             time.Now().Unix(),
-		"exp":             time.Now().Add(24 * time.Hour).Unix(),
+		"auth_time":       authTime.Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(h.cfg.GetJWTSecret())
+	return signAccessToken(h.cfg, claims)
 }
 
 func tenantResponse(tenant *models.Tenant) gin.H {
@@ -421,12 +643,17 @@ func tenantResponse(tenant *models.Tenant) gin.H {
 		"created_at":     tenant.CreatedAt,
 	}
 
-	if tenant.Subscription != nil {
-		resp["subscription"] = gin.H{
-			"id":     tenant.Subscription.ID,
-			"status": tenant.Subscription.Status,
-			"plan":   tenant.Subscription.Plan,
+	if sub := tenant.Subscription; sub != nil {
+		subResp := gin.H{
+			"id":     sub.ID,
+			"status": sub.Status,
+			"plan":   sub.Plan,
+		}
+		if sub.Status == "trialing" && sub.TrialEndsAt != nil {
+			subResp["trial_ends_at"] = sub.TrialEndsAt
+			subResp["trial_days_left"] = sub.TrialDaysLeft(time.Now())
 		}
+		resp["subscription"] = subResp
 	}
 
 	return resp
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/twofactor"
+	"gorm.io/gorm"
+)
+
+// challengeTokenTTL is how long a user has to enter their 2FA code after
+// a successful password check
+const challengeTokenTTL = 5 * time.Minute
+
+// After maxTwoFactorAttempts wrong codes in a row, 2FA login is locked for
+// twoFactorLockout, so the 10^6 TOTP codes can't be guessed through a
+// stream of challenge tokens
+const (
+	maxTwoFactorAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
+// Key derivation purposes. Challenge tokens are signed with their own key so
+// they can never be accepted as access tokens.
+const (
+	challengeKeyPurpose  = "2fa-challenge-token"
+	totpSecretKeyPurpose = "totp-secret-encryption"
+)
+
+// ============================================================================
+// Two-Factor Auth
+// ============================================================================
+
+// SetupTwoFactor generates a TOTP secret for the current user. 2FA is not
+// enabled until the first code is confirmed via VerifyTwoFactor.
+// POST /api/v1/auth/2fa/setup
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.AuthProvider != "local" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_provider", "message": "Two-factor auth is only available for email/password accounts"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "2fa_already_enabled", "message": "Two-factor auth is already enabled"})
+		return
+	}
+
+	enrollment, err := twofactor.Generate(h.cfg.TOTPIssuer, user.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to generate secret"})
+		return
+	}
+
+	encrypted, err := twofactor.Encrypt(h.cfg.DeriveKey(totpSecretKeyPurpose), enrollment.Secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to store secret"})
+		return
+	}
+
+	user.TOTPSecret = encrypted
+	if err := h.db.Save(user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to store secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      enrollment.Secret,
+		"otpauth_url": enrollment.OTPAuthURL,
+		"qr_code":     enrollment.QRCode,
+	})
+}
+
+// VerifyTwoFactor confirms the first TOTP code, enables 2FA and returns
+// backup codes. Backup codes are only shown once.
+// POST /api/v1/auth/2fa/verify
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Code is required"})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "2fa_already_enabled", "message": "Two-factor auth is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "2fa_not_setup", "message": "Call /auth/2fa/setup first"})
+		return
+	}
+
+	valid, err := h.acceptTOTP(user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to read secret"})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_code", "message": "Invalid verification code"})
+		return
+	}
+
+	codes, err := twofactor.GenerateBackupCodes(twofactor.BackupCodeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to generate backup codes"})
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.BackupCode{}).Error; err != nil {
+			return err
+		}
+		for _, code := range codes {
+			backup := models.BackupCode{UserID: user.ID, CodeHash: hashToken(twofactor.NormalizeBackupCode(code))}
+			if err := tx.Create(&backup).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(user).Update("totp_enabled", true).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to enable two-factor auth"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"totp_enabled": true,
+		"backup_codes": codes,
+	})
+}
+
+// TwoFactorLogin completes a login started by Login using a TOTP code or a
+// backup code. Each TOTP code is accepted once, and too many wrong codes
+// lock 2FA login for a while.
+// POST /api/v1/auth/2fa/login
+func (h *AuthHandler) TwoFactorLogin(c *gin.Context) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Challenge token and code are required"})
+		return
+	}
+
+	userID, err := h.parseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_challenge", "message": "Challenge token is invalid or expired"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_challenge", "message": "Challenge token is invalid or expired"})
+		return
+	}
+	if rejectDisabled(c, &user) {
+		return
+	}
+	if user.TOTPLockedUntil != nil && time.Now().Before(*user.TOTPLockedUntil) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(*user.TOTPLockedUntil).Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too_many_attempts", "message": "Too many invalid codes, please try again later"})
+		return
+	}
+
+	valid, err := h.acceptTOTP(&user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to read secret"})
+		return
+	}
+	if !valid {
+		valid, err = h.consumeBackupCode(&user, req.Code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to verify code"})
+			return
+		}
+	}
+	if !valid {
+		if err := h.recordTwoFactorFailure(&user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to verify code"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_code", "message": "Invalid verification code"})
+		return
+	}
+
+	if err := h.db.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"totp_failed_attempts": 0,
+		"totp_locked_until":    nil,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to verify code"})
+		return
+	}
+	user.TOTPFailedAttempts = 0
+	user.TOTPLockedUntil = nil
+
+	h.completeLogin(c, &user)
+}
+
+// ============================================================================
+// Two-Factor Helpers
+// ============================================================================
+
+// currentUser loads the authenticated user, responding with 404 if missing
+func (h *AuthHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "User not found"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// acceptTOTP checks a TOTP code and records its time step as used. Codes
+// from the recorded step or earlier are refused, so an observed code can't
+// be replayed while it is still valid.
+func (h *AuthHandler) acceptTOTP(user *models.User, code string) (bool, error) {
+	secret, err := twofactor.Decrypt(h.cfg.DeriveKey(totpSecretKeyPurpose), user.TOTPSecret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := twofactor.Validate(code, secret, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return false, nil
+	}
+
+	// Conditional, so two concurrent requests can't both use the code
+	result := h.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+// recordTwoFactorFailure counts a wrong 2FA code, locking 2FA login for
+// twoFactorLockout once maxTwoFactorAttempts are reached
+func (h *AuthHandler) recordTwoFactorFailure(user *models.User) error {
+	return h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			Update("totp_failed_attempts", gorm.Expr("totp_failed_attempts + 1")).Error; err != nil {
+			return err
+		}
+		var attempts []int
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Pluck("totp_failed_attempts", &attempts).Error; err != nil {
+			return err
+		}
+		if len(attempts) == 0 || attempts[0] < maxTwoFactorAttempts {
+			return nil
+		}
+		return tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"totp_failed_attempts": 0,
+			"totp_locked_until":    time.Now().Add(twoFactorLockout),
+		}).Error
+	})
+}
+
+// consumeBackupCode marks a matching unused backup code as used
+func (h *AuthHandler) consumeBackupCode(user *models.User, code string) (bool, error) {
+	result := h.db.Model(&models.BackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashToken(twofactor.NormalizeBackupCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (h *AuthHandler) generateChallengeToken(user *models.User) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":  user.ID.String(),
+		"type": "2fa_challenge",
+		"iat":  time.Now().Unix(),
+		"exp":  time.Now().Add(challengeTokenTTL).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(h.cfg.DeriveKey(challengeKeyPurpose))
+}
+
+func (h *AuthHandler) parseChallengeToken(tokenString string) (string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return h.cfg.DeriveKey(challengeKeyPurpose), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["type"] != "2fa_challenge" {
+		return "", fmt.Errorf("invalid challenge token")
+	}
+
+	return claims.GetSubject()
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/testutil"
+	"github.com/yourusername/saas-starter-kit/backend/internal/twofactor"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+func TestTwoFactorLogin(t *testing.T) {
+	cfg := testConfig()
+
+	// setup returns a router and a user with 2FA enabled
+	setup := func(t *testing.T) (*gin.Engine, *AuthHandler, *models.User) {
+		db := testutil.NewDB(t)
+		h := NewAuthHandler(db, cfg)
+		r := gin.New()
+		r.POST("/2fa/login", h.TwoFactorLogin)
+
+		user := createUser(t, db, cfg, "2fa@example.com")
+		encrypted, err := twofactor.Encrypt(cfg.DeriveKey(totpSecretKeyPurpose), testTOTPSecret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Model(user).Updates(map[string]interface{}{"totp_secret": encrypted, "totp_enabled": true}).Error; err != nil {
+			t.Fatal(err)
+		}
+		return r, h, user
+	}
+	login := func(t *testing.T, r *gin.Engine, h *AuthHandler, user *models.User, code string) int {
+		t.Helper()
+		challenge, err := h.generateChallengeToken(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := serve(r, http.MethodPost, "/2fa/login", gin.H{"challenge_token": challenge, "code": code})
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Error("429 without Retry-After")
+		}
+		return w.Code
+	}
+	currentCode := func(t *testing.T) string {
+		code, err := totp.GenerateCode(testTOTPSecret, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name  string
+		codes func(t *testing.T) []string
+		want  []int
+	}{
+		{
+			name:  "valid code",
+			codes: func(t *testing.T) []string { return []string{currentCode(t)} },
+			want:  []int{http.StatusOK},
+		},
+		{
+			name: "replayed code",
+			codes: func(t *testing.T) []string {
+				code := currentCode(t)
+				return []string{code, code}
+			},
+			want: []int{http.StatusOK, http.StatusUnauthorized},
+		},
+		{
+			name: "wrong codes lock 2FA login",
+			codes: func(t *testing.T) []string {
+				return []string{"bad-1", "bad-2", "bad-3", "bad-4", "bad-5", currentCode(t)}
+			},
+			want: []int{401, 401, 401, 401, 401, http.StatusTooManyRequests},
+		},
+		{
+			name: "success resets the count",
+			codes: func(t *testing.T) []string {
+				return []string{"bad-1", "bad-2", "bad-3", "bad-4", currentCode(t), "bad-5", "bad-6"}
+			},
+			want: []int{401, 401, 401, 401, http.StatusOK, 401, 401},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, h, user := setup(t)
+			for i, code := range tc.codes(t) {
+				if got := login(t, r, h, user, code); got != tc.want[i] {
+					t.Fatalf("attempt %d: status = %d, want %d", i+1, got, tc.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("backup codes still work once", func(t *testing.T) {
+		r, h, user := setup(t)
+		if err := h.db.Create(&models.BackupCode{UserID: user.ID, CodeHash: hashToken("abcde-fghij")}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got := login(t, r, h, user, "ABCDE-FGHIJ"); got != http.StatusOK {
+			t.Errorf("backup code: status = %d, want 200", got)
+		}
+		if got := login(t, r, h, user, "abcde-fghij"); got != http.StatusUnauthorized {
+			t.Errorf("reused backup code: status = %d, want 401", got)
+		}
+	})
+}
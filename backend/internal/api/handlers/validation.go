@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// respondInvalidRequest responds 400 invalid_request with message and, when
+// err is a binding error on fields of req, a "fields" map from JSON field
+// name to what is wrong with it, so forms can highlight the fields at fault
+func respondInvalidRequest(c *gin.Context, message string, err error, req interface{}) {
+	resp := gin.H{"error": "invalid_request", "message": message}
+	if fields := validationFields(err, req); len(fields) > 0 {
+		resp["fields"] = fields
+	}
+	c.JSON(http.StatusBadRequest, resp)
+}
+
+// validationFields translates binding errors on req (a pointer to the bound
+// struct) into messages keyed by JSON field name. Errors that aren't about a
+// field, such as malformed JSON, give nil.
+func validationFields(err error, req interface{}) map[string]string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return map[string]string{typeErr.Field: "must be " + jsonTypeName(typeErr.Type)}
+	}
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+
+	t := reflect.TypeOf(req)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	fields := make(map[string]string, len(errs))
+	for _, fe := range errs {
+		fields[jsonFieldName(t, fe.StructField())] = validationMessage(fe)
+	}
+	return fields
+}
+
+func jsonFieldName(t reflect.Type, name string) string {
+	if t.Kind() == reflect.Struct {
+		if f, ok := t.FieldByName(name); ok {
+			if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+				return tag
+			}
+		}
+	}
+	return name
+}
+
+func validationMessage(fe validator.FieldError) string {
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+	}
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "min":
+		return "must be at least " + fe.Param() + unit
+	case "max":
+		return "must be at most " + fe.Param() + unit
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "uuid":
+		return "must be a UUID"
+	}
+	return "is invalid"
+}
+
+// jsonTypeName describes the JSON value a Go type is decoded from
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	}
+	return "an object"
+}
@@ -6,19 +6,28 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/api/middleware"
 	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/events"
+	"github.com/yourusername/saas-starter-kit/backend/internal/hierarchy"
 	"github.com/yourusername/saas-starter-kit/backend/internal/models"
 	"gorm.io/gorm"
 )
 
+// workspaceLevel defines the roles available on workspace memberships and
+// workspace-scoped API keys, and the permissions each grants
+var workspaceLevel = hierarchy.DefaultConfig().GetLevel("workspace")
+
 type WorkspaceHandler struct {
-	db  *gorm.DB
-	cfg *config.Config
+	db      *gorm.DB
+	cfg     *config.Config
+	emitter events.Emitter
 }
 
 func NewWorkspaceHandler(db *gorm.DB, cfg *config.Config) *WorkspaceHandler {
-	return &WorkspaceHandler{db: db, cfg: cfg}
+	return &WorkspaceHandler{db: db, cfg: cfg, emitter: events.NewEmitter(cfg)}
 }
 
 // List returns all workspaces for the current tenant
@@ -40,6 +49,52 @@ func (h *WorkspaceHandler) List(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"workspaces": result})
 }
 
+// ListMyMemberships lists every workspace the current user is a member of,
+// across tenants, with their role and the workspace's tenant, for workspace
+// switchers. Paginated with limit (default 50, max 100) and offset.
+// GET /api/v1/me/memberships
+func (h *WorkspaceHandler) ListMyMemberships(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := h.db.Model(&models.Membership{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to fetch memberships"})
+		return
+	}
+
+	var memberships []models.Membership
+	if err := h.db.Preload("Workspace.Tenant").Where("user_id = ?", userID).Order("created_at ASC, id ASC").Limit(limit).Offset(offset).Find(&memberships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to fetch memberships"})
+		return
+	}
+
+	result := make([]gin.H, len(memberships))
+	for i, m := range memberships {
+		result[i] = gin.H{
+			"role":      m.Role,
+			"joined_at": m.CreatedAt,
+			"workspace": workspaceResponse(&m.Workspace),
+			"tenant": gin.H{
+				"id":           m.Workspace.Tenant.ID,
+				"slug":         m.Workspace.Tenant.Slug,
+				"display_name": m.Workspace.Tenant.DisplayName,
+			},
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"memberships": result,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
 // Create creates a new workspace
 // POST /api/v1/workspaces
 func (h *WorkspaceHandler) Create(c *gin.Context) {
@@ -76,16 +131,18 @@ func (h *WorkspaceHandler) Create(c *gin.Context) {
 		return
 	}
 
-	if tenant.Subscription != nil && tenant.Subscription.Plan.MaxWorkspaces > 0 {
-		var count int64
-		h.db.Model(&models.Workspace{}).Where("tenant_id = ?", tenantUUID).Count(&count)
-		if int(count) >= tenant.Subscription.Plan.MaxWorkspaces {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "workspace_limit_reached",
-				"message": "You have reached the maximum number of workspaces for your plan",
-				"limit":   tenant.Subscription.Plan.MaxWorkspaces,
-			})
-			return
+	if plan := effectivePlan(h.db, &tenant); plan != nil {
+		if plan.MaxWorkspaces > 0 {
+			var count int64
+			h.db.Model(&models.Workspace{}).Where("tenant_id = ?", tenantUUID).Count(&count)
+			if int(count) >= plan.MaxWorkspaces {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":   "workspace_limit_reached",
+					"message": "You have reached the maximum number of workspaces for your plan",
+					"limit":   plan.MaxWorkspaces,
+				})
+				return
+			}
 		}
 	}
 
@@ -122,6 +179,11 @@ func (h *WorkspaceHandler) Create(c *gin.Context) {
 
 	if err := tx.Create(&workspace).Error; err != nil {
 		tx.Rollback()
+		// Lost a race with a concurrent create of the same slug
+		if models.IsUniqueViolation(err, models.WorkspaceSlugIndex) {
+			c.JSON(http.StatusConflict, gin.H{"error": "slug_exists", "message": "A workspace with this slug already exists"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to create workspace"})
 		return
 	}
@@ -130,7 +192,7 @@ func (h *WorkspaceHandler) Create(c *gin.Context) {
 	membership := models.Membership{
 		UserID:      userUUID,
 		WorkspaceID: workspace.ID,
-		Role:        "admin",
+		Role:        string(hierarchy.RoleAdmin),
 	}
 
 	if err := tx.Create(&membership).Error; err != nil {
@@ -141,6 +203,14 @@ func (h *WorkspaceHandler) Create(c *gin.Context) {
 
 	tx.Commit()
 
+	h.emitter.Emit(events.WorkspaceCreated, map[string]interface{}{
+		"workspace_id": workspace.ID,
+		"tenant_id":    workspace.TenantID,
+		"slug":         workspace.Slug,
+		"name":         workspace.DisplayName,
+		"actor_id":     userUUID,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message":   "Workspace created successfully",
 		"workspace": workspaceResponse(&workspace),
@@ -154,12 +224,12 @@ func (h *WorkspaceHandler) Get(c *gin.Context) {
 	tenantID, _ := c.Get("tenant_id")
 	userID, _ := c.Get("user_id")
 
-	// Parse workspace ID (can be UUID or slug)
+	// The ID can be a UUID or a slug; slugs are only unique within a tenant
 	var workspace models.Workspace
 	var query *gorm.DB
 
 	if _, err := uuid.Parse(workspaceID); err == nil {
-		query = h.db.Where("id = ? AND tenant_id = ?", workspaceID, tenantID)
+		query = h.db.Where("id = ?", workspaceID)
 	} else {
 		query = h.db.Where("slug = ? AND tenant_id = ?", workspaceID, tenantID)
 	}
@@ -168,42 +238,78 @@ func (h *WorkspaceHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Workspace not found"})
 		return
 	}
+	if !middleware.ScopeToTenant(c, h.db, workspace.TenantID) {
+		return
+	}
 
-	// Check if user has access to this workspace
+	// Members see their own role; tenant admins see every workspace as admin
 	var membership models.Membership
-	if err := h.db.Where("user_id = ? AND workspace_id = ?", userID, workspace.ID).First(&membership).Error; err != nil {
-		// Check if user is tenant admin
-		var user models.User
-		h.db.First(&user, "id = ?", userID)
-		if user.AdminOfTenantID == nil || *user.AdminOfTenantID != workspace.TenantID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "You don't have access to this workspace"})
-			return
-		}
+	if err := h.db.Where("user_id = ? AND workspace_id = ?", userID, workspace.ID).First(&membership).Error; err != nil &&
+		!h.can(userID, &workspace, hierarchy.PermissionManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "You don't have access to this workspace"})
+		return
 	}
 
 	response := workspaceResponse(&workspace)
 	if membership.ID != uuid.Nil {
 		response["role"] = membership.Role
 	} else {
-		response["role"] = "admin" // Tenant admin
+		response["role"] = hierarchy.RoleAdmin // Tenant admin
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// Delete deletes a workspace
-// DELETE /api/v1/workspaces/:id
-func (h *WorkspaceHandler) Delete(c *gin.Context) {
-	workspaceID := c.Param("id")
+// Select makes a workspace the active one by issuing an access token whose
+// workspace_id claim binds it. The gate prefers the claim over the
+// X-Workspace-ID header and rejects requests where the two disagree.
+// POST /api/v1/workspaces/:id/select
+func (h *WorkspaceHandler) Select(c *gin.Context) {
 	tenantID, _ := c.Get("tenant_id")
 	userID, _ := c.Get("user_id")
 
 	var workspace models.Workspace
-	if err := h.db.Where("id = ? AND tenant_id = ?", workspaceID, tenantID).First(&workspace).Error; err != nil {
+	if err := h.db.Where("id = ? AND tenant_id = ?", c.Param("id"), tenantID).First(&workspace).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Workspace not found"})
 		return
 	}
 
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_user", "message": "Invalid user ID"})
+		return
+	}
+
+	isTenantAdmin := user.AdminOfTenantID != nil && *user.AdminOfTenantID == workspace.TenantID
+	var membership models.Membership
+	if err := h.db.Where("user_id = ? AND workspace_id = ?", user.ID, workspace.ID).First(&membership).Error; err != nil && !isTenantAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "You don't have access to this workspace"})
+		return
+	}
+
+	token, err := h.generateWorkspaceToken(c, &user, &workspace, isTenantAdmin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to issue token"})
+		return
+	}
+	setAuthCookie(c, h.cfg, token)
+
+	c.JSON(http.StatusOK, gin.H{
+		"workspace":    workspaceResponse(&workspace),
+		"access_token": token,
+	})
+}
+
+// Delete deletes a workspace
+// DELETE /api/v1/workspaces/:id
+func (h *WorkspaceHandler) Delete(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	workspace, ok := h.loadWorkspace(c)
+	if !ok {
+		return
+	}
+
 	// Cannot delete default workspace
 	if workspace.IsDefault {
 		c.JSON(http.StatusForbidden, gin.H{"error": "cannot_delete_default", "message": "Cannot delete the default workspace"})
@@ -211,19 +317,13 @@ func (h *WorkspaceHandler) Delete(c *gin.Context) {
 	}
 
 	// Check if user is workspace admin or tenant admin
-	var membership models.Membership
-	if err := h.db.Where("user_id = ? AND workspace_id = ? AND role = ?", userID, workspace.ID, "admin").First(&membership).Error; err != nil {
-		// Check if tenant admin
-		var user models.User
-		h.db.First(&user, "id = ?", userID)
-		if user.AdminOfTenantID == nil || *user.AdminOfTenantID != workspace.TenantID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Only workspace or tenant admins can delete workspaces"})
-			return
-		}
+	if !h.can(userID, workspace, hierarchy.PermissionManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Only workspace or tenant admins can delete workspaces"})
+		return
 	}
 
 	// Delete workspace (cascades to memberships)
-	if err := h.db.Delete(&workspace).Error; err != nil {
+	if err := h.db.Delete(workspace).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to delete workspace"})
 		return
 	}
@@ -243,13 +343,10 @@ func (h *WorkspaceHandler) AddMember(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Valid email is required"})
 		return
 	}
+	req.Email = h.cfg.NormalizeEmail(req.Email)
 
-	workspaceID := c.Param("id")
-	tenantID, _ := c.Get("tenant_id")
-
-	var workspace models.Workspace
-	if err := h.db.Where("id = ? AND tenant_id = ?", workspaceID, tenantID).First(&workspace).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Workspace not found"})
+	workspace, ok := h.loadWorkspace(c)
+	if !ok {
 		return
 	}
 
@@ -268,11 +365,11 @@ func (h *WorkspaceHandler) AddMember(c *gin.Context) {
 	}
 
 	// Set default role
-	role := req.Role
+	role := hierarchy.Role(req.Role)
 	if role == "" {
-		role = "member"
+		role = hierarchy.DefaultRole
 	}
-	if role != "admin" && role != "member" && role != "viewer" {
+	if !workspaceLevel.IsValidRole(role) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_role", "message": "Role must be admin, member, or viewer"})
 		return
 	}
@@ -281,7 +378,7 @@ func (h *WorkspaceHandler) AddMember(c *gin.Context) {
 	membership := models.Membership{
 		UserID:      user.ID,
 		WorkspaceID: workspace.ID,
-		Role:        role,
+		Role:        string(role),
 	}
 
 	if err := h.db.Create(&membership).Error; err != nil {
@@ -289,6 +386,16 @@ func (h *WorkspaceHandler) AddMember(c *gin.Context) {
 		return
 	}
 
+	actorID, _ := c.Get("user_id")
+	h.emitter.Emit(events.MemberAdded, map[string]interface{}{
+		"workspace_id": workspace.ID,
+		"tenant_id":    workspace.TenantID,
+		"user_id":      user.ID,
+		"email":        user.Email,
+		"role":         membership.Role,
+		"actor_id":     actorID,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Member added successfully",
 		"member": gin.H{
@@ -301,15 +408,152 @@ func (h *WorkspaceHandler) AddMember(c *gin.Context) {
 	})
 }
 
-// ListMembers returns all members of a workspace
-// GET /api/v1/workspaces/:id/members
-func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
-	workspaceID := c.Param("id")
-	tenantID, _ := c.Get("tenant_id")
+// UpdateMember changes a member's role
+// PUT /api/v1/workspaces/:id/members/:userId
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "message": "Role is required"})
+		return
+	}
+
+	role := hierarchy.Role(req.Role)
+	if !workspaceLevel.IsValidRole(role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_role", "message": "Role must be admin, member, or viewer"})
+		return
+	}
+
+	workspace, membership, ok := h.loadMemberForChange(c)
+	if !ok {
+		return
+	}
+
+	// Keep at least one workspace admin
+	if hierarchy.Role(membership.Role) == hierarchy.RoleAdmin && role != hierarchy.RoleAdmin && h.isLastWorkspaceAdmin(workspace.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "last_admin", "message": "Cannot demote the last admin of this workspace"})
+		return
+	}
 
+	membership.Role = string(role)
+	if err := h.db.Model(membership).Update("role", membership.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to update member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member updated successfully",
+		"member": gin.H{
+			"user_id":      membership.UserID,
+			"email":        membership.User.Email,
+			"name":         membership.User.Name,
+			"role":         membership.Role,
+			"workspace_id": workspace.ID,
+		},
+	})
+}
+
+// RemoveMember revokes a user's access to a workspace
+// DELETE /api/v1/workspaces/:id/members/:userId
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	workspace, membership, ok := h.loadMemberForChange(c)
+	if !ok {
+		return
+	}
+
+	// Keep at least one workspace admin
+	if hierarchy.Role(membership.Role) == hierarchy.RoleAdmin && h.isLastWorkspaceAdmin(workspace.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "last_admin", "message": "Cannot remove the last admin of this workspace"})
+		return
+	}
+
+	if err := h.db.Delete(membership).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "message": "Failed to remove member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// loadMemberForChange loads the workspace and the :userId membership for a
+// member update, checking the caller is a workspace or tenant admin. On
+// failure it writes the response and returns ok=false.
+func (h *WorkspaceHandler) loadMemberForChange(c *gin.Context) (*models.Workspace, *models.Membership, bool) {
+	userID, _ := c.Get("user_id")
+
+	workspace, ok := h.loadWorkspace(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	if !h.can(userID, workspace, hierarchy.PermissionManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access_denied", "message": "Only workspace or tenant admins can manage members"})
+		return nil, nil, false
+	}
+
+	var membership models.Membership
+	if err := h.db.Preload("User").Where("user_id = ? AND workspace_id = ?", c.Param("userId"), workspace.ID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_member", "message": "User is not a member of this workspace"})
+		return nil, nil, false
+	}
+
+	return workspace, &membership, true
+}
+
+// loadWorkspace loads the :id workspace and checks it belongs to the
+// caller's tenant. On failure it writes the response and returns ok=false.
+func (h *WorkspaceHandler) loadWorkspace(c *gin.Context) (*models.Workspace, bool) {
 	var workspace models.Workspace
-	if err := h.db.Where("id = ? AND tenant_id = ?", workspaceID, tenantID).First(&workspace).Error; err != nil {
+	if err := h.db.Where("id = ?", c.Param("id")).First(&workspace).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "message": "Workspace not found"})
+		return nil, false
+	}
+	if !middleware.ScopeToTenant(c, h.db, workspace.TenantID) {
+		return nil, false
+	}
+	return &workspace, true
+}
+
+// generateWorkspaceToken issues an access token bound to workspace through
+// the workspace_id claim. It keeps the auth_time of the current token, and
+// its impersonator and expiry when impersonating.
+func (h *WorkspaceHandler) generateWorkspaceToken(c *gin.Context, user *models.User, workspace *models.Workspace, isTenantAdmin bool) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":             user.ID.String(),
+		"email":           user.Email,
+		"name":            user.Name,
+		"type":            "platform",
+		"email_verified":  user.EmailVerified,
+		"is_tenant_admin": isTenantAdmin,
+		"tenant_id":       workspace.TenantID.String(),
+		"workspace_id":    workspace.ID.String(),
+		"auth_time":       authTimeFromContext(c).Unix(),
+	}
+	carryImpersonation(c, claims)
+
+	return signAccessToken(h.cfg, claims)
+}
+
+// can reports whether the user's workspace role, or their tenant admin
+// status, grants permission in the workspace
+func (h *WorkspaceHandler) can(userID interface{}, workspace *models.Workspace, permission hierarchy.Permission) bool {
+	return workspacePermissions(h.db, userID, workspace).Has(permission)
+}
+
+// isLastWorkspaceAdmin reports whether the workspace has at most one admin
+func (h *WorkspaceHandler) isLastWorkspaceAdmin(workspaceID uuid.UUID) bool {
+	var admins int64
+	h.db.Model(&models.Membership{}).Where("workspace_id = ? AND role = ?", workspaceID, hierarchy.RoleAdmin).Count(&admins)
+	return admins <= 1
+}
+
+// ListMembers returns all members of a workspace
+// GET /api/v1/workspaces/:id/members
+func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
+	workspace, ok := h.loadWorkspace(c)
+	if !ok {
 		return
 	}
 
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+func TestCreateWorkspaceDuplicateSlug(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	h := NewWorkspaceHandler(db, cfg)
+
+	acme := createWorkspace(t, db, "acme")
+	globex := createWorkspace(t, db, "globex")
+	user := createUser(t, db, cfg, "admin@example.com")
+
+	tests := []struct {
+		name     string
+		tenant   *models.Workspace // a workspace of the tenant to create in
+		body     gin.H
+		want     int
+		wantCode string
+	}{
+		{"new slug", acme, gin.H{"name": "Engineering", "slug": "eng"}, http.StatusCreated, ""},
+		{"same slug again", acme, gin.H{"name": "Eng 2", "slug": "eng"}, http.StatusConflict, "slug_exists"},
+		{"slug derived from the name", acme, gin.H{"name": "eng"}, http.StatusConflict, "slug_exists"},
+		{"slug of the default workspace", acme, gin.H{"name": "Acme", "slug": "acme"}, http.StatusConflict, "slug_exists"},
+		{"same slug in another tenant", globex, gin.H{"name": "Engineering", "slug": "eng"}, http.StatusCreated, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := asUser(user.ID.String(), gin.H{"tenant_id": tt.tenant.TenantID.String()})
+			r.POST("/workspaces", h.Create)
+
+			w := serve(r, http.MethodPost, "/workspaces", tt.body)
+			expectStatus(t, w, tt.want)
+			if tt.wantCode != "" {
+				if code := errorCode(t, w); code != tt.wantCode {
+					t.Errorf("error = %q, want %q", code, tt.wantCode)
+				}
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&models.Workspace{}).Where("tenant_id = ? AND slug = ?", acme.TenantID, "eng").Count(&count)
+	if count != 1 {
+		t.Errorf("%d workspaces with slug eng in the tenant, want 1", count)
+	}
+}
+
+// TestCreateWorkspaceRace creates the same slug twice concurrently. The
+// first request is held between its duplicate check and its insert until
+// the second has committed, so only the unique index can catch it.
+func TestCreateWorkspaceRace(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	if err := models.EnforceUniqueWorkspaceSlugs(db); err != nil {
+		t.Fatal(err)
+	}
+	acme := createWorkspace(t, db, "acme")
+	user := createUser(t, db, cfg, "admin@example.com")
+
+	held := make(chan struct{})
+	release := make(chan struct{})
+	var holding atomic.Bool
+	db.Callback().Create().Before("gorm:create").Register("test:hold_first_workspace", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Model.(*models.Workspace); !ok {
+			return
+		}
+		if holding.CompareAndSwap(false, true) {
+			close(held)
+			<-release
+		}
+	})
+
+	r := asUser(user.ID.String(), gin.H{"tenant_id": acme.TenantID.String()})
+	r.POST("/workspaces", NewWorkspaceHandler(db, cfg).Create)
+	body := gin.H{"name": "Engineering", "slug": "eng"}
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- serve(r, http.MethodPost, "/workspaces", body) }()
+	select {
+	case <-held:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first request never reached its insert")
+	}
+
+	expectStatus(t, serve(r, http.MethodPost, "/workspaces", body), http.StatusCreated)
+	close(release)
+
+	w := <-first
+	expectStatus(t, w, http.StatusConflict)
+	if code := errorCode(t, w); code != "slug_exists" {
+		t.Errorf("error = %q, want slug_exists", code)
+	}
+
+	var count int64
+	db.Model(&models.Workspace{}).Where("tenant_id = ? AND slug = ?", acme.TenantID, "eng").Count(&count)
+	if count != 1 {
+		t.Errorf("%d workspaces with slug eng in the tenant, want 1", count)
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxJSONDepth bounds object/array nesting in JSON bodies. Real payloads
+// stay a few levels deep; deeper ones only cost parse time and stack.
+const maxJSONDepth = 32
+
+// BodyLimit caps request bodies at limit bytes (<= 0 disables), or at
+// routeLimits[route] for routes that legitimately need more, keyed by route
+// pattern (c.FullPath()), e.g. document content. The body is read up front
+// so an oversized one gets 413 before a handler tries to bind it, and JSON
+// nested deeper than maxJSONDepth gets 400.
+func BodyLimit(limit int64, routeLimits map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		max := limit
+		if routeLimit, ok := routeLimits[c.FullPath()]; ok {
+			max = routeLimit
+		}
+		if max <= 0 {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > max {
+			payloadTooLarge(c)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, max))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				payloadTooLarge(c)
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "Failed to read request body",
+			})
+			return
+		}
+
+		if strings.HasPrefix(c.ContentType(), "application/json") && jsonDepth(body) > maxJSONDepth {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "JSON body is nested too deeply",
+			})
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+func payloadTooLarge(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":   "payload_too_large",
+		"message": "Request body is too large",
+	})
+}
+
+// jsonDepth returns the deepest object/array nesting in body, ignoring
+// brackets inside strings. Malformed JSON is left for the handler to reject.
+func jsonDepth(body []byte) int {
+	depth, deepest := 0, 0
+	inString, escaped := false, false
+	for _, b := range body {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+		switch b {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > deepest {
+				deepest = depth
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return deepest
+}
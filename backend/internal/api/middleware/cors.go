@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+)
+
+// OriginMatcher decides whether a request Origin is allowed. Origins are
+// matched exactly, except those with a "*" in the host, which stands for one
+// or more subdomain labels: https://*.example.com allows
+// https://app.example.com and https://a.b.example.com, but not
+// https://example.com.
+type OriginMatcher struct {
+	exact    map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// NewOriginMatcher compiles the allowed origins
+func NewOriginMatcher(origins []string) *OriginMatcher {
+	m := &OriginMatcher{exact: make(map[string]bool)}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		if !strings.Contains(origin, "*") {
+			m.exact[origin] = true
+			continue
+		}
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(origin), `\*`, `[a-z0-9-]+(?:\.[a-z0-9-]+)*`)
+		m.patterns = append(m.patterns, regexp.MustCompile("^"+pattern+"$"))
+	}
+	return m
+}
+
+// Allowed reports whether origin may make cross-origin requests
+func (m *OriginMatcher) Allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if m.exact[origin] {
+		return true
+	}
+	for _, pattern := range m.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS handles cross-origin requests from the configured origins. Allowed
+// origins are echoed back with credentials enabled; other origins get no
+// CORS headers, and their preflight requests are rejected with 403.
+func CORS(cfg *config.Config) gin.HandlerFunc {
+	origins := NewOriginMatcher(cfg.CORSAllowedOrigins)
+	methods := strings.Join(cfg.CORSAllowedMethods, ", ")
+	headers := strings.Join(cfg.CORSAllowedHeaders, ", ")
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		// The response depends on the Origin, so caches must key on it
+		c.Header("Vary", "Origin")
+
+		if !origins.Allowed(origin) {
+			if c.Request.Method == "OPTIONS" {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "origin_not_allowed", "message": "Origin is not allowed"})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Allow-Methods", methods)
+		c.Header("Access-Control-Allow-Headers", headers)
+		c.Header("Access-Control-Expose-Headers", "ETag")
+		c.Header("Access-Control-Allow-Credentials", "true")
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,47 +1,73 @@
 package middleware
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/hierarchy"
 	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/revocation"
+	"github.com/yourusername/saas-starter-kit/backend/internal/session"
 	"gorm.io/gorm"
 )
 
-// CORS middleware for handling Cross-Origin requests
-func CORS(frontendURL string) gin.HandlerFunc {
+// requestIDMaxLength bounds incoming correlation IDs so they can't bloat logs
+const requestIDMaxLength = 128
+
+// RequestID reads the correlation ID forwarded by the authz gate (or
+// generates one for direct calls), stores it as "request_id" in the context
+// and echoes it in the response
+func RequestID(header string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		origin := c.GetHeader("Origin")
-
-		// Allow the configured frontend URL and localhost for development
-		allowedOrigins := []string{frontendURL, "http://localhost:5173", "http://localhost:3000"}
-		allowed := false
-		for _, o := range allowedOrigins {
-			if origin == o {
-				allowed = true
-				break
-			}
+		id := c.GetHeader(header)
+		if !validRequestID(id) {
+			id = uuid.New().String()
 		}
 
-		if allowed {
-			c.Header("Access-Control-Allow-Origin", origin)
-		}
+		c.Set("request_id", id)
+		c.Header(header, id)
+		c.Next()
+	}
+}
 
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Workspace-ID")
-		c.Header("Access-Control-Allow-Credentials", "true")
+// Logger is gin's access log with the request ID appended
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %s | %3d | %13v | %15s | %-7s %#v | request_id=%s\n",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			p.StatusCode,
+			p.Latency,
+			p.ClientIP,
+			p.Method,
+			p.Path,
+			p.Keys["request_id"],
+		)
+	})
+}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
+// validRequestID accepts URL-safe characters only so a spoofed header can't
+// inject log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > requestIDMaxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.':
+		default:
+			return false
 		}
-
-		c.Next()
 	}
+	return true
 }
 
 // JWTClaims represents the JWT token claims
@@ -53,13 +79,41 @@ type JWTClaims struct {
 	EmailVerified bool   `json:"email_verified"`
 	IsTenantAdmin bool   `json:"is_tenant_admin"`
 	TenantID      string `json:"tenant_id,omitempty"`
+	WorkspaceID   string `json:"workspace_id,omitempty"`
+	AuthTime      int64  `json:"auth_time,omitempty"` // Unix time of the last real sign-in
+
+	// Impersonator is the platform admin acting as Sub, set on tokens from
+	// POST /api/v1/admin/impersonate/:userId
+	Impersonator string `json:"impersonator,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// RequireAuth middleware validates JWT tokens
-func RequireAuth(cfg *config.Config) gin.HandlerFunc {
+// CSRFHeader must accompany cookie-authenticated requests that change
+// state: a cross-site form can't set it, and a cross-site script can only
+// after a CORS preflight, which CORS passes for allowed origins only
+const CSRFHeader = "X-Requested-With"
+
+// AuthorizationHeader returns the request's Authorization header or, when it
+// is absent and AUTH_COOKIE_NAME is set, the access token cookie as a Bearer
+// header. The header always wins, so API clients are unaffected.
+func AuthorizationHeader(c *gin.Context, cfg *config.Config) string {
+	if header := c.GetHeader("Authorization"); header != "" || cfg.AuthCookieName == "" {
+		return header
+	}
+	if token, err := c.Cookie(cfg.AuthCookieName); err == nil && token != "" {
+		return "Bearer " + token
+	}
+	return ""
+}
+
+// RequireAuth middleware validates JWT tokens, from the Authorization header
+// or the access token cookie (for unsafe methods only with CSRFHeader),
+// rejects tokens of disabled users with account_disabled and of revoked
+// users with token_revoked and, with a non-nil sessions tracker, tokens of
+// timed-out sessions with session_expired
+func RequireAuth(cfg *config.Config, sessions *session.Tracker, revocations *revocation.List) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
+		authHeader := AuthorizationHeader(c, cfg)
 		if authHeader == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "missing_token",
@@ -68,6 +122,15 @@ func RequireAuth(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		// Browsers attach the cookie to cross-site requests too
+		if c.GetHeader("Authorization") == "" && !safeMethod(c.Request.Method) && c.GetHeader(CSRFHeader) == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "csrf_header_required",
+				"message": "Cookie-authenticated requests must send the " + CSRFHeader + " header",
+			})
+			return
+		}
+
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -81,8 +144,16 @@ func RequireAuth(cfg *config.Config) gin.HandlerFunc {
 
 		token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 			return cfg.GetJWTSecret(), nil
-		})
+		}, jwt.WithIssuer(cfg.JWTIssuer), jwt.WithAudience(cfg.JWTAudience))
 
+		// Tokens are issued to expire with their session, so an expired
+		// token past the absolute timeout means the session ended
+		if errors.Is(err, jwt.ErrTokenExpired) && token != nil {
+			if claims, ok := token.Claims.(*JWTClaims); ok && sessions.PastAbsolute(claims.AuthTime) {
+				abortSessionExpired(c)
+				return
+			}
+		}
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "invalid_token",
@@ -100,14 +171,115 @@ func RequireAuth(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		var issuedAt time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = claims.IssuedAt.Time
+		}
+		if err := revocations.Check(claims.Sub, issuedAt); err != nil {
+			switch {
+			case errors.Is(err, revocation.ErrUserDisabled):
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error":   "account_disabled",
+					"message": "This account has been disabled",
+				})
+				return
+			case errors.Is(err, revocation.ErrTokenRevoked):
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error":   "token_revoked",
+					"message": "Token has been revoked",
+				})
+				return
+			}
+			log.Printf("Failed to check whether user %s is disabled: %v", claims.Sub, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to check account status",
+			})
+			return
+		}
+
+		if err := sessions.Touch(claims.Sub, claims.AuthTime); err != nil {
+			if errors.Is(err, session.ErrExpired) {
+				abortSessionExpired(c)
+				return
+			}
+			log.Printf("Failed to check session of user %s: %v", claims.Sub, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_error",
+				"message": "Failed to check session",
+			})
+			return
+		}
+
 		// Set user info in context
 		c.Set("user_id", claims.Sub)
 		c.Set("user_email", claims.Email)
 		c.Set("user_name", claims.Name)
 		c.Set("is_tenant_admin", claims.IsTenantAdmin)
+		c.Set("auth_time", claims.AuthTime)
 		if claims.TenantID != "" {
 			c.Set("tenant_id", claims.TenantID)
 		}
+		if claims.WorkspaceID != "" {
+			c.Set("workspace_id", claims.WorkspaceID)
+		}
+		if claims.Impersonator != "" && claims.ExpiresAt != nil {
+			c.Set("impersonator_id", claims.Impersonator)
+			c.Set("impersonation_expires_at", claims.ExpiresAt.Time)
+		}
+
+		c.Next()
+	}
+}
+
+// safeMethod reports whether method only reads
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func abortSessionExpired(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"error":   "session_expired",
+		"message": "Your session has expired, please sign in again",
+	})
+}
+
+// RequireFreshAuth middleware rejects tokens whose auth_time is older than
+// maxAge, forcing the user to sign in again before a sensitive action.
+// Must run after RequireAuth.
+func RequireFreshAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authTime, _ := c.Get("auth_time")
+		ts, _ := authTime.(int64)
+
+		if ts == 0 || time.Since(time.Unix(ts, 0)) > maxAge {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "reauth_required",
+				"message": "Please sign in again to continue",
+				"max_age": int(maxAge.Seconds()),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ForbidImpersonation rejects impersonation tokens, for actions a support
+// engineer must not take on a user's behalf. Must run after RequireAuth.
+func ForbidImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonating := c.Get("impersonator_id"); impersonating {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "impersonation_forbidden",
+				"message": "This action is not available while impersonating a user",
+			})
+			return
+		}
 
 		c.Next()
 	}
@@ -149,6 +321,54 @@ func RequireTenant(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// ResolveRoot sets "root_id" to the hierarchy root the request acts in, for
+// the container handlers: the root the user administers, otherwise the root
+// of their highest membership. Users belonging to several roots pick one
+// with X-Root-ID. Requests without a root continue without "root_id".
+// Must run after RequireAuth.
+func ResolveRoot(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		requested := c.GetHeader("X-Root-ID")
+
+		var user hierarchy.User
+		if err := db.First(&user, "id = ?", userID).Error; err == nil && user.AdminOfRootID != nil &&
+			(requested == "" || requested == user.AdminOfRootID.String()) {
+			c.Set("root_id", user.AdminOfRootID.String())
+			c.Next()
+			return
+		}
+
+		query := db.Table("container_memberships m").
+			Select("rc.root_id").
+			Joins("JOIN resource_containers rc ON rc.id = m.container_id").
+			Where("m.user_id = ? AND m.deleted_at IS NULL AND rc.deleted_at IS NULL", userID)
+		if requested != "" {
+			if _, err := uuid.Parse(requested); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"error":   "invalid_root",
+					"message": "X-Root-ID must be a UUID",
+				})
+				return
+			}
+			query = query.Where("rc.root_id = ?", requested)
+		}
+
+		var rootIDs []uuid.UUID
+		if err := query.Order("rc.depth, m.created_at").Limit(1).Pluck("rc.root_id", &rootIDs).Error; err == nil && len(rootIDs) > 0 {
+			c.Set("root_id", rootIDs[0].String())
+		} else if requested != "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "no_root_access",
+				"message": "You are not a member of this organization",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // RequireTenantAdmin middleware ensures user is a tenant admin
 func RequireTenantAdmin(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -164,3 +384,26 @@ func RequireTenantAdmin(db *gorm.DB) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// ScopeToTenant checks that a resource the handler loaded belongs to the
+// tenant in the caller's token, so handlers can look resources up by ID
+// alone. Platform admins may reach any tenant's resources. Otherwise it
+// aborts with 403 tenant_mismatch and returns false; the handler must then
+// return without writing a response.
+func ScopeToTenant(c *gin.Context, db *gorm.DB, resourceTenantID uuid.UUID) bool {
+	if tenantID := c.GetString("tenant_id"); tenantID != "" && tenantID == resourceTenantID.String() {
+		return true
+	}
+
+	userID, _ := c.Get("user_id")
+	var user models.User
+	if err := db.First(&user, "id = ?", userID).Error; err == nil && user.IsPlatformAdmin {
+		return true
+	}
+
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":   "tenant_mismatch",
+		"message": "This resource belongs to another organization",
+	})
+	return false
+}
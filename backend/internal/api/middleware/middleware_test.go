@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/revocation"
+	"github.com/yourusername/saas-starter-kit/backend/internal/testutil"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func testConfig() *config.Config {
+	return &config.Config{JWTSecret: "test-secret", JWTIssuer: "test", JWTAudience: "test"}
+}
+
+// signToken issues an access token for userID the way the auth handlers do
+func signToken(t *testing.T, cfg *config.Config, userID string) string {
+	t.Helper()
+	return signTokenAuthedAt(t, cfg, userID, time.Now().Unix())
+}
+
+// signTokenAuthedAt issues an access token whose auth_time claim is
+// authTime; 0 leaves the claim out
+func signTokenAuthedAt(t *testing.T, cfg *config.Config, userID string, authTime int64) string {
+	t.Helper()
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{
+		Sub:      userID,
+		Type:     "platform",
+		AuthTime: authTime,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    cfg.JWTIssuer,
+			Audience:  jwt.ClaimStrings{cfg.JWTAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	})
+	signed, err := token.SignedString(cfg.GetJWTSecret())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestRequireAuthRejectsDisabledUsers(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+
+	active := models.User{Email: "active@example.com"}
+	disabledAt := time.Now().Add(-time.Hour)
+	disabled := models.User{Email: "disabled@example.com", DisabledAt: &disabledAt}
+	for _, u := range []*models.User{&active, &disabled} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := gin.New()
+	r.GET("/", RequireAuth(cfg, nil, revocation.NewList(db, time.Minute)), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name string
+		user models.User
+		want int
+	}{
+		{"active user", active, http.StatusNoContent},
+		// Issued after disabled_at, e.g. by a path that missed the check
+		{"disabled user", disabled, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+signToken(t, cfg, tt.user.ID.String()))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d; body %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireAuthCookieNeedsCSRFHeader(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+	cfg.AuthCookieName = "access_token"
+
+	user := models.User{Email: "user@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	token := signToken(t, cfg, user.ID.String())
+
+	r := gin.New()
+	r.Any("/", RequireAuth(cfg, nil, nil), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		bearer bool
+		csrf   bool
+		want   int
+	}{
+		{"cookie read", http.MethodGet, false, false, http.StatusNoContent},
+		{"cookie write without header", http.MethodPost, false, false, http.StatusForbidden},
+		{"cookie delete without header", http.MethodDelete, false, false, http.StatusForbidden},
+		{"cookie write with header", http.MethodPost, false, true, http.StatusNoContent},
+		{"bearer write", http.MethodPost, true, false, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.bearer {
+				req.Header.Set("Authorization", "Bearer "+token)
+			} else {
+				req.AddCookie(&http.Cookie{Name: cfg.AuthCookieName, Value: token})
+			}
+			if tt.csrf {
+				req.Header.Set(CSRFHeader, "XMLHttpRequest")
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d; body %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireFreshAuth(t *testing.T) {
+	db := testutil.NewDB(t)
+	cfg := testConfig()
+
+	user := models.User{Email: "user@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.POST("/", RequireAuth(cfg, nil, nil), RequireFreshAuth(10*time.Minute), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	now := time.Now()
+	tests := []struct {
+		name     string
+		authTime int64
+		want     int
+	}{
+		{"just signed in", now.Unix(), http.StatusNoContent},
+		{"signed in within the max age", now.Add(-9 * time.Minute).Unix(), http.StatusNoContent},
+		{"signed in too long ago", now.Add(-11 * time.Minute).Unix(), http.StatusUnauthorized},
+		{"token without auth_time", 0, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+signTokenAuthedAt(t, cfg, user.ID.String(), tt.authTime))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want == http.StatusUnauthorized && !strings.Contains(w.Body.String(), "reauth_required") {
+				t.Errorf("body = %s, want reauth_required", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		forwarded string // ID the gate put on the request, as Traefik forwards it
+		keep      bool
+	}{
+		{"uses the gate's ID", "3f2a9c0e-gate-id", true},
+		{"generates one for direct calls", "", false},
+		{"replaces one with a newline", "abc\nforged log line", false},
+		{"replaces one that is too long", strings.Repeat("a", requestIDMaxLength+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			prev := gin.DefaultWriter
+			gin.DefaultWriter = &logs
+			t.Cleanup(func() { gin.DefaultWriter = prev })
+
+			var seen string
+			r := gin.New()
+			r.Use(RequestID("X-Request-ID"), Logger())
+			r.GET("/", func(c *gin.Context) {
+				seen = c.GetString("request_id")
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.forwarded != "" {
+				req.Header.Set("X-Request-ID", tt.forwarded)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if tt.keep && seen != tt.forwarded {
+				t.Errorf("request_id = %q, want %q", seen, tt.forwarded)
+			}
+			if !tt.keep && (seen == tt.forwarded || !validRequestID(seen)) {
+				t.Errorf("request_id = %q, want a fresh valid ID", seen)
+			}
+			if echoed := w.Header().Get("X-Request-ID"); echoed != seen {
+				t.Errorf("response header = %q, want %q", echoed, seen)
+			}
+			if !strings.Contains(logs.String(), "request_id="+seen+"\n") {
+				t.Errorf("log = %q, want request_id=%s", logs.String(), seen)
+			}
+		})
+	}
+}
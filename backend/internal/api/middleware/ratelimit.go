@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitStore holds token buckets. MemoryRateLimitStore is enough for a
+// single backend replica; a shared store (e.g. Redis) can implement the same
+// interface for multiple replicas.
+type RateLimitStore interface {
+	// Take removes a token from key's bucket, which holds up to perMinute
+	// tokens and refills at perMinute per minute. When the bucket is empty it
+	// returns false and how long until the next token.
+	Take(ctx context.Context, key string, perMinute int) (bool, time.Duration, error)
+}
+
+// RateLimiter limits requests with one token bucket per client key
+type RateLimiter struct {
+	store     RateLimitStore
+	perMinute int // <= 0 disables the limit
+}
+
+// NewRateLimiter creates a limiter allowing perMinute requests per key, with
+// bursts of up to perMinute
+func NewRateLimiter(store RateLimitStore, perMinute int) *RateLimiter {
+	return &RateLimiter{store: store, perMinute: perMinute}
+}
+
+// PerIP limits requests by client IP across every route it is applied to
+func (l *RateLimiter) PerIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.allow(c, "ip:"+c.ClientIP()) {
+			c.Next()
+		}
+	}
+}
+
+// PerEmail limits requests by the "identifier" (email or username) or else
+// "email" field of the JSON body, per route, so one account can't be
+// brute-forced or spammed from many IPs. Requests without either pass
+// through to the handler's own validation.
+func (l *RateLimiter) PerEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := bodyEmail(c)
+		if email == "" || l.allow(c, "email:"+c.FullPath()+":"+email) {
+			c.Next()
+		}
+	}
+}
+
+// allow takes a token for key, responding 429 with Retry-After when none is
+// left. Store errors fail open so an outage doesn't lock everyone out.
+func (l *RateLimiter) allow(c *gin.Context, key string) bool {
+	if l.perMinute <= 0 {
+		return true
+	}
+
+	ok, wait, err := l.store.Take(c.Request.Context(), key, l.perMinute)
+	if err != nil {
+		log.Printf("Rate limit check failed: %v", err)
+		return true
+	}
+	if ok {
+		return true
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":   "rate_limited",
+		"message": "Too many requests, please try again later",
+	})
+	return false
+}
+
+// bodyEmail reads the normalized "identifier" or "email" field from a JSON
+// body, leaving the body intact for the handler
+func bodyEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		Identifier string `json:"identifier"`
+		Email      string `json:"email"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+	if req.Identifier != "" {
+		return strings.ToLower(strings.TrimSpace(req.Identifier))
+	}
+	return strings.ToLower(strings.TrimSpace(req.Email))
+}
+
+// ============================================================================
+// In-memory store
+// ============================================================================
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryRateLimitStore keeps token buckets in process. Limits are not shared
+// across replicas.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimitStore creates an in-process token bucket store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Take implements RateLimitStore
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, perMinute int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	capacity := float64(perMinute)
+	ratePerSec := capacity / 60
+
+	if now.Sub(s.lastPrune) > time.Minute {
+		s.prune(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	} else {
+		b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*ratePerSec)
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / ratePerSec * float64(time.Second))
+		return false, wait, nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
+
+// prune drops buckets untouched for a minute; they would have refilled
+// completely anyway. Caller must hold s.mu.
+func (s *MemoryRateLimitStore) prune(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= time.Minute {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastPrune = now
+}
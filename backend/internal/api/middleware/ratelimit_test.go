@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeClock is a MemoryRateLimitStore clock the test advances by hand
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestStore() (*MemoryRateLimitStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryRateLimitStore()
+	store.now = clock.Now
+	return store, clock
+}
+
+func TestMemoryRateLimitStoreBurst(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMinute int
+		burst     int           // requests sent at once
+		wait      time.Duration // then wait before one more
+		wantOK    int           // requests of the burst allowed
+		wantLast  bool          // whether the request after the wait is allowed
+	}{
+		{"burst within the limit", 10, 10, 0, 10, false},
+		{"burst over the limit", 10, 25, 0, 10, false},
+		{"one token refills", 60, 80, time.Second, 60, true},
+		{"refill is gradual", 60, 80, 500 * time.Millisecond, 60, false},
+		{"bucket caps at the limit", 5, 5, time.Hour, 5, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store, clock := newTestStore()
+			ctx := context.Background()
+
+			allowed := 0
+			var lastWait time.Duration
+			for i := 0; i < tc.burst; i++ {
+				ok, wait, err := store.Take(ctx, "ip:192.0.2.1", tc.perMinute)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok {
+					allowed++
+				} else {
+					lastWait = wait
+				}
+			}
+			if allowed != tc.wantOK {
+				t.Errorf("allowed %d of a burst of %d, want %d", allowed, tc.burst, tc.wantOK)
+			}
+			if tc.burst > tc.perMinute && (lastWait <= 0 || lastWait > time.Minute) {
+				t.Errorf("wait = %s, want within a minute", lastWait)
+			}
+
+			clock.now = clock.now.Add(tc.wait)
+			ok, _, _ := store.Take(ctx, "ip:192.0.2.1", tc.perMinute)
+			if ok != tc.wantLast {
+				t.Errorf("request after %s allowed = %v, want %v", tc.wait, ok, tc.wantLast)
+			}
+
+			// However long the bucket was idle, it holds at most the limit
+			allowed = 0
+			for i := 0; i < 3*tc.perMinute; i++ {
+				if ok, _, _ := store.Take(ctx, "ip:192.0.2.1", tc.perMinute); ok {
+					allowed++
+				}
+			}
+			if allowed > tc.perMinute {
+				t.Errorf("allowed %d more in a second burst, want at most %d", allowed, tc.perMinute)
+			}
+		})
+	}
+}
+
+func TestPerIPBurst(t *testing.T) {
+	const limit = 5
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   func(i int) string // X-Forwarded-For of request i
+		want429        int
+	}{
+		{
+			name:       "one client",
+			remoteAddr: "192.0.2.1:1234",
+			want429:    15,
+		},
+		{
+			name:         "spoofed X-Forwarded-For from an untrusted peer",
+			remoteAddr:   "192.0.2.1:1234",
+			forwardedFor: func(i int) string { return "198.51.100." + strconv.Itoa(i) },
+			want429:      15,
+		},
+		{
+			name:           "distinct clients behind a trusted proxy",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:1234",
+			forwardedFor:   func(i int) string { return "198.51.100." + strconv.Itoa(i%4) },
+			want429:        0,
+		},
+		{
+			name:           "one client behind a trusted proxy",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:1234",
+			forwardedFor:   func(int) string { return "198.51.100.7" },
+			want429:        15,
+		},
+		{
+			name:           "prepended entries behind a trusted proxy",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:1234",
+			forwardedFor:   func(i int) string { return "203.0.113." + strconv.Itoa(i) + ", 198.51.100.7" },
+			want429:        15,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store, _ := newTestStore()
+			r := gin.New()
+			if err := r.SetTrustedProxies(tc.trustedProxies); err != nil {
+				t.Fatal(err)
+			}
+			r.Use(NewRateLimiter(store, limit).PerIP())
+			r.GET("/auth/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			limited := 0
+			for i := 0; i < 20; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/auth/login", nil)
+				req.RemoteAddr = tc.remoteAddr
+				if tc.forwardedFor != nil {
+					req.Header.Set("X-Forwarded-For", tc.forwardedFor(i))
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				switch w.Code {
+				case http.StatusOK:
+				case http.StatusTooManyRequests:
+					limited++
+					if w.Header().Get("Retry-After") == "" {
+						t.Error("429 without Retry-After")
+					}
+				default:
+					t.Fatalf("status = %d", w.Code)
+				}
+			}
+			if limited != tc.want429 {
+				t.Errorf("%d of 20 requests limited, want %d", limited, tc.want429)
+			}
+		})
+	}
+}
+
+func TestPerEmailBurst(t *testing.T) {
+	store, _ := newTestStore()
+	r := gin.New()
+	r.Use(NewRateLimiter(store, 3).PerEmail())
+	r.POST("/auth/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Case and whitespace variants of one address share a bucket
+	for i, body := range []string{
+		`{"email":"a@example.com"}`,
+		`{"email":"A@Example.com"}`,
+		`{"identifier":" a@example.com "}`,
+	} {
+		if code := send(body); code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i, code)
+		}
+	}
+	if code := send(`{"email":"a@EXAMPLE.com"}`); code != http.StatusTooManyRequests {
+		t.Errorf("4th request status = %d, want 429", code)
+	}
+	if code := send(`{"email":"b@example.com"}`); code != http.StatusOK {
+		t.Errorf("other address status = %d, want 200", code)
+	}
+	// Bodies without an address reach the handler's own validation
+	if code := send(`not json`); code != http.StatusOK {
+		t.Errorf("body without an email status = %d, want 200", code)
+	}
+}
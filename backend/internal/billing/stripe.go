@@ -0,0 +1,211 @@
+// Package billing talks to Stripe over its REST API: creating Checkout
+// sessions and verifying webhook events.
+package billing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultAPIURL = "https://api.stripe.com/v1"
+
+// webhookTolerance is how old a webhook signature timestamp may be, to
+// limit replays
+const webhookTolerance = 5 * time.Minute
+
+var (
+	// ErrInvalidSignature is returned for webhooks not signed with the
+	// endpoint secret
+	ErrInvalidSignature = errors.New("invalid stripe signature")
+
+	// ErrStaleSignature is returned for webhooks signed too long ago
+	ErrStaleSignature = errors.New("stripe signature timestamp outside tolerance")
+)
+
+// Client calls the Stripe API with a secret key
+type Client struct {
+	secretKey  string
+	apiURL     string
+	httpClient *http.Client
+}
+
+// NewClient creates a Stripe client
+func NewClient(secretKey string) *Client {
+	return &Client{
+		secretKey:  secretKey,
+		apiURL:     defaultAPIURL,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// CheckoutParams describes a subscription Checkout session for one plan
+type CheckoutParams struct {
+	TenantID      string
+	PlanTier      string
+	PlanName      string
+	MonthlyCents  int
+	Currency      string
+	CustomerID    string // existing Stripe customer, if any
+	CustomerEmail string // used when there is no customer yet
+	SuccessURL    string
+	CancelURL     string
+}
+
+// CheckoutSession is the part of a Stripe Checkout session we use
+type CheckoutSession struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// CreateCheckoutSession creates a subscription Checkout session. The tenant
+// ID and plan tier are stored as subscription metadata so webhooks can be
+// matched to the local subscription.
+func (c *Client) CreateCheckoutSession(p CheckoutParams) (*CheckoutSession, error) {
+	form := url.Values{}
+	form.Set("mode", "subscription")
+	form.Set("success_url", p.SuccessURL)
+	form.Set("cancel_url", p.CancelURL)
+	form.Set("client_reference_id", p.TenantID)
+	if p.CustomerID != "" {
+		form.Set("customer", p.CustomerID)
+	} else if p.CustomerEmail != "" {
+		form.Set("customer_email", p.CustomerEmail)
+	}
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", p.Currency)
+	form.Set("line_items[0][price_data][unit_amount]", strconv.Itoa(p.MonthlyCents))
+	form.Set("line_items[0][price_data][recurring][interval]", "month")
+	form.Set("line_items[0][price_data][product_data][name]", p.PlanName)
+	form.Set("metadata[tenant_id]", p.TenantID)
+	form.Set("metadata[plan_tier]", p.PlanTier)
+	form.Set("subscription_data[metadata][tenant_id]", p.TenantID)
+	form.Set("subscription_data[metadata][plan_tier]", p.PlanTier)
+
+	req, err := http.NewRequest("POST", c.apiURL+"/checkout/sessions", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.secretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("create checkout session failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("create checkout session failed: %s - %s", resp.Status, string(body))
+	}
+
+	var session CheckoutSession
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return nil, fmt.Errorf("create checkout session failed: %w", err)
+	}
+	return &session, nil
+}
+
+// ============================================================================
+// Webhooks
+// ============================================================================
+
+// Event is a Stripe webhook event
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Subscription is the part of a Stripe subscription object we use
+type Subscription struct {
+	ID                 string            `json:"id"`
+	Customer           string            `json:"customer"`
+	Status             string            `json:"status"`
+	CurrentPeriodStart int64             `json:"current_period_start"`
+	CurrentPeriodEnd   int64             `json:"current_period_end"`
+	CanceledAt         *int64            `json:"canceled_at"`
+	Metadata           map[string]string `json:"metadata"`
+}
+
+// Invoice is the part of a Stripe invoice object we use
+type Invoice struct {
+	ID           string `json:"id"`
+	Customer     string `json:"customer"`
+	Subscription string `json:"subscription"`
+}
+
+// ParseWebhook verifies the Stripe-Signature header against the endpoint
+// secret and decodes the event
+func ParseWebhook(payload []byte, sigHeader, secret string) (*Event, error) {
+	if err := verifySignature(payload, sigHeader, secret, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("decode stripe event: %w", err)
+	}
+	return &event, nil
+}
+
+// verifySignature checks a "t=<unix>,v1=<hex hmac>" header. The signed
+// payload is "<t>.<body>"; any v1 signature may match, since Stripe sends
+// several while a secret is being rolled.
+func verifySignature(payload []byte, header, secret string, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	valid := false
+	for _, sig := range signatures {
+		decoded, err := hex.DecodeString(sig)
+		if err == nil && hmac.Equal(decoded, expected) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(ts, 0))
+	if age > webhookTolerance || age < -webhookTolerance {
+		return ErrStaleSignature
+	}
+	return nil
+}
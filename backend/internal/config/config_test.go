@@ -0,0 +1,75 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name            string
+		caseInsensitive bool
+		email           string
+		want            string
+	}{
+		{"already normal", true, "alice@example.com", "alice@example.com"},
+		{"mixed case", true, "Alice@Example.COM", "alice@example.com"},
+		{"surrounding whitespace", true, "  alice@example.com\n", "alice@example.com"},
+		{"case sensitive keeps case", false, " Alice@Example.com ", "Alice@Example.com"},
+		{"empty", true, "   ", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &Config{EmailCaseInsensitive: tc.caseInsensitive}
+			if got := cfg.NormalizeEmail(tc.email); got != tc.want {
+				t.Errorf("NormalizeEmail(%q) = %q, want %q", tc.email, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadDBStartup(t *testing.T) {
+	tests := []struct {
+		name         string
+		attempts     string
+		backoff      string
+		wantAttempts int
+		wantBackoff  time.Duration
+	}{
+		{"defaults", "", "", 10, time.Second},
+		{"configured", "3", "250ms", 3, 250 * time.Millisecond},
+		{"invalid values keep the defaults", "many", "soon", 10, time.Second},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DB_STARTUP_ATTEMPTS", tc.attempts)
+			t.Setenv("DB_STARTUP_BACKOFF", tc.backoff)
+			cfg := Load()
+			if cfg.DBStartupAttempts != tc.wantAttempts || cfg.DBStartupBackoff != tc.wantBackoff {
+				t.Errorf("got %d attempts, %s backoff; want %d, %s", cfg.DBStartupAttempts, cfg.DBStartupBackoff, tc.wantAttempts, tc.wantBackoff)
+			}
+		})
+	}
+}
+
+func TestLoadCleanupInterval(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"default", "", 10 * time.Minute},
+		{"configured", "1h", time.Hour},
+		{"disabled", "0s", 0},
+		{"invalid value keeps the default", "hourly", 10 * time.Minute},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("CLEANUP_INTERVAL", tc.value)
+			if got := Load().CleanupInterval; got != tc.want {
+				t.Errorf("CleanupInterval = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
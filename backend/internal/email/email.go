@@ -0,0 +1,162 @@
+package email
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"net/url"
+	"strings"
+
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers email messages
+type Sender interface {
+	Send(msg Message) error
+	// Enabled reports whether messages are actually delivered
+	Enabled() bool
+}
+
+// NewSender returns an SMTP sender when SMTP is configured, otherwise a NoopSender
+func NewSender(cfg *config.Config) Sender {
+	if cfg.HasSMTP() {
+		return &SMTPSender{
+			host:     cfg.SMTPHost,
+			port:     cfg.SMTPPort,
+			user:     cfg.SMTPUser,
+			password: cfg.SMTPPassword,
+			from:     cfg.FromEmail,
+		}
+	}
+	return &NoopSender{}
+}
+
+// ============================================================================
+// SMTP Sender
+// ============================================================================
+
+// SMTPSender sends email through an SMTP server using PLAIN auth
+type SMTPSender struct {
+	host     string
+	port     string
+	user     string
+	password string
+	from     string
+}
+
+// Send delivers the message via SMTP
+func (s *SMTPSender) Send(msg Message) error {
+	auth := smtp.PlainAuth("", s.user, s.password, s.host)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	addr := s.host + ":" + s.port
+	if err := smtp.SendMail(addr, auth, s.from, []string{msg.To}, []byte(b.String())); err != nil {
+		return fmt.Errorf("smtp send to %s failed: %w", msg.To, err)
+	}
+	return nil
+}
+
+// Enabled always returns true for SMTP
+func (s *SMTPSender) Enabled() bool {
+	return true
+}
+
+// ============================================================================
+// Noop Sender
+// ============================================================================
+
+// NoopSender logs messages instead of sending them (used when SMTP is not configured)
+type NoopSender struct{}
+
+// Send logs that the message was not delivered
+func (s *NoopSender) Send(msg Message) error {
+	log.Printf("SMTP not configured: email %q to %s was not sent", msg.Subject, msg.To)
+	return nil
+}
+
+// Enabled always returns false
+func (s *NoopSender) Enabled() bool {
+	return false
+}
+
+// ============================================================================
+// Templates
+// ============================================================================
+
+// VerificationEmail builds the email-verification message linking to the frontend
+func VerificationEmail(frontendURL, to, name, token string) Message {
+	link := frontendLink(frontendURL, "/verify-email", token)
+	return Message{
+		To:      to,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(`Hi %s,
+
+Thanks for signing up. Please verify your email address by opening the link below:
+
+%s
+
+This link expires in 24 hours. If you didn't create an account, you can ignore this email.
+`, greetingName(name, to), link),
+	}
+}
+
+// PasswordResetEmail builds the password-reset message linking to the frontend
+func PasswordResetEmail(frontendURL, to, name, token string) Message {
+	link := frontendLink(frontendURL, "/reset-password", token)
+	return Message{
+		To:      to,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(`Hi %s,
+
+We received a request to reset your password. Open the link below to choose a new one:
+
+%s
+
+This link expires in 1 hour. If you didn't request a reset, you can ignore this email.
+`, greetingName(name, to), link),
+	}
+}
+
+// InvitationEmail builds the workspace invitation message linking to the
+// frontend
+func InvitationEmail(frontendURL, to, inviterName, workspaceName, token string) Message {
+	link := frontendLink(frontendURL, "/accept-invitation", token)
+	return Message{
+		To:      to,
+		Subject: fmt.Sprintf("You've been invited to %s", workspaceName),
+		Body: fmt.Sprintf(`Hi,
+
+%s invited you to join the %s workspace. Open the link below to accept, signing in or creating an account with this email address:
+
+%s
+
+This invitation expires in 7 days. If you weren't expecting it, you can ignore this email.
+`, inviterName, workspaceName, link),
+	}
+}
+
+func frontendLink(frontendURL, path, token string) string {
+	return strings.TrimSuffix(frontendURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func greetingName(name, email string) string {
+	if name != "" {
+		return name
+	}
+	return email
+}
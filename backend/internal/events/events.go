@@ -0,0 +1,198 @@
+package events
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/config"
+)
+
+// Event types
+const (
+	MemberAdded      = "member.added"
+	WorkspaceCreated = "workspace.created"
+	ContainerCreated = "container.created"
+	DocumentCreated  = "document.created"
+	DocumentShared   = "document.shared"
+)
+
+// Event is the JSON payload delivered to the webhook
+type Event struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Emitter publishes events to integrators
+type Emitter interface {
+	// Emit queues an event; it never blocks the caller on delivery
+	Emit(eventType string, data map[string]interface{})
+}
+
+var (
+	webhooksMu sync.Mutex
+	webhooks   = make(map[*config.Config]*WebhookEmitter)
+)
+
+// NewEmitter returns a webhook emitter when EVENT_WEBHOOK_URL is set,
+// otherwise a NoopEmitter. Handlers built from the same config share one
+// webhook emitter, so events are delivered in the order they were emitted.
+func NewEmitter(cfg *config.Config) Emitter {
+	if !cfg.HasEventWebhook() {
+		return NoopEmitter{}
+	}
+
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+
+	w, ok := webhooks[cfg]
+	if !ok {
+		w = NewWebhookEmitter(cfg.EventWebhookURL, []byte(cfg.EventWebhookSecret), cfg.EventWebhookMaxAttempts)
+		webhooks[cfg] = w
+	}
+	return w
+}
+
+// ============================================================================
+// Webhook Emitter
+// ============================================================================
+
+const (
+	// webhookQueueSize is how many events can wait for delivery; events
+	// emitted while the queue is full are dropped
+	webhookQueueSize = 1000
+
+	// webhookRetryDelay is the wait before the first retry, doubled before
+	// each one after
+	webhookRetryDelay = time.Second
+)
+
+// WebhookEmitter POSTs events as JSON to a URL from a background worker, one
+// at a time in the order they were emitted. When a secret is set each
+// request is signed with HMAC-SHA256 of the body in the X-Webhook-Signature
+// header ("sha256=<hex>"). Connection errors, 429s and 5xx responses are
+// retried with exponential backoff; other responses are final.
+type WebhookEmitter struct {
+	url         string
+	secret      []byte
+	maxAttempts int
+	client      *http.Client
+	queue       chan Event
+}
+
+// NewWebhookEmitter starts a webhook emitter delivering to url
+func NewWebhookEmitter(url string, secret []byte, maxAttempts int) *WebhookEmitter {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	w := &WebhookEmitter{
+		url:         url,
+		secret:      secret,
+		maxAttempts: maxAttempts,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		queue: make(chan Event, webhookQueueSize),
+	}
+	go w.run()
+	return w
+}
+
+// Emit queues the event for delivery
+func (w *WebhookEmitter) Emit(eventType string, data map[string]interface{}) {
+	event := Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+
+	select {
+	case w.queue <- event:
+	default:
+		log.Printf("Event webhook queue full: dropped %s event %s", event.Type, event.ID)
+	}
+}
+
+func (w *WebhookEmitter) run() {
+	for event := range w.queue {
+		if err := w.deliver(event); err != nil {
+			log.Printf("Failed to deliver %s event %s: %v", event.Type, event.ID, err)
+		}
+	}
+}
+
+// deliver sends the event, retrying transient failures
+func (w *WebhookEmitter) deliver(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := w.send(event, body)
+		if err == nil || !retry || attempt >= w.maxAttempts {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// send makes one delivery attempt and reports whether a failure is worth
+// retrying
+func (w *WebhookEmitter) send(event Event, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", event.Type)
+	req.Header.Set("X-Webhook-ID", event.ID)
+	if len(w.secret) > 0 {
+		req.Header.Set("X-Webhook-Signature", Sign(w.secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("webhook delivery failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("webhook returned %s - %s", resp.Status, string(respBody))
+	}
+	return false, nil
+}
+
+// Sign returns the X-Webhook-Signature value for body. Receivers recompute
+// it over the raw request body and compare in constant time.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ============================================================================
+// Noop Emitter
+// ============================================================================
+
+// NoopEmitter discards events (used when no webhook is configured)
+type NoopEmitter struct{}
+
+// Emit does nothing
+func (NoopEmitter) Emit(string, map[string]interface{}) {}
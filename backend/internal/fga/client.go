@@ -0,0 +1,124 @@
+package fga
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TupleKey is an OpenFGA relationship tuple
+type TupleKey struct {
+	User     string `json:"user"`
+	Relation string `json:"relation"`
+	Object   string `json:"object"`
+}
+
+// Client writes relationship tuples to OpenFGA. Checks are done by the
+// authz gate; the backend only keeps the tuples in sync with its data.
+type Client struct {
+	baseURL string
+	storeID string
+	client  *http.Client
+}
+
+// NewClient creates an OpenFGA client. It returns nil when no store is
+// configured; a nil *Client ignores all writes.
+func NewClient(baseURL, storeID string) *Client {
+	if baseURL == "" || storeID == "" {
+		return nil
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		storeID: storeID,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+// Tuple builds a tuple for a user on an object, e.g.
+// Tuple("user", userID, "owner", "document", docID)
+func Tuple(userType, userID, relation, objectType, objectID string) TupleKey {
+	return TupleKey{
+		User:     userType + ":" + userID,
+		Relation: relation,
+		Object:   objectType + ":" + objectID,
+	}
+}
+
+// Write adds and removes tuples in one transaction
+func (c *Client) Write(ctx context.Context, writes, deletes []TupleKey) error {
+	return c.write(ctx, writes, deletes, false)
+}
+
+// Delete removes tuples, skipping any that don't exist rather than failing
+// the whole write
+func (c *Client) Delete(ctx context.Context, deletes []TupleKey) error {
+	return c.write(ctx, nil, deletes, true)
+}
+
+func (c *Client) write(ctx context.Context, writes, deletes []TupleKey, ignoreMissing bool) error {
+	if c == nil || (len(writes) == 0 && len(deletes) == 0) {
+		return nil
+	}
+
+	reqBody := map[string]interface{}{}
+	if len(writes) > 0 {
+		reqBody["writes"] = map[string]interface{}{"tuple_keys": writes}
+	}
+	if len(deletes) > 0 {
+		d := map[string]interface{}{"tuple_keys": deletes}
+		if ignoreMissing {
+			d["on_missing"] = "ignore"
+		}
+		reqBody["deletes"] = d
+	}
+
+	body, _ := json.Marshal(reqBody)
+	url := fmt.Sprintf("%s/stores/%s/write", c.baseURL, c.storeID)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("write failed: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
+
+// Ready checks that OpenFGA is reachable and the store exists
+func (c *Client) Ready(ctx context.Context) error {
+	url := fmt.Sprintf("%s/stores/%s", c.baseURL, c.storeID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("get store failed: %s - %s", resp.Status, string(body))
+	}
+
+	return nil
+}
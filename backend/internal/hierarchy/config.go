@@ -2,17 +2,28 @@ package hierarchy
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 )
 
 // Level represents a single level in the hierarchy
 type Level struct {
-	Name        string   `json:"name"`         // Internal name (e.g., "workspace", "project")
-	DisplayName string   `json:"display_name"` // UI display name (e.g., "Workspace", "Project")
-	Plural      string   `json:"plural"`       // Plural form (e.g., "workspaces", "projects")
-	URLPath     string   `json:"url_path"`     // API path segment (e.g., "workspaces", "projects")
-	Roles       []string `json:"roles"`        // Available roles at this level
-	IsRoot      bool     `json:"is_root"`      // Is this the root level (tenant)?
+	Name        string `json:"name"`         // Internal name (e.g., "workspace", "project")
+	DisplayName string `json:"display_name"` // UI display name (e.g., "Workspace", "Project")
+	Plural      string `json:"plural"`       // Plural form (e.g., "workspaces", "projects")
+	URLPath     string `json:"url_path"`     // API path segment (e.g., "workspaces", "projects")
+	Roles       []Role `json:"roles"`        // Available roles at this level
+	IsRoot      bool   `json:"is_root"`      // Is this the root level (tenant)?
+
+	// Inherits lists, per role, the roles whose permissions it also has,
+	// e.g. {"admin": ["editor"], "editor": ["member"]}. Roles not listed
+	// fall back to the built-in admin > member > viewer chain.
+	Inherits map[Role][]Role `json:"inherits,omitempty"`
+
+	// Permissions lists, per role, the permissions it grants directly.
+	// Roles not listed fall back to the built-in grants.
+	Permissions map[Role][]Permission `json:"permissions,omitempty"`
 }
 
 // Config defines the complete hierarchy configuration
@@ -39,7 +50,7 @@ func DefaultConfig() *Config {
 				DisplayName: "Organization",
 				Plural:      "organizations",
 				URLPath:     "tenant",
-				Roles:       []string{"admin", "member"},
+				Roles:       []Role{RoleAdmin, RoleMember},
 				IsRoot:      true,
 			},
 			{
@@ -47,7 +58,7 @@ func DefaultConfig() *Config {
 				DisplayName: "Workspace",
 				Plural:      "workspaces",
 				URLPath:     "workspaces",
-				Roles:       []string{"admin", "member", "viewer"},
+				Roles:       []Role{RoleAdmin, RoleMember, RoleViewer},
 				IsRoot:      false,
 			},
 		},
@@ -65,7 +76,7 @@ func MLPlatformConfig() *Config {
 				DisplayName: "Organization",
 				Plural:      "organizations",
 				URLPath:     "tenant",
-				Roles:       []string{"admin", "member"},
+				Roles:       []Role{RoleAdmin, RoleMember},
 				IsRoot:      true,
 			},
 			{
@@ -73,7 +84,7 @@ func MLPlatformConfig() *Config {
 				DisplayName: "Team",
 				Plural:      "teams",
 				URLPath:     "teams",
-				Roles:       []string{"admin", "member"},
+				Roles:       []Role{RoleAdmin, RoleMember},
 				IsRoot:      false,
 			},
 			{
@@ -81,7 +92,7 @@ func MLPlatformConfig() *Config {
 				DisplayName: "Project",
 				Plural:      "projects",
 				URLPath:     "projects",
-				Roles:       []string{"admin", "member", "viewer"},
+				Roles:       []Role{RoleAdmin, RoleMember, RoleViewer},
 				IsRoot:      false,
 			},
 		},
@@ -99,7 +110,7 @@ func DevOpsConfig() *Config {
 				DisplayName: "Organization",
 				Plural:      "organizations",
 				URLPath:     "tenant",
-				Roles:       []string{"admin", "member"},
+				Roles:       []Role{RoleAdmin, RoleMember},
 				IsRoot:      true,
 			},
 			{
@@ -107,7 +118,7 @@ func DevOpsConfig() *Config {
 				DisplayName: "Environment",
 				Plural:      "environments",
 				URLPath:     "environments",
-				Roles:       []string{"admin", "operator"},
+				Roles:       []Role{RoleAdmin, RoleOperator},
 				IsRoot:      false,
 			},
 			{
@@ -115,7 +126,7 @@ func DevOpsConfig() *Config {
 				DisplayName: "Service",
 				Plural:      "services",
 				URLPath:     "services",
-				Roles:       []string{"admin", "developer", "viewer"},
+				Roles:       []Role{RoleAdmin, RoleDeveloper, RoleViewer},
 				IsRoot:      false,
 			},
 		},
@@ -158,6 +169,54 @@ func LoadFromEnv() *Config {
 	}
 }
 
+// reservedURLPaths are /api/v1 segments served regardless of the hierarchy,
+// which no level may take
+var reservedURLPaths = map[string]bool{
+	"auth": true, "admin": true, "hierarchy": true, "me": true, "webhooks": true,
+}
+
+// Validate checks that the levels can be served: a root level first, unique
+// names and URL paths, and root_level/leaf_level naming configured levels
+func (c *Config) Validate() error {
+	if len(c.Levels) == 0 {
+		return fmt.Errorf("at least one level is required")
+	}
+	if !c.Levels[0].IsRoot {
+		return fmt.Errorf("the first level must be the root")
+	}
+
+	names := make(map[string]bool)
+	paths := make(map[string]bool)
+	for i, level := range c.Levels {
+		if level.Name == "" || level.URLPath == "" {
+			return fmt.Errorf("level %d: name and url_path are required", i)
+		}
+		if i > 0 && level.IsRoot {
+			return fmt.Errorf("level %s: only the first level can be the root", level.Name)
+		}
+		if strings.Contains(level.URLPath, "/") || reservedURLPaths[level.URLPath] {
+			return fmt.Errorf("level %s: url_path %q is not allowed", level.Name, level.URLPath)
+		}
+		if names[level.Name] || paths[level.URLPath] {
+			return fmt.Errorf("level %s: duplicate name or url_path", level.Name)
+		}
+		names[level.Name] = true
+		paths[level.URLPath] = true
+
+		if err := c.Levels[i].validateRoles(); err != nil {
+			return fmt.Errorf("level %s: %w", level.Name, err)
+		}
+	}
+
+	if c.RootLevel != c.Levels[0].Name {
+		return fmt.Errorf("root_level %q must name the first level", c.RootLevel)
+	}
+	if !names[c.LeafLevel] {
+		return fmt.Errorf("leaf_level %q is not a configured level", c.LeafLevel)
+	}
+	return nil
+}
+
 // GetLevel returns a level by name
 func (c *Config) GetLevel(name string) *Level {
 	for i := range c.Levels {
@@ -1,12 +1,32 @@
 package hierarchy
 
 import (
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrParentDeleted is returned when restoring a container whose parent
+	// is still soft-deleted
+	ErrParentDeleted = errors.New("parent container is deleted")
+
+	// ErrMoveIntoDescendant is returned when moving a container under
+	// itself or one of its descendants
+	ErrMoveIntoDescendant = errors.New("cannot move a container under itself or its descendants")
+
+	// ErrInvalidParentLevel is returned when a container is moved under a
+	// parent that is not at the level directly above it
+	ErrInvalidParentLevel = errors.New("new parent is not at the parent level")
+
+	// ErrLastAdmin is returned when a change would leave a container
+	// without an admin
+	ErrLastAdmin = errors.New("container must keep at least one admin")
+)
+
 // ResourceContainer represents a generic container at any level of the hierarchy
 // This unified model replaces separate Tenant/Workspace models
 type ResourceContainer struct {
@@ -23,6 +43,9 @@ type ResourceContainer struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 
+	// Soft delete; see SoftDeleteContainer
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
 	// Relationships
 	Parent   *ResourceContainer  `gorm:"foreignKey:ParentID" json:"-"`
 	Children []ResourceContainer `gorm:"foreignKey:ParentID" json:"-"`
@@ -42,6 +65,8 @@ type ContainerMembership struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
 	// Relationships
 	User      User              `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
 	Container ResourceContainer `gorm:"foreignKey:ContainerID;constraint:OnDelete:CASCADE" json:"-"`
@@ -149,10 +174,21 @@ func (r *Repository) CreateContainer(level, slug, displayName string, parentID *
 	return container, nil
 }
 
-// GetContainer retrieves a container by ID
+// GetContainer retrieves a container by ID. Soft-deleted containers are
+// not returned; use GetContainerIncludeDeleted for those.
 func (r *Repository) GetContainer(id uuid.UUID) (*ResourceContainer, error) {
+	return r.getContainer(r.db, id)
+}
+
+// GetContainerIncludeDeleted retrieves a container by ID, including a
+// soft-deleted one
+func (r *Repository) GetContainerIncludeDeleted(id uuid.UUID) (*ResourceContainer, error) {
+	return r.getContainer(r.db.Unscoped(), id)
+}
+
+func (r *Repository) getContainer(db *gorm.DB, id uuid.UUID) (*ResourceContainer, error) {
 	var container ResourceContainer
-	if err := r.db.First(&container, "id = ?", id).Error; err != nil {
+	if err := db.First(&container, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &container, nil
@@ -173,10 +209,21 @@ func (r *Repository) GetContainerBySlug(level, slug string, parentID *uuid.UUID)
 	return &container, nil
 }
 
-// ListChildren lists all direct children of a container
+// ListChildren lists all direct children of a container, excluding
+// soft-deleted ones
 func (r *Repository) ListChildren(parentID uuid.UUID, level string) ([]ResourceContainer, error) {
+	return r.listChildren(r.db, parentID, level)
+}
+
+// ListChildrenIncludeDeleted lists all direct children of a container,
+// including soft-deleted ones
+func (r *Repository) ListChildrenIncludeDeleted(parentID uuid.UUID, level string) ([]ResourceContainer, error) {
+	return r.listChildren(r.db.Unscoped(), parentID, level)
+}
+
+func (r *Repository) listChildren(db *gorm.DB, parentID uuid.UUID, level string) ([]ResourceContainer, error) {
 	var containers []ResourceContainer
-	query := r.db.Where("parent_id = ?", parentID)
+	query := db.Where("parent_id = ?", parentID)
 	if level != "" {
 		query = query.Where("level = ?", level)
 	}
@@ -186,10 +233,21 @@ func (r *Repository) ListChildren(parentID uuid.UUID, level string) ([]ResourceC
 	return containers, nil
 }
 
-// ListByRoot lists all containers under a root tenant
+// ListByRoot lists all containers under a root tenant, excluding
+// soft-deleted ones
 func (r *Repository) ListByRoot(rootID uuid.UUID, level string) ([]ResourceContainer, error) {
+	return r.listByRoot(r.db, rootID, level)
+}
+
+// ListByRootIncludeDeleted lists all containers under a root tenant,
+// including soft-deleted ones
+func (r *Repository) ListByRootIncludeDeleted(rootID uuid.UUID, level string) ([]ResourceContainer, error) {
+	return r.listByRoot(r.db.Unscoped(), rootID, level)
+}
+
+func (r *Repository) listByRoot(db *gorm.DB, rootID uuid.UUID, level string) ([]ResourceContainer, error) {
 	var containers []ResourceContainer
-	query := r.db.Where("root_id = ?", rootID)
+	query := db.Where("root_id = ?", rootID)
 	if level != "" {
 		query = query.Where("level = ?", level)
 	}
@@ -220,12 +278,159 @@ func (r *Repository) GetAncestors(containerID uuid.UUID) ([]ResourceContainer, e
 	return ancestors, nil
 }
 
+// UpdateContainer saves changes to a container's mutable fields
+// (DisplayName, Slug, Metadata, IsActive). Structural fields such as
+// ParentID and Path are never changed here.
+func (r *Repository) UpdateContainer(container *ResourceContainer) error {
+	return r.db.Model(container).
+		Select("DisplayName", "Slug", "Metadata", "IsActive").
+		Updates(container).Error
+}
+
+// MoveContainer reparents a container under newParentID. The container's
+// Depth, Path and, when the new parent is in another root, RootID are
+// recomputed along with those of all its descendants (soft-deleted ones
+// included, so they restore correctly).
+func (r *Repository) MoveContainer(id, newParentID uuid.UUID) (*ResourceContainer, error) {
+	container, err := r.GetContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	newParent, err := r.GetContainer(newParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if newParent.ID == container.ID || strings.HasPrefix(newParent.Path, container.Path+"/") {
+		return nil, ErrMoveIntoDescendant
+	}
+	if r.config != nil {
+		if parentLevel := r.config.GetParentLevel(container.Level); parentLevel == nil || parentLevel.Name != newParent.Level {
+			return nil, ErrInvalidParentLevel
+		}
+	}
+	if container.ParentID != nil && *container.ParentID == newParent.ID {
+		return container, nil
+	}
+
+	oldPath := container.Path
+	newPath := newParent.Path + "/" + container.ID.String()
+	depthDelta := newParent.Depth + 1 - container.Depth
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped().Session(&gorm.Session{})
+
+		// Rewrite the path prefix of every descendant
+		if err := tx.Model(&ResourceContainer{}).
+			Where("path LIKE ?", oldPath+"/%").
+			Updates(map[string]interface{}{
+				"path":    gorm.Expr("? || substr(path, ?)", newPath, len(oldPath)+1),
+				"depth":   gorm.Expr("depth + ?", depthDelta),
+				"root_id": newParent.RootID,
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&ResourceContainer{}).
+			Where("id = ?", container.ID).
+			Updates(map[string]interface{}{
+				"parent_id": newParent.ID,
+				"path":      newPath,
+				"depth":     newParent.Depth + 1,
+				"root_id":   newParent.RootID,
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetContainer(id)
+}
+
+// SoftDeleteContainer marks a container, its descendants and their
+// memberships as deleted. Everything is stamped with the same deleted_at so
+// RestoreContainer can bring back exactly what this removed.
+func (r *Repository) SoftDeleteContainer(id uuid.UUID) error {
+	container, err := r.GetContainer(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		subtree := tx.Model(&ResourceContainer{}).Select("id").
+			Where("id = ? OR path LIKE ?", container.ID, container.Path+"/%")
+
+		if err := tx.Model(&ContainerMembership{}).
+			Where("container_id IN (?)", subtree).
+			Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ResourceContainer{}).
+			Where("id = ? OR path LIKE ?", container.ID, container.Path+"/%").
+			Update("deleted_at", now).Error
+	})
+}
+
+// RestoreContainer undoes SoftDeleteContainer. Descendants and memberships
+// deleted separately (at a different time) stay deleted. The parent must
+// not itself be deleted.
+func (r *Repository) RestoreContainer(id uuid.UUID) error {
+	container, err := r.GetContainerIncludeDeleted(id)
+	if err != nil {
+		return err
+	}
+	if !container.DeletedAt.Valid {
+		return nil
+	}
+	if container.ParentID != nil {
+		if _, err := r.GetContainer(*container.ParentID); err != nil {
+			return ErrParentDeleted
+		}
+	}
+
+	deletedAt := container.DeletedAt.Time
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped().Session(&gorm.Session{})
+		subtree := tx.Model(&ResourceContainer{}).Select("id").
+			Where("(id = ? OR path LIKE ?) AND deleted_at = ?", container.ID, container.Path+"/%", deletedAt)
+
+		if err := tx.Model(&ContainerMembership{}).
+			Where("container_id IN (?) AND deleted_at = ?", subtree, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ResourceContainer{}).
+			Where("(id = ? OR path LIKE ?) AND deleted_at = ?", container.ID, container.Path+"/%", deletedAt).
+			Update("deleted_at", nil).Error
+	})
+}
+
+// PurgeDeleted permanently removes containers and memberships that were
+// soft-deleted more than olderThanDays days ago. It returns the number of
+// containers removed.
+func (r *Repository) PurgeDeleted(olderThanDays int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -olderThanDays)
+
+	var purged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped().Session(&gorm.Session{})
+		if err := tx.Where("deleted_at < ?", cutoff).Delete(&ContainerMembership{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("deleted_at < ?", cutoff).Delete(&ResourceContainer{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
 // AddMember adds a user to a container with a role
-func (r *Repository) AddMember(userID, containerID uuid.UUID, role string) error {
+func (r *Repository) AddMember(userID, containerID uuid.UUID, role Role) error {
 	membership := &ContainerMembership{
 		UserID:      userID,
 		ContainerID: containerID,
-		Role:        role,
+		Role:        string(role),
 	}
 	return r.db.Create(membership).Error
 }
@@ -239,6 +444,109 @@ func (r *Repository) GetMembership(userID, containerID uuid.UUID) (*ContainerMem
 	return &membership, nil
 }
 
+// EffectivePermissions resolves what a user may do in a container: the
+// permissions of their role there, expanded through the level's role
+// inheritance, plus admin permissions if they hold a role granting
+// PermissionManage on any ancestor. Users without access get an empty set.
+func (r *Repository) EffectivePermissions(userID, containerID uuid.UUID) (PermissionSet, error) {
+	container, err := r.GetContainer(containerID)
+	if err != nil {
+		return nil, err
+	}
+	ancestors, err := r.GetAncestors(containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := map[uuid.UUID]string{container.ID: container.Level}
+	ids := []uuid.UUID{container.ID}
+	for _, a := range ancestors {
+		levels[a.ID] = a.Level
+		ids = append(ids, a.ID)
+	}
+
+	var memberships []ContainerMembership
+	if err := r.db.Where("user_id = ? AND container_id IN ?", userID, ids).Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	permissions := make(PermissionSet)
+	level := r.config.GetLevel(container.Level)
+	if level == nil {
+		return permissions, nil
+	}
+	grant := func(role Role) {
+		for p := range level.PermissionsFor(role) {
+			permissions[p] = true
+		}
+	}
+	for _, m := range memberships {
+		if m.ContainerID == container.ID {
+			grant(Role(m.Role))
+			continue
+		}
+		if ancestorLevel := r.config.GetLevel(levels[m.ContainerID]); ancestorLevel != nil &&
+			ancestorLevel.PermissionsFor(Role(m.Role)).Has(PermissionManage) {
+			grant(RoleAdmin)
+		}
+	}
+	return permissions, nil
+}
+
+// UpdateMemberRole changes a member's role. Demoting the container's last
+// admin fails with ErrLastAdmin.
+func (r *Repository) UpdateMemberRole(userID, containerID uuid.UUID, role Role) (*ContainerMembership, error) {
+	var membership ContainerMembership
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND container_id = ?", userID, containerID).First(&membership).Error; err != nil {
+			return err
+		}
+		if Role(membership.Role) == RoleAdmin && role != RoleAdmin {
+			if err := ensureOtherAdmin(tx, containerID); err != nil {
+				return err
+			}
+		}
+		membership.Role = string(role)
+		return tx.Model(&membership).Update("role", membership.Role).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// RemoveMember removes a user from a container. Removing the container's
+// last admin fails with ErrLastAdmin.
+func (r *Repository) RemoveMember(userID, containerID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var membership ContainerMembership
+		if err := tx.Where("user_id = ? AND container_id = ?", userID, containerID).First(&membership).Error; err != nil {
+			return err
+		}
+		if Role(membership.Role) == RoleAdmin {
+			if err := ensureOtherAdmin(tx, containerID); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&membership).Error
+	})
+}
+
+// ensureOtherAdmin returns ErrLastAdmin unless the container has more than
+// one admin
+func ensureOtherAdmin(tx *gorm.DB, containerID uuid.UUID) error {
+	var admins int64
+	if err := tx.Model(&ContainerMembership{}).
+		Where("container_id = ? AND role = ?", containerID, RoleAdmin).
+		Count(&admins).Error; err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
 // ListMembers lists all members of a container
 func (r *Repository) ListMembers(containerID uuid.UUID) ([]ContainerMembership, error) {
 	var memberships []ContainerMembership
@@ -248,6 +556,45 @@ func (r *Repository) ListMembers(containerID uuid.UUID) ([]ContainerMembership,
 	return memberships, nil
 }
 
+// ListUserMemberships lists a page of a user's memberships in containers
+// that aren't deleted, with Container loaded, oldest first, and the total
+// number of such memberships
+func (r *Repository) ListUserMemberships(userID uuid.UUID, limit, offset int) ([]ContainerMembership, int64, error) {
+	active := func() *gorm.DB {
+		return r.db.Model(&ContainerMembership{}).
+			Joins("JOIN resource_containers rc ON rc.id = container_memberships.container_id AND rc.deleted_at IS NULL").
+			Where("container_memberships.user_id = ?", userID)
+	}
+
+	var total int64
+	if err := active().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var memberships []ContainerMembership
+	err := active().Preload("Container").
+		Order("container_memberships.created_at ASC, container_memberships.id ASC").
+		Limit(limit).Offset(offset).
+		Find(&memberships).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return memberships, total, nil
+}
+
+// GetContainers retrieves the containers with the given IDs that aren't
+// deleted
+func (r *Repository) GetContainers(ids []uuid.UUID) ([]ResourceContainer, error) {
+	var containers []ResourceContainer
+	if len(ids) == 0 {
+		return containers, nil
+	}
+	if err := r.db.Where("id IN ?", ids).Find(&containers).Error; err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
 // GetUserContainers lists all containers a user has access to at a given level
 func (r *Repository) GetUserContainers(userID uuid.UUID, level string) ([]ResourceContainer, error) {
 	var containers []ResourceContainer
@@ -255,6 +602,7 @@ func (r *Repository) GetUserContainers(userID uuid.UUID, level string) ([]Resour
 		SELECT DISTINCT rc.* FROM resource_containers rc
 		JOIN container_memberships cm ON rc.id = cm.container_id
 		WHERE cm.user_id = ? AND rc.level = ?
+		  AND rc.deleted_at IS NULL AND cm.deleted_at IS NULL
 		ORDER BY rc.created_at ASC
 	`
 	if err := r.db.Raw(query, userID, level).Scan(&containers).Error; err != nil {
@@ -263,6 +611,38 @@ func (r *Repository) GetUserContainers(userID uuid.UUID, level string) ([]Resour
 	return containers, nil
 }
 
+// GetUserContainersWithInheritance lists the containers at a level that a
+// user can access: those they are a member of, plus every descendant of a
+// container they are an admin of (e.g. all workspaces of a tenant they
+// administer). Descendants are found by materialized path within the same
+// root.
+func (r *Repository) GetUserContainersWithInheritance(userID uuid.UUID, level string) ([]ResourceContainer, error) {
+	var containers []ResourceContainer
+	query := `
+		SELECT rc.* FROM resource_containers rc
+		WHERE rc.level = ? AND rc.deleted_at IS NULL
+		  AND (
+			EXISTS (
+				SELECT 1 FROM container_memberships cm
+				WHERE cm.container_id = rc.id AND cm.user_id = ? AND cm.deleted_at IS NULL
+			)
+			OR EXISTS (
+				SELECT 1 FROM container_memberships cm
+				JOIN resource_containers anc ON anc.id = cm.container_id
+				WHERE cm.user_id = ? AND cm.role = ? AND cm.deleted_at IS NULL
+				  AND anc.deleted_at IS NULL
+				  AND anc.root_id = rc.root_id
+				  AND rc.path LIKE anc.path || '/%'
+			)
+		  )
+		ORDER BY rc.created_at ASC
+	`
+	if err := r.db.Raw(query, level, userID, userID, string(RoleAdmin)).Scan(&containers).Error; err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
 // AutoMigrate runs database migrations for hierarchy models
 func AutoMigrate(db *gorm.DB) error {
 	return db.AutoMigrate(
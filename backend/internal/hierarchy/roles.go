@@ -0,0 +1,212 @@
+package hierarchy
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Role is a membership role at a hierarchy level
+type Role string
+
+const (
+	RoleAdmin     Role = "admin"
+	RoleOperator  Role = "operator"
+	RoleDeveloper Role = "developer"
+	RoleMember    Role = "member"
+	RoleViewer    Role = "viewer"
+)
+
+// DefaultRole is assigned when a member is added without a role
+const DefaultRole = RoleMember
+
+// roleRanks orders roles by privilege. Level-specific contributor roles
+// (operator, developer) rank with member.
+var roleRanks = map[Role]int{
+	RoleAdmin:     3,
+	RoleOperator:  2,
+	RoleDeveloper: 2,
+	RoleMember:    2,
+	RoleViewer:    1,
+}
+
+// RolePrivilegeRank returns the role's privilege rank; higher is more
+// privileged and unknown roles rank 0
+func RolePrivilegeRank(role Role) int {
+	return roleRanks[role]
+}
+
+// AtLeast reports whether r is at least as privileged as min
+func (r Role) AtLeast(min Role) bool {
+	return RolePrivilegeRank(r) >= RolePrivilegeRank(min) && RolePrivilegeRank(r) > 0
+}
+
+// Outranks reports whether r is strictly more privileged than other
+func (r Role) Outranks(other Role) bool {
+	return RolePrivilegeRank(r) > RolePrivilegeRank(other)
+}
+
+// IsValidRole reports whether role is defined at this level
+func (l *Level) IsValidRole(role Role) bool {
+	for _, r := range l.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRole reports whether role is defined at the named level
+func (c *Config) IsValidRole(level string, role Role) bool {
+	l := c.GetLevel(level)
+	return l != nil && l.IsValidRole(role)
+}
+
+// Permission is a capability a role grants in a container
+type Permission string
+
+const (
+	PermissionRead          Permission = "read"           // view the container and its resources
+	PermissionWrite         Permission = "write"          // create and edit resources
+	PermissionManageMembers Permission = "manage_members" // invite, change and remove members
+	PermissionManage        Permission = "manage"         // settings, production changes, deletion
+)
+
+var knownPermissions = map[Permission]bool{
+	PermissionRead:          true,
+	PermissionWrite:         true,
+	PermissionManageMembers: true,
+	PermissionManage:        true,
+}
+
+// defaultInherits is the built-in chain admin > member > viewer.
+// Level-specific contributor roles (operator, developer) sit with member.
+var defaultInherits = map[Role][]Role{
+	RoleAdmin:     {RoleMember, RoleOperator, RoleDeveloper},
+	RoleOperator:  {RoleViewer},
+	RoleDeveloper: {RoleViewer},
+	RoleMember:    {RoleViewer},
+}
+
+// defaultPermissions are the permissions each built-in role grants itself,
+// before inheritance
+var defaultPermissions = map[Role][]Permission{
+	RoleAdmin:     {PermissionManageMembers, PermissionManage},
+	RoleOperator:  {PermissionWrite},
+	RoleDeveloper: {PermissionWrite},
+	RoleMember:    {PermissionWrite},
+	RoleViewer:    {PermissionRead},
+}
+
+// PermissionSet is a set of permissions
+type PermissionSet map[Permission]bool
+
+// Has reports whether p is in the set
+func (s PermissionSet) Has(p Permission) bool {
+	return s[p]
+}
+
+// List returns the permissions in the set, sorted
+func (s PermissionSet) List() []Permission {
+	list := make([]Permission, 0, len(s))
+	for p := range s {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// inherits returns the roles whose permissions role also has at this level
+func (l *Level) inherits(role Role) []Role {
+	if roles, ok := l.Inherits[role]; ok {
+		return roles
+	}
+	return defaultInherits[role]
+}
+
+func (l *Level) grants(role Role) []Permission {
+	if permissions, ok := l.Permissions[role]; ok {
+		return permissions
+	}
+	return defaultPermissions[role]
+}
+
+// PermissionsFor expands role into every permission it grants at this
+// level, including those of the roles it inherits. Unknown or empty roles
+// grant nothing.
+func (l *Level) PermissionsFor(role Role) PermissionSet {
+	set := make(PermissionSet)
+	if role == "" {
+		return set
+	}
+
+	seen := make(map[Role]bool)
+	queue := []Role{role}
+	for len(queue) > 0 {
+		r := queue[0]
+		queue = queue[1:]
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		for _, p := range l.grants(r) {
+			set[p] = true
+		}
+		queue = append(queue, l.inherits(r)...)
+	}
+	return set
+}
+
+// validateRoles checks that Inherits and Permissions only refer to the
+// level's roles and known permissions, and that inheritance has no cycles
+func (l *Level) validateRoles() error {
+	for role, inherited := range l.Inherits {
+		if !l.IsValidRole(role) {
+			return fmt.Errorf("inherits: unknown role %q", role)
+		}
+		for _, r := range inherited {
+			if !l.IsValidRole(r) {
+				return fmt.Errorf("inherits: role %q inherits unknown role %q", role, r)
+			}
+		}
+	}
+	for role, permissions := range l.Permissions {
+		if !l.IsValidRole(role) {
+			return fmt.Errorf("permissions: unknown role %q", role)
+		}
+		for _, p := range permissions {
+			if !knownPermissions[p] {
+				return fmt.Errorf("permissions: role %q grants unknown permission %q", role, p)
+			}
+		}
+	}
+
+	// Depth-first search for a role that reaches itself
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[Role]int)
+	var visit func(Role) error
+	visit = func(r Role) error {
+		switch state[r] {
+		case visiting:
+			return fmt.Errorf("inherits: cycle through role %q", r)
+		case done:
+			return nil
+		}
+		state[r] = visiting
+		for _, next := range l.inherits(r) {
+			if err := visit(next); err != nil {
+				return err
+			}
+		}
+		state[r] = done
+		return nil
+	}
+	for _, role := range l.Roles {
+		if err := visit(role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package hierarchy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoleRanks(t *testing.T) {
+	tests := []struct {
+		role, other            Role
+		wantAtLeast, wantOuter bool
+	}{
+		{RoleAdmin, RoleMember, true, true},
+		{RoleAdmin, RoleAdmin, true, false},
+		{RoleMember, RoleAdmin, false, false},
+		{RoleMember, RoleViewer, true, true},
+		{RoleOperator, RoleMember, true, false},
+		{RoleDeveloper, RoleViewer, true, true},
+		{RoleViewer, RoleMember, false, false},
+		{"owner", RoleViewer, false, false},
+		{"owner", "", false, false},
+		{RoleViewer, "owner", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.other), func(t *testing.T) {
+			if got := tt.role.AtLeast(tt.other); got != tt.wantAtLeast {
+				t.Errorf("AtLeast() = %v, want %v", got, tt.wantAtLeast)
+			}
+			if got := tt.role.Outranks(tt.other); got != tt.wantOuter {
+				t.Errorf("Outranks() = %v, want %v", got, tt.wantOuter)
+			}
+		})
+	}
+}
+
+func TestIsValidRole(t *testing.T) {
+	cfg := DevOpsConfig()
+	tests := []struct {
+		level string
+		role  Role
+		want  bool
+	}{
+		{"tenant", RoleAdmin, true},
+		{"tenant", RoleMember, true},
+		{"tenant", RoleViewer, false},
+		{"environment", RoleOperator, true},
+		{"environment", RoleDeveloper, false},
+		{"service", RoleDeveloper, true},
+		{"service", "Developer", false},
+		{"service", "", false},
+		{"cluster", RoleAdmin, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level+"/"+string(tt.role), func(t *testing.T) {
+			if got := cfg.IsValidRole(tt.level, tt.role); got != tt.want {
+				t.Errorf("IsValidRole(%q, %q) = %v, want %v", tt.level, tt.role, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionsFor(t *testing.T) {
+	builtIn := DefaultConfig().GetLevel("workspace")
+	custom := &Level{
+		Name:  "project",
+		Roles: []Role{RoleAdmin, "editor", RoleMember},
+		Inherits: map[Role][]Role{
+			RoleAdmin: {"editor"},
+			"editor":  {RoleMember},
+		},
+		Permissions: map[Role][]Permission{
+			"editor":   {PermissionWrite},
+			RoleMember: {PermissionRead},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		level *Level
+		role  Role
+		want  []Permission
+	}{
+		{"admin", builtIn, RoleAdmin, []Permission{PermissionManage, PermissionManageMembers, PermissionRead, PermissionWrite}},
+		{"member", builtIn, RoleMember, []Permission{PermissionRead, PermissionWrite}},
+		{"viewer", builtIn, RoleViewer, []Permission{PermissionRead}},
+		{"unknown role", builtIn, "owner", []Permission{}},
+		{"empty role", builtIn, "", []Permission{}},
+		{"custom chain", custom, RoleAdmin, []Permission{PermissionManage, PermissionManageMembers, PermissionRead, PermissionWrite}},
+		{"custom middle role", custom, "editor", []Permission{PermissionRead, PermissionWrite}},
+		{"custom grants override the built-in", custom, RoleMember, []Permission{PermissionRead}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.level.PermissionsFor(tt.role).List(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PermissionsFor(%q) = %v, want %v", tt.role, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   Level
+		wantErr string
+	}{
+		{"built-in roles", Level{Roles: []Role{RoleAdmin, RoleMember, RoleViewer}}, ""},
+		{"unknown inheriting role", Level{Roles: []Role{RoleAdmin}, Inherits: map[Role][]Role{"owner": {RoleAdmin}}}, "unknown role"},
+		{"unknown inherited role", Level{Roles: []Role{RoleAdmin}, Inherits: map[Role][]Role{RoleAdmin: {"owner"}}}, "inherits unknown role"},
+		{"unknown granting role", Level{Roles: []Role{RoleAdmin}, Permissions: map[Role][]Permission{"owner": {PermissionRead}}}, "unknown role"},
+		{"unknown permission", Level{Roles: []Role{RoleAdmin}, Permissions: map[Role][]Permission{RoleAdmin: {"delete"}}}, "unknown permission"},
+		{"cycle", Level{Roles: []Role{RoleAdmin, RoleMember}, Inherits: map[Role][]Role{RoleAdmin: {RoleMember}, RoleMember: {RoleAdmin}}}, "cycle"},
+		{"self cycle", Level{Roles: []Role{RoleAdmin}, Inherits: map[Role][]Role{RoleAdmin: {RoleAdmin}}}, "cycle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.level.validateRoles()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateRoles() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateRoles() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/testutil"
+)
+
+func TestCleanupExpired(t *testing.T) {
+	db := testutil.NewDB(t)
+	now := time.Now()
+
+	user := &models.User{Email: "user@example.com", AuthProvider: "local"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	tenant := createTenant(t, db, "acme")
+	workspace := &models.Workspace{TenantID: tenant.ID, Slug: "acme", DisplayName: "acme"}
+	if err := db.Create(workspace).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Expiry offsets from now of the rows created per table; negative is in
+	// the past
+	tests := []struct {
+		table   string
+		expires []time.Duration
+		create  func(expiresAt time.Time) interface{}
+		want    int64 // rows deleted
+	}{
+		{
+			table:   "oauth_states",
+			expires: []time.Duration{-time.Minute, -time.Hour, time.Minute},
+			create: func(expiresAt time.Time) interface{} {
+				return &models.OAuthState{State: uuid.NewString(), Provider: "google", ExpiresAt: expiresAt}
+			},
+			want: 2,
+		},
+		{
+			table:   "refresh_tokens",
+			expires: []time.Duration{-time.Second, time.Hour, 30 * 24 * time.Hour},
+			create: func(expiresAt time.Time) interface{} {
+				return &models.RefreshToken{UserID: user.ID, TokenHash: uuid.NewString(), ExpiresAt: expiresAt}
+			},
+			want: 1,
+		},
+		{
+			// Expired invitations are kept for InvitationRetention
+			table:   "invitations",
+			expires: []time.Duration{-models.InvitationRetention - time.Hour, -time.Hour, time.Hour},
+			create: func(expiresAt time.Time) interface{} {
+				return &models.Invitation{WorkspaceID: workspace.ID, Email: "invitee@example.com", Token: uuid.NewString(), InvitedByID: user.ID, ExpiresAt: expiresAt}
+			},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		for _, offset := range tt.expires {
+			if err := db.Create(tt.create(now.Add(offset))).Error; err != nil {
+				t.Fatalf("create %s: %v", tt.table, err)
+			}
+		}
+	}
+
+	deleted, err := models.CleanupExpired(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			if deleted[tt.table] != tt.want {
+				t.Errorf("deleted %d, want %d", deleted[tt.table], tt.want)
+			}
+			var left int64
+			if err := db.Model(tt.create(now)).Count(&left).Error; err != nil {
+				t.Fatal(err)
+			}
+			if left != int64(len(tt.expires))-tt.want {
+				t.Errorf("%d rows left, want %d", left, int64(len(tt.expires))-tt.want)
+			}
+		})
+	}
+
+	// Nothing left to delete on the next run
+	deleted, err = models.CleanupExpired(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for table, n := range deleted {
+		if n != 0 {
+			t.Errorf("second run deleted %d %s", n, table)
+		}
+	}
+}
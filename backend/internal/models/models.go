@@ -1,9 +1,14 @@
 package models
 
 import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
@@ -15,6 +20,7 @@ import (
 type User struct {
 	ID              uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
 	Email           string     `gorm:"uniqueIndex;not null" json:"email"`
+	Username        *string    `gorm:"uniqueIndex" json:"username,omitempty"` // lowercase, never contains "@"
 	Name            string     `json:"name"`
 	Picture         string     `json:"picture,omitempty"`
 	IsPlatformAdmin bool       `gorm:"default:false" json:"is_platform_admin"`
@@ -28,11 +34,29 @@ type User struct {
 	ResetToken    string     `gorm:"type:text" json:"-"`
 	ResetExpiry   *time.Time `json:"-"`
 
+	// Two-factor auth (TOTPSecret is AES-GCM encrypted). TOTPLastStep is
+	// the time step of the last accepted code, which can't be used again;
+	// TOTPFailedAttempts counts wrong codes towards a lockout until
+	// TOTPLockedUntil.
+	TOTPSecret         string     `gorm:"type:text" json:"-"`
+	TOTPEnabled        bool       `gorm:"default:false" json:"totp_enabled"`
+	TOTPLastStep       int64      `gorm:"default:0" json:"-"`
+	TOTPFailedAttempts int        `gorm:"default:0" json:"-"`
+	TOTPLockedUntil    *time.Time `json:"-"`
+
 	// Tenant admin fields
 	IsTenantAdmin       bool       `gorm:"default:false" json:"is_tenant_admin"`
 	AdminOfTenantID     *uuid.UUID `gorm:"type:uuid;index" json:"tenant_id,omitempty"`
 	SelectedPlanTier    PlanTier   `gorm:"type:varchar(20)" json:"selected_plan,omitempty"`
 
+	// DisabledAt blocks the account: no tokens are issued to it, and
+	// RequireAuth and the authz gate reject the ones it has
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+
+	// SSOTenantID is the tenant whose SSO created the account; only that
+	// tenant's IdP can sign it in
+	SSOTenantID *uuid.UUID `gorm:"type:uuid;index" json:"-"`
+
 	// Timestamps
 	LastLogin time.Time `json:"last_login,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
@@ -126,6 +150,33 @@ type Membership struct {
 	Workspace Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"-"`
 }
 
+// ============================================================================
+// Invitation Model
+// ============================================================================
+
+// Invitation invites an email address to a workspace. It is accepted by a
+// signed-in (or newly registering) user with the same email, which creates
+// their membership.
+type Invitation struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	WorkspaceID uuid.UUID  `gorm:"type:uuid;index;not null" json:"workspace_id"`
+	Email       string     `gorm:"index;not null" json:"email"`
+	Role        string     `gorm:"not null;default:'member'" json:"role"` // admin, member, viewer
+	Token       string     `gorm:"uniqueIndex;not null" json:"-"`         // SHA-256 of the raw token
+	InvitedByID uuid.UUID  `gorm:"type:uuid;not null" json:"invited_by_id"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Relationships
+	Workspace Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// IsExpired checks if the invitation can no longer be accepted
+func (i *Invitation) IsExpired() bool {
+	return time.Now().After(i.ExpiresAt)
+}
+
 // ============================================================================
 // Subscription & Plan Models
 // ============================================================================
@@ -141,19 +192,22 @@ const (
 
 // Plan represents a subscription plan
 type Plan struct {
-	ID                uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Tier              PlanTier  `gorm:"type:varchar(20);uniqueIndex;not null" json:"tier"`
-	Name              string    `gorm:"not null" json:"name"`
-	Description       string    `json:"description"`
-	MaxWorkspaces     int       `gorm:"default:-1" json:"max_workspaces"`   // -1 = unlimited
-	MaxUsersPerTenant int       `gorm:"default:-1" json:"max_users"`        // -1 = unlimited
-	MonthlyPriceCents int       `gorm:"default:0" json:"monthly_price"`
-	AnnualPriceCents  int       `gorm:"default:0" json:"annual_price"`
-	AllowsOnPrem      bool      `gorm:"default:false" json:"allows_on_prem"`
-	Features          string    `gorm:"type:jsonb" json:"features"`         // JSON array of feature strings
-	IsActive          bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                       uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Tier                     PlanTier  `gorm:"type:varchar(20);uniqueIndex;not null" json:"tier"`
+	Name                     string    `gorm:"not null" json:"name"`
+	Description              string    `json:"description"`
+	MaxWorkspaces            int       `gorm:"default:-1" json:"max_workspaces"`              // -1 = unlimited
+	MaxUsersPerTenant        int       `gorm:"default:-1" json:"max_users"`                   // -1 = unlimited
+	MaxDocumentsPerWorkspace int       `gorm:"default:-1" json:"max_documents_per_workspace"` // -1 = unlimited
+	MaxProjectsPerWorkspace  int       `gorm:"default:-1" json:"max_projects_per_workspace"`  // -1 = unlimited
+	MonthlyPriceCents        int       `gorm:"default:0" json:"monthly_price"`
+	AnnualPriceCents         int       `gorm:"default:0" json:"annual_price"`
+	TrialDays                int       `gorm:"default:0" json:"trial_days"` // 0 = no trial
+	AllowsOnPrem             bool      `gorm:"default:false" json:"allows_on_prem"`
+	Features                 string    `gorm:"type:jsonb" json:"features"` // JSON array of feature strings
+	IsActive                 bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt                time.Time `json:"created_at"`
+	UpdatedAt                time.Time `json:"updated_at"`
 }
 
 // Subscription links a tenant to a plan
@@ -161,12 +215,13 @@ type Subscription struct {
 	ID                   uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
 	TenantID             uuid.UUID `gorm:"type:uuid;uniqueIndex;not null" json:"tenant_id"`
 	PlanID               uuid.UUID `gorm:"type:uuid;not null" json:"plan_id"`
-	Status               string    `gorm:"default:'active'" json:"status"` // active, cancelled, past_due, trialing
+	Status               string    `gorm:"default:'active'" json:"status"` // active, trialing, incomplete (awaiting first payment), past_due, cancelled
 	CurrentPeriodStart   time.Time `json:"current_period_start"`
 	CurrentPeriodEnd     time.Time `json:"current_period_end"`
-	StripeCustomerID     string    `json:"-"`
-	StripeSubscriptionID string    `json:"-"`
+	StripeCustomerID     string    `gorm:"index" json:"-"`
+	StripeSubscriptionID string    `gorm:"index" json:"-"`
 	CancelledAt          *time.Time `json:"cancelled_at,omitempty"`
+	TrialEndsAt          *time.Time `json:"trial_ends_at,omitempty"`
 	CreatedAt            time.Time  `json:"created_at"`
 	UpdatedAt            time.Time  `json:"updated_at"`
 
@@ -175,19 +230,332 @@ type Subscription struct {
 	Plan   Plan   `gorm:"foreignKey:PlanID" json:"plan,omitempty"`
 }
 
+// IsActive reports whether the subscription is in good standing. Paid plan
+// features apply only while it is; otherwise the tenant gets Basic limits.
+func (s *Subscription) IsActive() bool {
+	return s.Status == "active" || s.Status == "trialing"
+}
+
+// StartTrial puts a new subscription to a paid plan with trial days into
+// trialing until the trial ends. Free plans and plans without a trial are
+// left unchanged.
+func (s *Subscription) StartTrial(plan *Plan, now time.Time) {
+	if plan.MonthlyPriceCents <= 0 || plan.TrialDays <= 0 {
+		return
+	}
+	trialEndsAt := now.AddDate(0, 0, plan.TrialDays)
+	s.Status = "trialing"
+	s.CurrentPeriodStart = now
+	s.CurrentPeriodEnd = trialEndsAt
+	s.TrialEndsAt = &trialEndsAt
+}
+
+// TrialDaysLeft returns the whole or partial days left in a running trial,
+// or 0 when the subscription isn't trialing
+func (s *Subscription) TrialDaysLeft(now time.Time) int {
+	if s.Status != "trialing" || s.TrialEndsAt == nil || !now.Before(*s.TrialEndsAt) {
+		return 0
+	}
+	return int((s.TrialEndsAt.Sub(now) + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
 // ============================================================================
 // OAuth State Model (for CSRF protection)
 // ============================================================================
 
 // OAuthState stores OAuth state for CSRF protection
 type OAuthState struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	State     string    `gorm:"uniqueIndex;not null"`
-	Provider  string    `gorm:"not null"` // google, github
-	Plan      string    // Optional: plan tier selected during signup
-	Flow      string    // signup, login
-	ExpiresAt time.Time
-	CreatedAt time.Time
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	State        string    `gorm:"uniqueIndex;not null"`
+	Provider     string    `gorm:"not null"` // google, github, sso
+	Plan         string    // Optional: plan tier selected during signup
+	Flow         string    // signup, login
+	CodeVerifier string    // PKCE verifier; empty for states created before PKCE
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+
+	// Tenant SSO only: the tenant signing in and the nonce expected in the
+	// ID token
+	TenantID *uuid.UUID `gorm:"type:uuid"`
+	Nonce    string
+}
+
+// ============================================================================
+// SSO Config Model
+// ============================================================================
+
+// SSOConfig is a tenant's OpenID Connect identity provider. Its members sign
+// in through the IdP at /api/v1/auth/sso/<tenant slug>/login.
+type SSOConfig struct {
+	ID           uuid.UUID   `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	TenantID     uuid.UUID   `gorm:"type:uuid;uniqueIndex;not null" json:"tenant_id"`
+	Issuer       string      `gorm:"not null" json:"issuer"` // discovery at <issuer>/.well-known/openid-configuration
+	ClientID     string      `gorm:"not null" json:"client_id"`
+	ClientSecret string      `gorm:"type:text;not null" json:"-"` // AES-GCM encrypted
+	Scopes       StringArray `gorm:"type:text[]" json:"scopes"`   // requested in addition to openid
+
+	// AllowedDomains restricts sign-in to these email domains; empty allows
+	// any
+	AllowedDomains StringArray `gorm:"type:text[]" json:"allowed_domains"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// ============================================================================
+// Refresh Token Model
+// ============================================================================
+
+// RefreshToken stores a hashed, long-lived token used to renew access tokens
+type RefreshToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null" json:"user_id"`
+	TokenHash string    `gorm:"uniqueIndex;not null" json:"-"` // SHA-256 of the raw token
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	Revoked   bool      `gorm:"default:false" json:"revoked"`
+	AuthTime  time.Time `json:"auth_time"` // when the user last authenticated; kept across rotations
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// IsValid checks if the refresh token can still be used
+func (t *RefreshToken) IsValid() bool {
+	return !t.Revoked && time.Now().Before(t.ExpiresAt)
+}
+
+// ============================================================================
+// Revoked Token Model
+// ============================================================================
+
+// RevokedToken records an access token, by its jti claim, that was revoked
+// before expiring (e.g. on logout). The authz gate rejects revoked tokens;
+// rows are deleted once the token would have expired anyway.
+type RevokedToken struct {
+	JTI       string    `gorm:"column:jti;primaryKey" json:"jti"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RevokedUser revokes every access token issued to a user up to RevokedAt,
+// for when their jti claims aren't known (e.g. the account was deleted).
+// The authz gate and RequireAuth reject those tokens; the row is deleted
+// once the last of them would have expired anyway.
+type RevokedUser struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	RevokedAt time.Time `gorm:"not null" json:"revoked_at"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+}
+
+// ============================================================================
+// Session Activity Model
+// ============================================================================
+
+// SessionActivity records when a session (a sign-in, identified by the user
+// and the auth_time claim of its tokens) was last used, for
+// SESSION_IDLE_TIMEOUT. The session has timed out once ExpiresAt passes.
+type SessionActivity struct {
+	UserID       uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	AuthTime     int64     `gorm:"primaryKey;autoIncrement:false" json:"auth_time"` // Unix seconds
+	LastActivity time.Time `gorm:"not null" json:"last_activity"`
+	ExpiresAt    time.Time `gorm:"index;not null" json:"expires_at"`
+}
+
+// SessionActivityRetention is how long timed-out session rows are kept.
+// It outlasts the refresh tokens of the session, so a deleted row can't
+// let them start the session over.
+const SessionActivityRetention = 31 * 24 * time.Hour
+
+// ============================================================================
+// Backup Code Model
+// ============================================================================
+
+// BackupCode is a hashed single-use recovery code for two-factor auth
+type BackupCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"not null" json:"-"` // SHA-256 of the normalized code
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// ============================================================================
+// API Key Model
+// ============================================================================
+
+// APIKey is a programmatic credential of the form sk-<key_id>-<secret>,
+// validated by the authz gate. Only the SHA-256 of the full key is stored.
+//
+// Scope: WorkspaceID for a single workspace, WorkspaceIDs for a set, or
+// TenantWide for every workspace in the tenant.
+type APIKey struct {
+	ID           uuid.UUID   `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	KeyID        string      `gorm:"uniqueIndex;not null" json:"key_id"`
+	KeyHash      string      `gorm:"not null" json:"-"`
+	Name         string      `json:"name"`
+	UserID       uuid.UUID   `gorm:"type:uuid;index;not null" json:"user_id"`
+	TenantID     uuid.UUID   `gorm:"type:uuid;index;not null" json:"tenant_id"`
+	WorkspaceID  *uuid.UUID  `gorm:"type:uuid;index" json:"workspace_id,omitempty"`
+	WorkspaceIDs StringArray `gorm:"type:text[]" json:"workspace_ids,omitempty"`
+	TenantWide   bool        `gorm:"not null;default:false" json:"tenant_wide"`
+	Role         string      `gorm:"not null;default:'member'" json:"role"` // admin, member, viewer
+	ExpiresAt    *time.Time  `json:"expires_at,omitempty"`
+	RevokedAt    *time.Time  `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+
+	// Relationships
+	User   User   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// IsActive checks if the key is neither revoked nor expired
+func (k *APIKey) IsActive() bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt)
+}
+
+// StringArray maps a Go string slice to a Postgres text[] column
+type StringArray []string
+
+// Value implements driver.Valuer
+func (a StringArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	quoted := make([]string, len(a))
+	for i, v := range a {
+		v = strings.ReplaceAll(v, `\`, `\\`)
+		v = strings.ReplaceAll(v, `"`, `\"`)
+		quoted[i] = `"` + v + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}", nil
+}
+
+// Scan implements sql.Scanner for simple (unnested, unquoted-comma) arrays
+func (a *StringArray) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into StringArray", src)
+	}
+
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	if s == "" {
+		*a = StringArray{}
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	for i, p := range parts {
+		parts[i] = strings.Trim(p, `"`)
+	}
+	*a = parts
+	return nil
+}
+
+// ============================================================================
+// Workspace Resource Models
+// ============================================================================
+
+// Document is a workspace-scoped resource with relationship-based access:
+// its owner and the users it is shared with (DocumentShare). Mirrored in
+// OpenFGA as document:<id>.
+type Document struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	WorkspaceID uuid.UUID `gorm:"type:uuid;index;not null" json:"workspace_id"`
+	OwnerID     uuid.UUID `gorm:"type:uuid;index;not null" json:"owner_id"`
+	Title       string    `gorm:"not null" json:"title"`
+	Content     string    `gorm:"type:text" json:"content"`
+	Visibility  string    `gorm:"not null;default:'workspace'" json:"visibility"` // workspace, private
+	Status      string    `gorm:"not null;default:'draft'" json:"status"`         // draft, published, archived
+	Version     int       `gorm:"not null;default:1" json:"version"`              // incremented on every update
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	Workspace Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"-"`
+	Owner     User      `gorm:"foreignKey:OwnerID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// DocumentShare grants a user a role on a document
+type DocumentShare struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	DocumentID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_document_share;not null" json:"document_id"`
+	UserID     uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_document_share;not null" json:"user_id"`
+	Role       string    `gorm:"not null" json:"role"` // editor, viewer
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Relationships
+	Document Document `gorm:"foreignKey:DocumentID;constraint:OnDelete:CASCADE" json:"-"`
+	User     User     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Project is a workspace-scoped resource with attribute-based access: what
+// a user may do depends on their workspace role and the project's
+// environment
+type Project struct {
+	ID          uuid.UUID   `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	WorkspaceID uuid.UUID   `gorm:"type:uuid;index;not null" json:"workspace_id"`
+	OwnerID     uuid.UUID   `gorm:"type:uuid;index;not null" json:"owner_id"`
+	Name        string      `gorm:"not null" json:"name"`
+	Description string      `json:"description"`
+	Environment string      `gorm:"not null;default:'development'" json:"environment"` // development, staging, production
+	Status      string      `gorm:"not null;default:'active'" json:"status"`           // active, paused, archived
+	Tags        StringArray `gorm:"type:text[]" json:"tags"`
+	Version     int         `gorm:"not null;default:1" json:"version"` // incremented on every update
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+
+	// Relationships
+	Workspace Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"-"`
+	Owner     User      `gorm:"foreignKey:OwnerID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// ============================================================================
+// Audit Log Model
+// ============================================================================
+
+// Audit log actions
+const (
+	AuditAccountDeleted = "account.deleted"
+
+	// A platform admin was issued a token to act as UserID; the authz gate
+	// records impersonation.used the first time each such token is used
+	AuditImpersonationStarted = "impersonation.started"
+	AuditImpersonationUsed    = "impersonation.used"
+)
+
+// AuditLog records a security-relevant account event. It holds IDs only, no
+// personal data, so entries can outlive the account they describe.
+type AuditLog struct {
+	ID       uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Action   string     `gorm:"index;not null" json:"action"`
+	UserID   uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	TenantID *uuid.UUID `gorm:"type:uuid;index" json:"tenant_id,omitempty"`
+
+	// ActorID is who performed the action when it wasn't UserID, e.g. the
+	// platform admin impersonating them
+	ActorID *uuid.UUID `gorm:"type:uuid;index" json:"actor_id,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // ============================================================================
@@ -201,50 +569,182 @@ func AutoMigrate(db *gorm.DB) error {
 		&Tenant{},
 		&Workspace{},
 		&Membership{},
+		&Invitation{},
 		&Plan{},
 		&Subscription{},
 		&OAuthState{},
+		&SSOConfig{},
+		&RefreshToken{},
+		&RevokedToken{},
+		&RevokedUser{},
+		&SessionActivity{},
+		&BackupCode{},
+		&APIKey{},
+		&Document{},
+		&DocumentShare{},
+		&Project{},
+		&AuditLog{},
 	)
 }
 
+// Unique indexes on users: GORM's on email and username, and the
+// case-insensitive one NormalizeUserEmails adds
+const (
+	UserEmailIndex      = "idx_users_email"
+	UserEmailLowerIndex = "idx_users_email_lower"
+	UserUsernameIndex   = "idx_users_username"
+)
+
+// NormalizeUserEmails lowercases and trims existing user emails and adds a
+// unique index on LOWER(email) so mixed-case duplicates cannot be created.
+// Rows whose normalized email collides with another account are left
+// untouched and reported so they can be merged manually.
+func NormalizeUserEmails(db *gorm.DB) error {
+	if err := db.Exec(`
+		UPDATE users u SET email = LOWER(TRIM(u.email))
+		WHERE u.email <> LOWER(TRIM(u.email))
+		AND NOT EXISTS (
+			SELECT 1 FROM users o
+			WHERE o.id <> u.id AND LOWER(TRIM(o.email)) = LOWER(TRIM(u.email))
+		)
+	`).Error; err != nil {
+		return err
+	}
+
+	var conflicts []string
+	if err := db.Raw(`
+		SELECT LOWER(TRIM(email)) FROM users
+		GROUP BY LOWER(TRIM(email))
+		HAVING COUNT(*) > 1
+	`).Scan(&conflicts).Error; err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d emails differ only by case and must be merged manually: %v", len(conflicts), conflicts)
+	}
+
+	return db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS ` + UserEmailLowerIndex + ` ON users (LOWER(email))`).Error
+}
+
+// WorkspaceSlugIndex is the unique index on workspaces (tenant_id, slug)
+const WorkspaceSlugIndex = "idx_workspaces_tenant_slug"
+
+// EnforceUniqueWorkspaceSlugs adds a unique index on (tenant_id, slug) so
+// concurrent creates cannot both claim a slug. Existing duplicates are
+// reported and must be renamed manually before the index can be created.
+func EnforceUniqueWorkspaceSlugs(db *gorm.DB) error {
+	var conflicts []string
+	if err := db.Raw(`
+		SELECT tenant_id || '/' || slug FROM workspaces
+		GROUP BY tenant_id, slug
+		HAVING COUNT(*) > 1
+	`).Scan(&conflicts).Error; err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d workspace slugs are duplicated within a tenant and must be renamed manually: %v", len(conflicts), conflicts)
+	}
+
+	return db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS ` + WorkspaceSlugIndex + ` ON workspaces (tenant_id, slug)`).Error
+}
+
+// IsUniqueViolation reports whether err is a Postgres unique violation of
+// the named index or constraint
+func IsUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
+// InvitationRetention is how long expired invitations are kept, so their
+// links still report "expired" rather than "not found"
+const InvitationRetention = 30 * 24 * time.Hour
+
+// CleanupExpired deletes expired OAuth states, refresh tokens, revoked
+// access tokens and users and (after InvitationRetention and
+// SessionActivityRetention) invitations and session activity, returning the
+// number of rows deleted per table
+func CleanupExpired(db *gorm.DB) (map[string]int64, error) {
+	now := time.Now()
+	deleted := make(map[string]int64)
+
+	steps := []struct {
+		table  string
+		model  interface{}
+		cutoff time.Time
+	}{
+		{"oauth_states", &OAuthState{}, now},
+		{"refresh_tokens", &RefreshToken{}, now},
+		{"revoked_tokens", &RevokedToken{}, now},
+		{"revoked_users", &RevokedUser{}, now},
+		{"invitations", &Invitation{}, now.Add(-InvitationRetention)},
+		{"session_activities", &SessionActivity{}, now.Add(-SessionActivityRetention)},
+	}
+	for _, step := range steps {
+		result := db.Where("expires_at < ?", step.cutoff).Delete(step.model)
+		if result.Error != nil {
+			return deleted, fmt.Errorf("cleanup %s: %w", step.table, result.Error)
+		}
+		deleted[step.table] = result.RowsAffected
+	}
+
+	return deleted, nil
+}
+
+// ExpireTrials moves trials that have ended to past_due, returning how many
+// were changed. Subscriptions linked to Stripe are left to its webhooks,
+// which report whether the first payment after the trial succeeded.
+func ExpireTrials(db *gorm.DB) (int64, error) {
+	result := db.Model(&Subscription{}).
+		Where("status = ? AND trial_ends_at < ? AND stripe_subscription_id = ''", "trialing", time.Now()).
+		Update("status", "past_due")
+	return result.RowsAffected, result.Error
+}
+
 // SeedPlans creates default subscription plans
 func SeedPlans(db *gorm.DB) error {
 	plans := []Plan{
 		{
-			Tier:              PlanTierBasic,
-			Name:              "Basic",
-			Description:       "For individual users",
-			MaxWorkspaces:     1,
-			MaxUsersPerTenant: 1,
-			MonthlyPriceCents: 0,
-			AnnualPriceCents:  0,
-			AllowsOnPrem:      false,
-			Features:          `["Core features", "Community support"]`,
-			IsActive:          true,
+			Tier:                     PlanTierBasic,
+			Name:                     "Basic",
+			Description:              "For individual users",
+			MaxWorkspaces:            1,
+			MaxUsersPerTenant:        1,
+			MaxDocumentsPerWorkspace: 100,
+			MaxProjectsPerWorkspace:  10,
+			MonthlyPriceCents:        0,
+			AnnualPriceCents:         0,
+			AllowsOnPrem:             false,
+			Features:                 `["Core features", "Community support"]`,
+			IsActive:                 true,
 		},
 		{
-			Tier:              PlanTierAdvanced,
-			Name:              "Advanced",
-			Description:       "For small teams",
-			MaxWorkspaces:     5,
-			MaxUsersPerTenant: 10,
-			MonthlyPriceCents: 4900,
-			AnnualPriceCents:  49000,
-			AllowsOnPrem:      false,
-			Features:          `["Everything in Basic", "SSO configuration", "Priority support", "API access"]`,
-			IsActive:          true,
+			Tier:                     PlanTierAdvanced,
+			Name:                     "Advanced",
+			Description:              "For small teams",
+			MaxWorkspaces:            5,
+			MaxUsersPerTenant:        10,
+			MaxDocumentsPerWorkspace: 10000,
+			MaxProjectsPerWorkspace:  100,
+			MonthlyPriceCents:        4900,
+			AnnualPriceCents:         49000,
+			TrialDays:                14,
+			AllowsOnPrem:             false,
+			Features:                 `["Everything in Basic", "SSO configuration", "Priority support", "API access"]`,
+			IsActive:                 true,
 		},
 		{
-			Tier:              PlanTierEnterprise,
-			Name:              "Enterprise",
-			Description:       "For large organizations",
-			MaxWorkspaces:     -1,
-			MaxUsersPerTenant: -1,
-			MonthlyPriceCents: 0, // Contact sales
-			AnnualPriceCents:  0,
-			AllowsOnPrem:      true,
-			Features:          `["Everything in Advanced", "Unlimited workspaces", "Unlimited users", "On-premises deployment", "Dedicated support", "Custom integrations"]`,
-			IsActive:          true,
+			Tier:                     PlanTierEnterprise,
+			Name:                     "Enterprise",
+			Description:              "For large organizations",
+			MaxWorkspaces:            -1,
+			MaxUsersPerTenant:        -1,
+			MaxDocumentsPerWorkspace: -1,
+			MaxProjectsPerWorkspace:  -1,
+			MonthlyPriceCents:        0, // Contact sales
+			AnnualPriceCents:         0,
+			AllowsOnPrem:             true,
+			Features:                 `["Everything in Advanced", "Unlimited workspaces", "Unlimited users", "On-premises deployment", "Dedicated support", "Custom integrations"]`,
+			IsActive:                 true,
 		},
 	}
 
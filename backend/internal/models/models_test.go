@@ -0,0 +1,37 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	emailViolation := &pgconn.PgError{Code: "23505", ConstraintName: UserEmailLowerIndex}
+
+	tests := []struct {
+		name       string
+		err        error
+		constraint string
+		want       bool
+	}{
+		{"matching index", emailViolation, UserEmailLowerIndex, true},
+		{"wrapped", fmt.Errorf("create user: %w", emailViolation), UserEmailLowerIndex, true},
+		{"other index", emailViolation, UserUsernameIndex, false},
+		{"other error code", &pgconn.PgError{Code: "23503", ConstraintName: UserEmailLowerIndex}, UserEmailLowerIndex, false},
+		{"not a postgres error", errors.New("duplicate key"), UserEmailLowerIndex, false},
+		{"nil", nil, UserEmailLowerIndex, false},
+		{"workspace slug index", &pgconn.PgError{Code: "23505", ConstraintName: WorkspaceSlugIndex}, WorkspaceSlugIndex, true},
+		{"workspace slug, other index", emailViolation, WorkspaceSlugIndex, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsUniqueViolation(tc.err, tc.constraint); got != tc.want {
+				t.Errorf("IsUniqueViolation = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
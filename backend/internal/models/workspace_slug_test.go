@@ -0,0 +1,72 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+func createTenant(t *testing.T, db *gorm.DB, slug string) *models.Tenant {
+	t.Helper()
+	tenant := &models.Tenant{Slug: slug, DisplayName: slug}
+	if err := db.Create(tenant).Error; err != nil {
+		t.Fatal(err)
+	}
+	return tenant
+}
+
+func createWorkspace(db *gorm.DB, tenant *models.Tenant, slug string) error {
+	return db.Create(&models.Workspace{TenantID: tenant.ID, Slug: slug, DisplayName: slug}).Error
+}
+
+func TestEnforceUniqueWorkspaceSlugs(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string // tenant/slug of workspaces present beforehand
+		wantErr  string
+		create   string // tenant/slug created afterwards
+		wantDup  bool   // whether that create must fail
+	}{
+		{"duplicate in one tenant", nil, "", "a/eng", true},
+		{"same slug in another tenant", nil, "", "b/eng", false},
+		{"new slug", nil, "", "a/ops", false},
+		{"existing duplicates are reported", []string{"a/ops", "a/ops"}, "a/ops", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			tenants := map[string]*models.Tenant{"a": createTenant(t, db, "a"), "b": createTenant(t, db, "b")}
+			add := func(ref string) error {
+				tenant, slug, _ := strings.Cut(ref, "/")
+				return createWorkspace(db, tenants[tenant], slug)
+			}
+			for _, ref := range append([]string{"a/eng"}, tt.existing...) {
+				if err := add(ref); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := models.EnforceUniqueWorkspaceSlugs(db)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tenants["a"].ID.String()+"/ops") {
+					t.Fatalf("EnforceUniqueWorkspaceSlugs() = %v, want the duplicate %s reported", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Idempotent, as it runs on every startup
+			if err := models.EnforceUniqueWorkspaceSlugs(db); err != nil {
+				t.Fatalf("second run: %v", err)
+			}
+
+			if err := add(tt.create); (err != nil) != tt.wantDup {
+				t.Errorf("create %s error = %v, want duplicate %v", tt.create, err, tt.wantDup)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hashing algorithms selectable with PASSWORD_HASH
+const (
+	AlgorithmBcrypt = "bcrypt"
+	AlgorithmArgon2 = "argon2"
+)
+
+// Argon2Params are the argon2id cost parameters
+type Argon2Params struct {
+	Memory  uint32 // KiB
+	Time    uint32
+	Threads uint8
+}
+
+// DefaultArgon2Params follow the OWASP recommendation for argon2id
+var DefaultArgon2Params = Argon2Params{Memory: 19 * 1024, Time: 2, Threads: 1}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// hashes of either algorithm. Hashes are self-describing: bcrypt's start
+// with "$2", argon2id's are PHC strings starting with "$argon2id$", so
+// switching algorithm keeps existing hashes working.
+type Hasher struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+// NewHasher creates a hasher for algorithm ("bcrypt" or "argon2") and the
+// bcrypt cost. On error it still returns a bcrypt hasher at the default
+// cost.
+func NewHasher(algorithm string, bcryptCost int) (*Hasher, error) {
+	h := &Hasher{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.DefaultCost, Argon2: DefaultArgon2Params}
+
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return h, fmt.Errorf("bcrypt cost %d must be between %d and %d", bcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if algorithm != AlgorithmBcrypt && algorithm != AlgorithmArgon2 {
+		return h, fmt.Errorf("unknown password hash %q (expected bcrypt or argon2)", algorithm)
+	}
+
+	h.Algorithm = algorithm
+	h.BcryptCost = bcryptCost
+	return h, nil
+}
+
+// Hash hashes password with the configured algorithm
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.Algorithm == AlgorithmArgon2 {
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, h.Argon2.Time, h.Argon2.Memory, h.Argon2.Threads, argon2KeyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, h.Argon2.Memory, h.Argon2.Time, h.Argon2.Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify reports whether password matches hash and, if so, whether hash
+// should be replaced because it uses another algorithm or cost than the
+// configured one. Empty or malformed hashes never match.
+func (h *Hasher) Verify(hash, password string) (ok, rehash bool) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false, false
+		}
+		got := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, false
+		}
+		return true, h.Algorithm != AlgorithmArgon2 || params != h.Argon2
+
+	case strings.HasPrefix(hash, "$2"):
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return false, false
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		return true, h.Algorithm != AlgorithmBcrypt || err != nil || cost != h.BcryptCost
+	}
+	return false, false
+}
+
+// decodeArgon2 parses "$argon2id$v=19$m=...,t=...,p=...$salt$key"
+func decodeArgon2(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("malformed argon2 hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2 parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("malformed argon2 key")
+	}
+	return params, salt, key, nil
+}
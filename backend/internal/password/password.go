@@ -0,0 +1,129 @@
+// Package password enforces the password policy: a minimum length, mixed
+// character classes and, optionally, a Have I Been Pwned breach check.
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Rules a password can fail, reported to clients in weak_password errors
+const (
+	RuleMinLength = "min_length"
+	RuleLowercase = "lowercase"
+	RuleUppercase = "uppercase"
+	RuleDigit     = "digit"
+	RuleBreached  = "breached"
+)
+
+// defaultBreachAPIURL is the Pwned Passwords k-anonymity range API
+const defaultBreachAPIURL = "https://api.pwnedpasswords.com/range/"
+
+// Policy checks new passwords
+type Policy struct {
+	MinLength    int
+	RequireMixed bool // lowercase, uppercase and a digit
+	BreachCheck  bool
+
+	breachAPIURL string
+	httpClient   *http.Client
+}
+
+// NewPolicy creates a policy. With breachCheck, passwords found in known
+// breaches are rejected.
+func NewPolicy(minLength int, requireMixed, breachCheck bool) *Policy {
+	return &Policy{
+		MinLength:    minLength,
+		RequireMixed: requireMixed,
+		BreachCheck:  breachCheck,
+		breachAPIURL: defaultBreachAPIURL,
+		httpClient:   &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+// Validate returns the rules password fails, or nil if it is acceptable.
+// The breach check only runs once the other rules pass; if the breach API
+// can't be reached the check is skipped, so signups keep working offline.
+func (p *Policy) Validate(ctx context.Context, password string) []string {
+	var failed []string
+
+	if len([]rune(password)) < p.MinLength {
+		failed = append(failed, RuleMinLength)
+	}
+
+	if p.RequireMixed {
+		var lower, upper, digit bool
+		for _, r := range password {
+			switch {
+			case unicode.IsLower(r):
+				lower = true
+			case unicode.IsUpper(r):
+				upper = true
+			case unicode.IsDigit(r):
+				digit = true
+			}
+		}
+		if !lower {
+			failed = append(failed, RuleLowercase)
+		}
+		if !upper {
+			failed = append(failed, RuleUppercase)
+		}
+		if !digit {
+			failed = append(failed, RuleDigit)
+		}
+	}
+
+	if len(failed) == 0 && p.BreachCheck {
+		breached, err := p.breached(ctx, password)
+		if err != nil {
+			log.Printf("Password breach check skipped: %v", err)
+		} else if breached {
+			failed = append(failed, RuleBreached)
+		}
+	}
+
+	return failed
+}
+
+// breached looks the password up in Pwned Passwords. Only the first five
+// hex characters of its SHA-1 are sent; the matching suffixes come back
+// padded with decoys (count 0) so the response size reveals nothing.
+func (p *Policy) breached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.breachAPIURL+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breach range lookup failed: %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && candidate == suffix && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,327 @@
+package resources
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrVersionConflict is returned by updates whose Version no longer
+	// matches the stored one, i.e. someone else saved in between
+	ErrVersionConflict = errors.New("version conflict")
+
+	// ErrAlreadyShared is returned when a document is already shared with
+	// the user
+	ErrAlreadyShared = errors.New("document already shared with user")
+)
+
+// Repository provides database operations for workspace-scoped documents
+// and projects
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new resource repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// ============================================================================
+// Documents
+// ============================================================================
+
+// CreateDocument creates a document
+func (r *Repository) CreateDocument(doc *models.Document) error {
+	doc.Version = 1
+	return r.db.Create(doc).Error
+}
+
+// GetDocument retrieves a document within a workspace
+func (r *Repository) GetDocument(workspaceID, id uuid.UUID) (*models.Document, error) {
+	var doc models.Document
+	if err := r.db.Where("id = ? AND workspace_id = ?", id, workspaceID).First(&doc).Error; err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// ListDocuments lists the workspace documents a user can see: those with
+// workspace visibility, their own, and those shared with them
+func (r *Repository) ListDocuments(workspaceID, userID uuid.UUID) ([]models.Document, error) {
+	var docs []models.Document
+	err := r.visibleDocuments(workspaceID, userID).
+		Order("created_at DESC").
+		Find(&docs).Error
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// visibleDocuments scopes a query to the workspace documents userID can see
+func (r *Repository) visibleDocuments(workspaceID, userID uuid.UUID) *gorm.DB {
+	return r.db.Model(&models.Document{}).
+		Where("workspace_id = ?", workspaceID).
+		Where("visibility = ? OR owner_id = ? OR id IN (?)", "workspace", userID,
+			r.db.Model(&models.DocumentShare{}).Select("document_id").Where("user_id = ?", userID))
+}
+
+// UpdateDocument saves doc if its Version matches the stored one and
+// increments the version; otherwise it returns ErrVersionConflict
+func (r *Repository) UpdateDocument(doc *models.Document) error {
+	result := r.db.Model(&models.Document{}).
+		Where("id = ? AND version = ?", doc.ID, doc.Version).
+		Updates(map[string]interface{}{
+			"title":      doc.Title,
+			"content":    doc.Content,
+			"visibility": doc.Visibility,
+			"status":     doc.Status,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	doc.Version++
+	return nil
+}
+
+// DeleteDocument deletes a document and its shares, returning the shares so
+// the caller can remove the matching OpenFGA tuples
+func (r *Repository) DeleteDocument(id uuid.UUID) ([]models.DocumentShare, error) {
+	var shares []models.DocumentShare
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", id).Find(&shares).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("document_id = ?", id).Delete(&models.DocumentShare{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Document{}, "id = ?", id).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// ShareDocument grants a user a role on a document
+func (r *Repository) ShareDocument(documentID, userID uuid.UUID, role string) (*models.DocumentShare, error) {
+	var count int64
+	r.db.Model(&models.DocumentShare{}).Where("document_id = ? AND user_id = ?", documentID, userID).Count(&count)
+	if count > 0 {
+		return nil, ErrAlreadyShared
+	}
+
+	share := &models.DocumentShare{
+		DocumentID: documentID,
+		UserID:     userID,
+		Role:       role,
+	}
+	if err := r.db.Create(share).Error; err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// ListShares lists a document's shares
+func (r *Repository) ListShares(documentID uuid.UUID) ([]models.DocumentShare, error) {
+	var shares []models.DocumentShare
+	if err := r.db.Where("document_id = ?", documentID).Order("created_at ASC").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// ShareRole returns the role a document is shared with the user, or ""
+func (r *Repository) ShareRole(documentID, userID uuid.UUID) string {
+	var share models.DocumentShare
+	if err := r.db.Where("document_id = ? AND user_id = ?", documentID, userID).First(&share).Error; err != nil {
+		return ""
+	}
+	return share.Role
+}
+
+// ============================================================================
+// Projects
+// ============================================================================
+
+// CreateProject creates a project
+func (r *Repository) CreateProject(proj *models.Project) error {
+	proj.Version = 1
+	return r.db.Create(proj).Error
+}
+
+// GetProject retrieves a project within a workspace
+func (r *Repository) GetProject(workspaceID, id uuid.UUID) (*models.Project, error) {
+	var proj models.Project
+	if err := r.db.Where("id = ? AND workspace_id = ?", id, workspaceID).First(&proj).Error; err != nil {
+		return nil, err
+	}
+	return &proj, nil
+}
+
+// ListProjects lists a workspace's projects, optionally filtered by
+// environment
+func (r *Repository) ListProjects(workspaceID uuid.UUID, environment string) ([]models.Project, error) {
+	var projects []models.Project
+	query := r.db.Where("workspace_id = ?", workspaceID)
+	if environment != "" {
+		query = query.Where("environment = ?", environment)
+	}
+	if err := query.Order("created_at DESC").Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// UpdateProject saves proj if its Version matches the stored one and
+// increments the version; otherwise it returns ErrVersionConflict
+func (r *Repository) UpdateProject(proj *models.Project) error {
+	result := r.db.Model(&models.Project{}).
+		Where("id = ? AND version = ?", proj.ID, proj.Version).
+		Updates(map[string]interface{}{
+			"name":        proj.Name,
+			"description": proj.Description,
+			"environment": proj.Environment,
+			"status":      proj.Status,
+			"tags":        proj.Tags,
+			"version":     gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	proj.Version++
+	return nil
+}
+
+// DeleteProject deletes a project
+func (r *Repository) DeleteProject(id uuid.UUID) error {
+	return r.db.Delete(&models.Project{}, "id = ?", id).Error
+}
+
+// ============================================================================
+// Feed
+// ============================================================================
+
+// Resource types in the workspace feed
+const (
+	TypeDocument = "document"
+	TypeProject  = "project"
+)
+
+// FeedFilter narrows the workspace feed. Empty fields match everything.
+type FeedFilter struct {
+	Type   string // TypeDocument or TypeProject
+	Status string
+}
+
+// FeedEntry is a document or project in the workspace feed, most recently
+// updated first. Exactly one of Document and Project is set, per Type.
+type FeedEntry struct {
+	Type      string
+	ID        uuid.UUID
+	UpdatedAt time.Time
+
+	Document *models.Document
+	Project  *models.Project
+}
+
+// ListFeed returns a page of the workspace's documents visible to userID
+// and its projects, most recently updated first, with the total number of
+// matching entries. The page is selected with a single UNION query; its
+// documents and projects are then loaded with one query each.
+func (r *Repository) ListFeed(workspaceID, userID uuid.UUID, filter FeedFilter, limit, offset int) ([]FeedEntry, int64, error) {
+	var parts []interface{}
+	if filter.Type == "" || filter.Type == TypeDocument {
+		docs := r.visibleDocuments(workspaceID, userID).Select("'document' AS type, id, updated_at")
+		if filter.Status != "" {
+			docs = docs.Where("status = ?", filter.Status)
+		}
+		parts = append(parts, docs)
+	}
+	if filter.Type == "" || filter.Type == TypeProject {
+		projects := r.db.Model(&models.Project{}).Select("'project' AS type, id, updated_at").
+			Where("workspace_id = ?", workspaceID)
+		if filter.Status != "" {
+			projects = projects.Where("status = ?", filter.Status)
+		}
+		parts = append(parts, projects)
+	}
+	if len(parts) == 0 {
+		return nil, 0, nil
+	}
+
+	from := "(?) AS feed"
+	if len(parts) == 2 {
+		from = "((?) UNION ALL (?)) AS feed"
+	}
+
+	var total int64
+	if err := r.db.Table(from, parts...).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []FeedEntry
+	err := r.db.Table(from, parts...).
+		Select("type, id, updated_at").
+		Order("updated_at DESC, id DESC").
+		Limit(limit).Offset(offset).
+		Scan(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var docIDs, projectIDs []uuid.UUID
+	for _, e := range entries {
+		if e.Type == TypeDocument {
+			docIDs = append(docIDs, e.ID)
+		} else {
+			projectIDs = append(projectIDs, e.ID)
+		}
+	}
+
+	docs := make(map[uuid.UUID]*models.Document, len(docIDs))
+	if len(docIDs) > 0 {
+		var list []models.Document
+		if err := r.db.Where("id IN ?", docIDs).Find(&list).Error; err != nil {
+			return nil, 0, err
+		}
+		for i := range list {
+			docs[list[i].ID] = &list[i]
+		}
+	}
+	projects := make(map[uuid.UUID]*models.Project, len(projectIDs))
+	if len(projectIDs) > 0 {
+		var list []models.Project
+		if err := r.db.Where("id IN ?", projectIDs).Find(&list).Error; err != nil {
+			return nil, 0, err
+		}
+		for i := range list {
+			projects[list[i].ID] = &list[i]
+		}
+	}
+
+	// Skip anything deleted between the two queries
+	page := entries[:0]
+	for _, e := range entries {
+		if e.Type == TypeDocument {
+			e.Document = docs[e.ID]
+		} else {
+			e.Project = projects[e.ID]
+		}
+		if e.Document != nil || e.Project != nil {
+			page = append(page, e)
+		}
+	}
+	return page, total, nil
+}
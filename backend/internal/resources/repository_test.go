@@ -0,0 +1,215 @@
+package resources
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"github.com/yourusername/saas-starter-kit/backend/internal/testutil"
+	"gorm.io/gorm"
+)
+
+// fixture is workspaces ws and other of one tenant, with users owner,
+// reader and outsider
+type fixture struct {
+	db       *gorm.DB
+	repo     *Repository
+	ws       *models.Workspace
+	other    *models.Workspace
+	owner    *models.User
+	reader   *models.User
+	outsider *models.User
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db := testutil.NewDB(t)
+	f := &fixture{db: db, repo: NewRepository(db)}
+
+	tenant := &models.Tenant{Slug: "acme", DisplayName: "Acme"}
+	create(t, db, tenant)
+	f.ws = &models.Workspace{TenantID: tenant.ID, Slug: "ws", DisplayName: "ws"}
+	f.other = &models.Workspace{TenantID: tenant.ID, Slug: "other", DisplayName: "other"}
+	create(t, db, f.ws)
+	create(t, db, f.other)
+
+	for _, u := range []**models.User{&f.owner, &f.reader, &f.outsider} {
+		*u = &models.User{Email: uuid.NewString() + "@example.com", AuthProvider: "local"}
+		create(t, db, *u)
+	}
+	return f
+}
+
+func create(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("create %T: %v", value, err)
+	}
+}
+
+func (f *fixture) createDocument(t *testing.T, workspace *models.Workspace, title, visibility string) *models.Document {
+	t.Helper()
+	doc := &models.Document{WorkspaceID: workspace.ID, OwnerID: f.owner.ID, Title: title, Visibility: visibility, Status: "draft"}
+	if err := f.repo.CreateDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func titles(docs []models.Document) []string {
+	var out []string
+	for _, d := range docs {
+		out = append(out, d.Title)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestListDocumentsVisibility(t *testing.T) {
+	f := newFixture(t)
+	f.createDocument(t, f.ws, "open", "workspace")
+	f.createDocument(t, f.ws, "private", "private")
+	shared := f.createDocument(t, f.ws, "shared", "private")
+	f.createDocument(t, f.other, "elsewhere", "workspace")
+	if _, err := f.repo.ShareDocument(shared.ID, f.reader.ID, "viewer"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		user *models.User
+		want []string
+	}{
+		{"owner sees all their documents", f.owner, []string{"open", "private", "shared"}},
+		{"shared user sees shared documents", f.reader, []string{"open", "shared"}},
+		{"others see workspace documents", f.outsider, []string{"open"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := f.repo.ListDocuments(f.ws.ID, tt.user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := titles(docs); !equal(got, tt.want) {
+				t.Errorf("documents = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourcesAreWorkspaceScoped(t *testing.T) {
+	f := newFixture(t)
+	doc := f.createDocument(t, f.ws, "doc", "workspace")
+	proj := &models.Project{WorkspaceID: f.ws.ID, OwnerID: f.owner.ID, Name: "proj", Environment: "development", Status: "active"}
+	if err := f.repo.CreateProject(proj); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		workspace *models.Workspace
+		wantFound bool
+	}{
+		{"own workspace", f.ws, true},
+		{"other workspace", f.other, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.repo.GetDocument(tt.workspace.ID, doc.ID)
+			if found := err == nil; found != tt.wantFound {
+				t.Errorf("GetDocument found = %v (%v), want %v", found, err, tt.wantFound)
+			}
+			if !tt.wantFound && !errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Errorf("GetDocument error = %v, want ErrRecordNotFound", err)
+			}
+
+			_, err = f.repo.GetProject(tt.workspace.ID, proj.ID)
+			if found := err == nil; found != tt.wantFound {
+				t.Errorf("GetProject found = %v (%v), want %v", found, err, tt.wantFound)
+			}
+
+			projects, err := f.repo.ListProjects(tt.workspace.ID, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found := len(projects) == 1; found != tt.wantFound {
+				t.Errorf("ListProjects returned %d projects", len(projects))
+			}
+		})
+	}
+}
+
+func TestListProjectsByEnvironment(t *testing.T) {
+	f := newFixture(t)
+	for _, env := range []string{"development", "staging", "production", "production"} {
+		proj := &models.Project{WorkspaceID: f.ws.ID, OwnerID: f.owner.ID, Name: env, Environment: env, Status: "active"}
+		if err := f.repo.CreateProject(proj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		environment string
+		want        int
+	}{
+		{"", 4},
+		{"development", 1},
+		{"production", 2},
+		{"unknown", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.environment, func(t *testing.T) {
+			projects, err := f.repo.ListProjects(f.ws.ID, tt.environment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(projects) != tt.want {
+				t.Errorf("got %d projects, want %d", len(projects), tt.want)
+			}
+		})
+	}
+}
+
+func TestShareDocument(t *testing.T) {
+	f := newFixture(t)
+	doc := f.createDocument(t, f.ws, "doc", "private")
+
+	if _, err := f.repo.ShareDocument(doc.ID, f.reader.ID, "editor"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.repo.ShareDocument(doc.ID, f.reader.ID, "viewer"); !errors.Is(err, ErrAlreadyShared) {
+		t.Errorf("second share error = %v, want ErrAlreadyShared", err)
+	}
+	if role := f.repo.ShareRole(doc.ID, f.reader.ID); role != "editor" {
+		t.Errorf("ShareRole = %q, want editor", role)
+	}
+	if role := f.repo.ShareRole(doc.ID, f.outsider.ID); role != "" {
+		t.Errorf("ShareRole of an unshared user = %q, want none", role)
+	}
+
+	// Deleting the document returns its shares so their tuples can go
+	shares, err := f.repo.DeleteDocument(doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 1 || shares[0].UserID != f.reader.ID {
+		t.Errorf("deleted shares = %+v, want the reader's", shares)
+	}
+	if left, _ := f.repo.ListShares(doc.ID); len(left) != 0 {
+		t.Errorf("%d shares left after delete", len(left))
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
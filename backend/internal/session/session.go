@@ -0,0 +1,110 @@
+package session
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrExpired is returned for a session past its idle or absolute timeout
+var ErrExpired = errors.New("session expired")
+
+// maxWriteInterval bounds how often a busy session's activity is written;
+// idle timeouts are enforced to within this
+const maxWriteInterval = time.Minute
+
+// Tracker enforces session timeouts. A session is one sign-in, identified
+// by the user and the auth_time claim that every token issued from it
+// carries, including refreshed and re-scoped ones. The absolute timeout
+// ends a session that long after sign-in; the idle timeout ends it after
+// that long without requests.
+type Tracker struct {
+	db       *gorm.DB
+	idle     time.Duration
+	absolute time.Duration
+}
+
+// NewTracker creates a tracker for the given timeouts (<= 0 disables
+// either). It returns nil, which enforces nothing, when both are disabled.
+func NewTracker(db *gorm.DB, idle, absolute time.Duration) *Tracker {
+	if idle <= 0 && absolute <= 0 {
+		return nil
+	}
+	return &Tracker{db: db, idle: idle, absolute: absolute}
+}
+
+// Touch returns ErrExpired if the session of userID signed in at authTime
+// (Unix seconds) has timed out, and otherwise records activity on it.
+// Tokens without an auth_time, such as impersonation tokens, don't belong
+// to a session and always pass.
+func (t *Tracker) Touch(userID string, authTime int64) error {
+	if t == nil || authTime <= 0 {
+		return nil
+	}
+
+	if t.PastAbsolute(authTime) {
+		return ErrExpired
+	}
+	if t.idle <= 0 {
+		return nil
+	}
+	now := time.Now()
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+
+	var activity models.SessionActivity
+	err = t.db.Where("user_id = ? AND auth_time = ?", uid, authTime).First(&activity).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// First request of the session
+	case err != nil:
+		return err
+	case now.After(activity.ExpiresAt):
+		return ErrExpired
+	case now.Sub(activity.LastActivity) < t.writeInterval():
+		return nil
+	}
+
+	activity = models.SessionActivity{UserID: uid, AuthTime: authTime, LastActivity: now, ExpiresAt: now.Add(t.idle)}
+	return t.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "auth_time"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_activity", "expires_at"}),
+	}).Create(&activity).Error
+}
+
+// PastAbsolute reports whether a session signed in at authTime has reached
+// the absolute timeout. Unlike Touch it needs no database access.
+func (t *Tracker) PastAbsolute(authTime int64) bool {
+	return t != nil && t.absolute > 0 && authTime > 0 && time.Since(time.Unix(authTime, 0)) > t.absolute
+}
+
+func (t *Tracker) writeInterval() time.Duration {
+	if interval := t.idle / 10; interval < maxWriteInterval {
+		return interval
+	}
+	return maxWriteInterval
+}
+
+// Expiry caps exp, the expiry of a token issued to a session signed in at
+// authTime, at the end of the session: the absolute timeout and, from now,
+// the idle timeout. Without an authTime only the idle timeout applies.
+func Expiry(exp time.Time, authTime int64, idle, absolute time.Duration) time.Time {
+	if idle > 0 {
+		if end := time.Now().Add(idle); end.Before(exp) {
+			exp = end
+		}
+	}
+	if absolute > 0 && authTime > 0 {
+		if end := time.Unix(authTime, 0).Add(absolute); end.Before(exp) {
+			exp = end
+		}
+	}
+	return exp
+}
@@ -0,0 +1,146 @@
+// Package testutil provides an in-memory database for handler and
+// repository tests
+package testutil
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yourusername/saas-starter-kit/backend/internal/hierarchy"
+	"github.com/yourusername/saas-starter-kit/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewDB returns a migrated in-memory SQLite database, private to the test.
+// Postgres' gen_random_uuid() defaults are replaced by assigning UUID
+// primary keys on create, and unique violations are reported as Postgres
+// errors, so models are created as they are in production.
+func NewDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	dsn := "file:" + uuid.NewString() + "?mode=memory&cache=shared&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db.Callback().Raw().Before("gorm:raw").Register("testutil:strip_uuid_default", stripUUIDDefault)
+	db.Callback().Create().Before("gorm:create").Register("testutil:assign_uuid", assignUUID)
+	db.Callback().Create().After("gorm:create").Register("testutil:unique_violation", translateUniqueViolation)
+	db.Callback().Update().After("gorm:update").Register("testutil:unique_violation", translateUniqueViolation)
+
+	if err := models.AutoMigrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := hierarchy.AutoMigrate(db); err != nil {
+		t.Fatalf("migrate hierarchy: %v", err)
+	}
+	return db
+}
+
+// stripUUIDDefault drops the Postgres-only column default from migrations
+func stripUUIDDefault(tx *gorm.DB) {
+	if sql := tx.Statement.SQL.String(); strings.Contains(sql, "DEFAULT gen_random_uuid()") {
+		tx.Statement.SQL.Reset()
+		tx.Statement.SQL.WriteString(strings.ReplaceAll(sql, "DEFAULT gen_random_uuid()", ""))
+	}
+}
+
+// assignUUID sets unset UUID primary keys of created rows
+func assignUUID(tx *gorm.DB) {
+	if tx.Statement.Schema == nil || tx.Statement.Schema.PrioritizedPrimaryField == nil {
+		return
+	}
+	field := tx.Statement.Schema.PrioritizedPrimaryField
+	if field.FieldType != reflect.TypeOf(uuid.UUID{}) {
+		return
+	}
+
+	assign := func(rv reflect.Value) {
+		if _, zero := field.ValueOf(tx.Statement.Context, rv); zero {
+			field.Set(tx.Statement.Context, rv, uuid.New())
+		}
+	}
+	switch rv := reflect.Indirect(tx.Statement.ReflectValue); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			assign(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		assign(rv)
+	}
+}
+
+// uniqueViolation matches SQLite's unique constraint errors, which name the
+// index of expression indexes and the table's columns otherwise
+var uniqueViolation = regexp.MustCompile(`UNIQUE constraint failed: (?:index '([^']+)'|(\w+)\.([\w., ]+?))(?: \(\d+\))?$`)
+
+// translateUniqueViolation replaces a SQLite unique constraint error with
+// the Postgres error models.IsUniqueViolation recognizes, naming the
+// violated index
+func translateUniqueViolation(tx *gorm.DB) {
+	if tx.Error == nil {
+		return
+	}
+	m := uniqueViolation.FindStringSubmatch(tx.Error.Error())
+	if m == nil {
+		return
+	}
+	index := m[1]
+	if index == "" {
+		var columns []string
+		for _, column := range strings.Split(m[3], ", ") {
+			columns = append(columns, strings.TrimPrefix(column, m[2]+"."))
+		}
+		index = uniqueIndexOn(tx, m[2], columns)
+	}
+	tx.Error = &pgconn.PgError{Code: "23505", ConstraintName: index, Message: tx.Error.Error()}
+}
+
+// uniqueIndexOn returns the name of table's unique index on exactly columns,
+// or "" if there is none
+func uniqueIndexOn(tx *gorm.DB, table string, columns []string) string {
+	ctx := tx.Statement.Context
+	rows, err := tx.Statement.ConnPool.QueryContext(ctx, `SELECT name FROM pragma_index_list(?) WHERE "unique" = 1`, table)
+	if err != nil {
+		return ""
+	}
+	var indexes []string
+	for rows.Next() {
+		var name string
+		if rows.Scan(&name) == nil {
+			indexes = append(indexes, name)
+		}
+	}
+	rows.Close()
+
+	for _, index := range indexes {
+		rows, err := tx.Statement.ConnPool.QueryContext(ctx, `SELECT name FROM pragma_index_info(?) ORDER BY seqno`, index)
+		if err != nil {
+			continue
+		}
+		var indexed []string
+		for rows.Next() {
+			var name string
+			if rows.Scan(&name) == nil {
+				indexed = append(indexed, name)
+			}
+		}
+		rows.Close()
+		if reflect.DeepEqual(indexed, columns) {
+			return index
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package twofactor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+func TestValidate(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+	now := time.Date(2026, 1, 1, 12, 0, 10, 0, time.UTC)
+	current := now.Unix() / period
+
+	codeAt := func(offset time.Duration) string {
+		code, err := totp.GenerateCode(secret, now.Add(offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current code", codeAt(0), current, true},
+		{"surrounding whitespace", " " + codeAt(0) + "\n", current, true},
+		{"previous period", codeAt(-period * time.Second), current - 1, true},
+		{"next period", codeAt(period * time.Second), current + 1, true},
+		{"two periods old", codeAt(-2 * period * time.Second), 0, false},
+		{"wrong code", "000000", 0, false},
+		{"not a code", "abc", 0, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			step, ok := Validate(tc.code, secret, now)
+			if ok != tc.wantOK || step != tc.wantStep {
+				t.Errorf("Validate = %d, %v; want %d, %v", step, ok, tc.wantStep, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestBackupCodes(t *testing.T) {
+	codes, err := GenerateBackupCodes(BackupCodeCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("code %q is not formatted xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+
+	if got := NormalizeBackupCode(" ABCDE-FGHIJ "); got != "abcde-fghij" {
+		t.Errorf("NormalizeBackupCode = %q", got)
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	sealed, err := Encrypt(key, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Decrypt(key, sealed); err != nil || got != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+
+	other := make([]byte, 32)
+	other[0] = 1
+	if _, err := Decrypt(other, sealed); err != ErrInvalidCiphertext {
+		t.Errorf("Decrypt with another key = %v, want ErrInvalidCiphertext", err)
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
@@ -11,6 +12,7 @@ import (
 	"math/big"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -64,30 +66,50 @@ type JWKS struct {
 	Keys []JWK `json:"keys"`
 }
 
+// jwksMinRefreshInterval limits how often an unknown kid can trigger a JWKS
+// re-fetch, so a burst of bad tokens can't hammer Casdoor
+const jwksMinRefreshInterval = 30 * time.Second
+
 // CasdoorValidator validates Casdoor JWT tokens
 type CasdoorValidator struct {
 	endpoint     string
 	organization string
 	application  string
-	publicKeys   map[string]*rsa.PublicKey
 	httpClient   *http.Client
+
+	// JWKS cache. publicKeys is replaced wholesale on each fetch so keys
+	// Casdoor has rotated out stop validating.
+	mu         sync.RWMutex
+	publicKeys map[string]*rsa.PublicKey
+	fetchedAt  time.Time
+	cacheTTL   time.Duration
+
+	// refreshMu serializes fetches; lastFetch is the last attempt, successful
+	// or not, and lastErr its result
+	refreshMu sync.Mutex
+	lastFetch time.Time
+	lastErr   error
 }
 
-// NewCasdoorValidator creates a new Casdoor JWT validator
-func NewCasdoorValidator(endpoint, organization, application string) (*CasdoorValidator, error) {
+// NewCasdoorValidator creates a new Casdoor JWT validator. The JWKS is
+// re-fetched after cacheTTL, or sooner when a token has an unknown kid.
+func NewCasdoorValidator(endpoint, organization, application string, cacheTTL time.Duration) (*CasdoorValidator, error) {
 	v := &CasdoorValidator{
 		endpoint:     strings.TrimSuffix(endpoint, "/"),
 		organization: organization,
 		application:  application,
 		publicKeys:   make(map[string]*rsa.PublicKey),
+		cacheTTL:     cacheTTL,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
 	}
 
 	// Try to fetch the JWKS
-	if err := v.fetchJWKS(); err != nil {
-		return v, fmt.Errorf("failed to fetch JWKS: %w", err)
+	v.lastFetch = time.Now()
+	v.lastErr = v.fetchJWKS(context.Background())
+	if v.lastErr != nil {
+		return v, fmt.Errorf("failed to fetch JWKS: %w", v.lastErr)
 	}
 
 	return v, nil
@@ -103,9 +125,10 @@ func (v *CasdoorValidator) ValidateToken(tokenString string) (*UserContext, erro
 		return nil, ErrInvalidToken
 	}
 
-	if len(v.publicKeys) == 0 {
-		// Try to fetch JWKS again
-		if err := v.fetchJWKS(); err != nil {
+	// Refresh an expired cache up front. A failed refresh keeps serving the
+	// keys we already have.
+	if v.cacheExpired() {
+		if err := v.refreshJWKS(context.Background()); err != nil && !v.hasKeys() {
 			return nil, fmt.Errorf("no public keys available: %w", err)
 		}
 	}
@@ -121,18 +144,13 @@ func (v *CasdoorValidator) ValidateToken(tokenString string) (*UserContext, erro
 		kid, ok := token.Header["kid"].(string)
 		if !ok {
 			// If no kid, try the first key
-			for _, key := range v.publicKeys {
+			if key := v.anyKey(); key != nil {
 				return key, nil
 			}
 			return nil, ErrNoCertificate
 		}
 
-		// Find the key by kid
-		if key, exists := v.publicKeys[kid]; exists {
-			return key, nil
-		}
-
-		return nil, fmt.Errorf("key not found for kid: %s", kid)
+		return v.keyForKid(kid)
 	})
 
 	if err != nil {
@@ -157,11 +175,18 @@ func (v *CasdoorValidator) ValidateToken(tokenString string) (*UserContext, erro
 	}, nil
 }
 
+// Ready checks that Casdoor's JWKS can be loaded, refreshing the cached keys.
+// Probes share the refresh rate limit, so within jwksMinRefreshInterval of
+// the last fetch they report its result without calling Casdoor.
+func (v *CasdoorValidator) Ready(ctx context.Context) error {
+	return v.refreshJWKS(ctx)
+}
+
 // fetchJWKS fetches the JSON Web Key Set from Casdoor
-func (v *CasdoorValidator) fetchJWKS() error {
+func (v *CasdoorValidator) fetchJWKS(ctx context.Context) error {
 	url := fmt.Sprintf("%s/.well-known/jwks", v.endpoint)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
@@ -187,18 +212,84 @@ func (v *CasdoorValidator) fetchJWKS() error {
 	}
 
 	// Parse all keys
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
 	for _, jwk := range jwks.Keys {
 		publicKey, err := jwkToPublicKey(jwk)
 		if err != nil {
 			continue // Skip invalid keys
 		}
-		v.publicKeys[jwk.Kid] = publicKey
+		keys[jwk.Kid] = publicKey
 	}
 
-	if len(v.publicKeys) == 0 {
+	if len(keys) == 0 {
 		return ErrNoCertificate
 	}
 
+	v.mu.Lock()
+	v.publicKeys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+
+	return nil
+}
+
+// keyForKid returns the key for kid, re-fetching the JWKS once if it is
+// unknown (e.g. after Casdoor rotated its signing key)
+func (v *CasdoorValidator) keyForKid(kid string) (*rsa.PublicKey, error) {
+	if key := v.lookupKey(kid); key != nil {
+		return key, nil
+	}
+
+	if err := v.refreshJWKS(context.Background()); err != nil {
+		return nil, fmt.Errorf("key not found for kid %s (refresh failed: %v)", kid, err)
+	}
+
+	if key := v.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key not found for kid: %s", kid)
+}
+
+// refreshJWKS re-fetches the JWKS unless another fetch happened within
+// jwksMinRefreshInterval, in which case it returns that fetch's error.
+// Concurrent callers wait for the in-flight fetch and then reuse its result.
+func (v *CasdoorValidator) refreshJWKS(ctx context.Context) error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	if time.Since(v.lastFetch) < jwksMinRefreshInterval {
+		return v.lastErr
+	}
+	v.lastFetch = time.Now()
+	v.lastErr = v.fetchJWKS(ctx)
+
+	return v.lastErr
+}
+
+func (v *CasdoorValidator) cacheExpired() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return len(v.publicKeys) == 0 || (v.cacheTTL > 0 && time.Since(v.fetchedAt) > v.cacheTTL)
+}
+
+func (v *CasdoorValidator) hasKeys() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return len(v.publicKeys) > 0
+}
+
+func (v *CasdoorValidator) lookupKey(kid string) *rsa.PublicKey {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.publicKeys[kid]
+}
+
+func (v *CasdoorValidator) anyKey() *rsa.PublicKey {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, key := range v.publicKeys {
+		return key
+	}
 	return nil
 }
 
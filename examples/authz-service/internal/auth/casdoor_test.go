@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer serves a one-key JWKS, or a 500 while failing is set, and
+// counts the requests it gets
+func jwksServer(t *testing.T) (url string, fetches *atomic.Int32, failing *atomic.Bool) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := JWKS{Keys: []JWK{{
+		Kid: "key-1",
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+
+	fetches = new(atomic.Int32)
+	failing = new(atomic.Bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if failing.Load() {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, fetches, failing
+}
+
+func TestReadyIsRateLimited(t *testing.T) {
+	url, fetches, failing := jwksServer(t)
+	v, err := NewCasdoorValidator(url, "org", "app", time.Hour)
+	if err != nil {
+		t.Fatalf("NewCasdoorValidator: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := v.Ready(context.Background()); err != nil {
+			t.Fatalf("Ready: %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want 1 (probes within the refresh interval reuse the last fetch)", got)
+	}
+
+	// Once the interval has passed a probe fetches again and reports failure
+	failing.Store(true)
+	v.lastFetch = time.Now().Add(-jwksMinRefreshInterval)
+	if err := v.Ready(context.Background()); err == nil {
+		t.Error("Ready succeeded while Casdoor is failing")
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", got)
+	}
+
+	// Later probes report the failure without calling Casdoor again
+	if err := v.Ready(context.Background()); err == nil {
+		t.Error("Ready succeeded after a failed fetch")
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", got)
+	}
+}
@@ -32,11 +32,28 @@ func NewClient(url, storeID string) (*Client, error) {
 	}, nil
 }
 
+// Ready checks that OpenFGA is reachable and the store has an
+// authorization model
+func (c *Client) Ready(ctx context.Context) error {
+	resp, err := c.client.ReadLatestAuthorizationModel(ctx).Execute()
+	if err != nil {
+		return err
+	}
+	if resp.AuthorizationModel == nil {
+		return fmt.Errorf("store %s has no authorization model", c.storeID)
+	}
+	return nil
+}
+
 // Check performs a permission check
 // user: "user:<user_id>"
 // relation: "can_read", "can_write", "can_manage", etc.
-// object: "container:<container_id>" or "document:<doc_id>"
+// object: "workspace:<workspace_id>" or "document:<doc_id>"
 func (c *Client) Check(ctx context.Context, user, relation, object string) (bool, error) {
+	if err := validateRefs(user, object); err != nil {
+		return false, err
+	}
+
 	body := client.ClientCheckRequest{
 		User:     user,
 		Relation: relation,
@@ -53,6 +70,10 @@ func (c *Client) Check(ctx context.Context, user, relation, object string) (bool
 
 // CheckWithTuple performs a permission check with contextual tuples
 func (c *Client) CheckWithTuple(ctx context.Context, user, relation, object string, contextualTuples []openfga.TupleKey) (bool, error) {
+	if err := validateRefs(user, object); err != nil {
+		return false, err
+	}
+
 	body := client.ClientCheckRequest{
 		User:     user,
 		Relation: relation,
@@ -73,6 +94,10 @@ func (c *Client) CheckWithTuple(ctx context.Context, user, relation, object stri
 
 // WriteTuple writes a relationship tuple
 func (c *Client) WriteTuple(ctx context.Context, user, relation, object string) error {
+	if err := validateRefs(user, object); err != nil {
+		return err
+	}
+
 	body := client.ClientWriteRequest{
 		Writes: []client.ClientTupleKey{
 			{
@@ -93,6 +118,10 @@ func (c *Client) WriteTuple(ctx context.Context, user, relation, object string)
 
 // DeleteTuple deletes a relationship tuple
 func (c *Client) DeleteTuple(ctx context.Context, user, relation, object string) error {
+	if err := validateRefs(user, object); err != nil {
+		return err
+	}
+
 	body := client.ClientWriteRequest{
 		Deletes: []client.ClientTupleKeyWithoutCondition{
 			{
@@ -126,3 +155,13 @@ func (c *Client) ListObjects(ctx context.Context, user, relation, objectType str
 
 	return resp.GetObjects(), nil
 }
+
+// validateRefs rejects malformed user/object references before they reach
+// OpenFGA
+func validateRefs(user, object string) error {
+	if _, err := ParseObjectRef(user); err != nil {
+		return err
+	}
+	_, err := ParseObjectRef(object)
+	return err
+}
@@ -0,0 +1,36 @@
+package fga
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/authz-service/internal/auth"
+)
+
+// AuthzContext carries everything an authorization decision may depend on.
+// Passing a single struct lets new attributes (IP, time, device, ...) reach
+// the check without changing call signatures.
+type AuthzContext struct {
+	Subject     *auth.UserContext
+	Object      ObjectRef // e.g. workspace:<workspace_id>
+	Action      string    // OpenFGA relation, e.g. "can_read"
+	Environment Environment
+}
+
+// Environment holds request-scoped attributes
+type Environment struct {
+	Method   string
+	Path     string
+	ClientIP string
+	Time     time.Time
+}
+
+// User returns the OpenFGA user identifier for the subject
+func (a *AuthzContext) User() string {
+	return UserRef(a.Subject.UserID).String()
+}
+
+// CheckContext performs a permission check described by an AuthzContext
+func (c *Client) CheckContext(ctx context.Context, actx *AuthzContext) (bool, error) {
+	return c.Check(ctx, actx.User(), actx.Action, actx.Object.String())
+}
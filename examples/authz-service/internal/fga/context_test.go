@@ -0,0 +1,85 @@
+package fga
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/authz-service/internal/auth"
+)
+
+const testStoreID = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+
+// checkRequest is the part of an OpenFGA check request body the tests look at
+type checkRequest struct {
+	TupleKey struct {
+		User     string `json:"user"`
+		Relation string `json:"relation"`
+		Object   string `json:"object"`
+	} `json:"tuple_key"`
+}
+
+// fakeCheckServer answers OpenFGA check requests with allow(user, relation,
+// object) and returns a client for it
+func fakeCheckServer(t *testing.T, allow func(user, relation, object string) bool) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/check") {
+			http.NotFound(w, r)
+			return
+		}
+		var req checkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		k := req.TupleKey
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"allowed": allow(k.User, k.Relation, k.Object)})
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(srv.URL, testStoreID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCheckContext(t *testing.T) {
+	c := fakeCheckServer(t, func(user, relation, object string) bool {
+		return user == "user:alice" && relation == "can_write" && object == "workspace:ws-1"
+	})
+
+	tests := []struct {
+		name    string
+		actx    AuthzContext
+		want    bool
+		wantErr bool
+	}{
+		{"allowed", AuthzContext{Subject: &auth.UserContext{UserID: "alice"}, Object: WorkspaceRef("ws-1"), Action: "can_write"}, true, false},
+		{"other action", AuthzContext{Subject: &auth.UserContext{UserID: "alice"}, Object: WorkspaceRef("ws-1"), Action: "can_manage"}, false, false},
+		{"other subject", AuthzContext{Subject: &auth.UserContext{UserID: "bob"}, Object: WorkspaceRef("ws-1"), Action: "can_write"}, false, false},
+		{"other workspace", AuthzContext{Subject: &auth.UserContext{UserID: "alice"}, Object: WorkspaceRef("ws-2"), Action: "can_write"}, false, false},
+		{"invalid subject", AuthzContext{Subject: &auth.UserContext{UserID: "a:b"}, Object: WorkspaceRef("ws-1"), Action: "can_write"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.CheckContext(context.Background(), &tt.actx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckContext() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CheckContext() = %v, want %v", got, tt.want)
+			}
+			if !tt.wantErr {
+				if user := tt.actx.User(); user != "user:"+tt.actx.Subject.UserID {
+					t.Errorf("User() = %q", user)
+				}
+			}
+		})
+	}
+}
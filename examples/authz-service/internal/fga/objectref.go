@@ -0,0 +1,81 @@
+package fga
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Object types defined in examples/deploy/model.json
+const (
+	TypeUser      = "user"
+	TypeWorkspace = "workspace"
+	TypeDocument  = "document"
+	TypeProject   = "project"
+)
+
+var objectTypes = map[string]bool{
+	TypeUser:      true,
+	TypeWorkspace: true,
+	TypeDocument:  true,
+	TypeProject:   true,
+}
+
+// ObjectRef is an OpenFGA object or user reference such as "document:doc-1"
+type ObjectRef struct {
+	Type string
+	ID   string
+}
+
+// UserRef returns the reference for a user ID
+func UserRef(id string) ObjectRef {
+	return ObjectRef{Type: TypeUser, ID: id}
+}
+
+// WorkspaceRef returns the reference for a workspace ID
+func WorkspaceRef(id string) ObjectRef {
+	return ObjectRef{Type: TypeWorkspace, ID: id}
+}
+
+// DocumentRef returns the reference for a document ID
+func DocumentRef(id string) ObjectRef {
+	return ObjectRef{Type: TypeDocument, ID: id}
+}
+
+// ProjectRef returns the reference for a project ID
+func ProjectRef(id string) ObjectRef {
+	return ObjectRef{Type: TypeProject, ID: id}
+}
+
+// ParseObjectRef parses and validates a "type:id" reference. The user
+// wildcard "user:*" is accepted.
+func ParseObjectRef(s string) (ObjectRef, error) {
+	typ, id, ok := strings.Cut(s, ":")
+	if !ok {
+		return ObjectRef{}, fmt.Errorf("invalid object reference %q: expected type:id", s)
+	}
+
+	ref := ObjectRef{Type: typ, ID: id}
+	if err := ref.Validate(); err != nil {
+		return ObjectRef{}, err
+	}
+	return ref, nil
+}
+
+// Validate checks the type is defined in the model and the ID is usable
+func (r ObjectRef) Validate() error {
+	if !objectTypes[r.Type] {
+		return fmt.Errorf("invalid object reference %q: unknown type %q", r.String(), r.Type)
+	}
+	if r.ID == "" {
+		return fmt.Errorf("invalid object reference %q: empty id", r.String())
+	}
+	if strings.ContainsAny(r.ID, ":# \t\n") {
+		return fmt.Errorf("invalid object reference %q: id contains a reserved character", r.String())
+	}
+	return nil
+}
+
+// String returns the "type:id" form used in OpenFGA requests
+func (r ObjectRef) String() string {
+	return r.Type + ":" + r.ID
+}
@@ -0,0 +1,60 @@
+package fga
+
+import "testing"
+
+func TestParseObjectRef(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ObjectRef
+		wantErr bool
+	}{
+		{in: "user:123", want: UserRef("123")},
+		{in: "workspace:ws-1", want: WorkspaceRef("ws-1")},
+		{in: "document:doc-1", want: DocumentRef("doc-1")},
+		{in: "project:p-1", want: ProjectRef("p-1")},
+		{in: "user:*", want: UserRef("*")},
+		{in: "doc-1", wantErr: true},
+		{in: "folder:f-1", wantErr: true}, // not a type in the model
+		{in: "document:", wantErr: true},
+		{in: ":doc-1", wantErr: true},
+		{in: "document:doc:1", wantErr: true},
+		{in: "workspace:ws-1#member", wantErr: true},
+		{in: "document:doc 1", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseObjectRef(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseObjectRef(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseObjectRef(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.in {
+				t.Errorf("String() = %q, want %q", got.String(), tt.in)
+			}
+		})
+	}
+}
+
+func TestValidateRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		object  string
+		wantErr bool
+	}{
+		{"valid", "user:1", "document:doc-1", false},
+		{"public wildcard", "user:*", "document:doc-1", false},
+		{"bad user", "1", "document:doc-1", true},
+		{"bad object", "user:1", "document:", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRefs(tt.user, tt.object); (err != nil) != tt.wantErr {
+				t.Errorf("validateRefs(%q, %q) = %v, want error %v", tt.user, tt.object, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,28 +2,56 @@ package handlers
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/authz-service/internal/auth"
 	"github.com/yourusername/authz-service/internal/fga"
+	"github.com/yourusername/authz-service/internal/requestid"
+)
+
+// Gin context keys the handler fills in for the per-request log entry
+const (
+	userKey      = "gate_user"
+	workspaceKey = "gate_workspace"
+	decisionKey  = "gate_decision"
 )
 
 // GateHandler handles ForwardAuth requests from Traefik
 type GateHandler struct {
 	jwtValidator *auth.CasdoorValidator
 	fgaClient    *fga.Client
+	public       PublicRoutes
+	relations    RelationRules
 	devMode      bool
+
+	// requireForwardedHeaders rejects requests missing the Traefik
+	// X-Forwarded-* headers instead of proceeding with empty values
+	requireForwardedHeaders bool
+
+	// failClosed denies requests when the permission check errors instead
+	// of letting them through
+	failClosed bool
 }
 
-// NewGateHandler creates a new gate handler
-func NewGateHandler(jwtValidator *auth.CasdoorValidator, fgaClient *fga.Client, devMode bool) *GateHandler {
+// NewGateHandler creates a new gate handler. public may be nil to use
+// DefaultPublicRoutes; relations may be nil to map methods to relations by
+// default.
+func NewGateHandler(jwtValidator *auth.CasdoorValidator, fgaClient *fga.Client, public PublicRoutes, relations RelationRules, devMode, requireForwardedHeaders, failClosed bool) *GateHandler {
+	if public == nil {
+		public = DefaultPublicRoutes
+	}
 	return &GateHandler{
-		jwtValidator: jwtValidator,
-		fgaClient:    fgaClient,
-		devMode:      devMode,
+		jwtValidator:            jwtValidator,
+		fgaClient:               fgaClient,
+		public:                  public,
+		relations:               relations,
+		devMode:                 devMode,
+		requireForwardedHeaders: requireForwardedHeaders,
+		failClosed:              failClosed,
 	}
 }
 
@@ -36,10 +64,23 @@ func (h *GateHandler) Handle(c *gin.Context) {
 	originalURI := c.GetHeader("X-Forwarded-Uri")
 	originalMethod := c.GetHeader("X-Forwarded-Method")
 
-	log.Printf("Gate: %s %s", originalMethod, originalURI)
+	defer logRequest(c, time.Now(), originalMethod, originalURI)
+
+	// Missing forwarded headers means Traefik is misconfigured
+	if originalMethod == "" || originalURI == "" {
+		if h.requireForwardedHeaders {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "missing_forwarded_headers",
+				"message": "X-Forwarded-Method and X-Forwarded-Uri headers are required",
+			})
+			return
+		}
+		logf(c, "Warning: missing X-Forwarded-Method/X-Forwarded-Uri headers, proceeding")
+	}
 
 	// Check if this is a public endpoint
-	if h.isPublicEndpoint(originalURI, originalMethod) {
+	if h.public.Match(originalMethod, originalURI) {
+		c.Set(decisionKey, "public")
 		c.Status(http.StatusOK)
 		return
 	}
@@ -47,6 +88,7 @@ func (h *GateHandler) Handle(c *gin.Context) {
 	// Dev mode - bypass authentication
 	if h.devMode {
 		h.setDevModeHeaders(c)
+		c.Set(decisionKey, "dev")
 		c.Status(http.StatusOK)
 		return
 	}
@@ -63,7 +105,7 @@ func (h *GateHandler) Handle(c *gin.Context) {
 
 	// Validate the JWT token
 	if h.jwtValidator == nil {
-		log.Println("Warning: JWT validator not configured")
+		logf(c, "Warning: JWT validator not configured")
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"error":   "configuration_error",
 			"message": "authentication service not configured",
@@ -73,7 +115,7 @@ func (h *GateHandler) Handle(c *gin.Context) {
 
 	userCtx, err := h.jwtValidator.ValidateToken(authHeader)
 	if err != nil {
-		log.Printf("Token validation failed: %v", err)
+		logf(c, "Token validation failed: %v", err)
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"error":   "unauthorized",
 			"message": "invalid or expired token",
@@ -83,13 +125,29 @@ func (h *GateHandler) Handle(c *gin.Context) {
 
 	// Extract workspace from headers (set by frontend)
 	workspaceID := c.GetHeader("X-Workspace-ID")
+	c.Set(userKey, userCtx)
+	c.Set(workspaceKey, workspaceID)
 
 	// Check OpenFGA permissions if configured and workspace is specified
+	decision := "authenticated"
+	if userCtx.IsGlobalAdmin {
+		decision = "admin-bypass"
+	}
 	if h.fgaClient != nil && workspaceID != "" && !userCtx.IsGlobalAdmin {
-		allowed, err := h.checkPermission(c.Request.Context(), userCtx, workspaceID, originalMethod)
+		actx := h.buildAuthzContext(c, userCtx, workspaceID, originalMethod, originalURI)
+		decision = actx.Action + " " + actx.Object.String()
+		allowed, err := h.checkPermission(c.Request.Context(), actx)
 		if err != nil {
-			log.Printf("Permission check error: %v", err)
-			// Allow on error (fail open) - you might want to fail closed in production
+			logf(c, "Permission check error: %v", err)
+			if h.failClosed {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error":   "forbidden",
+					"message": "permission check unavailable",
+				})
+				return
+			}
+			// FAIL_CLOSED=false: allow on error (fail open)
+			decision = "fail-open"
 		} else if !allowed {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
 				"error":   "forbidden",
@@ -102,54 +160,44 @@ func (h *GateHandler) Handle(c *gin.Context) {
 	// Set response headers for downstream services
 	h.setUserHeaders(c, userCtx, workspaceID)
 
+	c.Set(decisionKey, decision)
 	c.Status(http.StatusOK)
 }
 
-// isPublicEndpoint checks if the endpoint doesn't require authentication
-func (h *GateHandler) isPublicEndpoint(uri, method string) bool {
-	// Health check
-	if uri == "/health" || uri == "/api/health" {
-		return true
-	}
-
-	// Only specific auth endpoints are public
-	publicAuthEndpoints := []string{
-		"/api/v1/auth/login",
-		"/api/v1/auth/register",
-		"/api/v1/auth/callback",
-		"/api/v1/auth/config",
-		"/api/v1/auth/logout",
-		"/api/v1/auth/social/",
+// buildAuthzContext collects the subject, object, action and environment
+// attributes for a forwarded request
+func (h *GateHandler) buildAuthzContext(c *gin.Context, userCtx *auth.UserContext, workspaceID, method, uri string) *fga.AuthzContext {
+	return &fga.AuthzContext{
+		Subject: userCtx,
+		Object:  fga.WorkspaceRef(workspaceID),
+		Action:  h.relations.Relation(method, uri),
+		Environment: fga.Environment{
+			Method:   method,
+			Path:     uri,
+			ClientIP: c.ClientIP(),
+			Time:     time.Now(),
+		},
 	}
-
-	for _, endpoint := range publicAuthEndpoints {
-		if strings.HasPrefix(uri, endpoint) {
-			return true
-		}
-	}
-
-	return false
 }
 
-// checkPermission checks if the user has permission for the requested action
-func (h *GateHandler) checkPermission(ctx context.Context, userCtx *auth.UserContext, workspaceID, method string) (bool, error) {
-	user := "user:" + userCtx.UserID
-	object := "container:" + workspaceID
+// checkPermission checks if the subject may perform the requested action
+func (h *GateHandler) checkPermission(ctx context.Context, actx *fga.AuthzContext) (bool, error) {
+	return h.fgaClient.CheckContext(ctx, actx)
+}
 
-	// Map HTTP methods to OpenFGA relations
-	var relation string
+// methodToRelation maps HTTP methods to OpenFGA relations for requests no
+// RelationRule matches
+func methodToRelation(method string) string {
 	switch method {
 	case "GET", "HEAD", "OPTIONS":
-		relation = "can_read"
+		return "can_read"
 	case "POST", "PUT", "PATCH":
-		relation = "can_write"
+		return "can_write"
 	case "DELETE":
-		relation = "can_manage"
+		return "can_manage"
 	default:
-		relation = "can_read"
+		return "can_read"
 	}
-
-	return h.fgaClient.Check(ctx, user, relation, object)
 }
 
 // setUserHeaders sets headers for downstream services
@@ -188,3 +236,56 @@ func boolToString(b bool) string {
 	}
 	return "false"
 }
+
+// logf writes a gate log line tagged with the request's correlation ID
+func logf(c *gin.Context, format string, args ...interface{}) {
+	slog.Info(fmt.Sprintf(format, args...), "component", "gate", "request_id", requestid.Get(c))
+}
+
+// logRequest writes one structured entry per gate request with its outcome.
+// Denials have no recorded decision, so it is derived from the status.
+func logRequest(c *gin.Context, start time.Time, method, uri string) {
+	status := c.Writer.Status()
+	decision := c.GetString(decisionKey)
+	if decision == "" {
+		decision = statusDecision(status)
+	}
+
+	attrs := []any{
+		"component", "gate",
+		"request_id", requestid.Get(c),
+		"method", method,
+		"uri", uri,
+		"status", status,
+		"decision", decision,
+		"latency_ms", time.Since(start).Milliseconds(),
+	}
+	if v, ok := c.Get(userKey); ok {
+		userCtx := v.(*auth.UserContext)
+		attrs = append(attrs,
+			"user_id", userCtx.UserID,
+			"tenant_id", userCtx.Organization,
+			"workspace_id", c.GetString(workspaceKey),
+		)
+	}
+
+	level := slog.LevelInfo
+	if status >= http.StatusBadRequest {
+		level = slog.LevelWarn
+	}
+	slog.Log(c.Request.Context(), level, "gate request", attrs...)
+}
+
+// statusDecision names the outcome of a denied request
+func statusDecision(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "unauthenticated"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusBadRequest:
+		return "bad-request"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// forwardAuth sends a ForwardAuth request for method and uri, as Traefik
+// would, leaving out empty headers
+func forwardAuth(h *GateHandler, method, uri string, headers map[string]string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.GET("/auth", h.Handle)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth", nil)
+	if method != "" {
+		req.Header.Set("X-Forwarded-Method", method)
+	}
+	if uri != "" {
+		req.Header.Set("X-Forwarded-Uri", uri)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMissingForwardedHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		require bool
+		devMode bool
+		method  string
+		uri     string
+		want    int
+	}{
+		{"both present", true, true, http.MethodGet, "/api/v1/documents", http.StatusOK},
+		{"missing uri", true, true, http.MethodGet, "", http.StatusBadRequest},
+		{"missing method", true, true, "", "/api/v1/documents", http.StatusBadRequest},
+		{"missing both", true, true, "", "", http.StatusBadRequest},
+		{"missing both, not required", false, true, "", "", http.StatusOK},
+		{"not required still needs credentials", false, false, "", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewGateHandler(nil, nil, nil, nil, tt.devMode, tt.require, true)
+			w := forwardAuth(h, tt.method, tt.uri, nil)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusBadRequest && !strings.Contains(w.Body.String(), "missing_forwarded_headers") {
+				t.Errorf("body = %s, want missing_forwarded_headers", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PublicRoute is an allowlist entry for requests the gate lets through
+// without authentication. Exactly one of Path (exact match) and PathPrefix
+// must be set; Method scopes the entry to one HTTP method (empty = any).
+type PublicRoute struct {
+	Method     string `json:"method,omitempty"`
+	Path       string `json:"path,omitempty"`
+	PathPrefix string `json:"path_prefix,omitempty"`
+}
+
+// PublicRoutes is the gate's public-route allowlist. A request is public
+// when any entry matches it; entries only ever allow, so a method-scoped
+// entry never narrows another entry for the same path.
+type PublicRoutes []PublicRoute
+
+// DefaultPublicRoutes is used when no allowlist is configured
+var DefaultPublicRoutes = PublicRoutes{
+	{Path: "/health"},
+	{Path: "/api/health"},
+	{PathPrefix: "/api/v1/auth/login"},
+	{PathPrefix: "/api/v1/auth/register"},
+	{PathPrefix: "/api/v1/auth/callback"},
+	{PathPrefix: "/api/v1/auth/config"},
+	{PathPrefix: "/api/v1/auth/logout"},
+	{PathPrefix: "/api/v1/auth/social/"},
+}
+
+// Match reports whether a request is public. The query string is ignored.
+func (r PublicRoutes) Match(method, uri string) bool {
+	path, _, _ := strings.Cut(uri, "?")
+	for _, route := range r {
+		if route.Method != "" && !strings.EqualFold(route.Method, method) {
+			continue
+		}
+		if route.Path != "" && path == route.Path {
+			return true
+		}
+		if route.PathPrefix != "" && strings.HasPrefix(path, route.PathPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadPublicRoutes builds the allowlist from a JSON file of PublicRoute
+// entries, or else from spec, a comma-separated list of "[METHOD] path"
+// entries where a trailing "*" makes the path a prefix, e.g.
+// "GET /api/v1/tenant/plans,/api/v1/auth/*". With neither set it returns
+// DefaultPublicRoutes.
+func LoadPublicRoutes(spec, file string) (PublicRoutes, error) {
+	var routes PublicRoutes
+	switch {
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read public routes: %w", err)
+		}
+		if err := json.Unmarshal(data, &routes); err != nil {
+			return nil, fmt.Errorf("parse public routes %s: %w", file, err)
+		}
+	case strings.TrimSpace(spec) != "":
+		for _, entry := range strings.Split(spec, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			var route PublicRoute
+			if method, path, ok := strings.Cut(entry, " "); ok {
+				route.Method = method
+				entry = strings.TrimSpace(path)
+			}
+			if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+				route.PathPrefix = prefix
+			} else {
+				route.Path = entry
+			}
+			routes = append(routes, route)
+		}
+	default:
+		return DefaultPublicRoutes, nil
+	}
+
+	for i, route := range routes {
+		if (route.Path == "") == (route.PathPrefix == "") {
+			return nil, fmt.Errorf("public route %d: exactly one of path and path_prefix is required", i)
+		}
+		if !strings.HasPrefix(route.Path+route.PathPrefix, "/") {
+			return nil, fmt.Errorf("public route %d: path must start with /", i)
+		}
+		routes[i].Method = strings.ToUpper(route.Method)
+	}
+	return routes, nil
+}
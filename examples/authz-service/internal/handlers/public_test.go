@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPublicRoutesMatch(t *testing.T) {
+	routes := PublicRoutes{
+		{Path: "/status"},
+		{PathPrefix: "/api/v1/auth/"},
+		{Method: "GET", PathPrefix: "/api/v1/tenant/plans"},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		uri    string
+		want   bool
+	}{
+		{"exact path", "GET", "/status", true},
+		{"exact path with a query", "GET", "/status?verbose=1", true},
+		{"exact path doesn't match below it", "GET", "/status/db", false},
+		{"prefix", "POST", "/api/v1/auth/login", true},
+		{"prefix needs the slash", "POST", "/api/v1/authorize", false},
+		{"method-scoped entry", "GET", "/api/v1/tenant/plans", true},
+		{"method-scoped entry, lower case", "get", "/api/v1/tenant/plans/basic", true},
+		{"method-scoped entry, other method", "POST", "/api/v1/tenant/plans", false},
+		{"query can't fake a path", "GET", "/api/v1/documents?x=/status", false},
+		{"not listed", "GET", "/api/v1/documents", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routes.Match(tt.method, tt.uri); got != tt.want {
+				t.Errorf("Match(%s, %s) = %v, want %v", tt.method, tt.uri, got, tt.want)
+			}
+		})
+	}
+
+	for uri, want := range map[string]bool{
+		"/health":                true,
+		"/api/health":            true,
+		"/api/v1/auth/login":     true,
+		"/api/v1/auth/social/gh": true,
+		"/health/db":             false,
+		"/api/v1/auth/me":        false,
+	} {
+		if got := DefaultPublicRoutes.Match(http.MethodGet, uri); got != want {
+			t.Errorf("default routes match %s = %v, want %v", uri, got, want)
+		}
+	}
+}
+
+func TestLoadPublicRoutes(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	valid := writeFile("valid.json", `[{"method":"get","path":"/status"},{"path_prefix":"/api/v1/auth/"}]`)
+
+	tests := []struct {
+		name    string
+		spec    string
+		file    string
+		want    PublicRoutes
+		wantErr string
+	}{
+		{"defaults", "", "", DefaultPublicRoutes, ""},
+		{"blank spec", " , ", "", nil, ""},
+		{"spec", "GET /status, /api/v1/auth/*", "", PublicRoutes{{Method: "GET", Path: "/status"}, {PathPrefix: "/api/v1/auth/"}}, ""},
+		{"spec method is upper-cased", "post /hooks/*", "", PublicRoutes{{Method: "POST", PathPrefix: "/hooks/"}}, ""},
+		{"spec without a leading slash", "GET status", "", nil, "must start with /"},
+		{"bare prefix", "*", "", nil, "exactly one of path and path_prefix"},
+		{"file", "", valid, PublicRoutes{{Method: "GET", Path: "/status"}, {PathPrefix: "/api/v1/auth/"}}, ""},
+		{"file wins over spec", "/other", valid, PublicRoutes{{Method: "GET", Path: "/status"}, {PathPrefix: "/api/v1/auth/"}}, ""},
+		{"file entry with both paths", "", writeFile("both.json", `[{"path":"/a","path_prefix":"/b"}]`), nil, "exactly one of path and path_prefix"},
+		{"file entry with neither", "", writeFile("neither.json", `[{"method":"GET"}]`), nil, "exactly one of path and path_prefix"},
+		{"file not json", "", writeFile("bad.json", `{`), nil, "parse public routes"},
+		{"file missing", "", filepath.Join(dir, "missing.json"), nil, "read public routes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := LoadPublicRoutes(tt.spec, tt.file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadPublicRoutes() = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(routes) != len(tt.want) {
+				t.Fatalf("routes = %+v, want %+v", routes, tt.want)
+			}
+			for i := range routes {
+				if routes[i] != tt.want[i] {
+					t.Errorf("route %d = %+v, want %+v", i, routes[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGatePublicRoutes(t *testing.T) {
+	public, err := LoadPublicRoutes("GET /status", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewGateHandler(nil, nil, public, nil, false, true, true)
+
+	tests := []struct {
+		name   string
+		method string
+		uri    string
+		want   int
+	}{
+		{"listed route", http.MethodGet, "/status", http.StatusOK},
+		{"listed route, other method", http.MethodPost, "/status", http.StatusUnauthorized},
+		{"default route no longer public", http.MethodGet, "/health", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := forwardAuth(h, tt.method, tt.uri, nil); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// RelationRule picks the OpenFGA relation checked on the workspace for
+// matching requests, e.g. POST ^/api/v1/projects/[^/]+/deploy$ ->
+// can_deploy. Path is a regular expression matched against the request path
+// (unanchored unless it uses ^ and $); Method scopes the rule to one HTTP
+// method (empty = any).
+type RelationRule struct {
+	Method   string `json:"method,omitempty"`
+	Path     string `json:"path"`
+	Relation string `json:"relation"`
+
+	pattern *regexp.Regexp
+}
+
+// RelationRules are evaluated in order and the first match wins. Requests
+// no rule matches use the method-based default (methodToRelation).
+type RelationRules []RelationRule
+
+// Relation returns the relation to check for a request. The query string
+// is ignored.
+func (r RelationRules) Relation(method, uri string) string {
+	path, _, _ := strings.Cut(uri, "?")
+	for _, rule := range r {
+		if rule.Method != "" && !strings.EqualFold(rule.Method, method) {
+			continue
+		}
+		if rule.pattern.MatchString(path) {
+			return rule.Relation
+		}
+	}
+	return methodToRelation(method)
+}
+
+// LoadRelationRules reads a JSON array of {method, path, relation} rules.
+// An empty file name returns no rules, leaving the method-based default.
+func LoadRelationRules(file string) (RelationRules, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read relation rules: %w", err)
+	}
+
+	var rules RelationRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse relation rules %s: %w", file, err)
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Path == "" || rule.Relation == "" {
+			return nil, fmt.Errorf("relation rule %d: path and relation are required", i)
+		}
+		rule.pattern, err = regexp.Compile(rule.Path)
+		if err != nil {
+			return nil, fmt.Errorf("relation rule %d: invalid path pattern: %w", i, err)
+		}
+		rule.Method = strings.ToUpper(rule.Method)
+	}
+	return rules, nil
+}
@@ -0,0 +1,82 @@
+// Package requestid gives every gate request a correlation ID, reusing the
+// caller's when valid, so gate logs and upstream logs can be joined.
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKey is the gin context key holding the request's ID
+const ContextKey = "request_id"
+
+// maxLength bounds client-supplied IDs so they can't bloat logs
+const maxLength = 128
+
+// Middleware reuses a valid incoming ID or generates a new one, stores it in
+// the context and echoes it in the response header, which Traefik copies
+// onto the upstream request when listed in authResponseHeaders
+func Middleware(header string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(header)
+		if !valid(id) {
+			id = New()
+		}
+
+		c.Set(ContextKey, id)
+		c.Header(header, id)
+		c.Next()
+	}
+}
+
+// Get returns the request ID stored by Middleware, or "" if none
+func Get(c *gin.Context) string {
+	return c.GetString(ContextKey)
+}
+
+// New generates a random 128-bit hex ID
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Logger is the access log, written through slog with the request ID
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		slog.Info("http request",
+			"request_id", Get(c),
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
+
+// valid accepts IDs made of URL-safe characters only, so a spoofed header
+// can't inject log lines or break downstream parsers
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
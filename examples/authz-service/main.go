@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,15 +14,20 @@ import (
 	"github.com/yourusername/authz-service/internal/auth"
 	"github.com/yourusername/authz-service/internal/fga"
 	"github.com/yourusername/authz-service/internal/handlers"
+	"github.com/yourusername/authz-service/internal/requestid"
 )
 
 func main() {
+	setupLogging(getEnv("LOG_FORMAT", "json"))
+
 	// Initialize Casdoor JWT validator
 	casdoorEndpoint := getEnv("CASDOOR_ENDPOINT", "http://casdoor:8000")
 	casdoorOrg := getEnv("CASDOOR_ORGANIZATION", "built-in")
 	casdoorApp := getEnv("CASDOOR_APPLICATION", "app-built-in")
 
-	jwtValidator, err := auth.NewCasdoorValidator(casdoorEndpoint, casdoorOrg, casdoorApp)
+	jwksCacheTTL := getEnvDuration("JWKS_CACHE_TTL", time.Hour)
+
+	jwtValidator, err := auth.NewCasdoorValidator(casdoorEndpoint, casdoorOrg, casdoorApp, jwksCacheTTL)
 	if err != nil {
 		log.Printf("Warning: Casdoor validator initialization failed: %v", err)
 		log.Println("Running without JWT validation")
@@ -43,22 +51,60 @@ func main() {
 		log.Println("No OpenFGA store ID configured - permission checks disabled")
 	}
 
-	// Check for dev mode
+	// Check for dev mode. It grants platform admin to unauthenticated
+	// requests, so refuse to start with it in production.
 	devMode := getEnv("DEV_MODE", "false") == "true"
+	if err := checkDevMode(devMode, getEnv("ENVIRONMENT", "development")); err != nil {
+		log.Fatal(err)
+	}
 	if devMode {
-		log.Println("WARNING: Running in DEV_MODE - authentication bypassed!")
+		log.Println("WARNING: Running in DEV_MODE - authentication bypassed, all requests are platform admin!")
+	}
+
+	// Reject requests without Traefik's forwarded headers unless disabled
+	requireForwardedHeaders := getEnv("REQUIRE_FORWARDED_HEADERS", "true") == "true"
+
+	// Deny on permission check errors; defaults to on in release mode
+	failClosed := getEnv("FAIL_CLOSED", strconv.FormatBool(gin.Mode() == gin.ReleaseMode)) == "true"
+	if !failClosed {
+		log.Println("WARNING: FAIL_CLOSED=false - requests are allowed when the permission check errors")
+	}
+
+	// Public-route allowlist: PUBLIC_ROUTES_FILE (JSON) or PUBLIC_ROUTES,
+	// else the built-in defaults
+	publicRoutes, err := handlers.LoadPublicRoutes(getEnv("PUBLIC_ROUTES", ""), getEnv("PUBLIC_ROUTES_FILE", ""))
+	if err != nil {
+		log.Fatalf("Invalid public routes: %v", err)
+	}
+
+	// Per-route relations (e.g. can_deploy for deploys) from
+	// RELATION_RULES_FILE; other requests map by method
+	relationRules, err := handlers.LoadRelationRules(getEnv("RELATION_RULES_FILE", ""))
+	if err != nil {
+		log.Fatalf("Invalid relation rules: %v", err)
 	}
 
 	// Initialize handler
-	gateHandler := handlers.NewGateHandler(jwtValidator, fgaClient, devMode)
+	gateHandler := handlers.NewGateHandler(jwtValidator, fgaClient, publicRoutes, relationRules, devMode, requireForwardedHeaders, failClosed)
 
-	// Setup router
-	r := gin.Default()
+	// Setup router. The request ID is reused from the caller or generated,
+	// and echoed back so Traefik can forward it upstream.
+	r := gin.New()
+	r.Use(requestid.Middleware(getEnv("REQUEST_ID_HEADER", "X-Request-ID")), requestid.Logger(), gin.Recovery())
 
-	// Health check
+	// Health check (liveness) and dependency readiness
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok", "service": "authz"})
 	})
+	casdoorCheck := readinessCheck{name: "casdoor"}
+	if jwtValidator != nil {
+		casdoorCheck.check = jwtValidator.Ready
+	}
+	openfgaCheck := readinessCheck{name: "openfga"}
+	if fgaClient != nil {
+		openfgaCheck.check = fgaClient.Ready
+	}
+	r.GET("/ready", readyHandler(casdoorCheck, openfgaCheck))
 
 	// ForwardAuth endpoint - called by Traefik for every request
 	r.GET("/gate", gateHandler.Handle)
@@ -70,6 +116,26 @@ func main() {
 	r.Run(":" + port)
 }
 
+// checkDevMode rejects dev mode in the production environment
+func checkDevMode(devMode bool, environment string) error {
+	if devMode && strings.EqualFold(environment, "production") {
+		return errors.New("DEV_MODE=true is not allowed when ENVIRONMENT=production")
+	}
+	return nil
+}
+
+// setupLogging routes slog, and the standard log package through it, to
+// stdout as JSON, or key=value text when format is "text"
+func setupLogging(format string) {
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, nil)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, nil)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -77,6 +143,15 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
 func getStoreID() string {
 	// First check for direct environment variable
 	if storeID := os.Getenv("OPENFGA_STORE_ID"); storeID != "" {
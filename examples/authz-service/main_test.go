@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestCheckDevMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		devMode     bool
+		environment string
+		wantErr     bool
+	}{
+		{"dev mode in development", true, "development", false},
+		{"dev mode in staging", true, "staging", false},
+		{"dev mode in production", true, "production", true},
+		{"dev mode in production, any case", true, "Production", true},
+		{"production without dev mode", false, "production", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkDevMode(tt.devMode, tt.environment); (err != nil) != tt.wantErr {
+				t.Errorf("checkDevMode() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
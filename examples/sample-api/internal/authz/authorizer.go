@@ -0,0 +1,18 @@
+package authz
+
+// Authorizer is the relationship API the handlers depend on. OpenFGAClient
+// implements it against a real OpenFGA server; testutil.MemoryFGA implements
+// it in memory for tests.
+type Authorizer interface {
+	Check(user, relation, object string) (bool, error)
+	BatchCheck(checks []CheckRequest) ([]bool, error)
+	ListRelations(user, object string, relations []string) (map[string]bool, error)
+	ListUsers(object, relation, userType string) ([]string, error)
+	WriteTuple(user, relation, object string) error
+	WriteTuples(tuples []Tuple) error
+	DeleteTuple(user, relation, object string) error
+	UpdateTuples(writes, deletes []Tuple) error
+	ReadTuples(object string) ([]Tuple, error)
+}
+
+var _ Authorizer = (*OpenFGAClient)(nil)
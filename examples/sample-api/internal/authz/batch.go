@@ -0,0 +1,194 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxBatchChecks is OpenFGA's default limit on checks per batch-check call
+const maxBatchChecks = 50
+
+// batchFallbackWorkers bounds concurrent Check calls when the server has no
+// batch-check endpoint
+const batchFallbackWorkers = 10
+
+// CheckRequest is a single permission check in a BatchCheck
+type CheckRequest struct {
+	User     string
+	Relation string
+	Object   string
+}
+
+// BatchCheckError reports the checks that could not be evaluated, keyed by
+// their index in the request. Their results are returned as false.
+type BatchCheckError struct {
+	Failed map[int]error
+}
+
+func (e *BatchCheckError) Error() string {
+	indexes := make([]int, 0, len(e.Failed))
+	for i := range e.Failed {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	parts := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		parts = append(parts, fmt.Sprintf("check %d: %v", i, e.Failed[i]))
+	}
+	return fmt.Sprintf("%d batch checks failed: %s", len(e.Failed), strings.Join(parts, "; "))
+}
+
+// BatchCheck evaluates many checks at once and returns results in request
+// order. It uses OpenFGA's batch-check endpoint and falls back to concurrent
+// Check calls on servers that don't support it. If only some checks fail,
+// the other results are still returned along with a *BatchCheckError.
+func (c *OpenFGAClient) BatchCheck(checks []CheckRequest) ([]bool, error) {
+	results := make([]bool, len(checks))
+	failed := make(map[int]error)
+
+	for start := 0; start < len(checks); start += maxBatchChecks {
+		end := start + maxBatchChecks
+		if end > len(checks) {
+			end = len(checks)
+		}
+
+		if err := c.batchCheckChunk(checks[start:end], results[start:end], failed, start); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(failed) > 0 {
+		return results, &BatchCheckError{Failed: failed}
+	}
+	return results, nil
+}
+
+// batchCheckChunk fills results for up to maxBatchChecks checks. Per-check
+// failures are recorded in failed (offset by base); a returned error means
+// the whole chunk failed.
+func (c *OpenFGAClient) batchCheckChunk(checks []CheckRequest, results []bool, failed map[int]error, base int) error {
+	if c.batchUnsupported() {
+		c.concurrentCheck(checks, results, failed, base)
+		return nil
+	}
+
+	type tupleKey struct {
+		User     string `json:"user"`
+		Relation string `json:"relation"`
+		Object   string `json:"object"`
+	}
+	type batchItem struct {
+		TupleKey      tupleKey `json:"tuple_key"`
+		CorrelationID string   `json:"correlation_id"`
+	}
+
+	items := make([]batchItem, len(checks))
+	for i, check := range checks {
+		items[i] = batchItem{
+			TupleKey:      tupleKey{User: check.User, Relation: check.Relation, Object: check.Object},
+			CorrelationID: strconv.Itoa(i),
+		}
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"checks": items})
+	url := fmt.Sprintf("%s/stores/%s/batch-check", c.apiURL, c.storeID)
+	req, err := http.NewRequestWithContext(context.Background(), "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("batch check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Servers before batch-check support answer 404/501
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented {
+		c.markBatchUnsupported()
+		c.concurrentCheck(checks, results, failed, base)
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("batch check failed: %s - %s", resp.Status, string(respBody))
+	}
+
+	var result struct {
+		Result map[string]struct {
+			Allowed bool `json:"allowed"`
+			Error   *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("batch check failed: %w", err)
+	}
+
+	for i := range checks {
+		r, ok := result.Result[strconv.Itoa(i)]
+		switch {
+		case !ok:
+			failed[base+i] = fmt.Errorf("no result returned")
+		case r.Error != nil:
+			failed[base+i] = fmt.Errorf("%s", r.Error.Message)
+		default:
+			results[i] = r.Allowed
+		}
+	}
+
+	return nil
+}
+
+// concurrentCheck runs checks individually with a bounded worker pool
+func (c *OpenFGAClient) concurrentCheck(checks []CheckRequest, results []bool, failed map[int]error, base int) {
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, batchFallbackWorkers)
+	)
+
+	for i, check := range checks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, check CheckRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			allowed, err := c.Check(check.User, check.Relation, check.Object)
+			if err != nil {
+				mu.Lock()
+				failed[base+i] = err
+				mu.Unlock()
+				return
+			}
+			results[i] = allowed
+		}(i, check)
+	}
+
+	wg.Wait()
+}
+
+func (c *OpenFGAClient) batchUnsupported() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.noBatchCheck
+}
+
+func (c *OpenFGAClient) markBatchUnsupported() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.noBatchCheck = true
+}
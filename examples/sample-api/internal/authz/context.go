@@ -0,0 +1,40 @@
+package authz
+
+import (
+	"time"
+
+	"github.com/yourusername/sample-api/internal/store"
+)
+
+// AuthzContext carries everything an authorization decision may depend on.
+// Passing a single struct lets new attributes (IP, time, device, ...) reach
+// every policy without changing call signatures.
+type AuthzContext struct {
+	Subject     *store.UserContext
+	Object      Object
+	Action      string
+	Environment Environment
+}
+
+// Object is the resource being accessed
+type Object struct {
+	ObjectRef
+	Attributes map[string]string
+}
+
+// Environment holds request-scoped attributes
+type Environment struct {
+	ClientIP string
+	Time     time.Time
+}
+
+// Attr returns an object attribute, or "" if unset
+func (o Object) Attr(name string) string {
+	return o.Attributes[name]
+}
+
+// CheckContext performs a permission check for the subject, action and object
+// in the given context
+func (c *OpenFGAClient) CheckContext(actx *AuthzContext) (bool, error) {
+	return c.Check(UserRef(actx.Subject.UserID).String(), actx.Action, actx.Object.String())
+}
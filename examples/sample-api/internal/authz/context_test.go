@@ -0,0 +1,117 @@
+package authz
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/sample-api/internal/store"
+)
+
+const testStoreID = "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+
+// checkRequest is the part of an OpenFGA check request body the tests look at
+type checkRequest struct {
+	TupleKey struct {
+		User     string `json:"user"`
+		Relation string `json:"relation"`
+		Object   string `json:"object"`
+	} `json:"tuple_key"`
+}
+
+// fakeCheckServer answers OpenFGA check requests with allow(user, relation,
+// object) and returns a client for it
+func fakeCheckServer(t *testing.T, allow func(user, relation, object string) bool) *OpenFGAClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/check") {
+			http.NotFound(w, r)
+			return
+		}
+		var req checkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		k := req.TupleKey
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"allowed": allow(k.User, k.Relation, k.Object)})
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := NewOpenFGAClient(srv.URL, testStoreID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCheckContext(t *testing.T) {
+	c := fakeCheckServer(t, func(user, relation, object string) bool {
+		return user == "user:alice" && relation == "can_read" && object == "document:doc-1"
+	})
+
+	tests := []struct {
+		name    string
+		actx    AuthzContext
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "allowed",
+			actx: AuthzContext{Subject: &store.UserContext{UserID: "alice"}, Object: Object{ObjectRef: DocumentRef("doc-1")}, Action: "can_read"},
+			want: true,
+		},
+		{
+			name: "other action",
+			actx: AuthzContext{Subject: &store.UserContext{UserID: "alice"}, Object: Object{ObjectRef: DocumentRef("doc-1")}, Action: "can_write"},
+		},
+		{
+			name: "other subject",
+			actx: AuthzContext{Subject: &store.UserContext{UserID: "bob"}, Object: Object{ObjectRef: DocumentRef("doc-1")}, Action: "can_read"},
+		},
+		{
+			name: "other object",
+			actx: AuthzContext{Subject: &store.UserContext{UserID: "alice"}, Object: Object{ObjectRef: ProjectRef("doc-1")}, Action: "can_read"},
+		},
+		{
+			name:    "invalid object",
+			actx:    AuthzContext{Subject: &store.UserContext{UserID: "alice"}, Object: Object{ObjectRef: DocumentRef("")}, Action: "can_read"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.CheckContext(&tt.actx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckContext() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CheckContext() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObjectAttr(t *testing.T) {
+	obj := Object{ObjectRef: ProjectRef("p-1"), Attributes: map[string]string{"classification": "secret"}}
+	tests := []struct {
+		name string
+		obj  Object
+		attr string
+		want string
+	}{
+		{"set", obj, "classification", "secret"},
+		{"unset", obj, "region", ""},
+		{"no attributes", Object{ObjectRef: ProjectRef("p-1")}, "classification", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.obj.Attr(tt.attr); got != tt.want {
+				t.Errorf("Attr(%q) = %q, want %q", tt.attr, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package authz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	openfga "github.com/openfga/go-sdk"
+)
+
+// maxExpandDepth bounds how many usersets ExplainAccess follows from the
+// checked relation, like OpenFGA's own resolution depth limit
+const maxExpandDepth = 25
+
+// AccessGrant is one way a user gets a relation on an object
+type AccessGrant struct {
+	User string `json:"user"` // e.g. user:alice, or user:* for everyone
+
+	// Path lists the usersets followed from the checked relation to the
+	// tuple that grants it, e.g. document:d#can_read, document:d#workspace,
+	// workspace:ws#viewer, workspace:ws#member
+	Path   []string `json:"path"`
+	Reason string   `json:"reason"`
+
+	// Conditional is set when the grant sits under an intersection or
+	// exclusion, so it only applies if the other branches also allow it
+	Conditional bool `json:"conditional,omitempty"`
+}
+
+// ExplainAccess expands relation on object and flattens the userset tree
+// into the grants behind it: direct tuples, relations implied by other
+// relations, and paths inherited through parents such as the workspace.
+// OpenFGA's Expand only resolves one level, so usersets are expanded in
+// turn until only users remain.
+func (c *OpenFGAClient) ExplainAccess(relation, object string) ([]AccessGrant, error) {
+	if _, err := ParseObjectRef(object); err != nil {
+		return nil, err
+	}
+
+	e := &explainer{client: c, root: object, grants: []AccessGrant{}}
+	if err := e.expand(object+"#"+relation, nil, false); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(e.grants, func(i, j int) bool {
+		return e.grants[i].User < e.grants[j].User
+	})
+	return e.grants, nil
+}
+
+// explainer accumulates the grants found while walking expand trees
+type explainer struct {
+	client *OpenFGAClient
+	root   string
+	grants []AccessGrant
+}
+
+// expand resolves userset (object#relation) and walks its tree. path holds
+// the usersets already followed, which also guards against cycles.
+func (e *explainer) expand(userset string, path []string, conditional bool) error {
+	if len(path) >= maxExpandDepth {
+		return fmt.Errorf("expand too deep at %s", userset)
+	}
+	for _, seen := range path {
+		if seen == userset {
+			return nil
+		}
+	}
+
+	object, relation, _ := strings.Cut(userset, "#")
+	tree, err := e.client.Expand(relation, object)
+	if err != nil {
+		return err
+	}
+	if tree == nil || tree.Root == nil {
+		return nil
+	}
+
+	path = append(append([]string(nil), path...), userset)
+	return e.walk(tree.Root, path, conditional)
+}
+
+func (e *explainer) walk(node *openfga.Node, path []string, conditional bool) error {
+	switch {
+	case node.Union != nil:
+		for i := range node.Union.Nodes {
+			if err := e.walk(&node.Union.Nodes[i], path, conditional); err != nil {
+				return err
+			}
+		}
+	case node.Intersection != nil:
+		for i := range node.Intersection.Nodes {
+			if err := e.walk(&node.Intersection.Nodes[i], path, true); err != nil {
+				return err
+			}
+		}
+	case node.Difference != nil:
+		// Only the base grants access; the subtracted branch takes it away
+		return e.walk(&node.Difference.Base, path, true)
+	case node.Leaf != nil:
+		return e.walkLeaf(node.Leaf, path, conditional)
+	}
+	return nil
+}
+
+func (e *explainer) walkLeaf(leaf *openfga.Leaf, path []string, conditional bool) error {
+	switch {
+	case leaf.Users != nil:
+		for _, user := range leaf.Users.Users {
+			if strings.Contains(user, "#") {
+				// A userset grant such as workspace:ws#member
+				if err := e.expand(user, path, conditional); err != nil {
+					return err
+				}
+				continue
+			}
+			e.grants = append(e.grants, AccessGrant{
+				User:        user,
+				Path:        path,
+				Reason:      e.reason(user, path),
+				Conditional: conditional,
+			})
+		}
+	case leaf.Computed != nil:
+		return e.expand(leaf.Computed.Userset, path, conditional)
+	case leaf.TupleToUserset != nil:
+		// Tupleset links the object to its parents (document:d#workspace);
+		// Computed holds the parent usersets, e.g. workspace:ws#viewer
+		parentPath := append(append([]string(nil), path...), leaf.TupleToUserset.Tupleset)
+		for _, computed := range leaf.TupleToUserset.Computed {
+			if err := e.expand(computed.Userset, parentPath, conditional); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reason describes a grant from the userset holding its tuple (the last in
+// path): "direct owner grant" on the object itself, or "via workspace
+// membership (member of workspace:ws)" when inherited from another object
+func (e *explainer) reason(user string, path []string) string {
+	object, relation, _ := strings.Cut(path[len(path)-1], "#")
+
+	reason := fmt.Sprintf("direct %s grant", relation)
+	if object != e.root {
+		objectType, _, _ := strings.Cut(object, ":")
+		reason = fmt.Sprintf("via %s membership (%s of %s)", objectType, relation, object)
+	}
+	if strings.HasSuffix(user, ":*") {
+		reason += " to everyone"
+	}
+	return reason
+}
@@ -0,0 +1,157 @@
+package authz
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// FreezeWindow is a period in which deployments are blocked. It is either
+// weekly, from Start to End given as "Fri 17:00" and "Mon 09:00" in
+// Timezone, or a one-off maintenance window from From to Until.
+type FreezeWindow struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	Start    string `json:"start,omitempty"`
+	End      string `json:"end,omitempty"`
+	Timezone string `json:"timezone,omitempty"` // IANA name, default UTC
+
+	From  time.Time `json:"from,omitempty"`
+	Until time.Time `json:"until,omitempty"`
+
+	loc        *time.Location
+	start, end weekTime
+}
+
+// FreezeSchedule holds the freeze windows of each project environment
+type FreezeSchedule map[string][]FreezeWindow
+
+// LoadFreezeSchedule reads a JSON object mapping environments to their
+// freeze windows and validates it
+func LoadFreezeSchedule(path string) (FreezeSchedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule FreezeSchedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for env, windows := range schedule {
+		for i := range windows {
+			if err := windows[i].init(); err != nil {
+				return nil, fmt.Errorf("%s window %d (%s): %w", env, i, windows[i].Name, err)
+			}
+		}
+	}
+	return schedule, nil
+}
+
+// Active returns the window freezing deployments to env at t, or nil
+func (s FreezeSchedule) Active(env string, t time.Time) *FreezeWindow {
+	for i := range s[env] {
+		if s[env][i].Contains(t) {
+			return &s[env][i]
+		}
+	}
+	return nil
+}
+
+func (w *FreezeWindow) init() error {
+	if w.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	weekly := w.Start != "" || w.End != ""
+	oneOff := !w.From.IsZero() || !w.Until.IsZero()
+	switch {
+	case weekly && oneOff:
+		return fmt.Errorf("use either start/end or from/until, not both")
+	case oneOff:
+		if w.From.IsZero() || w.Until.IsZero() || !w.Until.After(w.From) {
+			return fmt.Errorf("from and until are required, with until after from")
+		}
+		return nil
+	case !weekly:
+		return fmt.Errorf("start/end or from/until is required")
+	}
+
+	var err error
+	if w.loc, err = time.LoadLocation(w.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", w.Timezone)
+	}
+	if w.start, err = parseWeekTime(w.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	if w.end, err = parseWeekTime(w.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	if w.start == w.end {
+		return fmt.Errorf("start and end must differ")
+	}
+	return nil
+}
+
+// Contains reports whether t falls inside the window
+func (w *FreezeWindow) Contains(t time.Time) bool {
+	if w.loc == nil {
+		return !t.Before(w.From) && t.Before(w.Until)
+	}
+
+	now := weekTimeOf(t.In(w.loc))
+	if w.start < w.end {
+		return now >= w.start && now < w.end
+	}
+	// Wraps around the end of the week, e.g. Fri 17:00 to Mon 09:00
+	return now >= w.start || now < w.end
+}
+
+// EndsAt returns when the window containing t closes
+func (w *FreezeWindow) EndsAt(t time.Time) time.Time {
+	if w.loc == nil {
+		return w.Until
+	}
+
+	local := t.In(w.loc)
+	days := (int(w.end.weekday()) - int(local.Weekday()) + 7) % 7
+	if days == 0 && w.end.minute() <= local.Hour()*60+local.Minute() {
+		days = 7
+	}
+	return time.Date(local.Year(), local.Month(), local.Day()+days,
+		w.end.minute()/60, w.end.minute()%60, 0, 0, w.loc)
+}
+
+// weekTime is a minute of the week, counted from Sunday 00:00
+type weekTime int
+
+const minutesPerDay = 24 * 60
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWeekTime parses "Fri 17:00"
+func parseWeekTime(s string) (weekTime, error) {
+	day, clock, ok := strings.Cut(strings.TrimSpace(s), " ")
+	weekday, known := weekdays[strings.ToLower(day)]
+	if !ok || !known {
+		return 0, fmt.Errorf("%q must be a weekday and time such as \"Fri 17:00\"", s)
+	}
+	at, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, fmt.Errorf("%q must be a weekday and time such as \"Fri 17:00\"", s)
+	}
+	return weekTime(int(weekday)*minutesPerDay + at.Hour()*60 + at.Minute()), nil
+}
+
+func weekTimeOf(t time.Time) weekTime {
+	return weekTime(int(t.Weekday())*minutesPerDay + t.Hour()*60 + t.Minute())
+}
+
+func (w weekTime) weekday() time.Weekday { return time.Weekday(int(w) / minutesPerDay) }
+func (w weekTime) minute() int           { return int(w) % minutesPerDay }
@@ -0,0 +1,161 @@
+package authz
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/openfga/go-sdk/client"
+)
+
+// listObjectsPageSize is how many objects ListObjectsPage returns at a time
+const listObjectsPageSize = 100
+
+// ErrInvalidContinuationToken is returned by ListObjectsPage for a token it
+// didn't issue
+var ErrInvalidContinuationToken = errors.New("invalid continuation token")
+
+// ListObjects lists every object of a given type that a user has access to.
+//
+// OpenFGA's list-objects endpoint has no continuation token: it stops at the
+// server's result limit (OPENFGA_LIST_OBJECTS_MAX_RESULTS, 1000 by default)
+// and silently drops the rest. ListObjects uses streamed-list-objects, which
+// has no such limit, and falls back to list-objects on servers without it.
+func (c *OpenFGAClient) ListObjects(user, relation, objectType string) ([]string, error) {
+	if c.streamedListUnsupported() {
+		return c.listObjectsOnce(user, relation, objectType)
+	}
+
+	reqBody := map[string]string{
+		"user":     user,
+		"relation": relation,
+		"type":     objectType,
+	}
+
+	body, _ := json.Marshal(reqBody)
+	url := fmt.Sprintf("%s/stores/%s/streamed-list-objects", c.apiURL, c.storeID)
+	req, err := http.NewRequestWithContext(context.Background(), "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("list objects failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented {
+		c.markStreamedListUnsupported()
+		return c.listObjectsOnce(user, relation, objectType)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list objects failed: %s - %s", resp.Status, string(respBody))
+	}
+
+	// The response is one JSON message per line, each a result or an error
+	objects := []string{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var msg struct {
+			Result *struct {
+				Object string `json:"object"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, fmt.Errorf("list objects failed: %w", err)
+		}
+		if msg.Error != nil {
+			return nil, fmt.Errorf("list objects failed: %s", msg.Error.Message)
+		}
+		if msg.Result != nil {
+			objects = append(objects, msg.Result.Object)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("list objects failed: %w", err)
+	}
+
+	return objects, nil
+}
+
+// ListObjectsPage returns one page of the objects ListObjects would return,
+// sorted, and the token for the next page ("" after the last page). Pass ""
+// to start. Since OpenFGA doesn't page list-objects itself, every page is
+// evaluated afresh: objects granted or revoked between calls appear or
+// disappear, but none is returned twice.
+func (c *OpenFGAClient) ListObjectsPage(user, relation, objectType, continuationToken string) ([]string, string, error) {
+	var after string
+	if continuationToken != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(continuationToken)
+		if err != nil || len(decoded) == 0 {
+			return nil, "", ErrInvalidContinuationToken
+		}
+		after = string(decoded)
+	}
+
+	objects, err := c.ListObjects(user, relation, objectType)
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Strings(objects)
+
+	start := sort.SearchStrings(objects, after)
+	if start < len(objects) && objects[start] == after {
+		start++
+	}
+	end := start + listObjectsPageSize
+	if end >= len(objects) {
+		return objects[start:], "", nil
+	}
+
+	page := objects[start:end]
+	return page, base64.RawURLEncoding.EncodeToString([]byte(page[len(page)-1])), nil
+}
+
+// listObjectsOnce is a single list-objects call, truncated at the server's
+// result limit
+func (c *OpenFGAClient) listObjectsOnce(user, relation, objectType string) ([]string, error) {
+	body := client.ClientListObjectsRequest{
+		User:     user,
+		Relation: relation,
+		Type:     objectType,
+	}
+
+	response, err := c.client.ListObjects(context.Background()).Body(body).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("list objects failed: %w", err)
+	}
+
+	return response.GetObjects(), nil
+}
+
+func (c *OpenFGAClient) streamedListUnsupported() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.noStreamedList
+}
+
+func (c *OpenFGAClient) markStreamedListUnsupported() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.noStreamedList = true
+}
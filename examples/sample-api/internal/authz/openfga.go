@@ -1,8 +1,15 @@
 package authz
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	openfga "github.com/openfga/go-sdk"
 	"github.com/openfga/go-sdk/client"
@@ -12,6 +19,16 @@ import (
 type OpenFGAClient struct {
 	client  *client.OpenFgaClient
 	storeID string
+
+	// Used for APIs the SDK version doesn't cover yet (ListUsers, BatchCheck)
+	apiURL     string
+	httpClient *http.Client
+
+	// Set once the server is found not to support batch-check or
+	// streamed-list-objects
+	mu             sync.RWMutex
+	noBatchCheck   bool
+	noStreamedList bool
 }
 
 // NewOpenFGAClient creates a new OpenFGA client
@@ -31,14 +48,33 @@ func NewOpenFGAClient(url, storeID string) (*OpenFGAClient, error) {
 	}
 
 	return &OpenFGAClient{
-		client:  fgaClient,
-		storeID: storeID,
+		client:     fgaClient,
+		storeID:    storeID,
+		apiURL:     strings.TrimSuffix(url, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
 	}, nil
 }
 
+// Ready checks that OpenFGA is reachable and the store has an
+// authorization model
+func (c *OpenFGAClient) Ready(ctx context.Context) error {
+	resp, err := c.client.ReadLatestAuthorizationModel(ctx).Execute()
+	if err != nil {
+		return err
+	}
+	if resp.AuthorizationModel == nil {
+		return fmt.Errorf("store %s has no authorization model", c.storeID)
+	}
+	return nil
+}
+
 // Check performs a permission check
 // Example: Check("user:123", "can_read", "document:doc-1")
 func (c *OpenFGAClient) Check(user, relation, object string) (bool, error) {
+	if err := validateRefs(user, object); err != nil {
+		return false, err
+	}
+
 	body := client.ClientCheckRequest{
 		User:     user,
 		Relation: relation,
@@ -55,6 +91,10 @@ func (c *OpenFGAClient) Check(user, relation, object string) (bool, error) {
 
 // WriteTuple writes a relationship tuple to OpenFGA
 func (c *OpenFGAClient) WriteTuple(user, relation, object string) error {
+	if err := validateRefs(user, object); err != nil {
+		return err
+	}
+
 	body := client.ClientWriteRequest{
 		Writes: []client.ClientTupleKey{
 			{
@@ -73,8 +113,44 @@ func (c *OpenFGAClient) WriteTuple(user, relation, object string) error {
 	return nil
 }
 
+// Tuple is one relationship tuple, e.g. user:alice viewer document:doc-1
+type Tuple struct {
+	User     string `json:"user"`
+	Relation string `json:"relation"`
+	Object   string `json:"object"`
+}
+
+// WriteTuples writes several tuples in one OpenFGA write. The write is
+// atomic: if any tuple is rejected (e.g. it already exists), none is
+// written.
+func (c *OpenFGAClient) WriteTuples(tuples []Tuple) error {
+	if len(tuples) == 0 {
+		return nil
+	}
+
+	writes := make([]client.ClientTupleKey, len(tuples))
+	for i, t := range tuples {
+		if err := validateRefs(t.User, t.Object); err != nil {
+			return err
+		}
+		writes[i] = client.ClientTupleKey{User: t.User, Relation: t.Relation, Object: t.Object}
+	}
+
+	body := client.ClientWriteRequest{Writes: writes}
+	_, err := c.client.Write(context.Background()).Body(body).Execute()
+	if err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteTuple deletes a relationship tuple from OpenFGA
 func (c *OpenFGAClient) DeleteTuple(user, relation, object string) error {
+	if err := validateRefs(user, object); err != nil {
+		return err
+	}
+
 	body := client.ClientWriteRequest{
 		Deletes: []client.ClientTupleKeyWithoutCondition{
 			{
@@ -93,32 +169,83 @@ func (c *OpenFGAClient) DeleteTuple(user, relation, object string) error {
 	return nil
 }
 
-// ListObjects lists objects of a given type that a user has access to
-func (c *OpenFGAClient) ListObjects(user, relation, objectType string) ([]string, error) {
-	body := client.ClientListObjectsRequest{
-		User:     user,
-		Relation: relation,
-		Type:     objectType,
+// UpdateTuples deletes and writes tuples in one OpenFGA write, so a
+// relationship can be moved from one user to another without a window in
+// which both or neither hold it. Like WriteTuples, it is all or nothing.
+func (c *OpenFGAClient) UpdateTuples(writes, deletes []Tuple) error {
+	if len(writes) == 0 && len(deletes) == 0 {
+		return nil
 	}
 
-	response, err := c.client.ListObjects(context.Background()).Body(body).Execute()
+	body := client.ClientWriteRequest{}
+	for _, t := range writes {
+		if err := validateRefs(t.User, t.Object); err != nil {
+			return err
+		}
+		body.Writes = append(body.Writes, client.ClientTupleKey{User: t.User, Relation: t.Relation, Object: t.Object})
+	}
+	for _, t := range deletes {
+		if err := validateRefs(t.User, t.Object); err != nil {
+			return err
+		}
+		body.Deletes = append(body.Deletes, client.ClientTupleKeyWithoutCondition{User: t.User, Relation: t.Relation, Object: t.Object})
+	}
+
+	_, err := c.client.Write(context.Background()).Body(body).Execute()
 	if err != nil {
-		return nil, fmt.Errorf("list objects failed: %w", err)
+		return fmt.Errorf("update failed: %w", err)
 	}
 
-	return response.GetObjects(), nil
+	return nil
 }
 
-// ListRelations lists relations a user has on an object
-func (c *OpenFGAClient) ListRelations(user, object string, relations []string) (map[string]bool, error) {
-	result := make(map[string]bool)
+// ReadTuples returns the tuples written directly on object, following
+// OpenFGA's pagination
+func (c *OpenFGAClient) ReadTuples(object string) ([]Tuple, error) {
+	if _, err := ParseObjectRef(object); err != nil {
+		return nil, err
+	}
 
-	for _, relation := range relations {
-		allowed, err := c.Check(user, relation, object)
+	var tuples []Tuple
+	var token string
+	for {
+		options := client.ClientReadOptions{}
+		if token != "" {
+			options.ContinuationToken = &token
+		}
+		response, err := c.client.Read(context.Background()).
+			Body(client.ClientReadRequest{Object: &object}).
+			Options(options).
+			Execute()
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("read failed: %w", err)
+		}
+
+		for _, t := range response.Tuples {
+			tuples = append(tuples, Tuple{User: t.Key.User, Relation: t.Key.Relation, Object: t.Key.Object})
+		}
+		if response.ContinuationToken == "" {
+			return tuples, nil
 		}
-		result[relation] = allowed
+		token = response.ContinuationToken
+	}
+}
+
+// ListRelations lists relations a user has on an object
+func (c *OpenFGAClient) ListRelations(user, object string, relations []string) (map[string]bool, error) {
+	checks := make([]CheckRequest, len(relations))
+	for i, relation := range relations {
+		checks[i] = CheckRequest{User: user, Relation: relation, Object: object}
+	}
+
+	allowed, err := c.BatchCheck(checks)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool)
+	for i, relation := range relations {
+		result[relation] = allowed[i]
 	}
 
 	return result, nil
@@ -138,3 +265,76 @@ func (c *OpenFGAClient) Expand(relation, object string) (*openfga.UsersetTree, e
 
 	return response.Tree, nil
 }
+
+// ListUsers lists the IDs of users of a given type that have a relation to an
+// object (the reverse of ListObjects). A public wildcard grant is returned
+// as "*".
+// Example: ListUsers("document:doc-1", "viewer", "user") -> ["alice", "bob"]
+func (c *OpenFGAClient) ListUsers(object, relation, userType string) ([]string, error) {
+	ref, err := ParseObjectRef(object)
+	if err != nil {
+		return nil, err
+	}
+	if !objectTypes[userType] {
+		return nil, fmt.Errorf("unknown user type %q", userType)
+	}
+
+	reqBody := map[string]interface{}{
+		"object":       map[string]string{"type": ref.Type, "id": ref.ID},
+		"relation":     relation,
+		"user_filters": []map[string]string{{"type": userType}},
+	}
+
+	body, _ := json.Marshal(reqBody)
+	url := fmt.Sprintf("%s/stores/%s/list-users", c.apiURL, c.storeID)
+	req, err := http.NewRequestWithContext(context.Background(), "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("list users failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("list users failed: %s - %s", resp.Status, string(respBody))
+	}
+
+	var result struct {
+		Users []struct {
+			Object *struct {
+				ID string `json:"id"`
+			} `json:"object"`
+			Wildcard *struct{} `json:"wildcard"`
+		} `json:"users"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("list users failed: %w", err)
+	}
+
+	users := make([]string, 0, len(result.Users))
+	for _, u := range result.Users {
+		switch {
+		case u.Object != nil:
+			users = append(users, u.Object.ID)
+		case u.Wildcard != nil:
+			users = append(users, "*")
+		}
+	}
+
+	return users, nil
+}
+
+// validateRefs rejects malformed user/object references before they reach
+// OpenFGA
+func validateRefs(user, object string) error {
+	if _, err := ParseObjectRef(user); err != nil {
+		return err
+	}
+	_, err := ParseObjectRef(object)
+	return err
+}
@@ -0,0 +1,168 @@
+package authz
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+)
+
+// CustomAttrPrefix namespaces a project's custom attributes in
+// Object.Attributes, so "attr.data_classification" can't shadow built-ins
+// like "environment"
+const CustomAttrPrefix = "attr."
+
+// RequestAttrPrefix namespaces attributes of the request rather than the
+// object, such as "request.client_ip"
+const RequestAttrPrefix = "request."
+
+// Condition operators
+const (
+	OpEquals    = "equals"
+	OpNotEquals = "not_equals"
+	OpIn        = "in"
+	OpNotIn     = "not_in"
+	OpExists    = "exists"
+	OpInCIDR    = "in_cidr"
+	OpNotInCIDR = "not_in_cidr"
+)
+
+// Condition tests one object attribute. Attribute is a built-in name
+// (environment, status, owner_id), a custom one as "attr.<key>", or a
+// request attribute (request.client_ip). in_cidr and not_in_cidr take CIDR
+// ranges in Values and compare an IP attribute against them.
+type Condition struct {
+	Attribute string   `json:"attribute"`
+	Operator  string   `json:"operator"`
+	Value     string   `json:"value,omitempty"`
+	Values    []string `json:"values,omitempty"`
+}
+
+// Policy is a data-driven deny rule: when all its conditions match, the
+// listed permissions are removed. Admins are exempt unless AppliesToAdmins.
+type Policy struct {
+	Name            string      `json:"name"`
+	Description     string      `json:"description"`
+	Deny            []string    `json:"deny"` // e.g. ["can_write", "can_deploy"]
+	Conditions      []Condition `json:"conditions"`
+	AppliesToAdmins bool        `json:"applies_to_admins"`
+}
+
+// LoadPolicies reads a JSON array of policies and validates them
+func LoadPolicies(path string) ([]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []Policy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i, p := range policies {
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("policy %d (%s): %w", i, p.Name, err)
+		}
+	}
+	return policies, nil
+}
+
+func (p Policy) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(p.Deny) == 0 {
+		return fmt.Errorf("deny must list at least one permission")
+	}
+	if len(p.Conditions) == 0 {
+		return fmt.Errorf("at least one condition is required")
+	}
+	for _, cond := range p.Conditions {
+		if cond.Attribute == "" {
+			return fmt.Errorf("condition attribute is required")
+		}
+		switch cond.Operator {
+		case OpEquals, OpNotEquals, OpExists:
+		case OpIn, OpNotIn:
+			if len(cond.Values) == 0 {
+				return fmt.Errorf("operator %q needs values", cond.Operator)
+			}
+		case OpInCIDR, OpNotInCIDR:
+			if len(cond.Values) == 0 {
+				return fmt.Errorf("operator %q needs values", cond.Operator)
+			}
+			for _, v := range cond.Values {
+				if _, _, err := net.ParseCIDR(v); err != nil {
+					return fmt.Errorf("operator %q: invalid CIDR %q", cond.Operator, v)
+				}
+			}
+		default:
+			return fmt.Errorf("unknown operator %q", cond.Operator)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether every condition holds for the object
+func (p Policy) Matches(obj Object) bool {
+	for _, cond := range p.Conditions {
+		if !cond.matches(obj) {
+			return false
+		}
+	}
+	return true
+}
+
+// Denies reports whether the policy removes the permission
+func (p Policy) Denies(permission string) bool {
+	for _, d := range p.Deny {
+		if d == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Condition) matches(obj Object) bool {
+	value, set := obj.Attributes[c.Attribute]
+	switch c.Operator {
+	case OpEquals:
+		return set && value == c.Value
+	case OpNotEquals:
+		return !set || value != c.Value
+	case OpIn:
+		return set && contains(c.Values, value)
+	case OpNotIn:
+		return !set || !contains(c.Values, value)
+	case OpExists:
+		return set
+	case OpInCIDR:
+		return set && inCIDRs(c.Values, value)
+	case OpNotInCIDR:
+		// An unknown or unparsable address is outside every range
+		return !set || !inCIDRs(c.Values, value)
+	}
+	return false
+}
+
+func inCIDRs(cidrs []string, v string) bool {
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package authz
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPolicies(t *testing.T) {
+	example, err := LoadPolicies("../../policies.example.json")
+	if err != nil {
+		t.Fatalf("example policies: %v", err)
+	}
+	if len(example) != 2 {
+		t.Errorf("loaded %d example policies, want 2", len(example))
+	}
+
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{"valid", `[{"name":"p","deny":["can_write"],"conditions":[{"attribute":"status","operator":"exists"}]}]`, ""},
+		{"empty list", `[]`, ""},
+		{"not json", `{`, "parse"},
+		{"no name", `[{"deny":["can_write"],"conditions":[{"attribute":"status","operator":"exists"}]}]`, "name is required"},
+		{"no deny", `[{"name":"p","conditions":[{"attribute":"status","operator":"exists"}]}]`, "deny must list"},
+		{"no conditions", `[{"name":"p","deny":["can_write"]}]`, "at least one condition"},
+		{"no attribute", `[{"name":"p","deny":["can_write"],"conditions":[{"operator":"exists"}]}]`, "attribute is required"},
+		{"in without values", `[{"name":"p","deny":["can_write"],"conditions":[{"attribute":"status","operator":"in"}]}]`, "needs values"},
+		{"unknown operator", `[{"name":"p","deny":["can_write"],"conditions":[{"attribute":"status","operator":"like"}]}]`, "unknown operator"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policies.json")
+			if err := os.WriteFile(path, []byte(tt.json), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadPolicies(path)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("LoadPolicies() = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("LoadPolicies() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadPolicies(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing file loaded")
+	}
+}
+
+func TestPolicyMatches(t *testing.T) {
+	obj := Object{Attributes: map[string]string{
+		"environment":                   "production",
+		CustomAttrPrefix + "data_class": "restricted",
+	}}
+
+	tests := []struct {
+		name string
+		cond Condition
+		want bool
+	}{
+		{"equals", Condition{Attribute: "environment", Operator: OpEquals, Value: "production"}, true},
+		{"equals other value", Condition{Attribute: "environment", Operator: OpEquals, Value: "staging"}, false},
+		{"equals unset", Condition{Attribute: "status", Operator: OpEquals, Value: ""}, false},
+		{"not equals", Condition{Attribute: "environment", Operator: OpNotEquals, Value: "staging"}, true},
+		{"not equals unset", Condition{Attribute: "status", Operator: OpNotEquals, Value: "archived"}, true},
+		{"in", Condition{Attribute: "attr.data_class", Operator: OpIn, Values: []string{"secret", "restricted"}}, true},
+		{"in unset", Condition{Attribute: "attr.owner", Operator: OpIn, Values: []string{"x"}}, false},
+		{"not in", Condition{Attribute: "attr.data_class", Operator: OpNotIn, Values: []string{"public"}}, true},
+		{"not in listed", Condition{Attribute: "attr.data_class", Operator: OpNotIn, Values: []string{"restricted"}}, false},
+		{"exists", Condition{Attribute: "attr.data_class", Operator: OpExists}, true},
+		{"exists unset", Condition{Attribute: "attr.environment", Operator: OpExists}, false},
+		{"unknown operator", Condition{Attribute: "environment", Operator: "like"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Policy{Name: "p", Deny: []string{"can_write"}, Conditions: []Condition{tt.cond}}
+			if got := p.Matches(obj); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// All conditions must hold
+	p := Policy{Conditions: []Condition{
+		{Attribute: "environment", Operator: OpEquals, Value: "production"},
+		{Attribute: "attr.data_class", Operator: OpEquals, Value: "public"},
+	}}
+	if p.Matches(obj) {
+		t.Error("policy matched with one condition failing")
+	}
+}
+
+func TestPolicyDenies(t *testing.T) {
+	p := Policy{Deny: []string{"can_write", "can_deploy"}}
+	for perm, want := range map[string]bool{"can_write": true, "can_deploy": true, "can_read": false} {
+		if got := p.Denies(perm); got != want {
+			t.Errorf("Denies(%q) = %v, want %v", perm, got, want)
+		}
+	}
+}
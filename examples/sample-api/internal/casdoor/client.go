@@ -1,6 +1,7 @@
 package casdoor
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/json"
@@ -11,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -60,8 +62,23 @@ type Client struct {
 	clientSecret string
 	organization string
 	application  string
-	publicKey    *rsa.PublicKey
 	httpClient   *http.Client
+
+	// Signing keys from Casdoor's JWKS, indexed by kid. Casdoor can have
+	// several active keys, e.g. while rotating.
+	mu            sync.RWMutex
+	jwksKeys      map[string]*rsa.PublicKey
+	jwksFetchedAt time.Time
+	jwksCacheTTL  time.Duration
+
+	// refreshMu serializes JWKS fetches; jwksLastFetch is the last attempt,
+	// successful or not
+	refreshMu     sync.Mutex
+	jwksLastFetch time.Time
+
+	// publicKey is the configured certificate, or the application cert
+	// fetched for Casdoor versions without a JWKS endpoint. Guarded by mu.
+	publicKey *rsa.PublicKey
 }
 
 // Config holds Casdoor client configuration
@@ -71,7 +88,8 @@ type Config struct {
 	ClientSecret string
 	Organization string
 	Application  string
-	Certificate  string // PEM-encoded certificate
+	Certificate  string        // PEM-encoded certificate
+	JWKSCacheTTL time.Duration // How long JWKS keys are used before a re-fetch
 }
 
 // NewClient creates a new Casdoor client
@@ -82,6 +100,7 @@ func NewClient(cfg Config) (*Client, error) {
 		clientSecret: cfg.ClientSecret,
 		organization: cfg.Organization,
 		application:  cfg.Application,
+		jwksCacheTTL: cfg.JWKSCacheTTL,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
@@ -108,31 +127,27 @@ func NewClientFromEnv() (*Client, error) {
 		Organization: getEnv("CASDOOR_ORGANIZATION", "built-in"),
 		Application:  getEnv("CASDOOR_APPLICATION", "app-built-in"),
 		Certificate:  os.Getenv("CASDOOR_CERTIFICATE"),
+		JWKSCacheTTL: getEnvDuration("JWKS_CACHE_TTL", time.Hour),
 	}
 
 	return NewClient(cfg)
 }
 
-// ValidateToken validates a Casdoor JWT token and returns the claims
+// ValidateToken validates a Casdoor JWT token and returns the claims. The
+// token's kid selects the signing key from Casdoor's JWKS; tokens the JWKS
+// can't verify fall back to the single application certificate.
 func (c *Client) ValidateToken(tokenString string) (*CasdoorClaims, error) {
 	// Remove "Bearer " prefix if present
 	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 	tokenString = strings.TrimPrefix(tokenString, "bearer ")
 
-	if c.publicKey == nil {
-		// If no certificate is configured, fetch it from Casdoor
-		if err := c.fetchCertificate(); err != nil {
-			return nil, fmt.Errorf("failed to fetch certificate: %w", err)
-		}
-	}
-
 	// Parse and validate the token
 	token, err := jwt.ParseWithClaims(tokenString, &CasdoorClaims{}, func(token *jwt.Token) (interface{}, error) {
 		// Validate the signing method
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return c.publicKey, nil
+		return c.keyFor(token)
 	})
 
 	if err != nil {
@@ -181,6 +196,55 @@ func (c *Client) GetUser(name string) (*User, error) {
 	return &user, nil
 }
 
+// Ready checks that Casdoor is reachable and its JWKS has signing keys,
+// refreshing the cached keys
+func (c *Client) Ready(ctx context.Context) error {
+	return c.fetchJWKS(ctx)
+}
+
+// keyFor picks the key to verify token with. A failed JWKS refresh keeps
+// serving the keys we already have.
+func (c *Client) keyFor(token *jwt.Token) (*rsa.PublicKey, error) {
+	if c.jwksExpired() {
+		c.refreshJWKS()
+	}
+
+	if kid, _ := token.Header["kid"].(string); kid != "" {
+		if key := c.lookupKey(kid); key != nil {
+			return key, nil
+		}
+		// Unknown kid, e.g. after Casdoor rotated its signing key
+		if err := c.refreshJWKS(); err == nil {
+			if key := c.lookupKey(kid); key != nil {
+				return key, nil
+			}
+		}
+	} else if key := c.onlyKey(); key != nil {
+		return key, nil
+	}
+
+	return c.certificateKey()
+}
+
+// certificateKey returns the configured certificate, fetching the
+// application cert from Casdoor if none was configured. This is the only
+// key source on Casdoor versions without /.well-known/jwks.
+func (c *Client) certificateKey() (*rsa.PublicKey, error) {
+	c.mu.RLock()
+	key := c.publicKey
+	c.mu.RUnlock()
+	if key != nil {
+		return key, nil
+	}
+
+	if err := c.fetchCertificate(); err != nil {
+		return nil, fmt.Errorf("failed to fetch certificate: %w", err)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.publicKey, nil
+}
+
 // fetchCertificate fetches the certificate from Casdoor
 func (c *Client) fetchCertificate() error {
 	url := fmt.Sprintf("%s/api/get-application?id=%s/%s", c.endpoint, c.organization, c.application)
@@ -217,7 +281,9 @@ func (c *Client) fetchCertificate() error {
 		return err
 	}
 
+	c.mu.Lock()
 	c.publicKey = publicKey
+	c.mu.Unlock()
 	return nil
 }
 
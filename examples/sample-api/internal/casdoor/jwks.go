@@ -0,0 +1,158 @@
+package casdoor
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// JWK is a JSON Web Key
+type JWK struct {
+	Use string   `json:"use"`
+	Kty string   `json:"kty"`
+	Kid string   `json:"kid"`
+	Alg string   `json:"alg"`
+	N   string   `json:"n"`
+	E   string   `json:"e"`
+	X5c []string `json:"x5c"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwksMinRefreshInterval limits how often an unknown kid can trigger a JWKS
+// re-fetch, so a burst of bad tokens can't hammer Casdoor
+const jwksMinRefreshInterval = 30 * time.Second
+
+// fetchJWKS fetches Casdoor's signing keys and replaces the cached ones, so
+// keys Casdoor has rotated out stop validating
+func (c *Client) fetchJWKS(ctx context.Context) error {
+	url := fmt.Sprintf("%s/.well-known/jwks", c.endpoint)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to get JWKS: %s (status: %d)", string(body), resp.StatusCode)
+	}
+
+	var jwks JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		publicKey, err := jwkToPublicKey(jwk)
+		if err != nil {
+			continue // Skip keys we can't use
+		}
+		keys[jwk.Kid] = publicKey
+	}
+	if len(keys) == 0 {
+		return ErrNoCertificate
+	}
+
+	c.mu.Lock()
+	c.jwksKeys = keys
+	c.jwksFetchedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+// refreshJWKS re-fetches the JWKS unless another fetch happened within
+// jwksMinRefreshInterval. Concurrent callers wait for the in-flight fetch
+// and then reuse its result.
+func (c *Client) refreshJWKS() error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	if time.Since(c.jwksLastFetch) < jwksMinRefreshInterval {
+		return nil
+	}
+	c.jwksLastFetch = time.Now()
+
+	return c.fetchJWKS(context.Background())
+}
+
+func (c *Client) jwksExpired() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.jwksKeys) == 0 || (c.jwksCacheTTL > 0 && time.Since(c.jwksFetchedAt) > c.jwksCacheTTL)
+}
+
+func (c *Client) lookupKey(kid string) *rsa.PublicKey {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.jwksKeys[kid]
+}
+
+// onlyKey returns the JWKS key if there is exactly one; with several, a
+// token without a kid can't be matched to one
+func (c *Client) onlyKey() *rsa.PublicKey {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.jwksKeys) != 1 {
+		return nil
+	}
+	for _, key := range c.jwksKeys {
+		return key
+	}
+	return nil
+}
+
+// jwkToPublicKey converts a JWK to an RSA public key
+func jwkToPublicKey(jwk JWK) (*rsa.PublicKey, error) {
+	// Try the x5c certificate first
+	if len(jwk.X5c) > 0 {
+		certDER, err := base64.StdEncoding.DecodeString(jwk.X5c[0])
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(certDER)
+		if err != nil {
+			return nil, err
+		}
+		if rsaKey, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			return rsaKey, nil
+		}
+	}
+
+	// Fall back to n and e
+	if jwk.N != "" && jwk.E != "" {
+		nBytes, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		eBytes, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		n := new(big.Int).SetBytes(nBytes)
+		e := int(new(big.Int).SetBytes(eBytes).Int64())
+
+		return &rsa.PublicKey{N: n, E: e}, nil
+	}
+
+	return nil, errors.New("unable to parse JWK")
+}
@@ -0,0 +1,205 @@
+package casdoor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnavailable is returned when Casdoor keeps failing after all retries
+	ErrUnavailable = errors.New("casdoor unavailable")
+	// ErrCircuitOpen is returned when Casdoor has failed repeatedly and calls
+	// are being short-circuited until the cooldown expires
+	ErrCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrUnavailable)
+)
+
+// ResilienceConfig controls retries and circuit breaking for Casdoor calls
+type ResilienceConfig struct {
+	MaxRetries       int           // Retries after the first attempt
+	Backoff          time.Duration // Initial backoff, doubled per retry
+	FailureThreshold int           // Consecutive failures before the circuit opens
+	Cooldown         time.Duration // How long the circuit stays open
+	Timeout          time.Duration // Per-attempt HTTP timeout; 0 means none
+}
+
+// ResilienceConfigFromEnv reads resilience settings from environment variables
+func ResilienceConfigFromEnv() ResilienceConfig {
+	return ResilienceConfig{
+		MaxRetries:       getEnvInt("CASDOOR_MAX_RETRIES", 2),
+		Backoff:          getEnvDuration("CASDOOR_RETRY_BACKOFF", 200*time.Millisecond),
+		FailureThreshold: getEnvInt("CASDOOR_BREAKER_THRESHOLD", 5),
+		Cooldown:         getEnvDuration("CASDOOR_BREAKER_COOLDOWN", 30*time.Second),
+		Timeout:          getEnvDuration("CASDOOR_HTTP_TIMEOUT", 10*time.Second),
+	}
+}
+
+// Resilience sends Casdoor HTTP calls with retry-with-backoff and a circuit
+// breaker. Transport errors and 5xx responses count as failures; any other
+// response (including 4xx) is returned to the caller as-is.
+type Resilience struct {
+	cfg    ResilienceConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// NewResilience creates a new retry/circuit breaker wrapper
+func NewResilience(cfg ResilienceConfig) *Resilience {
+	if cfg.FailureThreshold < 1 {
+		cfg.FailureThreshold = 1
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	return &Resilience{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Client returns the shared HTTP client for Casdoor calls, which applies
+// the configured timeout. Do sends its requests through it.
+func (r *Resilience) Client() *http.Client {
+	return r.client
+}
+
+// Do sends the request built by newRequest, retrying transient failures.
+// newRequest must build a fresh request on every call. Only idempotent
+// methods are retried after Casdoor may have received the request; other
+// methods, such as the POSTs that create users or change passwords, are
+// retried only when the connection could not be opened. Errors wrap
+// ErrUnavailable when Casdoor could not be reached or returned 5xx;
+// ErrCircuitOpen is returned without sending while the circuit is open.
+func (r *Resilience) Do(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	if !r.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	backoff := r.cfg.Backoff
+	var resp *http.Response
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				r.release()
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		var req *http.Request
+		req, err = newRequest()
+		if err != nil {
+			r.release()
+			return nil, err
+		}
+
+		resp, err = r.client.Do(req.WithContext(ctx))
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			r.recordSuccess()
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			// The caller went away; that says nothing about Casdoor
+			r.release()
+			return nil, ctx.Err()
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if attempt == r.cfg.MaxRetries || !retryable(req.Method, err) {
+			break
+		}
+	}
+
+	r.recordFailure()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnavailable, resp.Status)
+}
+
+// retryable reports whether a failed attempt may be sent again. A request
+// with an idempotent method can always be repeated; any other request only
+// when it failed while dialing, before anything reached Casdoor.
+func retryable(method string, err error) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// allow reports whether a call may proceed. Once the cooldown has elapsed a
+// single probe call is let through; its outcome closes or re-opens the circuit.
+func (r *Resilience) allow() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failures < r.cfg.FailureThreshold {
+		return true
+	}
+	if time.Now().Before(r.openUntil) || r.probing {
+		return false
+	}
+	r.probing = true
+	return true
+}
+
+func (r *Resilience) recordSuccess() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures = 0
+	r.probing = false
+}
+
+// release ends a call that neither succeeded nor failed, letting the next
+// probe through if this call was one
+func (r *Resilience) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.probing = false
+}
+
+func (r *Resilience) recordFailure() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures++
+	r.probing = false
+	if r.failures >= r.cfg.FailureThreshold {
+		r.openUntil = time.Now().Add(r.cfg.Cooldown)
+	}
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,158 @@
+package casdoor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	dialError  = 0  // the connection could not be opened
+	resetError = -1 // the connection dropped after the request was sent
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// responses makes r answer with the given status codes in turn (or the
+// transport errors above) and counts the requests sent
+func responses(r *Resilience, codes ...int) (calls *int) {
+	calls = new(int)
+	r.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		code := codes[len(codes)-1]
+		if *calls < len(codes) {
+			code = codes[*calls]
+		}
+		*calls++
+		switch code {
+		case dialError:
+			return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+		case resetError:
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+		}
+		return &http.Response{
+			StatusCode: code,
+			Status:     http.StatusText(code),
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+	return calls
+}
+
+func request(method string) func() (*http.Request, error) {
+	return func() (*http.Request, error) {
+		return http.NewRequest(method, "http://casdoor.test/api", nil)
+	}
+}
+
+func TestResilienceDo(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		maxRetries int
+		codes      []int
+		wantCalls  int
+		wantStatus int
+		wantErr    error
+	}{
+		{"success", "GET", 2, []int{200}, 1, 200, nil},
+		{"client error is not retried", "GET", 2, []int{404}, 1, 404, nil},
+		{"recovers after a 5xx", "GET", 2, []int{503, 200}, 2, 200, nil},
+		{"recovers after transport errors", "GET", 2, []int{dialError, resetError, 200}, 3, 200, nil},
+		{"keeps failing", "GET", 2, []int{500}, 3, 0, ErrUnavailable},
+		{"transport errors exhaust retries", "GET", 1, []int{resetError}, 2, 0, ErrUnavailable},
+		{"no retries", "GET", 0, []int{502, 200}, 1, 0, ErrUnavailable},
+		{"POST is not retried after a 5xx", "POST", 2, []int{503, 200}, 1, 0, ErrUnavailable},
+		{"POST is not retried after the request was sent", "POST", 2, []int{resetError, 200}, 1, 0, ErrUnavailable},
+		{"POST is retried when dialing fails", "POST", 2, []int{dialError, dialError, 200}, 3, 200, nil},
+		{"POST retried after a dial error stops at a 5xx", "POST", 2, []int{dialError, 500, 200}, 2, 0, ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewResilience(ResilienceConfig{MaxRetries: tt.maxRetries, FailureThreshold: 5, Cooldown: time.Minute})
+			calls := responses(r, tt.codes...)
+
+			resp, err := r.Do(context.Background(), request(tt.method))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("%d calls, want %d", *calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestResilienceDoCanceled(t *testing.T) {
+	r := NewResilience(ResilienceConfig{MaxRetries: 2, Backoff: time.Hour, FailureThreshold: 1, Cooldown: time.Minute})
+	calls := responses(r, 503)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Do(ctx, request("GET"))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Do() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Do() kept waiting out the backoff after the context was canceled")
+	}
+	if *calls != 1 {
+		t.Errorf("%d calls, want 1", *calls)
+	}
+
+	// A canceled call doesn't count against Casdoor
+	responses(r, 200)
+	if _, err := r.Do(context.Background(), request("GET")); err != nil {
+		t.Errorf("Do() after cancel error = %v, want nil", err)
+	}
+}
+
+func TestResilienceCircuitBreaker(t *testing.T) {
+	r := NewResilience(ResilienceConfig{FailureThreshold: 2, Cooldown: time.Hour})
+
+	steps := []struct {
+		name      string
+		code      int
+		expire    bool // end the cooldown first
+		wantErr   error
+		wantCalls int // requests sent in this step
+	}{
+		{"first failure", 500, false, ErrUnavailable, 1},
+		{"second failure opens the circuit", 500, false, ErrUnavailable, 1},
+		{"open circuit short-circuits", 200, false, ErrCircuitOpen, 0},
+		{"failed probe re-opens it", 500, true, ErrUnavailable, 1},
+		{"still open after the failed probe", 200, false, ErrCircuitOpen, 0},
+		{"successful probe closes it", 200, true, nil, 1},
+		{"closed again", 200, false, nil, 1},
+	}
+	for _, s := range steps {
+		if s.expire {
+			r.openUntil = time.Now().Add(-time.Second)
+		}
+		calls := responses(r, s.code)
+		_, err := r.Do(context.Background(), request("GET"))
+		if !errors.Is(err, s.wantErr) {
+			t.Fatalf("%s: error = %v, want %v", s.name, err, s.wantErr)
+		}
+		if *calls != s.wantCalls {
+			t.Errorf("%s: %d calls, want %d", s.name, *calls, s.wantCalls)
+		}
+	}
+}
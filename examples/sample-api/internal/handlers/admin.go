@@ -9,11 +9,11 @@ import (
 
 // AdminHandler handles platform admin operations
 type AdminHandler struct {
-	store *store.MemoryStore
+	store store.Store
 }
 
 // NewAdminHandler creates a new admin handler
-func NewAdminHandler(s *store.MemoryStore) *AdminHandler {
+func NewAdminHandler(s store.Store) *AdminHandler {
 	return &AdminHandler{store: s}
 }
 
@@ -145,3 +145,37 @@ func (h *AdminHandler) ListAllProjects(c *gin.Context) {
 	projects := h.store.GetAllProjects()
 	c.JSON(http.StatusOK, gin.H{"projects": projects})
 }
+
+// ListTrash returns deleted documents and projects that can still be restored
+func (h *AdminHandler) ListTrash(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"documents": h.store.ListDeletedDocuments(),
+		"projects":  h.store.ListDeletedProjects(),
+	})
+}
+
+// RestoreDocument takes a document out of the trash
+func (h *AdminHandler) RestoreDocument(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.store.RestoreDocument(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "deleted document not found"})
+		return
+	}
+
+	doc, _ := h.store.GetDocument(id, false)
+	c.JSON(http.StatusOK, gin.H{"message": "document restored", "document": doc})
+}
+
+// RestoreProject takes a project out of the trash
+func (h *AdminHandler) RestoreProject(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.store.RestoreProject(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "deleted project not found"})
+		return
+	}
+
+	proj, _ := h.store.GetProject(id)
+	c.JSON(http.StatusOK, gin.H{"message": "project restored", "project": proj})
+}
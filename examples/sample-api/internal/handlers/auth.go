@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"strings"
@@ -12,17 +13,23 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/sample-api/internal/casdoor"
 	"github.com/yourusername/sample-api/internal/middleware"
+	"github.com/yourusername/sample-api/internal/password"
 )
 
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
 	casdoorClient *casdoor.Client
+	idp           *casdoor.Resilience
+	passwords     *password.Policy
 }
 
-// NewAuthHandler creates a new auth handler
-func NewAuthHandler(client *casdoor.Client) *AuthHandler {
+// NewAuthHandler creates a new auth handler. New passwords must satisfy
+// passwords.
+func NewAuthHandler(client *casdoor.Client, resilience *casdoor.Resilience, passwords *password.Policy) *AuthHandler {
 	return &AuthHandler{
 		casdoorClient: client,
+		idp:           resilience,
+		passwords:     passwords,
 	}
 }
 
@@ -101,12 +108,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	resp, err := http.Post(idpEndpoint+"/api/login", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := h.idp.Do(c.Request.Context(), func() (*http.Request, error) {
+		return jsonRequest(idpEndpoint+"/api/login", jsonData)
+	})
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error":   "service_unavailable",
-			"message": "failed to connect to identity provider",
-		})
+		respondIDPUnavailable(c, err)
 		return
 	}
 	defer resp.Body.Close()
@@ -198,12 +204,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	resp, err := http.Post(idpEndpoint+"/api/signup", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := h.idp.Do(c.Request.Context(), func() (*http.Request, error) {
+		return jsonRequest(idpEndpoint+"/api/signup", jsonData)
+	})
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error":   "service_unavailable",
-			"message": "failed to connect to identity provider",
-		})
+		respondIDPUnavailable(c, err)
 		return
 	}
 	defer resp.Body.Close()
@@ -300,16 +305,11 @@ func (h *AuthHandler) Callback(c *gin.Context) {
 	}
 
 	jsonData, _ := json.Marshal(tokenPayload)
-	resp, err := http.Post(
-		idpEndpoint+"/api/login/oauth/access_token",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := h.idp.Do(c.Request.Context(), func() (*http.Request, error) {
+		return jsonRequest(idpEndpoint+"/api/login/oauth/access_token", jsonData)
+	})
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error":   "service_unavailable",
-			"message": "failed to exchange code for token",
-		})
+		respondIDPUnavailable(c, err)
 		return
 	}
 	defer resp.Body.Close()
@@ -362,6 +362,16 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	if failed := h.passwords.Validate(c.Request.Context(), req.NewPassword); len(failed) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        "weak_password",
+			"message":      "new password does not meet the requirements",
+			"failed_rules": failed,
+			"min_length":   h.passwords.MinLength,
+		})
+		return
+	}
+
 	idpEndpoint := getEnv("CASDOOR_ENDPOINT", "http://casdoor:8000")
 	org := getEnv("CASDOOR_ORGANIZATION", "saas-platform")
 
@@ -406,28 +416,22 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	formData := fmt.Sprintf("userOwner=%s&userName=%s&oldPassword=%s&newPassword=%s",
 		org, userID, req.OldPassword, req.NewPassword)
 
-	httpReq, err := http.NewRequest("POST", idpEndpoint+"/api/set-password", strings.NewReader(formData))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_error",
-			"message": "failed to prepare request",
-		})
-		return
-	}
+	resp, err := h.idp.Do(c.Request.Context(), func() (*http.Request, error) {
+		httpReq, err := http.NewRequest("POST", idpEndpoint+"/api/set-password", strings.NewReader(formData))
+		if err != nil {
+			return nil, err
+		}
 
-	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	// Pass the user's token for authentication
-	if authHeader != "" {
-		httpReq.Header.Set("Authorization", authHeader)
-	}
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		// Pass the user's token for authentication
+		if authHeader != "" {
+			httpReq.Header.Set("Authorization", authHeader)
+		}
 
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+		return httpReq, nil
+	})
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error":   "service_unavailable",
-			"message": "failed to connect to identity provider",
-		})
+		respondIDPUnavailable(c, err)
 		return
 	}
 	defer resp.Body.Close()
@@ -461,7 +465,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	// Use client credentials for this admin operation
 	clientID := os.Getenv("CASDOOR_CLIENT_ID")
 	clientSecret := os.Getenv("CASDOOR_CLIENT_SECRET")
-	clearPasswordChangeRequired(idpEndpoint, org, userID, clientID, clientSecret)
+	h.clearPasswordChangeRequired(idpEndpoint, org, userID, clientID, clientSecret)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "password changed successfully",
@@ -470,7 +474,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 }
 
 // clearPasswordChangeRequired updates the user's properties to remove the password change requirement
-func clearPasswordChangeRequired(idpEndpoint, org, userName, clientID, clientSecret string) {
+func (h *AuthHandler) clearPasswordChangeRequired(idpEndpoint, org, userName, clientID, clientSecret string) {
 	// Get the current user first using client credentials
 	getUserURL := fmt.Sprintf("%s/api/get-user?id=%s/%s", idpEndpoint, org, userName)
 
@@ -481,7 +485,7 @@ func clearPasswordChangeRequired(idpEndpoint, org, userName, clientID, clientSec
 	}
 	req.SetBasicAuth(clientID, clientSecret)
 
-	client := &http.Client{}
+	client := h.idp.Client()
 	resp, err := client.Do(req)
 	if err != nil {
 		fmt.Printf("clearPasswordChangeRequired: failed to get user: %v\n", err)
@@ -582,6 +586,25 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 	})
 }
 
+// respondIDPUnavailable reports a failed or short-circuited Casdoor call
+// jsonRequest builds a POST of body to url for Casdoor
+func jsonRequest(url string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func respondIDPUnavailable(c *gin.Context, err error) {
+	log.Printf("Casdoor call failed: %v", err)
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error":   "idp_unavailable",
+		"message": "identity provider is temporarily unavailable",
+	})
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
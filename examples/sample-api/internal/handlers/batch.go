@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Batch statuses. A batch always returns 200; callers read the overall
+// status and the per-item results (207 Multi-Status semantics).
+const (
+	BatchStatusOK      = "ok"      // every item succeeded
+	BatchStatusPartial = "partial" // some items succeeded, some failed
+	BatchStatusFailed  = "failed"  // no item succeeded
+)
+
+// maxBatchItems bounds the number of items in one batch request
+const maxBatchItems = 100
+
+// BatchItemResult is the outcome of one item in a batch request
+type BatchItemResult struct {
+	Index  int         `json:"index"`
+	Status string      `json:"status"` // "ok" or "error"
+	Error  string      `json:"error,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+}
+
+// BatchSummary counts the item outcomes of a batch request
+type BatchSummary struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Total     int `json:"total"`
+}
+
+// BatchResponse is the envelope returned by every batch endpoint
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+	Summary BatchSummary      `json:"summary"`
+	Status  string            `json:"status"`
+}
+
+func batchOK(index int, result interface{}) BatchItemResult {
+	return BatchItemResult{Index: index, Status: "ok", Result: result}
+}
+
+func batchError(index int, message string) BatchItemResult {
+	return BatchItemResult{Index: index, Status: "error", Error: message}
+}
+
+// newBatchResponse builds the envelope for results. When allowPartial is
+// false and any item failed, the successful items are reported as not
+// applied so the batch is all-or-nothing; the caller must not have applied
+// them either.
+func newBatchResponse(results []BatchItemResult, allowPartial bool) BatchResponse {
+	if !allowPartial && batchHasFailure(results) {
+		for i := range results {
+			if results[i].Status == "ok" {
+				results[i] = batchError(results[i].Index, "not applied: other items in the batch failed")
+			}
+		}
+	}
+
+	summary := BatchSummary{Total: len(results)}
+	for _, r := range results {
+		if r.Status == "ok" {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+
+	status := BatchStatusPartial
+	switch {
+	case summary.Failed == 0:
+		status = BatchStatusOK
+	case summary.Succeeded == 0:
+		status = BatchStatusFailed
+	}
+
+	return BatchResponse{Results: results, Summary: summary, Status: status}
+}
+
+func batchHasFailure(results []BatchItemResult) bool {
+	for _, r := range results {
+		if r.Status != "ok" {
+			return true
+		}
+	}
+	return false
+}
+
+// allowPartial reports whether the request accepts partial results. It is
+// on by default; ?partial=false makes the batch all-or-nothing.
+func allowPartial(c *gin.Context) bool {
+	return c.DefaultQuery("partial", "true") != "false"
+}
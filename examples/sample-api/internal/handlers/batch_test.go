@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/sample-api/internal/middleware"
+	"github.com/yourusername/sample-api/internal/store"
+)
+
+func TestNewBatchResponse(t *testing.T) {
+	ok, failed := batchOK(0, nil), batchError(0, "failed")
+
+	tests := []struct {
+		name         string
+		results      []BatchItemResult
+		allowPartial bool
+		wantStatus   string
+		wantSummary  BatchSummary
+	}{
+		{"all succeeded", []BatchItemResult{ok, ok}, true, BatchStatusOK, BatchSummary{Succeeded: 2, Total: 2}},
+		{"some failed", []BatchItemResult{ok, failed, ok}, true, BatchStatusPartial, BatchSummary{Succeeded: 2, Failed: 1, Total: 3}},
+		{"all failed", []BatchItemResult{failed, failed}, true, BatchStatusFailed, BatchSummary{Failed: 2, Total: 2}},
+		{"all-or-nothing with a failure", []BatchItemResult{ok, failed}, false, BatchStatusFailed, BatchSummary{Failed: 2, Total: 2}},
+		{"all-or-nothing without failures", []BatchItemResult{ok, ok}, false, BatchStatusOK, BatchSummary{Succeeded: 2, Total: 2}},
+		{"empty batch", nil, true, BatchStatusOK, BatchSummary{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := append([]BatchItemResult(nil), tt.results...)
+			resp := newBatchResponse(results, tt.allowPartial)
+			if resp.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", resp.Status, tt.wantStatus)
+			}
+			if resp.Summary != tt.wantSummary {
+				t.Errorf("summary = %+v, want %+v", resp.Summary, tt.wantSummary)
+			}
+		})
+	}
+}
+
+func TestShareBulk(t *testing.T) {
+	viewer := func(user string) gin.H { return gin.H{"user_id": user, "role": "viewer"} }
+	invalid := gin.H{"user_id": "dave", "role": "owner"}
+
+	tests := []struct {
+		name       string
+		query      string
+		shares     []gin.H
+		wantStatus string
+		wantItems  []string        // per-item status
+		wantShared map[string]bool // user -> has access afterwards
+	}{
+		{
+			name:       "all valid",
+			shares:     []gin.H{viewer("bob"), viewer("carol")},
+			wantStatus: BatchStatusOK,
+			wantItems:  []string{"ok", "ok"},
+			wantShared: map[string]bool{"bob": true, "carol": true},
+		},
+		{
+			name:       "partial by default",
+			shares:     []gin.H{viewer("bob"), invalid, viewer("bob")},
+			wantStatus: BatchStatusPartial,
+			wantItems:  []string{"ok", "error", "error"},
+			wantShared: map[string]bool{"bob": true, "dave": false},
+		},
+		{
+			name:       "all invalid",
+			shares:     []gin.H{invalid, {"role": "viewer"}},
+			wantStatus: BatchStatusFailed,
+			wantItems:  []string{"error", "error"},
+			wantShared: map[string]bool{"dave": false},
+		},
+		{
+			name:       "all-or-nothing changes nothing on failure",
+			query:      "?partial=false",
+			shares:     []gin.H{viewer("bob"), invalid},
+			wantStatus: BatchStatusFailed,
+			wantItems:  []string{"error", "error"},
+			wantShared: map[string]bool{"bob": false, "dave": false},
+		},
+		{
+			name:       "all-or-nothing applies a clean batch",
+			query:      "?partial=false",
+			shares:     []gin.H{viewer("bob"), viewer("carol")},
+			wantStatus: BatchStatusOK,
+			wantItems:  []string{"ok", "ok"},
+			wantShared: map[string]bool{"bob": true, "carol": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := store.NewMemoryStore()
+			if err := s.CreateDocument(&store.Document{ID: "doc-1", WorkspaceID: "ws-1", OwnerID: "alice", Visibility: "private"}); err != nil {
+				t.Fatal(err)
+			}
+			h := NewDocumentHandler(s, nil)
+
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set(middleware.UserContextKey, &store.UserContext{UserID: "alice", WorkspaceID: "ws-1"})
+			})
+			r.POST("/documents/:id/share/bulk", h.ShareBulk)
+
+			body, _ := json.Marshal(gin.H{"shares": tt.shares})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/documents/doc-1/share/bulk"+tt.query, bytes.NewReader(body)))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+
+			var resp BatchResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.wantStatus {
+				t.Errorf("batch status = %q, want %q", resp.Status, tt.wantStatus)
+			}
+			for i, want := range tt.wantItems {
+				if got := resp.Results[i]; got.Index != i || got.Status != want {
+					t.Errorf("item %d = %+v, want status %q", i, got, want)
+				}
+			}
+			for user, want := range tt.wantShared {
+				if got := s.GetUserDocumentRole("doc-1", user) != ""; got != want {
+					t.Errorf("%s has access = %v, want %v", user, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchLimits(t *testing.T) {
+	s := store.NewMemoryStore()
+	if err := s.CreateDocument(&store.Document{ID: "doc-1", WorkspaceID: "ws-1", OwnerID: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	h := NewDocumentHandler(s, nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(middleware.UserContextKey, &store.UserContext{UserID: "alice"})
+	})
+	r.POST("/documents/:id/share/bulk", h.ShareBulk)
+	r.POST("/check-permissions", CheckPermissions(nil))
+
+	shares := make([]gin.H, maxBatchItems+1)
+	checks := make([]gin.H, maxBatchItems+1)
+	for i := range shares {
+		shares[i] = gin.H{"user_id": "bob", "role": "viewer"}
+		checks[i] = gin.H{"user": "user:alice", "relation": "viewer", "object": "document:doc"}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   gin.H
+		want   int
+	}{
+		{"too many shares", http.MethodPost, "/documents/doc-1/share/bulk", gin.H{"shares": shares}, http.StatusBadRequest},
+		{"too many checks", http.MethodPost, "/check-permissions", gin.H{"checks": checks}, http.StatusBadRequest},
+		{"checks at the limit", http.MethodPost, "/check-permissions", gin.H{"checks": checks[:maxBatchItems]}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, bytes.NewReader(body)))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d; body %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
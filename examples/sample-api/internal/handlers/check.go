@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/sample-api/internal/authz"
+)
+
+// CheckPermissions evaluates several permission checks in one OpenFGA batch
+// check and returns a {allowed} result per check, in request order. Checks
+// that could not be evaluated are denied and carry an error.
+// POST /api/v1/check-permissions
+func CheckPermissions(fga authz.Authorizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Checks []struct {
+				User     string `json:"user"`
+				Relation string `json:"relation"`
+				Object   string `json:"object"`
+			} `json:"checks" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+		if len(req.Checks) > maxBatchItems {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d checks per request", maxBatchItems)})
+			return
+		}
+
+		checks := make([]authz.CheckRequest, len(req.Checks))
+		for i, check := range req.Checks {
+			for _, ref := range []string{check.User, check.Object} {
+				if _, err := authz.ParseObjectRef(ref); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("check %d: %v", i, err)})
+					return
+				}
+			}
+			checks[i] = authz.CheckRequest{User: check.User, Relation: check.Relation, Object: check.Object}
+		}
+
+		results := make([]gin.H, len(checks))
+		if fga == nil {
+			for i := range results {
+				results[i] = gin.H{"allowed": true, "mock": true}
+			}
+			c.JSON(http.StatusOK, results)
+			return
+		}
+
+		allowed, err := fga.BatchCheck(checks)
+		var batchErr *authz.BatchCheckError
+		if err != nil && !errors.As(err, &batchErr) {
+			log.Printf("Warning: Batch permission check failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "permission check failed"})
+			return
+		}
+
+		for i := range results {
+			if batchErr != nil && batchErr.Failed[i] != nil {
+				results[i] = gin.H{"allowed": false, "error": "permission check failed"}
+				continue
+			}
+			results[i] = gin.H{"allowed": allowed[i]}
+		}
+		c.JSON(http.StatusOK, results)
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/sample-api/internal/authz"
+	"github.com/yourusername/sample-api/internal/testutil"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// TestCheckPermissions shows how to test a handler against the in-memory
+// OpenFGA evaluator instead of a live server
+func TestCheckPermissions(t *testing.T) {
+	fga := testutil.NewMemoryFGA(nil)
+	if err := fga.WriteTuples([]authz.Tuple{
+		{User: "user:alice", Relation: "member", Object: "workspace:ws-1"},
+		{User: "workspace:ws-1", Relation: "workspace", Object: "document:doc-1"},
+		{User: "user:bob", Relation: "owner", Object: "document:doc-1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.POST("/check-permissions", CheckPermissions(fga))
+
+	type check struct {
+		User     string `json:"user"`
+		Relation string `json:"relation"`
+		Object   string `json:"object"`
+	}
+	tests := []struct {
+		name  string
+		check check
+		want  bool
+	}{
+		{"direct grant", check{"user:bob", "can_delete", "document:doc-1"}, true},
+		{"inherited from the workspace", check{"user:alice", "can_read", "document:doc-1"}, true},
+		{"denied", check{"user:alice", "can_delete", "document:doc-1"}, false},
+	}
+
+	checks := make([]check, len(tests))
+	for i, tc := range tests {
+		checks[i] = tc.check
+	}
+	body, _ := json.Marshal(gin.H{"checks": checks})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/check-permissions", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	var results []struct {
+		Allowed bool `json:"allowed"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(tests) {
+		t.Fatalf("got %d results, want %d", len(results), len(tests))
+	}
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if results[i].Allowed != tc.want {
+				t.Errorf("result %d allowed = %v, want %v", i, results[i].Allowed, tc.want)
+			}
+		})
+	}
+}
+
+// batchCheckFGA fails BatchCheck with err, returning allowed
+type batchCheckFGA struct {
+	authz.Authorizer
+	allowed []bool
+	err     error
+}
+
+func (f batchCheckFGA) BatchCheck(checks []authz.CheckRequest) ([]bool, error) {
+	return f.allowed, f.err
+}
+
+func TestCheckPermissionsErrors(t *testing.T) {
+	check := gin.H{"user": "user:bob", "relation": "can_read", "object": "document:doc-1"}
+	malformed := gin.H{"user": "bob", "relation": "can_read", "object": "document:doc-1"}
+	tooMany := make([]gin.H, maxBatchItems+1)
+	for i := range tooMany {
+		tooMany[i] = check
+	}
+
+	tests := []struct {
+		name       string
+		fga        authz.Authorizer
+		checks     []gin.H
+		wantStatus int
+		wantBody   string
+	}{
+		{"mock without OpenFGA", nil, []gin.H{check, check}, http.StatusOK,
+			`[{"allowed":true,"mock":true},{"allowed":true,"mock":true}]`},
+		{"malformed reference", nil, []gin.H{check, malformed}, http.StatusBadRequest, ""},
+		{"too many checks", nil, tooMany, http.StatusBadRequest, ""},
+		{"batch check failed", batchCheckFGA{err: errors.New("unavailable")}, []gin.H{check}, http.StatusInternalServerError, ""},
+		{"some checks failed are denied", batchCheckFGA{
+			allowed: []bool{true, false},
+			err:     &authz.BatchCheckError{Failed: map[int]error{1: errors.New("timeout")}},
+		}, []gin.H{check, check}, http.StatusOK,
+			`[{"allowed":true},{"allowed":false,"error":"permission check failed"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/check-permissions", CheckPermissions(tt.fga))
+
+			body, _ := json.Marshal(gin.H{"checks": tt.checks})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/check-permissions", bytes.NewReader(body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/sample-api/internal/middleware"
+	"github.com/yourusername/sample-api/internal/store"
+)
+
+// ListVersions returns a document's retained versions, newest first
+// GET /api/v1/documents/:id/versions
+func (h *DocumentHandler) ListVersions(c *gin.Context) {
+	userCtx := middleware.GetUserContext(c)
+	docID := c.Param("id")
+
+	doc, err := h.store.GetDocument(docID, false)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	if !h.canRead(userCtx, doc) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "access_denied",
+			"message": "You don't have permission to view this document",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document_id":     docID,
+		"current_version": doc.Version,
+		"versions":        h.store.ListDocumentVersions(docID),
+	})
+}
+
+// GetVersion returns one version of a document
+// GET /api/v1/documents/:id/versions/:n
+func (h *DocumentHandler) GetVersion(c *gin.Context) {
+	userCtx := middleware.GetUserContext(c)
+	docID := c.Param("id")
+
+	doc, err := h.store.GetDocument(docID, false)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	if !h.canRead(userCtx, doc) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "access_denied",
+			"message": "You don't have permission to view this document",
+		})
+		return
+	}
+
+	version, ok := h.lookupVersion(c, docID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"version": version})
+}
+
+// Revert restores an old version's title and content as a new version.
+// Like Update it needs write access and honors If-Match.
+// POST /api/v1/documents/:id/revert/:n
+func (h *DocumentHandler) Revert(c *gin.Context) {
+	userCtx := middleware.GetUserContext(c)
+	docID := c.Param("id")
+
+	doc, err := h.store.GetDocument(docID, false)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	// Check write permission using ReBAC
+	if !h.canWrite(userCtx, doc) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "access_denied",
+			"message": "You don't have permission to edit this document",
+		})
+		return
+	}
+
+	if !checkVersion(c, nil, doc.Version) {
+		return
+	}
+
+	version, ok := h.lookupVersion(c, docID)
+	if !ok {
+		return
+	}
+
+	doc.Title = version.Title
+	doc.Content = version.Content
+	doc.UpdatedBy = userCtx.UserID
+
+	if err := h.store.UpdateDocument(doc); err != nil {
+		if errors.Is(err, store.ErrVersionConflict) {
+			if current, err := h.store.GetDocument(docID, false); err == nil {
+				versionConflict(c, current.Version)
+				return
+			}
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	c.Header("ETag", versionETag(doc.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message":  fmt.Sprintf("Document reverted to version %d", version.Version),
+		"document": doc,
+	})
+}
+
+// lookupVersion loads the version named by the :n parameter, responding
+// 400/404 and returning ok=false if it is malformed or no longer retained
+func (h *DocumentHandler) lookupVersion(c *gin.Context, docID string) (*store.DocumentVersion, bool) {
+	n, err := strconv.Atoi(c.Param("n"))
+	if err != nil || n < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version must be a positive number"})
+		return nil, false
+	}
+
+	version, err := h.store.GetDocumentVersion(docID, n)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+		return nil, false
+	}
+	return version, true
+}
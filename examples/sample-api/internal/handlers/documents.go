@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"time"
 
@@ -22,49 +24,52 @@ import (
 //   - owner: can_read, can_write, can_delete, can_share
 //   - editor: can_read, can_write
 //   - viewer: can_read
+//
+// List, Create, Get, Update and Delete come from ResourceHandler, with
+// documentResource supplying the ReBAC rules.
 type DocumentHandler struct {
-	store *store.MemoryStore
-	fga   *authz.OpenFGAClient
-}
+	*ResourceHandler[*store.Document]
 
-func NewDocumentHandler(s *store.MemoryStore, fga *authz.OpenFGAClient) *DocumentHandler {
-	return &DocumentHandler{store: s, fga: fga}
+	store store.Store
+	fga   authz.Authorizer
 }
 
-// List returns documents the user can access
-// GET /api/v1/documents
-func (h *DocumentHandler) List(c *gin.Context) {
-	userCtx := middleware.GetUserContext(c)
+func NewDocumentHandler(s store.Store, fga authz.Authorizer) *DocumentHandler {
+	h := &DocumentHandler{store: s, fga: fga}
+	h.ResourceHandler = NewResourceHandler[*store.Document](documentResource{h})
+	return h
+}
 
-	// Get documents user can see based on visibility and sharing
-	docs := h.store.ListDocumentsForUser(userCtx.WorkspaceID, userCtx.UserID)
+// documentResource is the ResourceType for documents: access follows the
+// user's relationship to the document (ReBAC)
+type documentResource struct {
+	h *DocumentHandler
+}
 
-	// Enrich with user's permission level
-	type DocWithPermissions struct {
-		*store.Document
-		Permissions map[string]bool `json:"permissions"`
-	}
+func (documentResource) Kind() string   { return "document" }
+func (documentResource) Plural() string { return "documents" }
 
-	result := make([]DocWithPermissions, 0, len(docs))
-	for _, doc := range docs {
-		permissions := h.getUserPermissions(userCtx, doc)
-		result = append(result, DocWithPermissions{
-			Document:    doc,
-			Permissions: permissions,
-		})
-	}
+// List returns the documents the user can see based on visibility and
+// sharing
+func (r documentResource) List(c *gin.Context, userCtx *store.UserContext) []*store.Document {
+	return r.h.store.ListDocumentsForUser(userCtx.WorkspaceID, userCtx.UserID)
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"documents": result,
-		"total":     len(result),
-	})
+func (r documentResource) Load(id string) (*store.Document, error) {
+	return r.h.store.GetDocument(id, false)
 }
 
-// Create creates a new document
-// POST /api/v1/documents
-func (h *DocumentHandler) Create(c *gin.Context) {
-	userCtx := middleware.GetUserContext(c)
+func (r documentResource) Insert(doc *store.Document) error      { return r.h.store.CreateDocument(doc) }
+func (r documentResource) Save(doc *store.Document) error        { return r.h.store.UpdateDocument(doc) }
+func (documentResource) Version(doc *store.Document) int         { return doc.Version }
+func (documentResource) UpdatedAt(doc *store.Document) time.Time { return doc.UpdatedAt }
 
+// Remove moves the document to the trash. OpenFGA relationships stay until
+// the trash is purged so a restore brings back the same access; the store
+// hides trashed documents meanwhile.
+func (r documentResource) Remove(id string) error { return r.h.store.DeleteDocument(id) }
+
+func (documentResource) Decode(c *gin.Context, userCtx *store.UserContext) (*store.Document, bool) {
 	var req struct {
 		Title      string `json:"title" binding:"required"`
 		Content    string `json:"content"`
@@ -73,14 +78,14 @@ func (h *DocumentHandler) Create(c *gin.Context) {
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
-		return
+		return nil, false
 	}
 
 	if req.Visibility == "" {
 		req.Visibility = "workspace"
 	}
 
-	doc := &store.Document{
+	return &store.Document{
 		ID:          uuid.New().String(),
 		Title:       req.Title,
 		Content:     req.Content,
@@ -88,228 +93,554 @@ func (h *DocumentHandler) Create(c *gin.Context) {
 		OwnerID:     userCtx.UserID,
 		Visibility:  req.Visibility,
 		Status:      "draft",
+	}, true
+}
+
+func (documentResource) Apply(c *gin.Context, userCtx *store.UserContext, doc *store.Document) bool {
+	var req struct {
+		Title      *string `json:"title"`
+		Content    *string `json:"content"`
+		Visibility *string `json:"visibility"`
+		Status     *string `json:"status"`
+
+		// ExpectedVersion (or an If-Match header) rejects the update with
+		// 409 if the document changed since the client read it
+		ExpectedVersion *int `json:"expected_version"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return false
+	}
+
+	if !checkVersion(c, req.ExpectedVersion, doc.Version) {
+		return false
 	}
 
-	if err := h.store.CreateDocument(doc); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create document"})
+	if req.Title != nil {
+		doc.Title = *req.Title
+	}
+	if req.Content != nil {
+		doc.Content = *req.Content
+	}
+	if req.Visibility != nil {
+		// Only owner can change visibility
+		if doc.OwnerID != userCtx.UserID {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "access_denied",
+				"message": "Only the owner can change document visibility",
+			})
+			return false
+		}
+		doc.Visibility = *req.Visibility
+	}
+	if req.Status != nil {
+		doc.Status = *req.Status
+	}
+	doc.UpdatedBy = userCtx.UserID
+	return true
+}
+
+// Created writes the owner and workspace relationships to OpenFGA
+func (r documentResource) Created(userCtx *store.UserContext, doc *store.Document) {
+	if r.h.fga == nil {
 		return
 	}
+	// document:doc-id#owner@user:user-id
+	r.h.fga.WriteTuple(
+		authz.UserRef(userCtx.UserID).String(),
+		"owner",
+		authz.DocumentRef(doc.ID).String(),
+	)
+	// document:doc-id#workspace@workspace:workspace-id
+	r.h.fga.WriteTuple(
+		authz.WorkspaceRef(userCtx.WorkspaceID).String(),
+		"workspace",
+		authz.DocumentRef(doc.ID).String(),
+	)
+}
 
-	// Create OpenFGA relationship for owner
-	if h.fga != nil {
-		// document:doc-id#owner@user:user-id
-		h.fga.WriteTuple(
-			fmt.Sprintf("user:%s", userCtx.UserID),
-			"owner",
-			fmt.Sprintf("document:%s", doc.ID),
-		)
-		// document:doc-id#container@container:workspace-id
-		h.fga.WriteTuple(
-			fmt.Sprintf("container:%s", userCtx.WorkspaceID),
-			"container",
-			fmt.Sprintf("document:%s", doc.ID),
-		)
+func (r documentResource) Authorize(c *gin.Context, userCtx *store.UserContext, doc *store.Document, action string) *Denial {
+	switch action {
+	case "read":
+		if !r.h.canRead(userCtx, doc) {
+			return accessDenied("You don't have permission to view this document")
+		}
+	case "update":
+		if !r.h.canWrite(userCtx, doc) {
+			return accessDenied("You don't have permission to edit this document")
+		}
+	case "delete":
+		// Only owner can delete
+		if !r.h.canDelete(userCtx, doc) {
+			return accessDenied("Only the owner can delete this document")
+		}
 	}
+	return nil
+}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"document": doc,
-		"permissions": map[string]bool{
+// Permissions resolves the whole page in one batch instead of a check per
+// document
+func (r documentResource) Permissions(c *gin.Context, userCtx *store.UserContext, docs []*store.Document) []map[string]bool {
+	return r.h.getBatchPermissions(userCtx, docs)
+}
+
+func (r documentResource) Describe(c *gin.Context, userCtx *store.UserContext, doc *store.Document, action string) gin.H {
+	switch action {
+	case "create":
+		return gin.H{"permissions": map[string]bool{
 			"can_read":   true,
 			"can_write":  true,
 			"can_delete": true,
 			"can_share":  true,
-		},
-	})
+		}}
+	case "read":
+		return gin.H{
+			"permissions": r.h.getUserPermissions(userCtx, doc),
+			"shares":      r.h.store.GetDocumentShares(doc.ID),
+		}
+	}
+	return nil
 }
 
-// Get returns a specific document
-// GET /api/v1/documents/:id
-func (h *DocumentHandler) Get(c *gin.Context) {
+func (documentResource) ListMeta() gin.H { return nil }
+
+// accessDenied refuses a document action with 403 access_denied
+func accessDenied(message string) *Denial {
+	return &Denial{Status: http.StatusForbidden, Body: gin.H{
+		"error":   "access_denied",
+		"message": message,
+	}}
+}
+
+// Share shares a document with another user
+// POST /api/v1/documents/:id/share
+func (h *DocumentHandler) Share(c *gin.Context) {
 	userCtx := middleware.GetUserContext(c)
 	docID := c.Param("id")
 
-	doc, err := h.store.GetDocument(docID)
+	doc, err := h.store.GetDocument(docID, false)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
 		return
 	}
 
-	// Check access using ReBAC
-	if !h.canRead(userCtx, doc) {
+	// Only owner can share
+	if !h.canShare(userCtx, doc) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error":   "access_denied",
-			"message": "You don't have permission to view this document",
+			"message": "Only the owner can share this document",
 		})
 		return
 	}
 
-	permissions := h.getUserPermissions(userCtx, doc)
+	var req struct {
+		UserID     string `json:"user_id" binding:"required"`
+		Role       string `json:"role" binding:"required"` // editor, viewer
+		TTLSeconds int    `json:"ttl_seconds"`             // optional, 0 = permanent
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if req.TTLSeconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ttl_seconds must not be negative"})
+		return
+	}
+
+	if req.Role != "editor" && req.Role != "viewer" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be 'editor' or 'viewer'"})
+		return
+	}
+
+	share := store.DocumentShare{
+		DocumentID: docID,
+		UserID:     req.UserID,
+		Role:       req.Role,
+	}
+	if req.TTLSeconds > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.TTLSeconds) * time.Second)
+		share.ExpiresAt = &expiresAt
+	}
+
+	// Drop any lapsed share (and its tuple) so it can be replaced
+	h.expireShares(docID)
+
+	if err := h.store.AddDocumentShare(share); err != nil {
+		if err == store.ErrAlreadyExists {
+			c.JSON(http.StatusConflict, gin.H{"error": "user already has access"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to share document"})
+		return
+	}
+
+	// Create OpenFGA relationship
+	if h.fga != nil {
+		h.fga.WriteTuple(
+			authz.UserRef(req.UserID).String(),
+			req.Role,
+			authz.DocumentRef(docID).String(),
+		)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"document":    doc,
-		"permissions": permissions,
-		"shares":      h.store.GetDocumentShares(docID),
+		"message": fmt.Sprintf("Document shared with user as %s", req.Role),
+		"share":   share,
 	})
 }
 
-// Update updates a document
-// PUT /api/v1/documents/:id
-func (h *DocumentHandler) Update(c *gin.Context) {
+// ShareBulk shares a document with several users at once, e.g. a whole
+// team. Entries are validated like Share and their OpenFGA tuples written
+// in one batched write; see batch.go for the response.
+// POST /api/v1/documents/:id/share/bulk
+func (h *DocumentHandler) ShareBulk(c *gin.Context) {
 	userCtx := middleware.GetUserContext(c)
 	docID := c.Param("id")
 
-	doc, err := h.store.GetDocument(docID)
+	doc, err := h.store.GetDocument(docID, false)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
 		return
 	}
 
-	// Check write permission using ReBAC
-	if !h.canWrite(userCtx, doc) {
+	// Only owner can share
+	if !h.canShare(userCtx, doc) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error":   "access_denied",
-			"message": "You don't have permission to edit this document",
+			"message": "Only the owner can share this document",
 		})
 		return
 	}
 
 	var req struct {
-		Title      *string `json:"title"`
-		Content    *string `json:"content"`
-		Visibility *string `json:"visibility"`
-		Status     *string `json:"status"`
+		Shares []struct {
+			UserID     string `json:"user_id"`
+			Role       string `json:"role"`        // editor, viewer
+			TTLSeconds int    `json:"ttl_seconds"` // optional, 0 = permanent
+		} `json:"shares" binding:"required"`
 	}
-
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
+	if len(req.Shares) > maxBatchItems {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "batch_too_large",
+			"message": fmt.Sprintf("At most %d shares per request", maxBatchItems),
+		})
+		return
+	}
 
-	if req.Title != nil {
-		doc.Title = *req.Title
+	// Drop any lapsed shares (and their tuples) so they can be replaced
+	h.expireShares(docID)
+
+	// Validate every entry first so an all-or-nothing batch can bail out
+	// before changing anything
+	now := time.Now()
+	results := make([]BatchItemResult, len(req.Shares))
+	shares := make([]*store.DocumentShare, len(req.Shares))
+	seen := make(map[string]bool, len(req.Shares))
+	for i, entry := range req.Shares {
+		switch {
+		case entry.UserID == "":
+			results[i] = batchError(i, "user_id is required")
+			continue
+		case entry.Role != "editor" && entry.Role != "viewer":
+			results[i] = batchError(i, "role must be 'editor' or 'viewer'")
+			continue
+		case entry.TTLSeconds < 0:
+			results[i] = batchError(i, "ttl_seconds must not be negative")
+			continue
+		case seen[entry.UserID] || h.store.GetUserDocumentRole(docID, entry.UserID) != "":
+			results[i] = batchError(i, "user already has access")
+			continue
+		}
+		seen[entry.UserID] = true
+
+		share := store.DocumentShare{
+			DocumentID: docID,
+			UserID:     entry.UserID,
+			Role:       entry.Role,
+		}
+		if entry.TTLSeconds > 0 {
+			expiresAt := now.Add(time.Duration(entry.TTLSeconds) * time.Second)
+			share.ExpiresAt = &expiresAt
+		}
+		shares[i] = &share
+		results[i] = batchOK(i, share)
 	}
-	if req.Content != nil {
-		doc.Content = *req.Content
+
+	if !allowPartial(c) && batchHasFailure(results) {
+		c.JSON(http.StatusOK, newBatchResponse(results, false))
+		return
 	}
-	if req.Visibility != nil {
-		// Only owner can change visibility
-		if doc.OwnerID != userCtx.UserID {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "access_denied",
-				"message": "Only the owner can change document visibility",
-			})
-			return
+
+	var added []int
+	for i, share := range shares {
+		if share == nil {
+			continue
 		}
-		doc.Visibility = *req.Visibility
-	}
-	if req.Status != nil {
-		doc.Status = *req.Status
+		if err := h.store.AddDocumentShare(*share); err != nil {
+			if errors.Is(err, store.ErrAlreadyExists) {
+				results[i] = batchError(i, "user already has access")
+			} else {
+				results[i] = batchError(i, "failed to share document")
+			}
+			continue
+		}
+		added = append(added, i)
 	}
 
-	doc.UpdatedAt = time.Now()
-	h.store.UpdateDocument(doc)
+	// Create OpenFGA relationships in one write
+	if h.fga != nil && len(added) > 0 {
+		tuples := make([]authz.Tuple, len(added))
+		for j, i := range added {
+			tuples[j] = authz.Tuple{
+				User:     authz.UserRef(shares[i].UserID).String(),
+				Relation: shares[i].Role,
+				Object:   authz.DocumentRef(docID).String(),
+			}
+		}
+
+		// The write is atomic, so one rejected tuple fails them all; retry
+		// individually to find it and undo only that share
+		if err := h.fga.WriteTuples(tuples); err != nil {
+			log.Printf("Warning: Batched share write for document %s failed, retrying individually: %v", docID, err)
+			for j, i := range added {
+				if err := h.fga.WriteTuple(tuples[j].User, tuples[j].Relation, tuples[j].Object); err != nil {
+					log.Printf("Warning: Failed to write share tuple for document %s: %v", docID, err)
+					h.store.RemoveDocumentShare(docID, shares[i].UserID)
+					results[i] = batchError(i, "failed to grant access")
+				}
+			}
+		}
+	}
 
-	c.JSON(http.StatusOK, gin.H{"document": doc})
+	c.JSON(http.StatusOK, newBatchResponse(results, true))
 }
 
-// Delete deletes a document
-// DELETE /api/v1/documents/:id
-func (h *DocumentHandler) Delete(c *gin.Context) {
+// Unshare revokes a user's share of a document
+// DELETE /api/v1/documents/:id/share/:userId
+func (h *DocumentHandler) Unshare(c *gin.Context) {
 	userCtx := middleware.GetUserContext(c)
 	docID := c.Param("id")
+	userID := c.Param("userId")
 
-	doc, err := h.store.GetDocument(docID)
+	doc, err := h.store.GetDocument(docID, false)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
 		return
 	}
 
-	// Only owner can delete
-	if !h.canDelete(userCtx, doc) {
+	// Only owner can manage shares
+	if !h.canShare(userCtx, doc) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error":   "access_denied",
-			"message": "Only the owner can delete this document",
+			"message": "Only the owner can unshare this document",
 		})
 		return
 	}
 
-	h.store.DeleteDocument(docID)
+	if userID == doc.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the owner's access cannot be removed"})
+		return
+	}
 
-	// Remove OpenFGA relationships
+	share, err := h.store.RemoveDocumentShare(docID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "share not found"})
+		return
+	}
+
+	// Remove OpenFGA relationship
 	if h.fga != nil {
-		h.fga.DeleteTuple(
-			fmt.Sprintf("user:%s", doc.OwnerID),
-			"owner",
-			fmt.Sprintf("document:%s", docID),
-		)
+		if err := h.fga.DeleteTuple(
+			authz.UserRef(userID).String(),
+			share.Role,
+			authz.DocumentRef(docID).String(),
+		); err != nil {
+			log.Printf("Warning: Failed to delete share tuple for document %s: %v", docID, err)
+		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "document deleted"})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "share removed",
+		"share":   share,
+	})
 }
 
-// Share shares a document with another user
-// POST /api/v1/documents/:id/share
-func (h *DocumentHandler) Share(c *gin.Context) {
+// TransferOwnership makes another user the document's owner. The previous
+// owner keeps an editor share unless previous_owner_role says otherwise
+// ("viewer", or "none" to drop their access). The OpenFGA tuples are
+// swapped in one write; if it fails, the store change is rolled back so
+// the two never disagree about who owns the document.
+// POST /api/v1/documents/:id/transfer-ownership
+func (h *DocumentHandler) TransferOwnership(c *gin.Context) {
 	userCtx := middleware.GetUserContext(c)
 	docID := c.Param("id")
 
-	doc, err := h.store.GetDocument(docID)
+	doc, err := h.store.GetDocument(docID, false)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
 		return
 	}
 
-	// Only owner can share
+	// Only owner can give the document away
 	if !h.canShare(userCtx, doc) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error":   "access_denied",
-			"message": "Only the owner can share this document",
+			"message": "Only the owner can transfer this document",
 		})
 		return
 	}
 
 	var req struct {
-		UserID string `json:"user_id" binding:"required"`
-		Role   string `json:"role" binding:"required"` // editor, viewer
+		NewOwnerID        string `json:"new_owner_id" binding:"required"`
+		PreviousOwnerRole string `json:"previous_owner_role"` // editor (default), viewer, none
 	}
-
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	if req.Role != "editor" && req.Role != "viewer" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be 'editor' or 'viewer'"})
+	switch req.PreviousOwnerRole {
+	case "":
+		req.PreviousOwnerRole = "editor"
+	case "editor", "viewer", "none":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "previous_owner_role must be 'editor', 'viewer' or 'none'"})
+		return
+	}
+	if req.NewOwnerID == doc.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user already owns this document"})
 		return
 	}
 
-	share := store.DocumentShare{
-		DocumentID: docID,
-		UserID:     req.UserID,
-		Role:       req.Role,
+	// Drop any lapsed shares (and their tuples) so the new owner's current
+	// share is the one replaced
+	h.expireShares(docID)
+
+	previousOwner := doc.OwnerID
+	formerOwner := store.DocumentShare{DocumentID: docID, UserID: previousOwner}
+	if req.PreviousOwnerRole != "none" {
+		formerOwner.Role = req.PreviousOwnerRole
 	}
 
-	if err := h.store.AddDocumentShare(share); err != nil {
-		if err == store.ErrAlreadyExists {
-			c.JSON(http.StatusConflict, gin.H{"error": "user already has access"})
-			return
+	replaced, err := h.store.TransferDocumentOwnership(docID, previousOwner, req.NewOwnerID, formerOwner)
+	if err != nil {
+		switch err {
+		case store.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		case store.ErrVersionConflict:
+			c.JSON(http.StatusConflict, gin.H{"error": "document owner changed, reload and retry"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to transfer document"})
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to share document"})
 		return
 	}
 
-	// Create OpenFGA relationship
 	if h.fga != nil {
-		h.fga.WriteTuple(
-			fmt.Sprintf("user:%s", req.UserID),
-			req.Role,
-			fmt.Sprintf("document:%s", docID),
-		)
+		object := authz.DocumentRef(docID).String()
+		oldOwner := authz.UserRef(previousOwner).String()
+		newOwner := authz.UserRef(req.NewOwnerID).String()
+
+		deletes := []authz.Tuple{{User: oldOwner, Relation: "owner", Object: object}}
+		if replaced.Role != "" {
+			deletes = append(deletes, authz.Tuple{User: newOwner, Relation: replaced.Role, Object: object})
+		}
+		writes := []authz.Tuple{{User: newOwner, Relation: "owner", Object: object}}
+		if formerOwner.Role != "" {
+			writes = append(writes, authz.Tuple{User: oldOwner, Relation: formerOwner.Role, Object: object})
+		}
+
+		if err := h.fga.UpdateTuples(writes, deletes); err != nil {
+			log.Printf("Failed to transfer ownership tuples for document %s: %v", docID, err)
+			restore := store.DocumentShare{DocumentID: docID, UserID: req.NewOwnerID}
+			if replaced.Role != "" {
+				restore = replaced
+			}
+			if _, rbErr := h.store.TransferDocumentOwnership(docID, req.NewOwnerID, previousOwner, restore); rbErr != nil {
+				log.Printf("Failed to roll back ownership transfer of document %s: %v", docID, rbErr)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update permissions, ownership not transferred"})
+			return
+		}
+	}
+
+	doc, err = h.store.GetDocument(docID, false)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": fmt.Sprintf("Document shared with user as %s", req.Role),
-		"share":   share,
+		"message":             "ownership transferred",
+		"document":            doc,
+		"previous_owner_id":   previousOwner,
+		"previous_owner_role": req.PreviousOwnerRole,
+	})
+}
+
+// documentActions are the actions Can evaluates, with the relation each
+// requires and the message the operation is refused with
+var documentActions = map[string]struct {
+	relation string
+	denied   string
+}{
+	"read":   {"viewer", "You don't have permission to view this document"},
+	"update": {"editor", "You don't have permission to edit this document"},
+	"delete": {"owner", "Only the owner can delete this document"},
+	"share":  {"owner", "Only the owner can share this document"},
+}
+
+// Can evaluates whether the user may perform ?action= (read, update,
+// delete or share) on the document without performing it, using the same
+// checks as the operation. policy is the relation the action requires.
+// POST /api/v1/documents/:id/can?action=share
+func (h *DocumentHandler) Can(c *gin.Context) {
+	userCtx := middleware.GetUserContext(c)
+	action := c.Query("action")
+
+	rule, ok := documentActions[action]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_action",
+			"message": "action must be read, update, delete or share",
+		})
+		return
+	}
+
+	doc, err := h.store.GetDocument(c.Param("id"), false)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	var allowed bool
+	switch action {
+	case "read":
+		allowed = h.canRead(userCtx, doc)
+	case "update":
+		allowed = h.canWrite(userCtx, doc)
+	case "delete":
+		allowed = h.canDelete(userCtx, doc)
+	case "share":
+		allowed = h.canShare(userCtx, doc)
+	}
+
+	var reason, policy string
+	if !allowed {
+		reason, policy = rule.denied, rule.relation
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"action":  action,
+		"allowed": allowed,
+		"reason":  reason,
+		"policy":  policy,
 	})
 }
 
@@ -319,7 +650,7 @@ func (h *DocumentHandler) GetPermissions(c *gin.Context) {
 	userCtx := middleware.GetUserContext(c)
 	docID := c.Param("id")
 
-	doc, err := h.store.GetDocument(docID)
+	doc, err := h.store.GetDocument(docID, false)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
 		return
@@ -338,13 +669,71 @@ func (h *DocumentHandler) GetPermissions(c *gin.Context) {
 
 // Permission check helpers - ReBAC logic
 
+// GetAccess returns everyone with access to a document, grouped by role
+// GET /api/v1/documents/:id/access
+func (h *DocumentHandler) GetAccess(c *gin.Context) {
+	userCtx := middleware.GetUserContext(c)
+	docID := c.Param("id")
+
+	doc, err := h.store.GetDocument(docID, false)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	if !h.canRead(userCtx, doc) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "access_denied",
+			"message": "You don't have permission to view this document",
+		})
+		return
+	}
+
+	access := map[string][]string{
+		"owner":  {},
+		"editor": {},
+		"viewer": {},
+	}
+
+	if h.fga != nil {
+		// Revoke lapsed temporary shares before reading tuples back
+		h.expireShares(docID)
+
+		// One reverse lookup per role instead of a check per user
+		object := authz.DocumentRef(docID).String()
+		for role := range access {
+			users, err := h.fga.ListUsers(object, role, authz.TypeUser)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list document access"})
+				return
+			}
+			access[role] = users
+		}
+	} else {
+		// Mock mode: derive from the store
+		access["owner"] = append(access["owner"], doc.OwnerID)
+		for _, share := range h.store.GetDocumentShares(docID) {
+			access[share.Role] = append(access[share.Role], share.UserID)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": docID,
+		"access":      access,
+	})
+}
+
 func (h *DocumentHandler) getUserPermissions(userCtx *store.UserContext, doc *store.Document) map[string]bool {
 	// Check via OpenFGA if available
 	if h.fga != nil {
+		// Expired shares must not grant access even if the janitor hasn't
+		// removed their tuples yet
+		h.expireShares(doc.ID)
+
 		relations, err := h.fga.ListRelations(
-			fmt.Sprintf("user:%s", userCtx.UserID),
-			fmt.Sprintf("document:%s", doc.ID),
-			[]string{"can_read", "can_write", "can_manage"},
+			authz.UserRef(userCtx.UserID).String(),
+			authz.DocumentRef(doc.ID).String(),
+			documentRelations,
 		)
 		if err == nil {
 			return map[string]bool{
@@ -357,6 +746,11 @@ func (h *DocumentHandler) getUserPermissions(userCtx *store.UserContext, doc *st
 	}
 
 	// Fallback to local logic
+	return h.localPermissions(userCtx, doc)
+}
+
+// localPermissions evaluates permissions from the store without OpenFGA
+func (h *DocumentHandler) localPermissions(userCtx *store.UserContext, doc *store.Document) map[string]bool {
 	return map[string]bool{
 		"can_read":   h.canRead(userCtx, doc),
 		"can_write":  h.canWrite(userCtx, doc),
@@ -365,6 +759,80 @@ func (h *DocumentHandler) getUserPermissions(userCtx *store.UserContext, doc *st
 	}
 }
 
+// documentRelations are the OpenFGA relations checked for each document
+var documentRelations = []string{"can_read", "can_write", "can_manage"}
+
+// getBatchPermissions is getUserPermissions for many documents using a single
+// OpenFGA batch check. Results are in the same order as docs; documents whose
+// checks fail fall back to local logic.
+func (h *DocumentHandler) getBatchPermissions(userCtx *store.UserContext, docs []*store.Document) []map[string]bool {
+	permissions := make([]map[string]bool, len(docs))
+
+	if h.fga == nil || len(docs) == 0 {
+		for i, doc := range docs {
+			permissions[i] = h.getUserPermissions(userCtx, doc)
+		}
+		return permissions
+	}
+
+	// Expired shares must not grant access even if the janitor hasn't
+	// removed their tuples yet
+	h.expireShares("")
+
+	user := authz.UserRef(userCtx.UserID).String()
+	checks := make([]authz.CheckRequest, 0, len(docs)*len(documentRelations))
+	for _, doc := range docs {
+		for _, relation := range documentRelations {
+			checks = append(checks, authz.CheckRequest{
+				User:     user,
+				Relation: relation,
+				Object:   authz.DocumentRef(doc.ID).String(),
+			})
+		}
+	}
+
+	allowed, err := h.fga.BatchCheck(checks)
+	var partial *authz.BatchCheckError
+	if err != nil && !errors.As(err, &partial) {
+		log.Printf("Warning: Batch permission check failed, using local rules: %v", err)
+		for i, doc := range docs {
+			permissions[i] = h.localPermissions(userCtx, doc)
+		}
+		return permissions
+	}
+	if partial != nil {
+		log.Printf("Warning: Some permission checks failed, using local rules for those documents: %v", partial)
+	}
+
+	n := len(documentRelations)
+	for i, doc := range docs {
+		if partial != nil && docHasFailedCheck(partial, i*n, n) {
+			permissions[i] = h.localPermissions(userCtx, doc)
+			continue
+		}
+		canRead, canWrite, canManage := allowed[i*n], allowed[i*n+1], allowed[i*n+2]
+		permissions[i] = map[string]bool{
+			"can_read":   canRead,
+			"can_write":  canWrite,
+			"can_delete": canManage,
+			"can_share":  canManage,
+		}
+	}
+
+	return permissions
+}
+
+// docHasFailedCheck reports whether any of the n checks starting at offset
+// failed
+func docHasFailedCheck(partial *authz.BatchCheckError, offset, n int) bool {
+	for j := offset; j < offset+n; j++ {
+		if _, ok := partial.Failed[j]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *DocumentHandler) canRead(userCtx *store.UserContext, doc *store.Document) bool {
 	// Platform admin can read everything
 	if userCtx.IsPlatformAdmin {
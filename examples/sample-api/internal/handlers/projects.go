@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,72 +23,81 @@ import (
 //   - Resource: environment (prod/staging/dev), status, tags
 //   - Context: time of day, IP address, etc.
 //
-// Example Policies:
+// Example Policies ("admins" are platform admins and workspace admins):
 // - Only admins can deploy to production
 // - Developers can deploy to staging/development
 // - Archived projects are read-only
 // - Production projects require approval for changes
+//
+// Deployments can add their own deny policies on custom project attributes
+// (e.g. data_classification) without code changes; see authz.Policy. Deploy
+// freeze windows per environment (e.g. no production deploys over the
+// weekend) block deployments by time; see authz.FreezeSchedule.
+//
+// List, Create, Get, Update and Delete come from ResourceHandler, with
+// projectResource supplying the ABAC rules.
 type ProjectHandler struct {
-	store *store.MemoryStore
-	fga   *authz.OpenFGAClient
-}
+	*ResourceHandler[*store.Project]
 
-func NewProjectHandler(s *store.MemoryStore, fga *authz.OpenFGAClient) *ProjectHandler {
-	return &ProjectHandler{store: s, fga: fga}
+	store    store.Store
+	fga      authz.Authorizer
+	policies []authz.Policy
+	freezes  authz.FreezeSchedule
 }
 
-// List returns all projects in the workspace
-// GET /api/v1/projects
-func (h *ProjectHandler) List(c *gin.Context) {
-	userCtx := middleware.GetUserContext(c)
-
-	// ABAC: Filter by environment if user doesn't have full access
-	env := c.Query("environment")
+// NewProjectHandler creates a project handler. policies are evaluated after
+// the built-in ones; freezes block deployments during their windows unless
+// the user is a platform admin. Either may be nil.
+func NewProjectHandler(s store.Store, fga authz.Authorizer, policies []authz.Policy, freezes authz.FreezeSchedule) *ProjectHandler {
+	h := &ProjectHandler{store: s, fga: fga, policies: policies, freezes: freezes}
+	h.ResourceHandler = NewResourceHandler[*store.Project](projectResource{h})
+	return h
+}
 
-	var projects []*store.Project
-	if env != "" {
-		projects = h.store.ListProjectsByEnvironment(userCtx.WorkspaceID, env)
-	} else {
-		projects = h.store.ListProjects(userCtx.WorkspaceID)
-	}
+// projectResource is the ResourceType for projects: access follows the
+// attributes of the user, the project and the request (ABAC)
+type projectResource struct {
+	h *ProjectHandler
+}
 
-	// Enrich with permissions based on ABAC policies
-	type ProjectWithPermissions struct {
-		*store.Project
-		Permissions map[string]bool `json:"permissions"`
-	}
+func (projectResource) Kind() string   { return "project" }
+func (projectResource) Plural() string { return "projects" }
 
-	result := make([]ProjectWithPermissions, 0, len(projects))
-	for _, proj := range projects {
-		permissions := h.evaluateABACPolicies(userCtx, proj)
-		result = append(result, ProjectWithPermissions{
-			Project:     proj,
-			Permissions: permissions,
-		})
+// List returns the workspace's projects, optionally filtered by the
+// environment query parameter
+func (r projectResource) List(c *gin.Context, userCtx *store.UserContext) []*store.Project {
+	if env := c.Query("environment"); env != "" {
+		return r.h.store.ListProjectsByEnvironment(userCtx.WorkspaceID, env)
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"projects": result,
-		"total":    len(result),
-		"policies": h.getActivePolicies(),
-	})
+	return r.h.store.ListProjects(userCtx.WorkspaceID)
 }
 
-// Create creates a new project
-// POST /api/v1/projects
-func (h *ProjectHandler) Create(c *gin.Context) {
-	userCtx := middleware.GetUserContext(c)
+func (r projectResource) Load(id string) (*store.Project, error) { return r.h.store.GetProject(id) }
+func (r projectResource) Insert(proj *store.Project) error       { return r.h.store.CreateProject(proj) }
+func (r projectResource) Save(proj *store.Project) error         { return r.h.store.UpdateProject(proj) }
+func (r projectResource) Remove(id string) error                 { return r.h.store.DeleteProject(id) }
+func (projectResource) Version(proj *store.Project) int          { return proj.Version }
+func (projectResource) UpdatedAt(proj *store.Project) time.Time  { return proj.UpdatedAt }
 
+func (r projectResource) Decode(c *gin.Context, userCtx *store.UserContext) (*store.Project, bool) {
 	var req struct {
 		Name        string   `json:"name" binding:"required"`
 		Description string   `json:"description"`
 		Environment string   `json:"environment"` // production, staging, development
 		Tags        []string `json:"tags"`
+
+		// Attributes are custom key/values that policies can reference,
+		// e.g. {"data_classification": "restricted"}
+		Attributes map[string]string `json:"attributes"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
-		return
+		return nil, false
+	}
+
+	if !validAttributes(c, req.Attributes) {
+		return nil, false
 	}
 
 	if req.Environment == "" {
@@ -94,16 +105,16 @@ func (h *ProjectHandler) Create(c *gin.Context) {
 	}
 
 	// ABAC Policy: Only admins can create production projects
-	if req.Environment == "production" && !h.isAdmin(userCtx) {
+	if req.Environment == "production" && !r.h.isAdmin(userCtx) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error":   "policy_violation",
 			"message": "Only administrators can create production projects",
 			"policy":  "create_production_project",
 		})
-		return
+		return nil, false
 	}
 
-	proj := &store.Project{
+	return &store.Project{
 		ID:          uuid.New().String(),
 		Name:        req.Name,
 		Description: req.Description,
@@ -112,102 +123,59 @@ func (h *ProjectHandler) Create(c *gin.Context) {
 		Environment: req.Environment,
 		Status:      "active",
 		Tags:        req.Tags,
-	}
-
-	if err := h.store.CreateProject(proj); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create project"})
-		return
-	}
-
-	c.JSON(http.StatusCreated, gin.H{
-		"project":     proj,
-		"permissions": h.evaluateABACPolicies(userCtx, proj),
-	})
-}
-
-// Get returns a specific project
-// GET /api/v1/projects/:id
-func (h *ProjectHandler) Get(c *gin.Context) {
-	userCtx := middleware.GetUserContext(c)
-	projID := c.Param("id")
-
-	proj, err := h.store.GetProject(projID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
-		return
-	}
-
-	// Workspace isolation
-	if proj.WorkspaceID != userCtx.WorkspaceID && !userCtx.IsPlatformAdmin {
-		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
-		return
-	}
-
-	permissions := h.evaluateABACPolicies(userCtx, proj)
-
-	c.JSON(http.StatusOK, gin.H{
-		"project":     proj,
-		"permissions": permissions,
-	})
+		Attributes:  req.Attributes,
+	}, true
 }
 
-// Update updates a project
-// PUT /api/v1/projects/:id
-func (h *ProjectHandler) Update(c *gin.Context) {
-	userCtx := middleware.GetUserContext(c)
-	projID := c.Param("id")
-
-	proj, err := h.store.GetProject(projID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
-		return
-	}
-
-	permissions := h.evaluateABACPolicies(userCtx, proj)
-
-	// ABAC Policy: Check write permission
-	if !permissions["can_write"] {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":   "policy_violation",
-			"message": "You don't have permission to modify this project",
-			"reason":  h.getWriteDenialReason(userCtx, proj),
-		})
-		return
-	}
-
+func (r projectResource) Apply(c *gin.Context, userCtx *store.UserContext, proj *store.Project) bool {
 	var req struct {
 		Name        *string  `json:"name"`
 		Description *string  `json:"description"`
 		Environment *string  `json:"environment"`
 		Status      *string  `json:"status"`
 		Tags        []string `json:"tags"`
+
+		// Attributes, when present, replace the project's custom attributes
+		Attributes map[string]string `json:"attributes"`
+
+		// ExpectedVersion (or an If-Match header) rejects the update with
+		// 409 if the project changed since the client read it
+		ExpectedVersion *int `json:"expected_version"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
-		return
+		return false
+	}
+
+	if !checkVersion(c, req.ExpectedVersion, proj.Version) {
+		return false
+	}
+
+	if !validAttributes(c, req.Attributes) {
+		return false
 	}
 
 	// ABAC Policy: Environment change restrictions
 	if req.Environment != nil && *req.Environment != proj.Environment {
 		// Can't move to production without admin role
-		if *req.Environment == "production" && !h.isAdmin(userCtx) {
+		if *req.Environment == "production" && !r.h.isAdmin(userCtx) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error":   "policy_violation",
 				"message": "Only administrators can promote projects to production",
 				"policy":  "promote_to_production",
 			})
-			return
+			return false
 		}
 
 		// Can't demote production without admin role
-		if proj.Environment == "production" && !h.isAdmin(userCtx) {
+		if proj.Environment == "production" && !r.h.isAdmin(userCtx) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error":   "policy_violation",
 				"message": "Only administrators can modify production projects",
 				"policy":  "modify_production",
 			})
-			return
+			return false
 		}
 	}
 
@@ -226,42 +194,60 @@ func (h *ProjectHandler) Update(c *gin.Context) {
 	if req.Tags != nil {
 		proj.Tags = req.Tags
 	}
+	if req.Attributes != nil {
+		proj.Attributes = req.Attributes
+	}
+	return true
+}
 
-	proj.UpdatedAt = time.Now()
-	h.store.UpdateProject(proj)
+func (projectResource) Created(userCtx *store.UserContext, proj *store.Project) {}
 
-	c.JSON(http.StatusOK, gin.H{
-		"project":     proj,
-		"permissions": h.evaluateABACPolicies(userCtx, proj),
-	})
+func (r projectResource) Authorize(c *gin.Context, userCtx *store.UserContext, proj *store.Project, action string) *Denial {
+	switch action {
+	case "read":
+		// Workspace isolation
+		if proj.WorkspaceID != userCtx.WorkspaceID && !userCtx.IsPlatformAdmin {
+			return &Denial{Status: http.StatusNotFound, Body: gin.H{"error": "project not found"}}
+		}
+	case "update":
+		// ABAC Policy: Check write permission
+		if !r.h.evaluateABACPolicies(r.h.authzContext(c, userCtx, proj, action))["can_write"] {
+			return policyViolation("You don't have permission to modify this project", r.h.getWriteDenialReason(userCtx, proj))
+		}
+	case "delete":
+		// ABAC Policy: Check delete permission
+		if !r.h.evaluateABACPolicies(r.h.authzContext(c, userCtx, proj, action))["can_delete"] {
+			return policyViolation("You don't have permission to delete this project", r.h.getDeleteDenialReason(userCtx, proj))
+		}
+	}
+	return nil
 }
 
-// Delete deletes a project
-// DELETE /api/v1/projects/:id
-func (h *ProjectHandler) Delete(c *gin.Context) {
-	userCtx := middleware.GetUserContext(c)
-	projID := c.Param("id")
-
-	proj, err := h.store.GetProject(projID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
-		return
+// Permissions enriches listed projects with permissions based on ABAC
+// policies
+func (r projectResource) Permissions(c *gin.Context, userCtx *store.UserContext, projects []*store.Project) []map[string]bool {
+	permissions := make([]map[string]bool, len(projects))
+	for i, proj := range projects {
+		permissions[i] = r.h.evaluateABACPolicies(r.h.authzContext(c, userCtx, proj, "read"))
 	}
+	return permissions
+}
 
-	permissions := h.evaluateABACPolicies(userCtx, proj)
+func (r projectResource) Describe(c *gin.Context, userCtx *store.UserContext, proj *store.Project, action string) gin.H {
+	return gin.H{"permissions": r.h.evaluateABACPolicies(r.h.authzContext(c, userCtx, proj, action))}
+}
 
-	// ABAC Policy: Check delete permission
-	if !permissions["can_delete"] {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":   "policy_violation",
-			"message": "You don't have permission to delete this project",
-			"reason":  h.getDeleteDenialReason(userCtx, proj),
-		})
-		return
-	}
+func (r projectResource) ListMeta() gin.H {
+	return gin.H{"policies": r.h.getActivePolicies()}
+}
 
-	h.store.DeleteProject(projID)
-	c.JSON(http.StatusOK, gin.H{"message": "project deleted"})
+// policyViolation refuses a project action with 403 policy_violation
+func policyViolation(message, reason string) *Denial {
+	return &Denial{Status: http.StatusForbidden, Body: gin.H{
+		"error":   "policy_violation",
+		"message": message,
+		"reason":  reason,
+	}}
 }
 
 // Deploy triggers a deployment for the project
@@ -276,19 +262,30 @@ func (h *ProjectHandler) Deploy(c *gin.Context) {
 		return
 	}
 
-	permissions := h.evaluateABACPolicies(userCtx, proj)
+	actx := h.authzContext(c, userCtx, proj, "deploy")
+	permissions := h.evaluateABACPolicies(actx)
 
 	// ABAC Policy: Check deploy permission
 	if !permissions["can_deploy"] {
-		c.JSON(http.StatusForbidden, gin.H{
+		response := gin.H{
 			"error":   "policy_violation",
 			"message": "You don't have permission to deploy this project",
 			"reason":  h.getDeployDenialReason(userCtx, proj),
 			"policies": []string{
 				"Only admins can deploy to production",
 				"Project must be in 'active' status",
+				"No deployments during a freeze window",
 			},
-		})
+		}
+		if freeze := h.activeFreeze(userCtx, proj.Environment, actx.Environment.Time); freeze != nil {
+			response["freeze_window"] = gin.H{
+				"name":        freeze.Name,
+				"description": freeze.Description,
+				"environment": proj.Environment,
+				"ends_at":     freeze.EndsAt(actx.Environment.Time),
+			}
+		}
+		c.JSON(http.StatusForbidden, response)
 		return
 	}
 
@@ -302,14 +299,76 @@ func (h *ProjectHandler) Deploy(c *gin.Context) {
 	})
 }
 
+// projectActions maps the actions Can evaluates to the permission each
+// needs
+var projectActions = map[string]string{
+	"read":   "can_read",
+	"update": "can_write",
+	"delete": "can_delete",
+	"deploy": "can_deploy",
+}
+
+// Can evaluates whether the user may perform ?action= (read, update,
+// delete or deploy) on the project without performing it, so clients can
+// disable controls with the reason the action would be refused
+// POST /api/v1/projects/:id/can?action=deploy
+func (h *ProjectHandler) Can(c *gin.Context) {
+	userCtx := middleware.GetUserContext(c)
+	action := c.Query("action")
+
+	permission, ok := projectActions[action]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_action",
+			"message": "action must be read, update, delete or deploy",
+		})
+		return
+	}
+
+	proj, err := h.store.GetProject(c.Param("id"))
+	if err != nil || (proj.WorkspaceID != userCtx.WorkspaceID && !userCtx.IsPlatformAdmin) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
+
+	allowed := h.evaluateABACPolicies(h.authzContext(c, userCtx, proj, action))[permission]
+
+	var reason, policy string
+	if !allowed {
+		reason = h.denialReason(userCtx, proj, permission)
+		policy = h.denialPolicy(userCtx, proj, permission)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"action":  action,
+		"allowed": allowed,
+		"reason":  reason,
+		"policy":  policy,
+	})
+}
+
 // ABAC Policy Evaluation
 
-func (h *ProjectHandler) evaluateABACPolicies(userCtx *store.UserContext, proj *store.Project) map[string]bool {
+// authzContext builds the attribute set the ABAC policies evaluate
+func (h *ProjectHandler) authzContext(c *gin.Context, userCtx *store.UserContext, proj *store.Project, action string) *authz.AuthzContext {
+	return &authz.AuthzContext{
+		Subject: userCtx,
+		Object:  projectObject(proj),
+		Action:  action,
+		Environment: authz.Environment{
+			ClientIP: userCtx.ClientIP,
+			Time:     time.Now(),
+		},
+	}
+}
+
+func (h *ProjectHandler) evaluateABACPolicies(actx *authz.AuthzContext) map[string]bool {
+	userCtx := actx.Subject
 	isAdmin := h.isAdmin(userCtx)
-	isOwner := proj.OwnerID == userCtx.UserID
-	isProduction := proj.Environment == "production"
-	isArchived := proj.Status == "archived"
-	isPaused := proj.Status == "paused"
+	isOwner := actx.Object.Attr("owner_id") == userCtx.UserID
+	isProduction := actx.Object.Attr("environment") == "production"
+	isArchived := actx.Object.Attr("status") == "archived"
+	isPaused := actx.Object.Attr("status") == "paused"
 
 	// Base permissions for workspace members
 	canRead := true
@@ -348,6 +407,11 @@ func (h *ProjectHandler) evaluateABACPolicies(userCtx *store.UserContext, proj *
 		canDeploy = true
 	}
 
+	// Policy: No deployments during a freeze window of the environment
+	if h.activeFreeze(userCtx, actx.Object.Attr("environment"), actx.Environment.Time) != nil {
+		canDeploy = false
+	}
+
 	// Platform admins override all
 	if userCtx.IsPlatformAdmin {
 		canRead = true
@@ -356,21 +420,133 @@ func (h *ProjectHandler) evaluateABACPolicies(userCtx *store.UserContext, proj *
 		canDeploy = !isArchived
 	}
 
-	return map[string]bool{
+	permissions := map[string]bool{
 		"can_read":   canRead,
 		"can_write":  canWrite,
 		"can_delete": canDelete,
 		"can_deploy": canDeploy,
 	}
+
+	// Configured policies can only take permissions away
+	for _, p := range h.matchingPolicies(userCtx, actx.Object) {
+		for _, perm := range p.Deny {
+			if _, ok := permissions[perm]; ok {
+				permissions[perm] = false
+			}
+		}
+	}
+
+	return permissions
+}
+
+// projectObject exposes a project's built-in attributes, and its custom
+// ones under authz.CustomAttrPrefix, to policies
+func projectObject(proj *store.Project) authz.Object {
+	attrs := map[string]string{
+		"owner_id":    proj.OwnerID,
+		"environment": proj.Environment,
+		"status":      proj.Status,
+	}
+	for k, v := range proj.Attributes {
+		attrs[authz.CustomAttrPrefix+k] = v
+	}
+	return authz.Object{ObjectRef: authz.ProjectRef(proj.ID), Attributes: attrs}
+}
+
+// activeFreeze returns the freeze window blocking deployments to
+// environment at t, or nil. Platform admins are never frozen out.
+func (h *ProjectHandler) activeFreeze(userCtx *store.UserContext, environment string, t time.Time) *authz.FreezeWindow {
+	if userCtx.IsPlatformAdmin {
+		return nil
+	}
+	return h.freezes.Active(environment, t)
+}
+
+// matchingPolicies returns the configured policies that apply to the user
+// and object. Request attributes are added to the object's, so policies can
+// also match on where the request comes from.
+func (h *ProjectHandler) matchingPolicies(userCtx *store.UserContext, obj authz.Object) []authz.Policy {
+	if userCtx.ClientIP != "" {
+		attrs := make(map[string]string, len(obj.Attributes)+1)
+		for k, v := range obj.Attributes {
+			attrs[k] = v
+		}
+		attrs[authz.RequestAttrPrefix+"client_ip"] = userCtx.ClientIP
+		obj.Attributes = attrs
+	}
+
+	var matched []authz.Policy
+	for _, p := range h.policies {
+		if h.isAdmin(userCtx) && !p.AppliesToAdmins {
+			continue
+		}
+		if p.Matches(obj) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
 }
 
+// policyDenialReason returns the description of the first configured
+// policy denying the permission, or ""
+func (h *ProjectHandler) policyDenialReason(userCtx *store.UserContext, proj *store.Project, permission string) string {
+	for _, p := range h.matchingPolicies(userCtx, projectObject(proj)) {
+		if p.Denies(permission) {
+			if p.Description != "" {
+				return p.Description
+			}
+			return "Denied by policy " + p.Name
+		}
+	}
+	return ""
+}
+
+// maxAttributes bounds the custom attributes on one project
+const maxAttributes = 50
+
+// validAttributes responds 400 and returns false if attrs has empty keys
+// or too many entries
+func validAttributes(c *gin.Context, attrs map[string]string) bool {
+	if len(attrs) > maxAttributes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": "too many attributes"})
+		return false
+	}
+	for k := range attrs {
+		if k == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": "attribute names must not be empty"})
+			return false
+		}
+	}
+	return true
+}
+
+// isAdmin reports whether the user is a platform admin or an admin of their
+// workspace in OpenFGA. The workspace check runs once per request.
 func (h *ProjectHandler) isAdmin(userCtx *store.UserContext) bool {
-	// In real app, check workspace role from OpenFGA or database
-	// For demo, use platform admin flag
-	return userCtx.IsPlatformAdmin
+	if userCtx.IsPlatformAdmin {
+		return true
+	}
+	if h.fga == nil || userCtx.WorkspaceID == "" {
+		return false
+	}
+	return userCtx.WorkspaceAdmin(func() bool {
+		allowed, err := h.fga.Check(
+			authz.UserRef(userCtx.UserID).String(),
+			"admin",
+			authz.WorkspaceRef(userCtx.WorkspaceID).String(),
+		)
+		if err != nil {
+			log.Printf("Workspace admin check failed for %s on %s: %v", userCtx.UserID, userCtx.WorkspaceID, err)
+			return false
+		}
+		return allowed
+	})
 }
 
 func (h *ProjectHandler) getWriteDenialReason(userCtx *store.UserContext, proj *store.Project) string {
+	if reason := h.policyDenialReason(userCtx, proj, "can_write"); reason != "" {
+		return reason
+	}
 	if proj.Status == "archived" {
 		return "Archived projects cannot be modified"
 	}
@@ -381,6 +557,9 @@ func (h *ProjectHandler) getWriteDenialReason(userCtx *store.UserContext, proj *
 }
 
 func (h *ProjectHandler) getDeleteDenialReason(userCtx *store.UserContext, proj *store.Project) string {
+	if reason := h.policyDenialReason(userCtx, proj, "can_delete"); reason != "" {
+		return reason
+	}
 	if proj.Status == "archived" {
 		return "Archived projects cannot be deleted"
 	}
@@ -394,20 +573,70 @@ func (h *ProjectHandler) getDeleteDenialReason(userCtx *store.UserContext, proj
 }
 
 func (h *ProjectHandler) getDeployDenialReason(userCtx *store.UserContext, proj *store.Project) string {
+	if reason := h.policyDenialReason(userCtx, proj, "can_deploy"); reason != "" {
+		return reason
+	}
 	if proj.Status == "archived" {
 		return "Archived projects cannot be deployed"
 	}
 	if proj.Status == "paused" {
 		return "Paused projects cannot be deployed"
 	}
+	if freeze := h.activeFreeze(userCtx, proj.Environment, time.Now()); freeze != nil {
+		if freeze.Description != "" {
+			return freeze.Description
+		}
+		return "Deployments to " + proj.Environment + " are frozen (" + freeze.Name + ")"
+	}
 	if proj.Environment == "production" && !h.isAdmin(userCtx) {
 		return "Only administrators can deploy to production"
 	}
 	return "Insufficient permissions"
 }
 
+// denialReason explains why permission is denied, using the reason helper
+// of the operation that needs it
+func (h *ProjectHandler) denialReason(userCtx *store.UserContext, proj *store.Project, permission string) string {
+	switch permission {
+	case "can_write":
+		return h.getWriteDenialReason(userCtx, proj)
+	case "can_delete":
+		return h.getDeleteDenialReason(userCtx, proj)
+	case "can_deploy":
+		return h.getDeployDenialReason(userCtx, proj)
+	}
+	if reason := h.policyDenialReason(userCtx, proj, permission); reason != "" {
+		return reason
+	}
+	return "Insufficient permissions"
+}
+
+// denialPolicy names the policy behind denialReason: a configured policy or
+// one of the built-in ones listed by getActivePolicies
+func (h *ProjectHandler) denialPolicy(userCtx *store.UserContext, proj *store.Project, permission string) string {
+	for _, p := range h.matchingPolicies(userCtx, projectObject(proj)) {
+		if p.Denies(permission) {
+			return p.Name
+		}
+	}
+	freeze := h.activeFreeze(userCtx, proj.Environment, time.Now())
+	switch {
+	case proj.Status == "archived" && permission != "can_read":
+		return "archived_read_only"
+	case proj.Status == "paused" && permission == "can_deploy":
+		return "paused_no_deploy"
+	case permission == "can_deploy" && freeze != nil:
+		return freeze.Name
+	case proj.Environment == "production" && !h.isAdmin(userCtx):
+		return "production_admin_only"
+	case permission == "can_delete":
+		return "owner_can_delete"
+	}
+	return ""
+}
+
 func (h *ProjectHandler) getActivePolicies() []map[string]string {
-	return []map[string]string{
+	policies := []map[string]string{
 		{
 			"name":        "production_admin_only",
 			"description": "Only administrators can modify production projects",
@@ -425,4 +654,25 @@ func (h *ProjectHandler) getActivePolicies() []map[string]string {
 			"description": "Project owners can delete non-production projects",
 		},
 	}
+	for _, p := range h.policies {
+		policies = append(policies, map[string]string{
+			"name":        p.Name,
+			"description": p.Description,
+		})
+	}
+	envs := make([]string, 0, len(h.freezes))
+	for env := range h.freezes {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	for _, env := range envs {
+		for _, w := range h.freezes[env] {
+			policies = append(policies, map[string]string{
+				"name":        w.Name,
+				"description": w.Description,
+				"environment": env,
+			})
+		}
+	}
+	return policies
 }
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/sample-api/internal/authz"
+	"github.com/yourusername/sample-api/internal/store"
+)
+
+func TestProjectAttributePolicies(t *testing.T) {
+	policies, err := authz.LoadPolicies("../../policies.example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewProjectHandler(nil, nil, policies, nil)
+
+	member := &store.UserContext{UserID: "member"}
+	admin := &store.UserContext{UserID: "admin", IsPlatformAdmin: true}
+
+	tests := []struct {
+		name       string
+		user       *store.UserContext
+		attributes map[string]string
+		want       map[string]bool
+		wantReason string // policy denying can_write, else can_deploy
+	}{
+		{
+			name: "no attributes",
+			user: member,
+			want: map[string]bool{"can_read": true, "can_write": true, "can_delete": false, "can_deploy": true},
+		},
+		{
+			name:       "restricted data, member",
+			user:       member,
+			attributes: map[string]string{"data_classification": "restricted"},
+			want:       map[string]bool{"can_read": true, "can_write": false, "can_delete": false, "can_deploy": false},
+			wantReason: "Projects holding restricted data can only be changed or deployed by admins",
+		},
+		{
+			name:       "restricted data, admin is exempt",
+			user:       admin,
+			attributes: map[string]string{"data_classification": "restricted"},
+			want:       map[string]bool{"can_read": true, "can_write": true, "can_delete": true, "can_deploy": true},
+		},
+		{
+			name:       "change freeze applies to admins",
+			user:       admin,
+			attributes: map[string]string{"change_freeze": "true"},
+			want:       map[string]bool{"can_read": true, "can_write": true, "can_delete": true, "can_deploy": false},
+			wantReason: "Frozen projects cannot be deployed, even by admins",
+		},
+		{
+			name:       "custom attributes can't shadow built-ins",
+			user:       member,
+			attributes: map[string]string{"environment": "development", "owner_id": "member"},
+			want:       map[string]bool{"can_read": true, "can_write": true, "can_delete": false, "can_deploy": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proj := &store.Project{ID: "p-1", OwnerID: "owner", Environment: "staging", Status: "active", Attributes: tt.attributes}
+			actx := &authz.AuthzContext{Subject: tt.user, Object: projectObject(proj)}
+
+			got := h.evaluateABACPolicies(actx)
+			for perm, want := range tt.want {
+				if got[perm] != want {
+					t.Errorf("%s = %v, want %v", perm, got[perm], want)
+				}
+			}
+
+			reason := h.policyDenialReason(tt.user, proj, "can_write")
+			if reason == "" {
+				reason = h.policyDenialReason(tt.user, proj, "can_deploy")
+			}
+			if reason != tt.wantReason {
+				t.Errorf("denial reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestValidAttributes(t *testing.T) {
+	tooMany := map[string]string{}
+	for i := 0; i <= maxAttributes; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+
+	tests := []struct {
+		name  string
+		attrs map[string]string
+		want  bool
+	}{
+		{"none", nil, true},
+		{"some", map[string]string{"data_classification": "restricted"}, true},
+		{"empty name", map[string]string{"": "x"}, false},
+		{"too many", tooMany, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			if got := validAttributes(c, tt.attrs); got != tt.want {
+				t.Errorf("validAttributes() = %v, want %v", got, tt.want)
+			}
+			if !tt.want && w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/sample-api/internal/store"
+)
+
+// quotaExceeded responds 403 with the limit if err is a workspace quota
+// error, and reports whether it did
+func quotaExceeded(c *gin.Context, err error) bool {
+	var quota *store.QuotaError
+	if !errors.As(err, &quota) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":   "quota_exceeded",
+		"message": fmt.Sprintf("this workspace has reached its limit of %d %s", quota.Limit, quota.Resource),
+		"limit":   quota.Limit,
+	})
+	return true
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/sample-api/internal/authz"
+	"github.com/yourusername/sample-api/internal/store"
+)
+
+// reconciledRelations are the document relations the store is the source of
+// truth for; tuples with any other relation are left alone
+var reconciledRelations = map[string]bool{
+	"workspace": true,
+	"owner":     true,
+	"editor":    true,
+	"viewer":    true,
+}
+
+// ReconcileOptions control what Reconcile changes. The zero value is a dry
+// run that only reports drift.
+type ReconcileOptions struct {
+	Apply         bool // write missing tuples
+	DeleteOrphans bool // with Apply, also delete tuples the store has no share for
+}
+
+// ReconcileReport is the drift Reconcile found and what it repaired
+type ReconcileReport struct {
+	DryRun    bool          `json:"dry_run"`
+	Documents int           `json:"documents"`
+	Missing   []authz.Tuple `json:"missing"`
+	Orphaned  []authz.Tuple `json:"orphaned"`
+	Written   int           `json:"written"`
+	Deleted   int           `json:"deleted"`
+	Errors    []string      `json:"errors,omitempty"`
+}
+
+// Reconcile compares every document's workspace and shares (trashed
+// documents included, as they keep their tuples until purged) with the
+// tuples in OpenFGA. Tuples the store implies but OpenFGA lacks are
+// missing; tuples OpenFGA has that the store doesn't are orphaned. With
+// opts.Apply each document is repaired in one OpenFGA write. A document
+// that can't be read or repaired is reported in Errors and skipped.
+func (h *DocumentHandler) Reconcile(ctx context.Context, opts ReconcileOptions) (*ReconcileReport, error) {
+	report := &ReconcileReport{
+		DryRun:   !opts.Apply,
+		Missing:  []authz.Tuple{},
+		Orphaned: []authz.Tuple{},
+	}
+
+	docs := append(h.store.GetAllDocuments(), h.store.ListDeletedDocuments()...)
+	sort.Slice(docs, func(i, j int) bool { return docs[i].ID < docs[j].ID })
+
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.Documents++
+
+		object := authz.DocumentRef(doc.ID).String()
+		actual, err := h.fga.ReadTuples(object)
+		if err != nil {
+			report.Errors = append(report.Errors, doc.ID+": "+err.Error())
+			continue
+		}
+
+		expected := h.expectedTuples(doc)
+		have := make(map[authz.Tuple]bool, len(actual))
+		for _, t := range actual {
+			have[t] = true
+		}
+
+		var missing, orphaned []authz.Tuple
+		for _, t := range expected {
+			if !have[t] {
+				missing = append(missing, t)
+			}
+		}
+		want := make(map[authz.Tuple]bool, len(expected))
+		for _, t := range expected {
+			want[t] = true
+		}
+		for _, t := range actual {
+			if reconciledRelations[t.Relation] && !want[t] {
+				orphaned = append(orphaned, t)
+			}
+		}
+
+		report.Missing = append(report.Missing, missing...)
+		report.Orphaned = append(report.Orphaned, orphaned...)
+
+		if !opts.Apply {
+			continue
+		}
+		var deletes []authz.Tuple
+		if opts.DeleteOrphans {
+			deletes = orphaned
+		}
+		if len(missing) == 0 && len(deletes) == 0 {
+			continue
+		}
+		if err := h.fga.UpdateTuples(missing, deletes); err != nil {
+			report.Errors = append(report.Errors, doc.ID+": "+err.Error())
+			continue
+		}
+		report.Written += len(missing)
+		report.Deleted += len(deletes)
+	}
+
+	return report, nil
+}
+
+// expectedTuples are the tuples the store implies for a document: its
+// workspace and a tuple per active share, the owner's included
+func (h *DocumentHandler) expectedTuples(doc *store.Document) []authz.Tuple {
+	object := authz.DocumentRef(doc.ID).String()
+
+	var tuples []authz.Tuple
+	if doc.WorkspaceID != "" {
+		tuples = append(tuples, authz.Tuple{User: authz.WorkspaceRef(doc.WorkspaceID).String(), Relation: "workspace", Object: object})
+	}
+	for _, share := range h.store.GetDocumentShares(doc.ID) {
+		tuples = append(tuples, authz.Tuple{User: authz.UserRef(share.UserID).String(), Relation: share.Role, Object: object})
+	}
+	return tuples
+}
+
+// ReconcileAuthz reports drift between the store and OpenFGA. It is a dry
+// run unless ?dry_run=false; add ?delete_orphans=true to also remove tuples
+// the store has no record of.
+// POST /api/v1/admin/reconcile-authz
+func (h *DocumentHandler) ReconcileAuthz(c *gin.Context) {
+	if h.fga == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "OpenFGA is not configured"})
+		return
+	}
+
+	opts := ReconcileOptions{
+		Apply:         c.Query("dry_run") == "false",
+		DeleteOrphans: c.Query("delete_orphans") == "true",
+	}
+
+	report, err := h.Reconcile(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reconciliation interrupted", "report": report})
+		return
+	}
+
+	if opts.Apply && (report.Written > 0 || report.Deleted > 0) {
+		log.Printf("Authz reconcile: wrote %d and deleted %d tuple(s)", report.Written, report.Deleted)
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// StartAuthzReconciler periodically writes tuples missing from OpenFGA.
+// Orphaned tuples are only reported, since deleting them could revoke access
+// granted outside this service. Runs until the process exits.
+func (h *DocumentHandler) StartAuthzReconciler(interval time.Duration) {
+	if interval <= 0 || h.fga == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			report, err := h.Reconcile(context.Background(), ReconcileOptions{Apply: true})
+			if err != nil {
+				log.Printf("Authz reconciler: %v", err)
+				continue
+			}
+			if report.Written > 0 || len(report.Orphaned) > 0 || len(report.Errors) > 0 {
+				log.Printf("Authz reconciler: wrote %d missing tuple(s), %d orphaned, %d error(s)",
+					report.Written, len(report.Orphaned), len(report.Errors))
+			}
+		}
+	}()
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/sample-api/internal/middleware"
+	"github.com/yourusername/sample-api/internal/store"
+)
+
+// ResourceType plugs one kind of workspace resource into ResourceHandler:
+// how it is stored, how requests create and change it, and how access to it
+// is evaluated. Documents (ReBAC) and projects (ABAC) are the built-in
+// types; a new resource type only needs its own ResourceType to get list,
+// get, create, update and delete endpoints.
+//
+// Actions passed to Authorize and Describe are "read", "create", "update"
+// and "delete".
+type ResourceType[T any] interface {
+	// Kind names one resource in response keys and messages ("document");
+	// Plural names the list in list responses ("documents")
+	Kind() string
+	Plural() string
+
+	// Store access
+	List(c *gin.Context, userCtx *store.UserContext) []T
+	Load(id string) (T, error)
+	Insert(item T) error
+	Save(item T) error
+	Remove(id string) error
+	Version(item T) int
+	UpdatedAt(item T) time.Time
+
+	// Decode builds a new resource from a create request. On a bad request
+	// or a policy violation it responds and returns false.
+	Decode(c *gin.Context, userCtx *store.UserContext) (T, bool)
+
+	// Apply changes item from an update request, including its optimistic
+	// concurrency check. On a bad request or a policy violation it responds
+	// and returns false.
+	Apply(c *gin.Context, userCtx *store.UserContext, item T) bool
+
+	// Created runs once a new resource is stored, e.g. to write its
+	// relationship tuples
+	Created(userCtx *store.UserContext, item T)
+
+	// Authorize returns nil if the user may perform action on item, or the
+	// response refusing it
+	Authorize(c *gin.Context, userCtx *store.UserContext, item T, action string) *Denial
+
+	// Permissions returns the user's permissions on each listed item, in
+	// order
+	Permissions(c *gin.Context, userCtx *store.UserContext, items []T) []map[string]bool
+
+	// Describe returns the fields sent next to item in responses to action,
+	// such as the user's permissions; ListMeta those sent with a list
+	Describe(c *gin.Context, userCtx *store.UserContext, item T, action string) gin.H
+	ListMeta() gin.H
+}
+
+// Denial is a refused action: the status and body to respond with
+type Denial struct {
+	Status int
+	Body   gin.H
+}
+
+// ResourceHandler serves the CRUD endpoints of one resource type. The
+// request flow (load, authorize, bind, store, respond) is shared; what
+// differs per type is delegated to its ResourceType.
+type ResourceHandler[T any] struct {
+	resource ResourceType[T]
+}
+
+// NewResourceHandler creates a handler for a resource type
+func NewResourceHandler[T any](resource ResourceType[T]) *ResourceHandler[T] {
+	return &ResourceHandler[T]{resource: resource}
+}
+
+// List returns the resources the user can see, with their permissions
+// GET /api/v1/{resources}
+func (h *ResourceHandler[T]) List(c *gin.Context) {
+	userCtx := middleware.GetUserContext(c)
+
+	items := h.resource.List(c, userCtx)
+	permissions := h.resource.Permissions(c, userCtx, items)
+
+	result := make([]withPermissions[T], 0, len(items))
+	for i, item := range items {
+		result = append(result, withPermissions[T]{item: item, permissions: permissions[i]})
+	}
+
+	response := gin.H{
+		h.resource.Plural(): result,
+		"total":             len(result),
+	}
+	for k, v := range h.resource.ListMeta() {
+		response[k] = v
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Create creates a resource
+// POST /api/v1/{resources}
+func (h *ResourceHandler[T]) Create(c *gin.Context) {
+	userCtx := middleware.GetUserContext(c)
+
+	item, ok := h.resource.Decode(c, userCtx)
+	if !ok {
+		return
+	}
+
+	if err := h.resource.Insert(item); err != nil {
+		if quotaExceeded(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create " + h.resource.Kind()})
+		return
+	}
+
+	h.resource.Created(userCtx, item)
+
+	c.JSON(http.StatusCreated, h.response(c, userCtx, item, "create"))
+}
+
+// Get returns a resource. It carries ETag and Last-Modified headers and
+// answers 304 Not Modified to If-None-Match or If-Modified-Since when the
+// client's copy is current.
+// GET /api/v1/{resources}/:id
+func (h *ResourceHandler[T]) Get(c *gin.Context) {
+	userCtx := middleware.GetUserContext(c)
+
+	item, ok := h.load(c, userCtx, "read")
+	if !ok {
+		return
+	}
+
+	body, err := json.Marshal(h.response(c, userCtx, item, "read"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode " + h.resource.Kind()})
+		return
+	}
+
+	if notModified(c, contentETag(h.resource.Version(item), body), h.resource.UpdatedAt(item)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// Update updates a resource
+// PUT /api/v1/{resources}/:id
+func (h *ResourceHandler[T]) Update(c *gin.Context) {
+	userCtx := middleware.GetUserContext(c)
+	id := c.Param("id")
+
+	item, ok := h.load(c, userCtx, "update")
+	if !ok {
+		return
+	}
+
+	if !h.resource.Apply(c, userCtx, item) {
+		return
+	}
+
+	if err := h.resource.Save(item); err != nil {
+		if errors.Is(err, store.ErrVersionConflict) {
+			if current, err := h.resource.Load(id); err == nil {
+				versionConflict(c, h.resource.Version(current))
+				return
+			}
+		}
+		h.notFound(c)
+		return
+	}
+
+	c.Header("ETag", versionETag(h.resource.Version(item)))
+	c.JSON(http.StatusOK, h.response(c, userCtx, item, "update"))
+}
+
+// Delete deletes a resource
+// DELETE /api/v1/{resources}/:id
+func (h *ResourceHandler[T]) Delete(c *gin.Context) {
+	userCtx := middleware.GetUserContext(c)
+
+	if _, ok := h.load(c, userCtx, "delete"); !ok {
+		return
+	}
+
+	h.resource.Remove(c.Param("id"))
+	c.JSON(http.StatusOK, gin.H{"message": h.resource.Kind() + " deleted"})
+}
+
+// load fetches the resource named by the :id parameter and authorizes
+// action on it, responding and returning false if either fails
+func (h *ResourceHandler[T]) load(c *gin.Context, userCtx *store.UserContext, action string) (T, bool) {
+	item, err := h.resource.Load(c.Param("id"))
+	if err != nil {
+		h.notFound(c)
+		return item, false
+	}
+
+	if denial := h.resource.Authorize(c, userCtx, item, action); denial != nil {
+		c.JSON(denial.Status, denial.Body)
+		return item, false
+	}
+	return item, true
+}
+
+func (h *ResourceHandler[T]) notFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{"error": h.resource.Kind() + " not found"})
+}
+
+// response is item under its kind plus the fields Describe adds
+func (h *ResourceHandler[T]) response(c *gin.Context, userCtx *store.UserContext, item T, action string) gin.H {
+	response := gin.H{h.resource.Kind(): item}
+	for k, v := range h.resource.Describe(c, userCtx, item, action) {
+		response[k] = v
+	}
+	return response
+}
+
+// withPermissions is a listed resource with the user's permissions added
+// to its JSON object
+type withPermissions[T any] struct {
+	item        T
+	permissions map[string]bool
+}
+
+func (w withPermissions[T]) MarshalJSON() ([]byte, error) {
+	item, err := json.Marshal(w.item)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := json.Marshal(w.permissions)
+	if err != nil {
+		return nil, err
+	}
+	if len(item) < 2 || item[0] != '{' {
+		return nil, errors.New("resource must marshal to a JSON object")
+	}
+
+	out := append([]byte(nil), item[:len(item)-1]...)
+	if len(item) > 2 {
+		out = append(out, ',')
+	}
+	out = append(out, `"permissions":`...)
+	out = append(out, permissions...)
+	return append(out, '}'), nil
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/yourusername/sample-api/internal/authz"
+)
+
+// StartShareJanitor periodically removes expired temporary shares and their
+// OpenFGA tuples. Runs until the process exits.
+func (h *DocumentHandler) StartShareJanitor(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if n := h.expireShares(""); n > 0 {
+				log.Printf("Share janitor: removed %d expired share(s)", n)
+			}
+		}
+	}()
+}
+
+// expireShares removes lapsed shares for a document (or all documents when
+// docID is empty) and revokes the matching OpenFGA relationships
+func (h *DocumentHandler) expireShares(docID string) int {
+	expired := h.store.RemoveExpiredShares(docID, time.Now())
+
+	if h.fga != nil {
+		for _, share := range expired {
+			if err := h.fga.DeleteTuple(
+				authz.UserRef(share.UserID).String(),
+				share.Role,
+				authz.DocumentRef(share.DocumentID).String(),
+			); err != nil {
+				log.Printf("Warning: Failed to delete expired share tuple for document %s: %v", share.DocumentID, err)
+			}
+		}
+	}
+
+	return len(expired)
+}
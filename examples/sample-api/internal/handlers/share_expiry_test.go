@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/sample-api/internal/authz"
+	"github.com/yourusername/sample-api/internal/store"
+	"github.com/yourusername/sample-api/internal/testutil"
+)
+
+func TestExpireShares(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		docID       string
+		wantRemoved int
+		wantTuples  map[string]bool // user -> whether its viewer tuple on doc-1 remains
+	}{
+		{"one document", "doc-1", 1, map[string]bool{"bob": false, "carol": true}},
+		{"other document", "doc-2", 0, map[string]bool{"bob": true, "carol": true}},
+		{"all documents", "", 1, map[string]bool{"bob": false, "carol": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := store.NewMemoryStore()
+			fga := testutil.NewMemoryFGA(nil)
+			h := NewDocumentHandler(s, fga)
+
+			for _, id := range []string{"doc-1", "doc-2"} {
+				if err := s.CreateDocument(&store.Document{ID: id, WorkspaceID: "ws-1", OwnerID: "alice"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, share := range []store.DocumentShare{
+				{DocumentID: "doc-1", UserID: "bob", Role: "viewer", ExpiresAt: &past},
+				{DocumentID: "doc-1", UserID: "carol", Role: "viewer", ExpiresAt: &future},
+			} {
+				if err := s.AddDocumentShare(share); err != nil {
+					t.Fatal(err)
+				}
+				if err := fga.WriteTuple(authz.UserRef(share.UserID).String(), share.Role, authz.DocumentRef(share.DocumentID).String()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if n := h.expireShares(tt.docID); n != tt.wantRemoved {
+				t.Errorf("expireShares() = %d, want %d", n, tt.wantRemoved)
+			}
+			for user, want := range tt.wantTuples {
+				got, err := fga.Check(authz.UserRef(user).String(), "viewer", authz.DocumentRef("doc-1").String())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("%s viewer tuple present = %v, want %v", user, got, want)
+				}
+			}
+		})
+	}
+}
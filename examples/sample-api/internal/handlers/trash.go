@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/yourusername/sample-api/internal/authz"
+)
+
+// trashPurgeInterval is how often the trash janitor looks for items past
+// their retention
+const trashPurgeInterval = time.Hour
+
+// StartTrashJanitor periodically removes documents and projects trashed more
+// than retention ago, along with the purged documents' OpenFGA tuples. Runs
+// until the process exits.
+func (h *DocumentHandler) StartTrashJanitor(retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if docs, projects := h.purgeTrash(retention); docs > 0 || projects > 0 {
+				log.Printf("Trash janitor: purged %d document(s) and %d project(s)", docs, projects)
+			}
+		}
+	}()
+}
+
+// purgeTrash permanently deletes expired trash and revokes the OpenFGA
+// relationships of purged documents
+func (h *DocumentHandler) purgeTrash(retention time.Duration) (int, int) {
+	docs, projects := h.store.PurgeDeleted(retention)
+
+	if h.fga != nil {
+		for _, purged := range docs {
+			object := authz.DocumentRef(purged.Document.ID).String()
+			if err := h.fga.DeleteTuple(authz.WorkspaceRef(purged.Document.WorkspaceID).String(), "workspace", object); err != nil {
+				log.Printf("Warning: Failed to delete workspace tuple for purged document %s: %v", purged.Document.ID, err)
+			}
+			for _, share := range purged.Shares {
+				if err := h.fga.DeleteTuple(authz.UserRef(share.UserID).String(), share.Role, object); err != nil {
+					log.Printf("Warning: Failed to delete share tuple for purged document %s: %v", purged.Document.ID, err)
+				}
+			}
+		}
+	}
+
+	return len(docs), projects
+}
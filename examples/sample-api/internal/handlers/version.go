@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// versionETag formats a resource version as an ETag
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// contentETag is the ETag of a GET response: the version and a hash of the
+// body. It changes on every edit, and also when the shares or permissions
+// in the body change without a new version. If-Match accepts it like a
+// bare version.
+func contentETag(version int, body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + strconv.Itoa(version) + "-" + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// notModified sets the ETag and Last-Modified headers of a GET response and
+// reports whether the client's copy is current, per If-None-Match or, when
+// that is absent, If-Modified-Since. The caller responds 304 if so.
+func notModified(c *gin.Context, etag string, modified time.Time) bool {
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := c.GetHeader("If-Modified-Since"); ifModifiedSince != "" {
+		since, err := http.ParseTime(ifModifiedSince)
+		// Last-Modified has one-second resolution
+		return err == nil && !modified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// expectedVersion returns the version the client based its update on, from
+// the If-Match header or the expected_version body field (If-Match wins).
+// If-Match takes a version ETag ("3") or a content ETag ("3-<hash>"), whose
+// hash is ignored. ok is false when neither was sent; err is set for a
+// malformed If-Match.
+func expectedVersion(c *gin.Context, bodyVersion *int) (version int, ok bool, err error) {
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		tag, _, _ := strings.Cut(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), "-")
+		v, err := strconv.Atoi(tag)
+		if err != nil {
+			return 0, false, err
+		}
+		return v, true, nil
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, true, nil
+	}
+	return 0, false, nil
+}
+
+// checkVersion responds 400/409 and returns false if the request's expected
+// version is malformed or differs from current
+func checkVersion(c *gin.Context, bodyVersion *int, current int) bool {
+	expected, ok, err := expectedVersion(c, bodyVersion)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_if_match",
+			"message": "If-Match must be a version number",
+		})
+		return false
+	}
+	if ok && expected != current {
+		versionConflict(c, current)
+		return false
+	}
+	return true
+}
+
+// versionConflict responds 409 with the current version so the client can
+// reload and retry
+func versionConflict(c *gin.Context, current int) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":           "version_conflict",
+		"message":         "The resource was modified by someone else; reload and try again",
+		"current_version": current,
+	})
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"log"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/sample-api/internal/casdoor"
@@ -13,8 +14,13 @@ const (
 	CasdoorClaimsKey  = "casdoor_claims"
 )
 
-// CasdoorAuth validates Casdoor JWT tokens
-func CasdoorAuth(client *casdoor.Client) gin.HandlerFunc {
+// CasdoorAuth validates Casdoor JWT tokens.
+//
+// A missing token is rejected with 401 missing_token, and an invalid one
+// with 401 unauthorized; a valid token lacking a role is rejected later with
+// 403 by RequirePlatformAdmin or the handlers. Only when allowAnonymous is
+// set (development) does a request without a token proceed as "anonymous".
+func CasdoorAuth(client *casdoor.Client, allowAnonymous bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -23,6 +29,20 @@ func CasdoorAuth(client *casdoor.Client) gin.HandlerFunc {
 			authHeader = c.Query("token")
 		}
 
+		// "Bearer " with nothing after it is as good as no token
+		if token := strings.TrimSpace(authHeader); token == "" || strings.EqualFold(token, "bearer") {
+			authHeader = ""
+		}
+
+		if authHeader == "" && !allowAnonymous {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(401, gin.H{
+				"error":   "missing_token",
+				"message": "authentication required",
+			})
+			return
+		}
+
 		var userCtx store.UserContext
 
 		if authHeader != "" {
@@ -53,14 +73,14 @@ func CasdoorAuth(client *casdoor.Client) gin.HandlerFunc {
 				userCtx.WorkspaceID = c.Query("workspace_id")
 			}
 		} else {
-			// No auth header - use development defaults if DEV_MODE is enabled
-			// In production, you would reject the request here
+			// No auth header, allowed in development only
 			userCtx = store.UserContext{
 				UserID:      "anonymous",
 				WorkspaceID: c.Query("workspace_id"),
 			}
 		}
 
+		userCtx.ClientIP = GetClientIP(c)
 		c.Set(UserContextKey, &userCtx)
 		c.Next()
 	}
@@ -93,6 +113,7 @@ func ExtractAuthHeaders() gin.HandlerFunc {
 			userCtx.WorkspaceID = "workspace-1"
 		}
 
+		userCtx.ClientIP = GetClientIP(c)
 		c.Set(UserContextKey, &userCtx)
 		c.Next()
 	}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIPKey holds the resolved client IP in the gin context
+const ClientIPKey = "client_ip"
+
+// ClientIPResolver finds the address of the client behind reverse proxies.
+// X-Forwarded-For and X-Real-IP are only believed when the connection comes
+// from a trusted proxy; otherwise anyone could claim any address.
+type ClientIPResolver struct {
+	trusted []*net.IPNet
+}
+
+// NewClientIPResolver creates a resolver trusting proxies in the given
+// CIDRs (bare IPs are treated as /32 or /128). With none, forwarding
+// headers are ignored and the peer address is the client IP.
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	r := &ClientIPResolver{}
+	for _, cidr := range trustedProxies {
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		r.trusted = append(r.trusted, network)
+	}
+	return r, nil
+}
+
+// Resolve returns the client IP of req. X-Forwarded-For is read from the
+// right, skipping trusted proxies, so entries a client prepends itself are
+// never reached; X-Real-IP is used when X-Forwarded-For is absent.
+func (r *ClientIPResolver) Resolve(req *http.Request) string {
+	peer := remoteIP(req.RemoteAddr)
+	if peer == nil {
+		return ""
+	}
+	if !r.isTrusted(peer) {
+		return peer.String()
+	}
+
+	if xff := req.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				// A malformed hop can't be attributed; fall back to the peer
+				break
+			}
+			if !r.isTrusted(ip) || i == 0 {
+				return ip.String()
+			}
+		}
+		return peer.String()
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(req.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return peer.String()
+}
+
+func (r *ClientIPResolver) isTrusted(ip net.IP) bool {
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteIP(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// ClientIP resolves the client IP once per request and stores it under
+// ClientIPKey. Register it before the auth middleware, which copies it to
+// the UserContext for ABAC policies.
+func ClientIP(resolver *ClientIPResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ClientIPKey, resolver.Resolve(c.Request))
+		c.Next()
+	}
+}
+
+// GetClientIP returns the IP resolved by ClientIP, or the peer address if
+// the middleware didn't run
+func GetClientIP(c *gin.Context) string {
+	if ip := c.GetString(ClientIPKey); ip != "" {
+		return ip
+	}
+	if ip := remoteIP(c.Request.RemoteAddr); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"regexp"
+	"strings"
+)
+
+// OriginMatcher decides whether a request Origin is allowed. Origins are
+// matched exactly, except those with a "*" in the host, which stands for one
+// or more subdomain labels: https://*.example.com allows
+// https://app.example.com and https://a.b.example.com, but not
+// https://example.com.
+type OriginMatcher struct {
+	exact    map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// NewOriginMatcher compiles the allowed origins
+func NewOriginMatcher(origins []string) *OriginMatcher {
+	m := &OriginMatcher{exact: make(map[string]bool)}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		if !strings.Contains(origin, "*") {
+			m.exact[origin] = true
+			continue
+		}
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(origin), `\*`, `[a-z0-9-]+(?:\.[a-z0-9-]+)*`)
+		m.patterns = append(m.patterns, regexp.MustCompile("^"+pattern+"$"))
+	}
+	return m
+}
+
+// Allowed reports whether origin may make cross-origin requests
+func (m *OriginMatcher) Allowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if m.exact[origin] {
+		return true
+	}
+	for _, pattern := range m.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/sample-api/internal/casdoor"
+	"github.com/yourusername/sample-api/internal/store"
+)
+
+// ProvisionUser keeps a local User record for everyone who authenticates,
+// so admin listings and stats show real users. The profile comes from the
+// Casdoor claims in direct mode, or X-User-ID and X-User-Email from the
+// authz service in gateway mode. Requests whose user came from neither
+// (anonymous or demo defaults) are not provisioned. Register it after
+// CasdoorAuth or ExtractAuthHeaders.
+//
+// Provisioning failures are logged and the request continues; the local
+// record doesn't take part in authorization.
+func ProvisionUser(s store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userCtx := GetUserContext(c)
+		if user := provisionedUser(c, userCtx); user != nil {
+			if _, err := s.ProvisionUser(user); err != nil {
+				log.Printf("Failed to provision user %s: %v", user.ID, err)
+			}
+		}
+		c.Next()
+	}
+}
+
+// provisionedUser returns the authenticated user's profile, or nil if the
+// request has no authenticated identity
+func provisionedUser(c *gin.Context, userCtx *store.UserContext) *store.User {
+	if userCtx == nil || userCtx.UserID == "" {
+		return nil
+	}
+
+	if value, ok := c.Get(CasdoorClaimsKey); ok {
+		claims := value.(*casdoor.CasdoorClaims)
+		return &store.User{
+			ID:              userCtx.UserID,
+			Email:           claims.Email,
+			Name:            claims.DisplayName,
+			Picture:         claims.Avatar,
+			IsPlatformAdmin: userCtx.IsPlatformAdmin,
+		}
+	}
+
+	// Gateway mode; the demo defaults for requests without headers aren't
+	// real users
+	if c.GetHeader("X-User-ID") != userCtx.UserID {
+		return nil
+	}
+	return &store.User{
+		ID:              userCtx.UserID,
+		Email:           c.GetHeader("X-User-Email"),
+		IsPlatformAdmin: userCtx.IsPlatformAdmin,
+	}
+}
@@ -0,0 +1,237 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore is a MemoryStore that writes a JSON snapshot to disk after
+// every change and loads it on startup, so demo data survives restarts.
+// Reads are served from memory.
+type FileStore struct {
+	*MemoryStore
+	path string
+
+	// saveMu keeps snapshots from being written out of order
+	saveMu sync.Mutex
+}
+
+// snapshot is the on-disk format of a FileStore
+type snapshot struct {
+	Users      map[string]*User           `json:"users"`
+	Tenants    map[string]*Tenant         `json:"tenants"`
+	Workspaces map[string]*Workspace      `json:"workspaces"`
+	Documents  map[string]*Document       `json:"documents"`
+	Shares     map[string][]DocumentShare `json:"shares"`
+	Projects   map[string]*Project        `json:"projects"`
+
+	Versions map[string][]DocumentVersion `json:"versions,omitempty"`
+}
+
+// NewFileStore opens the store at path, loading existing data if the file
+// exists. The parent directory is created if needed.
+func NewFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create store directory: %w", err)
+	}
+
+	s := &FileStore{MemoryStore: NewMemoryStore(), path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read store file: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parse store file %s: %w", path, err)
+	}
+	s.restore(snap)
+
+	return s, nil
+}
+
+// save writes the current state to disk. The file is replaced atomically
+// so a crash mid-write can't leave it truncated.
+func (s *FileStore) save() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.RLock()
+	data, err := json.MarshalIndent(snapshot{
+		Users:      s.users,
+		Tenants:    s.tenants,
+		Workspaces: s.workspaces,
+		Documents:  s.documents,
+		Shares:     s.shares,
+		Projects:   s.projects,
+		Versions:   s.versions,
+	}, "", "  ")
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("encode store: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write store file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replace store file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) restore(snap snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, v := range snap.Users {
+		s.users[id] = v
+	}
+	for id, v := range snap.Tenants {
+		s.tenants[id] = v
+	}
+	for id, v := range snap.Workspaces {
+		s.workspaces[id] = v
+	}
+	for id, v := range snap.Documents {
+		s.documents[id] = v
+	}
+	for id, v := range snap.Shares {
+		s.shares[id] = v
+	}
+	for id, v := range snap.Projects {
+		s.projects[id] = v
+	}
+	for id, v := range snap.Versions {
+		s.versions[id] = v
+	}
+}
+
+// persist saves after a successful change and passes err through
+func (s *FileStore) persist(err error) error {
+	if err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// Mutating operations persist after the in-memory change
+
+func (s *FileStore) CreateUser(user *User) error {
+	return s.persist(s.MemoryStore.CreateUser(user))
+}
+
+func (s *FileStore) UpdateUser(user *User) error {
+	return s.persist(s.MemoryStore.UpdateUser(user))
+}
+
+func (s *FileStore) DeleteUser(id string) error {
+	return s.persist(s.MemoryStore.DeleteUser(id))
+}
+
+// ProvisionUser only saves when the user was created or changed, so the
+// per-request call stays cheap
+func (s *FileStore) ProvisionUser(user *User) (bool, error) {
+	changed, err := s.MemoryStore.ProvisionUser(user)
+	if err != nil || !changed {
+		return changed, err
+	}
+	return true, s.save()
+}
+
+func (s *FileStore) SetPlatformAdmin(userID string, isAdmin bool) error {
+	return s.persist(s.MemoryStore.SetPlatformAdmin(userID, isAdmin))
+}
+
+func (s *FileStore) CreateTenant(tenant *Tenant) error {
+	return s.persist(s.MemoryStore.CreateTenant(tenant))
+}
+
+func (s *FileStore) DeleteTenant(id string) error {
+	return s.persist(s.MemoryStore.DeleteTenant(id))
+}
+
+func (s *FileStore) CreateWorkspace(workspace *Workspace) error {
+	return s.persist(s.MemoryStore.CreateWorkspace(workspace))
+}
+
+func (s *FileStore) DeleteWorkspace(id string) error {
+	return s.persist(s.MemoryStore.DeleteWorkspace(id))
+}
+
+func (s *FileStore) CreateDocument(doc *Document) error {
+	return s.persist(s.MemoryStore.CreateDocument(doc))
+}
+
+func (s *FileStore) UpdateDocument(doc *Document) error {
+	return s.persist(s.MemoryStore.UpdateDocument(doc))
+}
+
+func (s *FileStore) DeleteDocument(id string) error {
+	return s.persist(s.MemoryStore.DeleteDocument(id))
+}
+
+func (s *FileStore) RestoreDocument(id string) error {
+	return s.persist(s.MemoryStore.RestoreDocument(id))
+}
+
+func (s *FileStore) AddDocumentShare(share DocumentShare) error {
+	return s.persist(s.MemoryStore.AddDocumentShare(share))
+}
+
+func (s *FileStore) RemoveDocumentShare(docID, userID string) (DocumentShare, error) {
+	share, err := s.MemoryStore.RemoveDocumentShare(docID, userID)
+	return share, s.persist(err)
+}
+
+func (s *FileStore) TransferDocumentOwnership(docID, fromOwner, toOwner string, formerOwner DocumentShare) (DocumentShare, error) {
+	replaced, err := s.MemoryStore.TransferDocumentOwnership(docID, fromOwner, toOwner, formerOwner)
+	return replaced, s.persist(err)
+}
+
+func (s *FileStore) RemoveExpiredShares(docID string, now time.Time) []DocumentShare {
+	removed := s.MemoryStore.RemoveExpiredShares(docID, now)
+	if len(removed) > 0 {
+		if err := s.save(); err != nil {
+			log.Printf("Warning: Failed to persist expired share removal: %v", err)
+		}
+	}
+	return removed
+}
+
+func (s *FileStore) CreateProject(proj *Project) error {
+	return s.persist(s.MemoryStore.CreateProject(proj))
+}
+
+func (s *FileStore) UpdateProject(proj *Project) error {
+	return s.persist(s.MemoryStore.UpdateProject(proj))
+}
+
+func (s *FileStore) DeleteProject(id string) error {
+	return s.persist(s.MemoryStore.DeleteProject(id))
+}
+
+func (s *FileStore) RestoreProject(id string) error {
+	return s.persist(s.MemoryStore.RestoreProject(id))
+}
+
+func (s *FileStore) PurgeDeleted(olderThan time.Duration) ([]PurgedDocument, int) {
+	docs, projects := s.MemoryStore.PurgeDeleted(olderThan)
+	if len(docs) > 0 || projects > 0 {
+		if err := s.save(); err != nil {
+			log.Printf("Warning: Failed to persist trash purge: %v", err)
+		}
+	}
+	return docs, projects
+}
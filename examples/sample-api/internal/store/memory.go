@@ -2,6 +2,7 @@ package store
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 )
@@ -9,30 +10,77 @@ import (
 var (
 	ErrNotFound      = errors.New("not found")
 	ErrAlreadyExists = errors.New("already exists")
+
+	// ErrVersionConflict is returned by updates whose Version no longer
+	// matches the stored one, i.e. someone else saved in between
+	ErrVersionConflict = errors.New("version conflict")
 )
 
+// QuotaError is returned when creating a document or project would take a
+// workspace past its quota
+type QuotaError struct {
+	Resource string // "documents" or "projects"
+	Limit    int
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("workspace %s quota of %d reached", e.Resource, e.Limit)
+}
+
+// DefaultMaxDocumentVersions is how many versions of each document are kept
+// unless SetMaxDocumentVersions says otherwise
+const DefaultMaxDocumentVersions = 50
+
 // MemoryStore is an in-memory store for demo purposes
 type MemoryStore struct {
-	mu         sync.RWMutex
-	users      map[string]*User
-	tenants    map[string]*Tenant
-	workspaces map[string]*Workspace
-	documents  map[string]*Document
-	shares     map[string][]DocumentShare // documentID -> shares
-	projects   map[string]*Project
+	mu          sync.RWMutex
+	users       map[string]*User
+	tenants     map[string]*Tenant
+	workspaces  map[string]*Workspace
+	documents   map[string]*Document
+	shares      map[string][]DocumentShare   // documentID -> shares
+	versions    map[string][]DocumentVersion // documentID -> versions, oldest first
+	projects    map[string]*Project
+	maxVersions int
+
+	// Per-workspace quotas; -1 means unlimited
+	maxDocuments int
+	maxProjects  int
 }
 
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		users:      make(map[string]*User),
-		tenants:    make(map[string]*Tenant),
-		workspaces: make(map[string]*Workspace),
-		documents:  make(map[string]*Document),
-		shares:     make(map[string][]DocumentShare),
-		projects:   make(map[string]*Project),
+		users:       make(map[string]*User),
+		tenants:     make(map[string]*Tenant),
+		workspaces:  make(map[string]*Workspace),
+		documents:   make(map[string]*Document),
+		shares:      make(map[string][]DocumentShare),
+		versions:    make(map[string][]DocumentVersion),
+		projects:    make(map[string]*Project),
+		maxVersions: DefaultMaxDocumentVersions,
+
+		maxDocuments: -1,
+		maxProjects:  -1,
 	}
 }
 
+// SetMaxDocumentVersions sets how many versions of each document are kept
+// (<= 0 keeps all). Older versions are pruned on the next update.
+func (s *MemoryStore) SetMaxDocumentVersions(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxVersions = n
+}
+
+// SetWorkspaceQuotas sets how many documents and projects each workspace
+// may hold (-1 is unlimited). Trashed items don't count.
+func (s *MemoryStore) SetWorkspaceQuotas(maxDocuments, maxProjects int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxDocuments = maxDocuments
+	s.maxProjects = maxProjects
+}
+
 // User operations
 
 func (s *MemoryStore) CreateUser(user *User) error {
@@ -108,6 +156,56 @@ func (s *MemoryStore) ListUsers() []*User {
 	return users
 }
 
+// ProvisionUser records a user seen on an authenticated request: it creates
+// them, or updates their email, name and picture where user has a new
+// non-empty value. Platform admin status is only copied on creation;
+// afterwards SetPlatformAdmin owns it. It reports whether anything changed,
+// and takes only a read lock when nothing did.
+func (s *MemoryStore) ProvisionUser(user *User) (bool, error) {
+	s.mu.RLock()
+	existing, exists := s.users[user.ID]
+	upToDate := exists && !profileChanged(existing, user)
+	s.mu.RUnlock()
+	if upToDate {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists = s.users[user.ID]
+	if !exists {
+		created := *user
+		created.CreatedAt = time.Now()
+		created.UpdatedAt = created.CreatedAt
+		s.users[user.ID] = &created
+		return true, nil
+	}
+	if !profileChanged(existing, user) {
+		return false, nil
+	}
+
+	if user.Email != "" {
+		existing.Email = user.Email
+	}
+	if user.Name != "" {
+		existing.Name = user.Name
+	}
+	if user.Picture != "" {
+		existing.Picture = user.Picture
+	}
+	existing.UpdatedAt = time.Now()
+	return true, nil
+}
+
+// profileChanged reports whether update has a non-empty profile field that
+// differs from user's
+func profileChanged(user, update *User) bool {
+	return (update.Email != "" && update.Email != user.Email) ||
+		(update.Name != "" && update.Name != user.Name) ||
+		(update.Picture != "" && update.Picture != user.Picture)
+}
+
 func (s *MemoryStore) SetPlatformAdmin(userID string, isAdmin bool) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -248,12 +346,25 @@ func (s *MemoryStore) GetPlatformStats() *PlatformStats {
 		}
 	}
 
+	docCount := 0
+	for _, doc := range s.documents {
+		if doc.DeletedAt == nil {
+			docCount++
+		}
+	}
+	projectCount := 0
+	for _, proj := range s.projects {
+		if proj.DeletedAt == nil {
+			projectCount++
+		}
+	}
+
 	return &PlatformStats{
 		TotalUsers:      len(s.users),
 		TotalTenants:    len(s.tenants),
 		TotalWorkspaces: len(s.workspaces),
-		TotalDocuments:  len(s.documents),
-		TotalProjects:   len(s.projects),
+		TotalDocuments:  docCount,
+		TotalProjects:   projectCount,
 		AdminCount:      adminCount,
 	}
 }
@@ -265,7 +376,9 @@ func (s *MemoryStore) GetAllDocuments() []*Document {
 
 	docs := make([]*Document, 0, len(s.documents))
 	for _, doc := range s.documents {
-		docs = append(docs, doc)
+		if doc.DeletedAt == nil {
+			docs = append(docs, doc)
+		}
 	}
 	return docs
 }
@@ -277,7 +390,9 @@ func (s *MemoryStore) GetAllProjects() []*Project {
 
 	projects := make([]*Project, 0, len(s.projects))
 	for _, proj := range s.projects {
-		projects = append(projects, proj)
+		if proj.DeletedAt == nil {
+			projects = append(projects, proj)
+		}
 	}
 	return projects
 }
@@ -291,7 +406,19 @@ func (s *MemoryStore) CreateDocument(doc *Document) error {
 	if _, exists := s.documents[doc.ID]; exists {
 		return ErrAlreadyExists
 	}
+	if s.maxDocuments >= 0 {
+		count := 0
+		for _, d := range s.documents {
+			if d.WorkspaceID == doc.WorkspaceID && d.DeletedAt == nil {
+				count++
+			}
+		}
+		if count >= s.maxDocuments {
+			return &QuotaError{Resource: "documents", Limit: s.maxDocuments}
+		}
+	}
 
+	doc.Version = 1
 	doc.CreatedAt = time.Now()
 	doc.UpdatedAt = time.Now()
 	s.documents[doc.ID] = doc
@@ -301,53 +428,150 @@ func (s *MemoryStore) CreateDocument(doc *Document) error {
 		{DocumentID: doc.ID, UserID: doc.OwnerID, Role: "owner"},
 	}
 
+	if doc.UpdatedBy == "" {
+		doc.UpdatedBy = doc.OwnerID
+	}
+	s.recordVersion(doc)
+
 	return nil
 }
 
-func (s *MemoryStore) GetDocument(id string) (*Document, error) {
+// GetDocument returns a copy of the document; save changes with
+// UpdateDocument. Trashed documents are not found unless includeDeleted.
+func (s *MemoryStore) GetDocument(id string, includeDeleted bool) (*Document, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	doc, exists := s.documents[id]
-	if !exists {
+	if !exists || (doc.DeletedAt != nil && !includeDeleted) {
 		return nil, ErrNotFound
 	}
-	return doc, nil
+	cp := *doc
+	return &cp, nil
 }
 
+// UpdateDocument saves doc if its Version matches the stored one and
+// increments the version; otherwise it returns ErrVersionConflict
 func (s *MemoryStore) UpdateDocument(doc *Document) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.documents[doc.ID]; !exists {
+	stored, exists := s.documents[doc.ID]
+	if !exists || stored.DeletedAt != nil {
 		return ErrNotFound
 	}
+	if stored.Version != doc.Version {
+		return ErrVersionConflict
+	}
 
+	doc.Version++
 	doc.UpdatedAt = time.Now()
-	s.documents[doc.ID] = doc
+	cp := *doc
+	s.documents[doc.ID] = &cp
+	s.recordVersion(&cp)
 	return nil
 }
 
+// recordVersion snapshots doc as a new version, pruning the oldest beyond
+// maxVersions. Caller must hold s.mu.
+func (s *MemoryStore) recordVersion(doc *Document) {
+	versions := append(s.versions[doc.ID], DocumentVersion{
+		DocumentID: doc.ID,
+		Version:    doc.Version,
+		Title:      doc.Title,
+		Content:    doc.Content,
+		EditedBy:   doc.UpdatedBy,
+		CreatedAt:  doc.UpdatedAt,
+	})
+	if s.maxVersions > 0 && len(versions) > s.maxVersions {
+		versions = append([]DocumentVersion(nil), versions[len(versions)-s.maxVersions:]...)
+	}
+	s.versions[doc.ID] = versions
+}
+
+// ListDocumentVersions returns the document's retained versions, newest
+// first
+func (s *MemoryStore) ListDocumentVersions(docID string) []DocumentVersion {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := s.versions[docID]
+	list := make([]DocumentVersion, len(versions))
+	for i, v := range versions {
+		list[len(versions)-1-i] = v
+	}
+	return list
+}
+
+// GetDocumentVersion returns one retained version of a document
+func (s *MemoryStore) GetDocumentVersion(docID string, version int) (*DocumentVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, v := range s.versions[docID] {
+		if v.Version == version {
+			cp := v
+			return &cp, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// DeleteDocument moves a document to the trash. Its shares are kept so
+// RestoreDocument brings it back as it was.
 func (s *MemoryStore) DeleteDocument(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.documents[id]; !exists {
+	doc, exists := s.documents[id]
+	if !exists || doc.DeletedAt != nil {
 		return ErrNotFound
 	}
 
-	delete(s.documents, id)
-	delete(s.shares, id)
+	now := time.Now()
+	cp := *doc
+	cp.DeletedAt = &now
+	s.documents[id] = &cp
 	return nil
 }
 
+// RestoreDocument takes a document out of the trash
+func (s *MemoryStore) RestoreDocument(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, exists := s.documents[id]
+	if !exists || doc.DeletedAt == nil {
+		return ErrNotFound
+	}
+
+	cp := *doc
+	cp.DeletedAt = nil
+	s.documents[id] = &cp
+	return nil
+}
+
+// ListDeletedDocuments returns the documents in the trash (for admin)
+func (s *MemoryStore) ListDeletedDocuments() []*Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var docs []*Document
+	for _, doc := range s.documents {
+		if doc.DeletedAt != nil {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
 func (s *MemoryStore) ListDocuments(workspaceID string) []*Document {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	var docs []*Document
 	for _, doc := range s.documents {
-		if doc.WorkspaceID == workspaceID {
+		if doc.WorkspaceID == workspaceID && doc.DeletedAt == nil {
 			docs = append(docs, doc)
 		}
 	}
@@ -360,7 +584,7 @@ func (s *MemoryStore) ListDocumentsForUser(workspaceID, userID string) []*Docume
 
 	var docs []*Document
 	for _, doc := range s.documents {
-		if doc.WorkspaceID != workspaceID {
+		if doc.WorkspaceID != workspaceID || doc.DeletedAt != nil {
 			continue
 		}
 
@@ -385,8 +609,9 @@ func (s *MemoryStore) hasDocumentAccess(docID, userID string) bool {
 	if !exists {
 		return false
 	}
+	now := time.Now()
 	for _, share := range shares {
-		if share.UserID == userID {
+		if share.UserID == userID && !share.IsExpired(now) {
 			return true
 		}
 	}
@@ -397,15 +622,20 @@ func (s *MemoryStore) AddDocumentShare(share DocumentShare) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.documents[share.DocumentID]; !exists {
+	if doc, exists := s.documents[share.DocumentID]; !exists || doc.DeletedAt != nil {
 		return ErrNotFound
 	}
 
-	// Check if already shared
+	// Check if already shared (an expired share can be replaced)
+	now := time.Now()
 	shares := s.shares[share.DocumentID]
-	for _, existing := range shares {
+	for i, existing := range shares {
 		if existing.UserID == share.UserID {
-			return ErrAlreadyExists
+			if !existing.IsExpired(now) {
+				return ErrAlreadyExists
+			}
+			shares[i] = share
+			return nil
 		}
 	}
 
@@ -413,26 +643,127 @@ func (s *MemoryStore) AddDocumentShare(share DocumentShare) error {
 	return nil
 }
 
+// RemoveDocumentShare revokes a user's share and returns it. The owner's
+// share can't be removed.
+func (s *MemoryStore) RemoveDocumentShare(docID, userID string) (DocumentShare, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if doc, exists := s.documents[docID]; !exists || doc.DeletedAt != nil || doc.OwnerID == userID {
+		return DocumentShare{}, ErrNotFound
+	}
+
+	shares := s.shares[docID]
+	for i, share := range shares {
+		if share.UserID == userID {
+			s.shares[docID] = append(shares[:i:i], shares[i+1:]...)
+			return share, nil
+		}
+	}
+	return DocumentShare{}, ErrNotFound
+}
+
+// GetDocumentShares returns the document's active (unexpired) shares
 func (s *MemoryStore) GetDocumentShares(docID string) []DocumentShare {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return s.shares[docID]
+	now := time.Now()
+	var active []DocumentShare
+	for _, share := range s.shares[docID] {
+		if !share.IsExpired(now) {
+			active = append(active, share)
+		}
+	}
+	return active
 }
 
 func (s *MemoryStore) GetUserDocumentRole(docID, userID string) string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	now := time.Now()
 	shares := s.shares[docID]
 	for _, share := range shares {
-		if share.UserID == userID {
+		if share.UserID == userID && !share.IsExpired(now) {
 			return share.Role
 		}
 	}
 	return ""
 }
 
+// RemoveExpiredShares deletes shares that expired before now and returns
+// them so callers can clean up related state. An empty docID sweeps all
+// documents.
+func (s *MemoryStore) RemoveExpiredShares(docID string, now time.Time) []DocumentShare {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed []DocumentShare
+	for id, shares := range s.shares {
+		if docID != "" && id != docID {
+			continue
+		}
+		kept := shares[:0]
+		for _, share := range shares {
+			if share.IsExpired(now) {
+				removed = append(removed, share)
+			} else {
+				kept = append(kept, share)
+			}
+		}
+		s.shares[id] = kept
+	}
+	return removed
+}
+
+// TransferDocumentOwnership makes toOwner the document's owner in place of
+// fromOwner, failing with ErrVersionConflict if fromOwner no longer owns it.
+// toOwner's previous share is replaced and returned; fromOwner keeps
+// formerOwner's role (and expiry), or no share if its Role is empty. The
+// document's version is unchanged.
+func (s *MemoryStore) TransferDocumentOwnership(docID, fromOwner, toOwner string, formerOwner DocumentShare) (DocumentShare, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, exists := s.documents[docID]
+	if !exists || doc.DeletedAt != nil {
+		return DocumentShare{}, ErrNotFound
+	}
+	if doc.OwnerID != fromOwner {
+		return DocumentShare{}, ErrVersionConflict
+	}
+
+	now := time.Now()
+	var replaced DocumentShare
+	var kept []DocumentShare
+	for _, share := range s.shares[docID] {
+		switch share.UserID {
+		case toOwner:
+			if !share.IsExpired(now) {
+				replaced = share
+			}
+		case fromOwner:
+		default:
+			kept = append(kept, share)
+		}
+	}
+
+	kept = append(kept, DocumentShare{DocumentID: docID, UserID: toOwner, Role: "owner"})
+	if formerOwner.Role != "" {
+		formerOwner.DocumentID = docID
+		formerOwner.UserID = fromOwner
+		kept = append(kept, formerOwner)
+	}
+	s.shares[docID] = kept
+
+	cp := *doc
+	cp.OwnerID = toOwner
+	cp.UpdatedAt = now
+	s.documents[docID] = &cp
+	return replaced, nil
+}
+
 // Project operations
 
 func (s *MemoryStore) CreateProject(proj *Project) error {
@@ -442,56 +773,114 @@ func (s *MemoryStore) CreateProject(proj *Project) error {
 	if _, exists := s.projects[proj.ID]; exists {
 		return ErrAlreadyExists
 	}
+	if s.maxProjects >= 0 {
+		count := 0
+		for _, p := range s.projects {
+			if p.WorkspaceID == proj.WorkspaceID && p.DeletedAt == nil {
+				count++
+			}
+		}
+		if count >= s.maxProjects {
+			return &QuotaError{Resource: "projects", Limit: s.maxProjects}
+		}
+	}
 
+	proj.Version = 1
 	proj.CreatedAt = time.Now()
 	proj.UpdatedAt = time.Now()
 	s.projects[proj.ID] = proj
 	return nil
 }
 
+// GetProject returns a copy of the project; save changes with
+// UpdateProject
 func (s *MemoryStore) GetProject(id string) (*Project, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	proj, exists := s.projects[id]
-	if !exists {
+	if !exists || proj.DeletedAt != nil {
 		return nil, ErrNotFound
 	}
-	return proj, nil
+	cp := *proj
+	return &cp, nil
 }
 
+// UpdateProject saves proj if its Version matches the stored one and
+// increments the version; otherwise it returns ErrVersionConflict
 func (s *MemoryStore) UpdateProject(proj *Project) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.projects[proj.ID]; !exists {
+	stored, exists := s.projects[proj.ID]
+	if !exists || stored.DeletedAt != nil {
 		return ErrNotFound
 	}
+	if stored.Version != proj.Version {
+		return ErrVersionConflict
+	}
 
+	proj.Version++
 	proj.UpdatedAt = time.Now()
-	s.projects[proj.ID] = proj
+	cp := *proj
+	s.projects[proj.ID] = &cp
 	return nil
 }
 
+// DeleteProject moves a project to the trash
 func (s *MemoryStore) DeleteProject(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.projects[id]; !exists {
+	proj, exists := s.projects[id]
+	if !exists || proj.DeletedAt != nil {
+		return ErrNotFound
+	}
+
+	now := time.Now()
+	cp := *proj
+	cp.DeletedAt = &now
+	s.projects[id] = &cp
+	return nil
+}
+
+// RestoreProject takes a project out of the trash
+func (s *MemoryStore) RestoreProject(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proj, exists := s.projects[id]
+	if !exists || proj.DeletedAt == nil {
 		return ErrNotFound
 	}
 
-	delete(s.projects, id)
+	cp := *proj
+	cp.DeletedAt = nil
+	s.projects[id] = &cp
 	return nil
 }
 
+// ListDeletedProjects returns the projects in the trash (for admin)
+func (s *MemoryStore) ListDeletedProjects() []*Project {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var projects []*Project
+	for _, proj := range s.projects {
+		if proj.DeletedAt != nil {
+			projects = append(projects, proj)
+		}
+	}
+	return projects
+}
+
 func (s *MemoryStore) ListProjects(workspaceID string) []*Project {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	var projects []*Project
 	for _, proj := range s.projects {
-		if proj.WorkspaceID == workspaceID {
+		if proj.WorkspaceID == workspaceID && proj.DeletedAt == nil {
 			projects = append(projects, proj)
 		}
 	}
@@ -504,9 +893,41 @@ func (s *MemoryStore) ListProjectsByEnvironment(workspaceID, env string) []*Proj
 
 	var projects []*Project
 	for _, proj := range s.projects {
-		if proj.WorkspaceID == workspaceID && proj.Environment == env {
+		if proj.WorkspaceID == workspaceID && proj.Environment == env && proj.DeletedAt == nil {
 			projects = append(projects, proj)
 		}
 	}
 	return projects
 }
+
+// Trash
+
+// PurgeDeleted permanently removes documents and projects trashed more than
+// olderThan ago. Purged documents are returned with their shares so callers
+// can revoke related state.
+func (s *MemoryStore) PurgeDeleted(olderThan time.Duration) ([]PurgedDocument, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-olderThan)
+
+	var docs []PurgedDocument
+	for id, doc := range s.documents {
+		if doc.DeletedAt != nil && doc.DeletedAt.Before(cutoff) {
+			docs = append(docs, PurgedDocument{Document: doc, Shares: s.shares[id]})
+			delete(s.documents, id)
+			delete(s.shares, id)
+			delete(s.versions, id)
+		}
+	}
+
+	projects := 0
+	for id, proj := range s.projects {
+		if proj.DeletedAt != nil && proj.DeletedAt.Before(cutoff) {
+			delete(s.projects, id)
+			projects++
+		}
+	}
+
+	return docs, projects
+}
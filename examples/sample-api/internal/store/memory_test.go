@@ -0,0 +1,148 @@
+package store
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestDocumentShareIsExpired(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		want      bool
+	}{
+		{"permanent", nil, false},
+		{"expires later", at(time.Minute), false},
+		{"expires now", at(0), true},
+		{"expired", at(-time.Minute), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			share := DocumentShare{ExpiresAt: tt.expiresAt}
+			if got := share.IsExpired(now); got != tt.want {
+				t.Errorf("IsExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveExpiredShares(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		docID       string
+		wantRemoved []string // document/user of the removed shares
+		wantActive  map[string][]string
+	}{
+		{
+			name:        "one document",
+			docID:       "doc-1",
+			wantRemoved: []string{"doc-1/bob"},
+			wantActive:  map[string][]string{"doc-1": {"alice", "carol"}, "doc-2": {"alice"}},
+		},
+		{
+			name:        "all documents",
+			wantRemoved: []string{"doc-1/bob", "doc-2/dave"},
+			wantActive:  map[string][]string{"doc-1": {"alice", "carol"}, "doc-2": {"alice"}},
+		},
+		{
+			name:       "document without expired shares",
+			docID:      "doc-3",
+			wantActive: map[string][]string{"doc-1": {"alice", "carol"}, "doc-2": {"alice"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			for _, id := range []string{"doc-1", "doc-2", "doc-3"} {
+				if err := s.CreateDocument(&Document{ID: id, WorkspaceID: "ws-1", OwnerID: "alice"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, share := range []DocumentShare{
+				{DocumentID: "doc-1", UserID: "bob", Role: "viewer", ExpiresAt: &past},
+				{DocumentID: "doc-1", UserID: "carol", Role: "editor", ExpiresAt: &future},
+				{DocumentID: "doc-2", UserID: "dave", Role: "viewer", ExpiresAt: &past},
+			} {
+				if err := s.AddDocumentShare(share); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Expired shares grant nothing even before they are removed
+			if role := s.GetUserDocumentRole("doc-1", "bob"); role != "" {
+				t.Errorf("expired share role = %q, want none", role)
+			}
+
+			var removed []string
+			for _, share := range s.RemoveExpiredShares(tt.docID, time.Now()) {
+				removed = append(removed, share.DocumentID+"/"+share.UserID)
+			}
+			sort.Strings(removed)
+			if !equal(removed, tt.wantRemoved) {
+				t.Errorf("removed %v, want %v", removed, tt.wantRemoved)
+			}
+
+			for docID, want := range tt.wantActive {
+				var users []string
+				for _, share := range s.GetDocumentShares(docID) {
+					users = append(users, share.UserID)
+				}
+				sort.Strings(users)
+				if !equal(users, want) {
+					t.Errorf("%s shares = %v, want %v", docID, users, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAddDocumentShareReplacesExpired(t *testing.T) {
+	s := NewMemoryStore()
+	if err := s.CreateDocument(&Document{ID: "doc-1", WorkspaceID: "ws-1", OwnerID: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		share   DocumentShare
+		wantErr error
+	}{
+		{"new temporary share", DocumentShare{DocumentID: "doc-1", UserID: "bob", Role: "viewer", ExpiresAt: &past}, nil},
+		{"replaces the expired share", DocumentShare{DocumentID: "doc-1", UserID: "bob", Role: "editor"}, nil},
+		{"active share is kept", DocumentShare{DocumentID: "doc-1", UserID: "bob", Role: "viewer"}, ErrAlreadyExists},
+		{"missing document", DocumentShare{DocumentID: "doc-2", UserID: "bob", Role: "viewer"}, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.AddDocumentShare(tt.share); err != tt.wantErr {
+				t.Errorf("AddDocumentShare() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if role := s.GetUserDocumentRole("doc-1", "bob"); role != "editor" {
+		t.Errorf("role = %q, want editor", role)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
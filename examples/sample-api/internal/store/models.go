@@ -53,15 +53,37 @@ type Document struct {
 	OwnerID     string    `json:"owner_id"`
 	Visibility  string    `json:"visibility"` // public, workspace, private
 	Status      string    `json:"status"`     // draft, published, archived
+	Version     int       `json:"version"`    // incremented on every update
+	UpdatedBy   string    `json:"updated_by,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// DeletedAt is set while the document is in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// DocumentVersion is a snapshot of a document's title and content as saved
+// at one version
+type DocumentVersion struct {
+	DocumentID string    `json:"document_id"`
+	Version    int       `json:"version"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	EditedBy   string    `json:"edited_by"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 // DocumentShare represents a sharing relationship
 type DocumentShare struct {
-	DocumentID string `json:"document_id"`
-	UserID     string `json:"user_id"`
-	Role       string `json:"role"` // owner, editor, viewer
+	DocumentID string     `json:"document_id"`
+	UserID     string     `json:"user_id"`
+	Role       string     `json:"role"`                 // owner, editor, viewer
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // nil = permanent
+}
+
+// IsExpired reports whether a temporary share has lapsed
+func (s DocumentShare) IsExpired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
 }
 
 // Project represents a project resource (for ABAC demo)
@@ -75,8 +97,23 @@ type Project struct {
 	Environment string    `json:"environment"` // production, staging, development
 	Status      string    `json:"status"`      // active, paused, archived
 	Tags        []string  `json:"tags"`
+	Version     int       `json:"version"` // incremented on every update
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Attributes are custom key/values (e.g. data_classification) that
+	// configured ABAC policies can reference as "attr.<key>"
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// DeletedAt is set while the project is in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// PurgedDocument is a document removed for good from the trash, with the
+// shares it had, so callers can clean up related state
+type PurgedDocument struct {
+	Document *Document
+	Shares   []DocumentShare
 }
 
 // UserContext represents the authenticated user context
@@ -86,6 +123,23 @@ type UserContext struct {
 	WorkspaceID     string   `json:"workspace_id"`
 	IsPlatformAdmin bool     `json:"is_platform_admin"`
 	Roles           []string `json:"roles"` // workspace roles
+
+	// ClientIP is the caller's address as resolved behind trusted proxies
+	ClientIP string `json:"client_ip,omitempty"`
+
+	// workspaceAdmin caches WorkspaceAdmin for the rest of the request
+	workspaceAdmin *bool
+}
+
+// WorkspaceAdmin reports whether the user administers WorkspaceID. resolve
+// is called the first time only; the answer is cached on the context, which
+// lives for one request.
+func (u *UserContext) WorkspaceAdmin(resolve func() bool) bool {
+	if u.workspaceAdmin == nil {
+		isAdmin := resolve()
+		u.workspaceAdmin = &isAdmin
+	}
+	return *u.workspaceAdmin
 }
 
 // PermissionCheck represents a permission check result
@@ -0,0 +1,79 @@
+package store
+
+import "time"
+
+// Store is the data access used by the handlers. MemoryStore keeps
+// everything in memory; FileStore also persists it to a JSON file.
+type Store interface {
+	// Users
+	CreateUser(user *User) error
+	GetUser(id string) (*User, error)
+	GetUserByEmail(email string) (*User, error)
+	UpdateUser(user *User) error
+	DeleteUser(id string) error
+	ListUsers() []*User
+	SetPlatformAdmin(userID string, isAdmin bool) error
+	ProvisionUser(user *User) (changed bool, err error)
+
+	// Tenants
+	CreateTenant(tenant *Tenant) error
+	GetTenant(id string) (*Tenant, error)
+	ListTenants() []*Tenant
+	DeleteTenant(id string) error
+
+	// Workspaces
+	CreateWorkspace(workspace *Workspace) error
+	GetWorkspace(id string) (*Workspace, error)
+	ListWorkspaces() []*Workspace
+	ListWorkspacesByTenant(tenantID string) []*Workspace
+	DeleteWorkspace(id string) error
+
+	// Platform admin
+	GetPlatformStats() *PlatformStats
+	GetAllDocuments() []*Document
+	GetAllProjects() []*Project
+
+	// Documents. Deleting moves a document to the trash; trashed documents
+	// are left out of lists and GetDocument unless includeDeleted is set.
+	// Creating fails with a *QuotaError once the workspace is at its quota.
+	CreateDocument(doc *Document) error
+	GetDocument(id string, includeDeleted bool) (*Document, error)
+	UpdateDocument(doc *Document) error
+	DeleteDocument(id string) error
+	RestoreDocument(id string) error
+	ListDeletedDocuments() []*Document
+
+	// Document versions, recorded on create and every update; only the
+	// newest are kept
+	ListDocumentVersions(docID string) []DocumentVersion
+	GetDocumentVersion(docID string, version int) (*DocumentVersion, error)
+	ListDocuments(workspaceID string) []*Document
+	ListDocumentsForUser(workspaceID, userID string) []*Document
+
+	// Document shares
+	AddDocumentShare(share DocumentShare) error
+	RemoveDocumentShare(docID, userID string) (DocumentShare, error)
+	GetDocumentShares(docID string) []DocumentShare
+	GetUserDocumentRole(docID, userID string) string
+	RemoveExpiredShares(docID string, now time.Time) []DocumentShare
+	TransferDocumentOwnership(docID, fromOwner, toOwner string, formerOwner DocumentShare) (replaced DocumentShare, err error)
+
+	// Projects. Deleting moves a project to the trash, and creating is
+	// limited by a quota, like documents.
+	CreateProject(proj *Project) error
+	GetProject(id string) (*Project, error)
+	UpdateProject(proj *Project) error
+	DeleteProject(id string) error
+	RestoreProject(id string) error
+	ListDeletedProjects() []*Project
+	ListProjects(workspaceID string) []*Project
+	ListProjectsByEnvironment(workspaceID, env string) []*Project
+
+	// Trash
+	PurgeDeleted(olderThan time.Duration) (docs []PurgedDocument, projects int)
+}
+
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*FileStore)(nil)
+)
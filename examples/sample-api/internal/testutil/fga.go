@@ -0,0 +1,363 @@
+// Package testutil provides test doubles for the sample API
+package testutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yourusername/sample-api/internal/authz"
+)
+
+// maxCheckDepth bounds relation resolution, like OpenFGA's resolution depth
+// limit, so a cyclic model fails instead of recursing forever
+const maxCheckDepth = 25
+
+// Relation describes how a relation is resolved: from tuples written
+// directly on it, from other relations on the same object (Computed), and
+// from a relation on objects linked through another relation (FromParent,
+// OpenFGA's "X from Y").
+type Relation struct {
+	Direct     bool
+	Computed   []string
+	FromParent []ParentRelation
+}
+
+// ParentRelation is "Relation from Tupleset"
+type ParentRelation struct {
+	Tupleset string // relation linking the object to its parent, e.g. "workspace"
+	Relation string // relation checked on the parent, e.g. "viewer"
+}
+
+// Model maps object type -> relation name -> resolution rules
+type Model map[string]map[string]Relation
+
+// DemoModel mirrors examples/deploy/model.json
+var DemoModel = Model{
+	authz.TypeWorkspace: {
+		"admin":  {Direct: true},
+		"member": {Direct: true, Computed: []string{"admin"}},
+		"viewer": {Direct: true, Computed: []string{"member"}},
+	},
+	authz.TypeDocument: {
+		"workspace":  {Direct: true},
+		"owner":      {Direct: true},
+		"editor":     {Direct: true},
+		"viewer":     {Direct: true},
+		"can_read":   {Computed: []string{"viewer", "editor", "owner"}, FromParent: []ParentRelation{{"workspace", "viewer"}}},
+		"can_write":  {Computed: []string{"editor", "owner"}},
+		"can_delete": {Computed: []string{"owner"}},
+		"can_share":  {Computed: []string{"owner"}},
+	},
+	authz.TypeProject: {
+		"workspace":  {Direct: true},
+		"owner":      {Direct: true},
+		"admin":      {Direct: true},
+		"member":     {Direct: true, Computed: []string{"admin", "owner"}},
+		"can_read":   {Computed: []string{"member"}, FromParent: []ParentRelation{{"workspace", "viewer"}}},
+		"can_write":  {Computed: []string{"admin", "owner"}},
+		"can_delete": {Computed: []string{"owner"}},
+	},
+}
+
+type tuple struct {
+	user, relation, object string
+}
+
+// MemoryFGA is an in-memory authz.Authorizer for tests. It stores tuples and
+// resolves relations with a Model, supporting direct grants, type wildcards
+// ("user:*"), usersets ("workspace:ws-1#member"), computed relations and
+// parent relations. It is deterministic and needs no OpenFGA server.
+type MemoryFGA struct {
+	model Model
+
+	mu     sync.RWMutex
+	tuples map[tuple]bool
+}
+
+var _ authz.Authorizer = (*MemoryFGA)(nil)
+
+// NewMemoryFGA creates an empty evaluator for model (DemoModel if nil)
+func NewMemoryFGA(model Model) *MemoryFGA {
+	if model == nil {
+		model = DemoModel
+	}
+	return &MemoryFGA{model: model, tuples: make(map[tuple]bool)}
+}
+
+// WriteTuple stores a tuple. Like OpenFGA, writing an existing tuple or one
+// the model doesn't allow is an error.
+func (m *MemoryFGA) WriteTuple(user, relation, object string) error {
+	if err := m.validate(user, relation, object); err != nil {
+		return err
+	}
+	if !m.relation(object, relation).Direct {
+		return fmt.Errorf("relation %q on %s cannot be written directly", relation, object)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := tuple{user, relation, object}
+	if m.tuples[t] {
+		return fmt.Errorf("tuple already exists: %s#%s@%s", object, relation, user)
+	}
+	m.tuples[t] = true
+	return nil
+}
+
+// WriteTuples stores several tuples atomically: if any is invalid or
+// already exists, none is written
+func (m *MemoryFGA) WriteTuples(tuples []authz.Tuple) error {
+	for _, t := range tuples {
+		if err := m.validate(t.User, t.Relation, t.Object); err != nil {
+			return err
+		}
+		if !m.relation(t.Object, t.Relation).Direct {
+			return fmt.Errorf("relation %q on %s cannot be written directly", t.Relation, t.Object)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	batch := make(map[tuple]bool, len(tuples))
+	for _, t := range tuples {
+		key := tuple{t.User, t.Relation, t.Object}
+		if m.tuples[key] || batch[key] {
+			return fmt.Errorf("tuple already exists: %s#%s@%s", t.Object, t.Relation, t.User)
+		}
+		batch[key] = true
+	}
+	for key := range batch {
+		m.tuples[key] = true
+	}
+	return nil
+}
+
+// DeleteTuple removes a tuple. Like OpenFGA, deleting a missing tuple is an
+// error.
+func (m *MemoryFGA) DeleteTuple(user, relation, object string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := tuple{user, relation, object}
+	if !m.tuples[t] {
+		return fmt.Errorf("tuple does not exist: %s#%s@%s", object, relation, user)
+	}
+	delete(m.tuples, t)
+	return nil
+}
+
+// UpdateTuples deletes and writes tuples atomically: if any delete is
+// missing or any write is invalid or already exists (after the deletes),
+// nothing changes
+func (m *MemoryFGA) UpdateTuples(writes, deletes []authz.Tuple) error {
+	for _, t := range writes {
+		if err := m.validate(t.User, t.Relation, t.Object); err != nil {
+			return err
+		}
+		if !m.relation(t.Object, t.Relation).Direct {
+			return fmt.Errorf("relation %q on %s cannot be written directly", t.Relation, t.Object)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := make(map[tuple]bool, len(deletes))
+	for _, t := range deletes {
+		key := tuple{t.User, t.Relation, t.Object}
+		if !m.tuples[key] || removed[key] {
+			return fmt.Errorf("tuple does not exist: %s#%s@%s", t.Object, t.Relation, t.User)
+		}
+		removed[key] = true
+	}
+	added := make(map[tuple]bool, len(writes))
+	for _, t := range writes {
+		key := tuple{t.User, t.Relation, t.Object}
+		if (m.tuples[key] && !removed[key]) || added[key] {
+			return fmt.Errorf("tuple already exists: %s#%s@%s", t.Object, t.Relation, t.User)
+		}
+		added[key] = true
+	}
+
+	for key := range removed {
+		delete(m.tuples, key)
+	}
+	for key := range added {
+		m.tuples[key] = true
+	}
+	return nil
+}
+
+// ReadTuples returns the tuples written directly on object, sorted for
+// deterministic results
+func (m *MemoryFGA) ReadTuples(object string) ([]authz.Tuple, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tuples []authz.Tuple
+	for t := range m.tuples {
+		if t.object == object {
+			tuples = append(tuples, authz.Tuple{User: t.user, Relation: t.relation, Object: t.object})
+		}
+	}
+	sort.Slice(tuples, func(i, j int) bool {
+		if tuples[i].Relation != tuples[j].Relation {
+			return tuples[i].Relation < tuples[j].Relation
+		}
+		return tuples[i].User < tuples[j].User
+	})
+	return tuples, nil
+}
+
+// Check reports whether user has relation on object
+func (m *MemoryFGA) Check(user, relation, object string) (bool, error) {
+	if err := m.validate(user, relation, object); err != nil {
+		return false, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.check(user, relation, object, 0)
+}
+
+// BatchCheck evaluates checks in order. Failed checks are reported in a
+// *authz.BatchCheckError, as OpenFGAClient does.
+func (m *MemoryFGA) BatchCheck(checks []authz.CheckRequest) ([]bool, error) {
+	results := make([]bool, len(checks))
+	failed := make(map[int]error)
+	for i, check := range checks {
+		allowed, err := m.Check(check.User, check.Relation, check.Object)
+		if err != nil {
+			failed[i] = err
+			continue
+		}
+		results[i] = allowed
+	}
+	if len(failed) > 0 {
+		return results, &authz.BatchCheckError{Failed: failed}
+	}
+	return results, nil
+}
+
+// ListRelations reports which of relations user has on object
+func (m *MemoryFGA) ListRelations(user, object string, relations []string) (map[string]bool, error) {
+	result := make(map[string]bool, len(relations))
+	for _, relation := range relations {
+		allowed, err := m.Check(user, relation, object)
+		if err != nil {
+			return nil, err
+		}
+		result[relation] = allowed
+	}
+	return result, nil
+}
+
+// ListUsers returns the sorted IDs of userType users with relation on object,
+// considering every user that appears in a tuple. A wildcard grant is
+// returned as "*".
+func (m *MemoryFGA) ListUsers(object, relation, userType string) ([]string, error) {
+	if _, err := authz.ParseObjectRef(object); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	candidates := make(map[string]bool)
+	for t := range m.tuples {
+		if ref, err := authz.ParseObjectRef(t.user); err == nil && ref.Type == userType {
+			candidates[ref.ID] = true
+		}
+	}
+
+	users := []string{}
+	for id := range candidates {
+		allowed, err := m.check(userType+":"+id, relation, object, 0)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			users = append(users, id)
+		}
+	}
+	sort.Strings(users)
+	return users, nil
+}
+
+// check resolves relation for user on object. Caller must hold m.mu.
+func (m *MemoryFGA) check(user, relation, object string, depth int) (bool, error) {
+	if depth > maxCheckDepth {
+		return false, fmt.Errorf("resolution too deep checking %s#%s@%s", object, relation, user)
+	}
+
+	rel := m.relation(object, relation)
+
+	if rel.Direct {
+		if m.tuples[tuple{user, relation, object}] {
+			return true, nil
+		}
+		if userType, _, ok := strings.Cut(user, ":"); ok && m.tuples[tuple{userType + ":*", relation, object}] {
+			return true, nil
+		}
+		// Userset grants, e.g. document:d#viewer@workspace:ws#member
+		for t := range m.tuples {
+			if t.relation != relation || t.object != object {
+				continue
+			}
+			setObject, setRelation, ok := strings.Cut(t.user, "#")
+			if !ok {
+				continue
+			}
+			if allowed, err := m.check(user, setRelation, setObject, depth+1); err != nil || allowed {
+				return allowed, err
+			}
+		}
+	}
+
+	for _, computed := range rel.Computed {
+		if allowed, err := m.check(user, computed, object, depth+1); err != nil || allowed {
+			return allowed, err
+		}
+	}
+
+	for _, parent := range rel.FromParent {
+		for t := range m.tuples {
+			if t.relation != parent.Tupleset || t.object != object {
+				continue
+			}
+			if allowed, err := m.check(user, parent.Relation, t.user, depth+1); err != nil || allowed {
+				return allowed, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// relation returns the model's rules for relation on object's type; unknown
+// relations resolve to nothing
+func (m *MemoryFGA) relation(object, relation string) Relation {
+	objectType, _, _ := strings.Cut(object, ":")
+	return m.model[objectType][relation]
+}
+
+// validate rejects malformed references and relations missing from the
+// model, which OpenFGA would also reject
+func (m *MemoryFGA) validate(user, relation, object string) error {
+	userRef, _, _ := strings.Cut(user, "#")
+	if _, err := authz.ParseObjectRef(userRef); err != nil {
+		return err
+	}
+	if _, err := authz.ParseObjectRef(object); err != nil {
+		return err
+	}
+	objectType, _, _ := strings.Cut(object, ":")
+	if _, ok := m.model[objectType][relation]; !ok {
+		return fmt.Errorf("relation %q not defined for type %q", relation, objectType)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package testutil
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/sample-api/internal/authz"
+)
+
+// demoTuples is a workspace with an admin and a member, a document in it
+// shared with an outside editor, and a document shared with everyone
+func demoTuples(t *testing.T) *MemoryFGA {
+	t.Helper()
+	fga := NewMemoryFGA(nil)
+	err := fga.WriteTuples([]authz.Tuple{
+		{User: "user:alice", Relation: "admin", Object: "workspace:ws-1"},
+		{User: "user:bob", Relation: "member", Object: "workspace:ws-1"},
+		{User: "workspace:ws-1", Relation: "workspace", Object: "document:doc-1"},
+		{User: "user:carol", Relation: "owner", Object: "document:doc-1"},
+		{User: "user:dave", Relation: "editor", Object: "document:doc-1"},
+		{User: "user:*", Relation: "viewer", Object: "document:public"},
+		{User: "workspace:ws-1#member", Relation: "editor", Object: "document:team"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fga
+}
+
+func TestMemoryFGACheck(t *testing.T) {
+	fga := demoTuples(t)
+
+	tests := []struct {
+		name     string
+		user     string
+		relation string
+		object   string
+		want     bool
+	}{
+		{"direct grant", "user:carol", "owner", "document:doc-1", true},
+		{"computed from a direct grant", "user:dave", "can_write", "document:doc-1", true},
+		{"inherited from the workspace", "user:bob", "can_read", "document:doc-1", true},
+		{"inherited through workspace role chain", "user:alice", "can_read", "document:doc-1", true},
+		{"wildcard grant", "user:erin", "can_read", "document:public", true},
+		{"userset grant", "user:bob", "can_write", "document:team", true},
+		{"denied: workspace member can't write", "user:bob", "can_write", "document:doc-1", false},
+		{"denied: editor can't delete", "user:dave", "can_delete", "document:doc-1", false},
+		{"denied: stranger", "user:erin", "can_read", "document:doc-1", false},
+		{"denied: wildcard is per object", "user:erin", "can_read", "document:team", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := fga.Check(tc.user, tc.relation, tc.object)
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Check(%s, %s, %s) = %v, want %v", tc.user, tc.relation, tc.object, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMemoryFGAWrites(t *testing.T) {
+	tests := []struct {
+		name    string
+		apply   func(fga *MemoryFGA) error
+		wantErr bool
+	}{
+		{"duplicate tuple", func(fga *MemoryFGA) error {
+			return fga.WriteTuple("user:carol", "owner", "document:doc-1")
+		}, true},
+		{"computed relation", func(fga *MemoryFGA) error {
+			return fga.WriteTuple("user:erin", "can_read", "document:doc-1")
+		}, true},
+		{"unknown relation", func(fga *MemoryFGA) error {
+			return fga.WriteTuple("user:erin", "approver", "document:doc-1")
+		}, true},
+		{"malformed user", func(fga *MemoryFGA) error {
+			return fga.WriteTuple("erin", "viewer", "document:doc-1")
+		}, true},
+		{"missing delete", func(fga *MemoryFGA) error {
+			return fga.DeleteTuple("user:erin", "viewer", "document:doc-1")
+		}, true},
+		{"batch with one duplicate writes nothing", func(fga *MemoryFGA) error {
+			return fga.WriteTuples([]authz.Tuple{
+				{User: "user:erin", Relation: "viewer", Object: "document:doc-1"},
+				{User: "user:carol", Relation: "owner", Object: "document:doc-1"},
+			})
+		}, true},
+		{"ownership transfer", func(fga *MemoryFGA) error {
+			return fga.UpdateTuples(
+				[]authz.Tuple{{User: "user:erin", Relation: "owner", Object: "document:doc-1"}},
+				[]authz.Tuple{{User: "user:carol", Relation: "owner", Object: "document:doc-1"}},
+			)
+		}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fga := demoTuples(t)
+			before, _ := fga.ReadTuples("document:doc-1")
+
+			err := tc.apply(fga)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			after, _ := fga.ReadTuples("document:doc-1")
+			if tc.wantErr && !reflect.DeepEqual(before, after) {
+				t.Errorf("failed write changed tuples: %v -> %v", before, after)
+			}
+		})
+	}
+
+	t.Run("transfer moves the grant", func(t *testing.T) {
+		fga := demoTuples(t)
+		if err := fga.UpdateTuples(
+			[]authz.Tuple{{User: "user:erin", Relation: "owner", Object: "document:doc-1"}},
+			[]authz.Tuple{{User: "user:carol", Relation: "owner", Object: "document:doc-1"}},
+		); err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := fga.Check("user:carol", "can_delete", "document:doc-1"); ok {
+			t.Error("previous owner can still delete")
+		}
+		if ok, _ := fga.Check("user:erin", "can_delete", "document:doc-1"); !ok {
+			t.Error("new owner can't delete")
+		}
+	})
+}
+
+func TestMemoryFGABatchCheckAndListUsers(t *testing.T) {
+	fga := demoTuples(t)
+
+	results, err := fga.BatchCheck([]authz.CheckRequest{
+		{User: "user:carol", Relation: "can_delete", Object: "document:doc-1"},
+		{User: "user:erin", Relation: "can_read", Object: "document:doc-1"},
+		{User: "user:erin", Relation: "no_such_relation", Object: "document:doc-1"},
+	})
+	var batchErr *authz.BatchCheckError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 || batchErr.Failed[2] == nil {
+		t.Fatalf("BatchCheck error = %v, want only check 2 failed", err)
+	}
+	if !results[0] || results[1] {
+		t.Errorf("BatchCheck = %v, want [true false ...]", results)
+	}
+
+	users, err := fga.ListUsers("document:doc-1", "can_read", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"alice", "bob", "carol", "dave"}; !reflect.DeepEqual(users, want) {
+		t.Errorf("ListUsers = %v, want %v", users, want)
+	}
+}
+
+func TestMemoryFGACycle(t *testing.T) {
+	fga := NewMemoryFGA(Model{
+		authz.TypeDocument: {
+			"a": {Computed: []string{"b"}},
+			"b": {Computed: []string{"a"}},
+		},
+	})
+	_, err := fga.Check("user:x", "a", "document:1")
+	if err == nil || !strings.Contains(err.Error(), "too deep") {
+		t.Errorf("Check = %v, want a resolution depth error", err)
+	}
+}
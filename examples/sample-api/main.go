@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/yourusername/sample-api/internal/casdoor"
 	"github.com/yourusername/sample-api/internal/handlers"
 	"github.com/yourusername/sample-api/internal/middleware"
+	"github.com/yourusername/sample-api/internal/password"
 	"github.com/yourusername/sample-api/internal/store"
 )
 
@@ -36,10 +38,24 @@ func main() {
 		log.Println("No OpenFGA store ID configured - using mock authorization")
 	}
 
+	// Leave the interface nil, not holding a nil *OpenFGAClient, so the
+	// handlers' "no OpenFGA" checks still work
+	var authorizer authz.Authorizer
+	if fgaClient != nil {
+		authorizer = fgaClient
+	}
+
 	// Check auth mode
 	authMode := getEnv("AUTH_MODE", "gateway")
 	log.Printf("Auth mode: %s", authMode)
 
+	// Direct mode rejects requests without a token unless DEV_MODE allows
+	// anonymous access; REQUIRE_AUTH=true rejects them even then
+	allowAnonymous := getEnv("DEV_MODE", "false") == "true" && getEnv("REQUIRE_AUTH", "false") != "true"
+	if authMode == "direct" && allowAnonymous {
+		log.Println("Warning: DEV_MODE allows unauthenticated requests as \"anonymous\"")
+	}
+
 	// Initialize Casdoor client (only needed for direct mode)
 	var casdoorClient *casdoor.Client
 	if authMode == "direct" {
@@ -54,34 +70,118 @@ func main() {
 		log.Println("Gateway mode: trusting headers from Traefik/AuthZ service")
 	}
 
-	// Initialize in-memory store (replace with real DB in production)
-	dataStore := store.NewMemoryStore()
+	// Initialize store (replace with real DB in production).
+	// STORE_BACKEND=file keeps data across restarts.
+	var dataStore store.Store
+	maxVersions := getEnvInt64("DOCUMENT_VERSION_LIMIT", store.DefaultMaxDocumentVersions)
+	maxDocuments := int(getEnvInt64("MAX_DOCUMENTS_PER_WORKSPACE", -1))
+	maxProjects := int(getEnvInt64("MAX_PROJECTS_PER_WORKSPACE", -1))
+	switch backend := getEnv("STORE_BACKEND", "memory"); backend {
+	case "memory":
+		memoryStore := store.NewMemoryStore()
+		memoryStore.SetMaxDocumentVersions(int(maxVersions))
+		memoryStore.SetWorkspaceQuotas(maxDocuments, maxProjects)
+		dataStore = memoryStore
+	case "file":
+		storeFile := getEnv("STORE_FILE", "data/sample-api.json")
+		fileStore, err := store.NewFileStore(storeFile)
+		if err != nil {
+			log.Fatalf("Failed to open file store: %v", err)
+		}
+		fileStore.SetMaxDocumentVersions(int(maxVersions))
+		fileStore.SetWorkspaceQuotas(maxDocuments, maxProjects)
+		dataStore = fileStore
+		log.Printf("Using file store: %s", storeFile)
+	default:
+		log.Fatalf("Unknown STORE_BACKEND %q (expected memory or file)", backend)
+	}
 
-	// Seed sample data (Casdoor manages users, we just need tenant/workspace data)
-	seedSampleData(dataStore)
-	seedData(dataStore)
+	// Seed sample data (Casdoor manages users, we just need tenant/workspace data).
+	// A persisted store keeps its data, so only seed an empty one.
+	if len(dataStore.ListTenants()) == 0 {
+		seedSampleData(dataStore)
+		seedData(dataStore)
+	}
 
 	// Initialize handlers
-	docHandler := handlers.NewDocumentHandler(dataStore, fgaClient)
-	projectHandler := handlers.NewProjectHandler(dataStore, fgaClient)
+	docHandler := handlers.NewDocumentHandler(dataStore, authorizer)
+	docHandler.StartShareJanitor(getEnvDuration("SHARE_JANITOR_INTERVAL", time.Minute))
+	docHandler.StartTrashJanitor(getEnvDuration("TRASH_RETENTION", 30*24*time.Hour))
+	docHandler.StartAuthzReconciler(getEnvDuration("AUTHZ_RECONCILE_INTERVAL", 0))
+	var projectPolicies []authz.Policy
+	if policyFile := getEnv("ABAC_POLICY_FILE", ""); policyFile != "" {
+		var err error
+		projectPolicies, err = authz.LoadPolicies(policyFile)
+		if err != nil {
+			log.Fatalf("Failed to load ABAC policies: %v", err)
+		}
+		log.Printf("Loaded %d ABAC policies from %s", len(projectPolicies), policyFile)
+	}
+	var deployFreezes authz.FreezeSchedule
+	if freezeFile := getEnv("DEPLOY_FREEZE_FILE", ""); freezeFile != "" {
+		var err error
+		deployFreezes, err = authz.LoadFreezeSchedule(freezeFile)
+		if err != nil {
+			log.Fatalf("Failed to load deploy freeze windows: %v", err)
+		}
+		log.Printf("Loaded deploy freeze windows for %d environments from %s", len(deployFreezes), freezeFile)
+	}
+	projectHandler := handlers.NewProjectHandler(dataStore, authorizer, projectPolicies, deployFreezes)
 	adminHandler := handlers.NewAdminHandler(dataStore)
-	authHandler := handlers.NewAuthHandler(casdoorClient)
+	idpResilience := casdoor.NewResilience(casdoor.ResilienceConfigFromEnv())
+	passwordPolicy := password.NewPolicy(
+		int(getEnvInt64("PASSWORD_MIN_LENGTH", 8)),
+		getEnv("PASSWORD_REQUIRE_MIXED", "true") == "true",
+		getEnv("PASSWORD_BREACH_CHECK", "true") == "true",
+	)
+	authHandler := handlers.NewAuthHandler(casdoorClient, idpResilience, passwordPolicy)
 
 	// Setup router
 	r := gin.Default()
 
-	// CORS
+	// Client IP behind proxies. Forwarding headers are only trusted from
+	// TRUSTED_PROXIES; by default none are and the peer address is used.
+	trustedProxies := getEnvList("TRUSTED_PROXIES", nil)
+	clientIPs, err := middleware.NewClientIPResolver(trustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	// Keep gin's own c.ClientIP (used by its request log) in line
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(middleware.ClientIP(clientIPs))
+
+	// CORS. Disallowed origins are rejected with 403 and never echoed back.
+	origins := middleware.NewOriginMatcher(getEnvList("CORS_ALLOWED_ORIGINS",
+		[]string{"http://localhost:3000", "http://localhost:3001", "http://localhost:5173", "http://localhost:4455"}))
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:3001", "http://localhost:5173", "http://localhost:4455"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Authorization", "Content-Type", "X-User-ID", "X-Tenant-ID", "X-Workspace-ID"},
+		AllowOriginFunc:  origins.Allowed,
+		AllowMethods:     getEnvList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+		AllowHeaders:     getEnvList("CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "X-User-ID", "X-Tenant-ID", "X-Workspace-ID"}),
 		AllowCredentials: true,
 	}))
 
-	// Health check
+	// Request body limits; document content may be larger than API payloads
+	maxDocumentBody := getEnvInt64("MAX_DOCUMENT_BODY_BYTES", 10<<20)
+	r.Use(middleware.BodyLimit(getEnvInt64("MAX_BODY_BYTES", 1<<20), map[string]int64{
+		"/api/v1/documents":     maxDocumentBody,
+		"/api/v1/documents/:id": maxDocumentBody,
+	}))
+
+	// Health check (liveness) and dependency readiness
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
+	openfgaCheck := readinessCheck{name: "openfga"}
+	if fgaClient != nil {
+		openfgaCheck.check = fgaClient.Ready
+	}
+	casdoorCheck := readinessCheck{name: "casdoor"}
+	if casdoorClient != nil {
+		casdoorCheck.check = casdoorClient.Ready
+	}
+	r.GET("/ready", readyHandler(openfgaCheck, casdoorCheck))
 
 	// Auth routes (public - headless mode)
 	authRoutes := r.Group("/api/v1/auth")
@@ -94,8 +194,8 @@ func main() {
 		authRoutes.GET("/social/:provider", authHandler.GetSocialLoginURL) // Get OAuth URL
 		// Protected auth routes (require authentication)
 		if authMode == "direct" && casdoorClient != nil {
-			authRoutes.GET("/me", middleware.CasdoorAuth(casdoorClient), authHandler.GetMe)
-			authRoutes.POST("/change-password", middleware.CasdoorAuth(casdoorClient), authHandler.ChangePassword)
+			authRoutes.GET("/me", middleware.CasdoorAuth(casdoorClient, false), authHandler.GetMe)
+			authRoutes.POST("/change-password", middleware.CasdoorAuth(casdoorClient, false), authHandler.ChangePassword)
 		} else {
 			authRoutes.GET("/me", middleware.ExtractAuthHeaders(), authHandler.GetMe)
 			authRoutes.POST("/change-password", middleware.ExtractAuthHeaders(), authHandler.ChangePassword)
@@ -106,13 +206,15 @@ func main() {
 	api := r.Group("/api/v1")
 	if authMode == "direct" && casdoorClient != nil {
 		// Direct mode: validate Casdoor JWT in this service
-		api.Use(middleware.CasdoorAuth(casdoorClient))
+		api.Use(middleware.CasdoorAuth(casdoorClient, allowAnonymous))
 		log.Println("API using direct Casdoor JWT validation")
 	} else {
 		// Gateway mode: trust headers from Traefik/AuthZ
 		api.Use(middleware.ExtractAuthHeaders())
 		log.Println("API using gateway headers (X-User-ID, X-Tenant-ID, etc.)")
 	}
+	// Record authenticated users locally for the admin endpoints
+	api.Use(middleware.ProvisionUser(dataStore))
 	{
 		// Document routes (ReBAC example)
 		docs := api.Group("/documents")
@@ -123,7 +225,16 @@ func main() {
 			docs.PUT("/:id", docHandler.Update)
 			docs.DELETE("/:id", docHandler.Delete)
 			docs.POST("/:id/share", docHandler.Share)
+			docs.POST("/:id/share/bulk", docHandler.ShareBulk)
+			docs.DELETE("/:id/share/:userId", docHandler.Unshare)
+			docs.POST("/:id/transfer-ownership", docHandler.TransferOwnership)
 			docs.GET("/:id/permissions", docHandler.GetPermissions)
+			docs.POST("/:id/can", docHandler.Can)
+			docs.GET("/:id/access", docHandler.GetAccess)
+			docs.GET("/:id/access-tree", docHandler.GetAccessTree)
+			docs.GET("/:id/versions", docHandler.ListVersions)
+			docs.GET("/:id/versions/:n", docHandler.GetVersion)
+			docs.POST("/:id/revert/:n", docHandler.Revert)
 		}
 
 		// Project routes (ABAC example)
@@ -135,6 +246,7 @@ func main() {
 			projects.PUT("/:id", projectHandler.Update)
 			projects.DELETE("/:id", projectHandler.Delete)
 			projects.POST("/:id/deploy", projectHandler.Deploy)
+			projects.POST("/:id/can", projectHandler.Can)
 		}
 
 		// Permission check endpoint
@@ -149,6 +261,13 @@ func main() {
 				return
 			}
 
+			for _, ref := range []string{req.User, req.Object} {
+				if _, err := authz.ParseObjectRef(ref); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+			}
+
 			if fgaClient == nil {
 				c.JSON(http.StatusOK, gin.H{"allowed": true, "mock": true})
 				return
@@ -163,6 +282,9 @@ func main() {
 			c.JSON(http.StatusOK, gin.H{"allowed": allowed})
 		})
 
+		// Batch permission check endpoint
+		api.POST("/check-permissions", handlers.CheckPermissions(authorizer))
+
 		// Admin routes (require platform admin)
 		admin := api.Group("/admin")
 		admin.Use(middleware.RequirePlatformAdmin())
@@ -188,6 +310,14 @@ func main() {
 			// View all resources
 			admin.GET("/documents", adminHandler.ListAllDocuments)
 			admin.GET("/projects", adminHandler.ListAllProjects)
+
+			// Trash (soft-deleted documents and projects)
+			admin.GET("/trash", adminHandler.ListTrash)
+			admin.POST("/trash/documents/:id/restore", adminHandler.RestoreDocument)
+			admin.POST("/trash/projects/:id/restore", adminHandler.RestoreProject)
+
+			// Store-to-OpenFGA drift (dry run unless ?dry_run=false)
+			admin.POST("/reconcile-authz", docHandler.ReconcileAuthz)
 		}
 	}
 
@@ -196,7 +326,7 @@ func main() {
 	r.Run(":" + port)
 }
 
-func seedSampleData(s *store.MemoryStore) {
+func seedSampleData(s store.Store) {
 	// Create sample tenants (organizations in Casdoor map to tenants here)
 	tenants := []*store.Tenant{
 		{
@@ -232,7 +362,7 @@ func seedSampleData(s *store.MemoryStore) {
 	log.Printf("Seeded %d tenants, %d workspaces", len(tenants), len(workspaces))
 }
 
-func seedData(s *store.MemoryStore) {
+func seedData(s store.Store) {
 	// Seed some sample documents
 	s.CreateDocument(&store.Document{
 		ID:          "doc-1",
@@ -295,6 +425,38 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+func getEnvInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList reads a comma-separated list, ignoring blank entries
+func getEnvList(key string, defaultValue []string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
+
 func getStoreID() string {
 	// First check for direct environment variable
 	if storeID := os.Getenv("OPENFGA_STORE_ID"); storeID != "" {
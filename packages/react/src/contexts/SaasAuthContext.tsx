@@ -46,6 +46,7 @@ interface SaasAuthContextValue {
   verifyEmail: (token: string) => Promise<void>
   forgotPassword: (email: string) => Promise<void>
   resetPassword: (token: string, newPassword: string) => Promise<void>
+  resendLink: (token: string, type: 'verification' | 'password_reset') => Promise<void>
   logout: () => void
   refreshUser: () => Promise<void>
 
@@ -293,6 +294,20 @@ export function SaasAuthProvider({
     [api]
   )
 
+  const resendLink = useCallback(
+    async (token: string, type: 'verification' | 'password_reset') => {
+      setError(null)
+      try {
+        await api.post('/api/v1/auth/resend-link', { token, type })
+      } catch (err) {
+        const apiError = err as ApiError
+        setError({ code: apiError.error || 'resend_error', message: apiError.message || 'Request failed' })
+        throw err
+      }
+    },
+    [api]
+  )
+
   const refreshUser = useCallback(async () => {
     const token = storage.getItem('token')
     if (!token || isTokenExpired(token)) {
@@ -485,6 +500,7 @@ export function SaasAuthProvider({
     verifyEmail,
     forgotPassword,
     resetPassword,
+    resendLink,
     logout,
     refreshUser,
 
@@ -4,6 +4,7 @@ import type { ReactNode } from 'react'
 export interface User {
   id: string
   email: string
+  username?: string | null
   name: string
   picture?: string
   auth_provider: string
@@ -17,7 +18,7 @@ export interface User {
 }
 
 // Auth types
-export type AuthProvider = 'google' | 'github' | 'local'
+export type AuthProvider = 'google' | 'github' | 'microsoft' | 'local'
 export type PlanTier = 'basic' | 'advanced' | 'enterprise'
 
 export interface AuthError {
@@ -28,6 +29,7 @@ export interface AuthError {
 export interface ApiError {
   error: string
   message: string
+  fields?: Record<string, string>
   details?: unknown
 }
 
@@ -91,6 +93,7 @@ export interface Plan {
   max_users: number
   monthly_price: number
   annual_price: number
+  trial_days: number
   allows_on_prem: boolean
   features: string[]
   is_active: boolean
@@ -103,6 +106,8 @@ export interface Subscription {
   status: 'active' | 'cancelled' | 'past_due' | 'trialing'
   current_period_start: string
   current_period_end: string
+  trial_ends_at?: string
+  trial_days_left?: number
   plan?: Plan
 }
 